@@ -0,0 +1,114 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+
+	"echo-base/events"
+)
+
+// Counter names
+const (
+	CounterLogins        = "logins"
+	CounterRegistrations = "registrations"
+	CounterFailedLogins  = "failed_logins"
+)
+
+// counterNames lists every counter, in the order they are reported
+var counterNames = []string{CounterLogins, CounterRegistrations, CounterFailedLogins}
+
+// Store persists counter values across restarts
+type Store interface {
+	// Load returns the stored value of every persisted counter
+	Load() (map[string]int64, error)
+
+	// Add increments a stored counter by delta
+	Add(name string, delta int64) error
+
+	// Reset zeroes every stored counter
+	Reset() error
+}
+
+// Counters holds lifetime counters updated from domain events. Values live in memory
+// and, when a store is set, are also written through to it.
+type Counters struct {
+	values map[string]*atomic.Int64
+	store  Store
+}
+
+// NewCounters creates counters starting at zero; store may be nil to keep them in memory only
+func NewCounters(store Store) *Counters {
+	values := make(map[string]*atomic.Int64, len(counterNames))
+	for _, name := range counterNames {
+		values[name] = new(atomic.Int64)
+	}
+
+	return &Counters{
+		values: values,
+		store:  store,
+	}
+}
+
+// Load restores the counters from the store, first zeroing it when reset is set
+func (c *Counters) Load(reset bool) error {
+	if c.store == nil {
+		return nil
+	}
+
+	if reset {
+		return c.store.Reset()
+	}
+
+	stored, err := c.store.Load()
+	if err != nil {
+		return err
+	}
+	for name, value := range stored {
+		if counter, ok := c.values[name]; ok {
+			counter.Store(value)
+		}
+	}
+	return nil
+}
+
+// Incr increments a counter; persistence errors are only logged
+func (c *Counters) Incr(name string) {
+	counter, ok := c.values[name]
+	if !ok {
+		return
+	}
+	counter.Add(1)
+
+	if c.store != nil {
+		if err := c.store.Add(name, 1); err != nil {
+			log.Printf("error persisting counter %s: %v\n", name, err)
+		}
+	}
+}
+
+// Snapshot returns the current value of every counter
+func (c *Counters) Snapshot() map[string]int64 {
+	snapshot := make(map[string]int64, len(c.values))
+	for name, counter := range c.values {
+		snapshot[name] = counter.Load()
+	}
+	return snapshot
+}
+
+// Subscribe increments the counters from login and registration events. Events are
+// delivered at least once by the outbox relay, so counts are approximate.
+func Subscribe(bus events.Bus, c *Counters) {
+	counted := map[string]string{
+		events.UserLoggedIn:   CounterLogins,
+		events.UserRegistered: CounterRegistrations,
+		events.LoginFailed:    CounterFailedLogins,
+	}
+
+	for event, counter := range counted {
+		bus.Subscribe(event, func(ctx context.Context, _ events.Event) error {
+			c.Incr(counter)
+			return nil
+		})
+	}
+}
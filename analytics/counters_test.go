@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeStore keeps persisted counters in a map
+type fakeStore struct {
+	mu     sync.Mutex
+	values map[string]int64
+	err    error
+}
+
+func (s *fakeStore) Load() (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	values := make(map[string]int64, len(s.values))
+	for name, value := range s.values {
+		values[name] = value
+	}
+	return values, nil
+}
+
+func (s *fakeStore) Add(name string, delta int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.values[name] += delta
+	return nil
+}
+
+func (s *fakeStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = make(map[string]int64)
+	return s.err
+}
+
+func TestCountersLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    map[string]int64
+		reset     bool
+		storeErr  error
+		want      map[string]int64
+		wantErr   bool
+		wantStore map[string]int64
+	}{
+		{
+			name:      "restores persisted values",
+			stored:    map[string]int64{CounterLogins: 5, CounterRegistrations: 2, "retired": 9},
+			want:      map[string]int64{CounterLogins: 6, CounterRegistrations: 2, CounterFailedLogins: 0},
+			wantStore: map[string]int64{CounterLogins: 6, CounterRegistrations: 2, "retired": 9},
+		},
+		{
+			name:      "reset at startup",
+			stored:    map[string]int64{CounterLogins: 5},
+			reset:     true,
+			want:      map[string]int64{CounterLogins: 1, CounterRegistrations: 0, CounterFailedLogins: 0},
+			wantStore: map[string]int64{CounterLogins: 1},
+		},
+		{name: "store unavailable", stored: map[string]int64{}, storeErr: errors.New("connection refused"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{values: tt.stored, err: tt.storeErr}
+			counters := NewCounters(store)
+
+			err := counters.Load(tt.reset)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			counters.Incr(CounterLogins)
+			counters.Incr("unknown")
+
+			if got := counters.Snapshot(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counters = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(store.values, tt.wantStore) {
+				t.Errorf("stored = %v, want %v", store.values, tt.wantStore)
+			}
+		})
+	}
+}
+
+func TestCountersConcurrentIncr(t *testing.T) {
+	counters := NewCounters(nil)
+	if err := counters.Load(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				counters.Incr(CounterRegistrations)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := counters.Snapshot()[CounterRegistrations]; got != 1000 {
+		t.Errorf("registrations = %d, want 1000", got)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps size suffixes to their multiplier
+var byteUnits = map[string]int64{
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// ParseByteSize parses a positive size in bytes such as "512", "64K", "1M" or "1G"
+func ParseByteSize(s string) (int64, error) {
+	number, multiplier := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for suffix, unit := range byteUnits {
+		if trimmed, ok := strings.CutSuffix(number, suffix); ok {
+			number, multiplier = trimmed, unit
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q: must be a positive number of bytes, optionally suffixed with K, M or G", s)
+	}
+	return n * multiplier, nil
+}
+
+// BodyLimits parses BodyLimit and BodyLimitRoutes, returning the default request body
+// limit and the limits of the routes that override it, keyed by route path
+func (c *Config) BodyLimits() (int64, map[string]int64, error) {
+	limit, err := ParseByteSize(c.BodyLimit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid BODY_LIMIT: %w", err)
+	}
+
+	routes := make(map[string]int64, len(c.BodyLimitRoutes))
+	for _, entry := range c.BodyLimitRoutes {
+		path, size, found := strings.Cut(entry, "=")
+		if !found || !strings.HasPrefix(path, "/") {
+			return 0, nil, fmt.Errorf("invalid BODY_LIMIT_ROUTES entry %q: must be /path=size", entry)
+		}
+		routeLimit, err := ParseByteSize(size)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid BODY_LIMIT_ROUTES entry %q: %w", entry, err)
+		}
+		routes[path] = routeLimit
+	}
+	return limit, routes, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "512", want: 512},
+		{size: "64K", want: 64 << 10},
+		{size: "1m", want: 1 << 20},
+		{size: " 2G ", want: 2 << 30},
+		{size: "", wantErr: true},
+		{size: "0", wantErr: true},
+		{size: "-1M", wantErr: true},
+		{size: "1MB", wantErr: true},
+		{size: "M", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseByteSize(tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      string
+		routes     []string
+		wantLimit  int64
+		wantRoutes map[string]int64
+		wantErr    bool
+	}{
+		{name: "default only", limit: "1M", wantLimit: 1 << 20, wantRoutes: map[string]int64{}},
+		{
+			name:       "route overrides",
+			limit:      "1M",
+			routes:     []string{"/api/v1/uploads=10M", "/api/v1/users/:id=4K"},
+			wantLimit:  1 << 20,
+			wantRoutes: map[string]int64{"/api/v1/uploads": 10 << 20, "/api/v1/users/:id": 4 << 10},
+		},
+		{name: "invalid default", limit: "lots", wantErr: true},
+		{name: "route without a size", limit: "1M", routes: []string{"/api/v1/uploads"}, wantErr: true},
+		{name: "route without a leading slash", limit: "1M", routes: []string{"uploads=10M"}, wantErr: true},
+		{name: "route with an invalid size", limit: "1M", routes: []string{"/api/v1/uploads=big"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{BodyLimit: tt.limit, BodyLimitRoutes: tt.routes}
+
+			limit, routes, err := cfg.BodyLimits()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if limit != tt.wantLimit || !reflect.DeepEqual(routes, tt.wantRoutes) {
+				t.Errorf("BodyLimits() = %d, %v; want %d, %v", limit, routes, tt.wantLimit, tt.wantRoutes)
+			}
+		})
+	}
+}
@@ -1,24 +1,532 @@
 package config
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogHeaders are logged when LOG_HEADERS is unset. The request ID is always logged.
+var defaultLogHeaders = []string{"X-Forwarded-For", "User-Agent"}
+
+// defaultReservedUsernames cannot be claimed when RESERVED_USERNAMES is unset
+var defaultReservedUsernames = []string{"admin", "api", "me", "root", "support", "system"}
+
+// defaultAPIKeyScopes are the scopes API keys may carry when API_KEY_SCOPES is unset
+var defaultAPIKeyScopes = []string{"read", "write"}
+
+// defaultLoadShedExemptPaths are never shed when LOAD_SHED_EXEMPT_PATHS is unset
+var defaultLoadShedExemptPaths = []string{"/health", "/health/live", "/health/ready"}
+
+// defaultCORSAllowedMethods and defaultCORSAllowedHeaders are used when
+// CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS are unset
+var (
+	defaultCORSAllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"}
+	defaultCORSAllowedHeaders = []string{"Content-Type", "Authorization", "X-API-Key"}
+)
+
+// defaultCompressionExcludedTypes are never compressed when COMPRESSION_EXCLUDED_TYPES is unset
+var defaultCompressionExcludedTypes = []string{"image/", "video/", "audio/", "application/zip", "application/gzip", "text/event-stream"}
+
+// defaultStatsSignupWindows are counted in admin stats when STATS_SIGNUP_WINDOWS is unset
+var defaultStatsSignupWindows = []string{"24h", "7d", "30d"}
+
+// developmentTOTPEncryptionKey encrypts TOTP secrets outside production when
+// TOTP_ENCRYPTION_KEY is unset
+const developmentTOTPEncryptionKey = "change-me-totp-encryption-key"
+
+// appEnvs are the accepted APP_ENV values
+var appEnvs = []string{"development", "test", "staging", "production"}
+
+// envErrors collects the environment values that failed to parse while loading, which
+// fall back to their defaults. Each loader takes them over so they are reported.
+var (
+	envErrorsMu sync.Mutex
+	envErrors   []error
 )
 
 // Config holds application configuration
 type Config struct {
+	// loadErrs are the values Load could not parse; Validate reports them
+	loadErrs []error
+
 	AppName string
 	AppEnv  string
 	Port    string
+
+	// ShutdownTimeout is how long in-flight requests may run after a shutdown signal
+	ShutdownTimeout time.Duration
+
+	// HealthCheckTimeout bounds the database ping of the readiness probe
+	HealthCheckTimeout time.Duration
+
+	// AuthDisabled makes protected routes act as the fake AuthDisabledUser* user instead of
+	// validating tokens. For tests and local development only; refused in production.
+	AuthDisabled       bool
+	AuthDisabledUserID int64
+	AuthDisabledEmail  string
+	AuthDisabledRoleID int64
+
+	// RequestIDHeader carries the request correlation ID in and out of the service
+	RequestIDHeader string
+
+	// LogFormat is the access log format: "text" or "json" (one object per request)
+	LogFormat string
+
+	// LogSlowOnly only logs requests slower than LogSlowThreshold (and failed requests)
+	LogSlowOnly      bool
+	LogSlowThreshold time.Duration
+
+	// LogHeaders are the request headers included in access logs. Sensitive headers
+	// (Authorization, cookies) are redacted unless LogSensitiveHeaders is enabled.
+	LogHeaders          []string
+	LogSensitiveHeaders bool
+
+	// UsernameRequired makes the username mandatory at registration
+	UsernameRequired bool
+
+	// UsernameLowercase stores usernames in lowercase. Usernames are unique regardless of case
+	// either way, and ReservedUsernames cannot be claimed in any case.
+	UsernameLowercase bool
+	ReservedUsernames []string
+
+	// FrontendURL is the base URL used to build links in emails (reset, verify)
+	FrontendURL string
+
+	// RequestTxGroups lists the route groups (admin, users, profile) whose requests each
+	// run in a single database transaction
+	RequestTxGroups []string
+
+	// NoStoreAuthenticated sends Cache-Control: no-store, private on protected routes
+	NoStoreAuthenticated bool
+
+	// HeadRequests answers HEAD requests on GET routes with headers and no body
+	HeadRequests bool
+
+	// StripPathPrefix is removed from incoming request paths before routing
+	StripPathPrefix string
+
+	// JSONPretty indents JSON responses (defaults to on in development)
+	JSONPretty bool
+
+	// ResponseMeta adds api_version and server_time to response envelopes
+	ResponseMeta bool
+
+	// EmailDomainDenyList blocks registration from these email domains.
+	// When EmailDomainAllowList is set, only its domains may register instead.
+	EmailDomainDenyList     []string
+	EmailDomainDenyListFile string
+	EmailDomainAllowList    []string
+
+	// StrictEmailMX rejects registrations whose email domain has no MX record. Lookups
+	// time out after EmailMXTimeout; lookup errors are ignored unless EmailMXFailClosed is set.
+	StrictEmailMX     bool
+	EmailMXTimeout    time.Duration
+	EmailMXFailClosed bool
+
+	// OutboxPollInterval and OutboxBatchSize control the outbox relay. A failed event is
+	// retried after OutboxRetryBackoff, doubling with each failure, and dead-lettered after
+	// OutboxMaxAttempts. OutboxLease is how long a run may take to dispatch a claimed batch
+	// before other runs deliver it again.
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxMaxAttempts  int
+	OutboxRetryBackoff time.Duration
+	OutboxLease        time.Duration
+
+	// SessionIdleTimeout logs out sessions idle for longer than this (0 disables).
+	// SessionActivityWriteInterval throttles last-activity writes.
+	SessionIdleTimeout           time.Duration
+	SessionActivityWriteInterval time.Duration
+
+	// AuthCheckUserStatus looks the user up on every authenticated request so tokens of
+	// suspended or deleted users stop working before they expire (costs a query per request)
+	AuthCheckUserStatus bool
+
+	// Suspicious login detection: flag logins from devices unseen within the lookback
+	// window, or when active sessions within the session window exceed the max (0 disables)
+	SuspiciousLoginNewDevice     bool
+	SuspiciousLoginLookback      time.Duration
+	SuspiciousLoginMaxSessions   int
+	SuspiciousLoginSessionWindow time.Duration
+
+	// LoginHistoryMaskIP coarsens IPs in the caller's login history to their /24 (IPv4) or /48 (IPv6)
+	LoginHistoryMaskIP bool
+
+	// Password complexity policy enforced at registration and by generated passwords
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+
+	// StatsSignupWindows are the default windows (e.g. 24h, 7d) for recent-signup counts in admin stats
+	StatsSignupWindows []string
+
+	// PaginationMaxOffset rejects pages whose offset ((page-1)*limit) exceeds it (0 disables)
+	PaginationMaxOffset int64
+
+	// ValidationMaxErrors caps the field errors returned per response (0 returns all)
+	ValidationMaxErrors int
+
+	// ValidationLocales are the languages validation messages can be returned in, chosen
+	// by Accept-Language (en, es, fr, id); ValidationDefaultLocale is used otherwise
+	ValidationLocales       []string
+	ValidationDefaultLocale string
+
+	// TOTPIssuer names the service in authenticator apps; TOTPEncryptionKey encrypts stored TOTP secrets
+	TOTPIssuer        string
+	TOTPEncryptionKey string
+
+	// RefreshTokenRotate issues a new refresh token on every refresh; the old one stays
+	// valid until it expires
+	RefreshTokenRotate bool
+
+	// TrustedDeviceTTL is how long a remembered device stays trusted (0 disables remembering devices)
+	TrustedDeviceTTL time.Duration
+
+	// APIKeysEnabled lets users create API keys under /profile/api-keys, at most
+	// APIKeysMaxPerUser active at once, and authenticate protected routes with one in
+	// the X-API-Key header; keys may only carry scopes from APIKeyScopes, and need
+	// "read" for GET and HEAD requests and "write" for the rest
+	APIKeysEnabled    bool
+	APIKeysMaxPerUser int
+	APIKeyScopes      []string
+
+	// RolesPublic exposes the role list without authentication (otherwise admin-only);
+	// RolesCacheTTL caches it in memory (0 disables caching)
+	RolesPublic   bool
+	RolesCacheTTL time.Duration
+
+	// RolesAutoCreate recreates missing required roles at startup instead of refusing to start
+	RolesAutoCreate bool
+
+	// StatsCountersPersist stores the lifetime login/registration counters in the database
+	// so they survive restarts; StatsCountersResetOnStart zeroes the stored counters at boot
+	StatsCountersPersist      bool
+	StatsCountersResetOnStart bool
+
+	// SessionRetention is how long inactive sessions are kept before the cleanup job deletes them
+	SessionRetention       time.Duration
+	SessionCleanupInterval time.Duration
+
+	// MetricsEnabled records request and database pool metrics and serves them at /metrics
+	MetricsEnabled bool
+
+	// SeedAdmin creates an admin from AdminName, AdminEmail and AdminPassword at startup
+	// when no admin exists yet (also enabled by the -seed flag)
+	SeedAdmin     bool
+	AdminName     string
+	AdminEmail    string
+	AdminPassword string
+
+	// CORSAllowedOrigins are the origins browsers may call the API from; when empty any
+	// origin is allowed without credentials. CORSAllowedMethods and CORSAllowedHeaders
+	// are the methods and request headers allowed in cross-origin requests.
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+
+	// BodyLimit is the largest request body accepted, such as "1M"; BodyLimitRoutes
+	// overrides it for individual routes as "/path=size" entries
+	BodyLimit       string
+	BodyLimitRoutes []string
+
+	// CompressionEnabled gzips responses for clients that accept it. CompressionLevel is
+	// the gzip level (-1 or 0 for the default, 1-9), bodies shorter than CompressionMinLength
+	// bytes are sent as they are, and so are responses whose Content-Type starts with one
+	// of CompressionExcludedTypes.
+	CompressionEnabled       bool
+	CompressionLevel         int
+	CompressionMinLength     int
+	CompressionExcludedTypes []string
+
+	// ErrorReportURL receives panics and 5xx errors as JSON POSTs (empty disables reporting)
+	ErrorReportURL     string
+	ErrorReportTimeout time.Duration
+
+	// AlertWebhookURL and AlertEmails receive operator alerts; alerting is disabled when
+	// neither is set. An alert is sent when AlertErrorRatePercent of the requests in
+	// AlertErrorRateWindow end in a 5xx status (0 disables, and windows with fewer than
+	// AlertErrorRateMinRequests requests are ignored) or when the database health check,
+	// run every AlertHealthCheckInterval, fails AlertHealthCheckFailures times in a row.
+	// AlertCooldown is the minimum time between two alerts of the same kind.
+	AlertWebhookURL           string
+	AlertEmails               []string
+	AlertErrorRatePercent     int
+	AlertErrorRateWindow      time.Duration
+	AlertErrorRateMinRequests int
+	AlertHealthCheckInterval  time.Duration
+	AlertHealthCheckFailures  int
+	AlertCooldown             time.Duration
+
+	// Mail settings; emails are logged instead of sent when SMTPHost is empty
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	MailFrom          string
+	EmailTemplatesDir string
+
+	// WelcomeEmailEnabled sends a welcome email after registration
+	WelcomeEmailEnabled bool
+
+	// RequireEmailVerification blocks login until the user opens the verification link
+	// emailed on registration; links expire after EmailVerificationExpiration
+	RequireEmailVerification    bool
+	EmailVerificationExpiration time.Duration
+
+	// EmailChangeConfirm keeps an email change pending until the link emailed to the new
+	// address is opened. Otherwise the address changes at once and, when verification is
+	// required, the account is unverified until the new address is confirmed.
+	EmailChangeConfirm bool
+
+	// PasswordResetExpiration is how long a forgot-password link stays valid. Reset tokens
+	// are stateless, so all of a user's outstanding links stop working at the first reset.
+	// There is deliberately no setting capping how many a user may hold: nothing records
+	// issued tokens, and this expiration plus the auth rate limit already bound them.
+	PasswordResetExpiration time.Duration
+
+	// RateLimitPerMinute limits requests per caller on protected routes (0 disables);
+	// RateLimitBurst defaults to RateLimitPerMinute
+	RateLimitPerMinute int
+	RateLimitBurst     int
+
+	// AuthRateLimitPerMinute limits attempts on the auth endpoints per client IP (0 disables),
+	// and per email address too when AuthRateLimitByEmail is set; AuthRateLimitBurst
+	// defaults to AuthRateLimitPerMinute
+	AuthRateLimitPerMinute int
+	AuthRateLimitBurst     int
+	AuthRateLimitByEmail   bool
+
+	// Load shedding returns 503 for new requests while the average latency exceeds
+	// LoadShedLatency or more than LoadShedMaxInFlight requests are in progress (0 disables
+	// either trigger). LoadShedWindow is how quickly the latency average forgets old
+	// requests; LoadShedExemptPaths are route paths that are never shed.
+	LoadShedLatency     time.Duration
+	LoadShedMaxInFlight int
+	LoadShedWindow      time.Duration
+	LoadShedExemptPaths []string
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
-	return &Config{
+	appEnv := getEnv("APP_ENV", "development")
+
+	logHeaders := getEnvList("LOG_HEADERS")
+	if logHeaders == nil {
+		logHeaders = defaultLogHeaders
+	}
+
+	reservedUsernames := getEnvList("RESERVED_USERNAMES")
+	if reservedUsernames == nil {
+		reservedUsernames = defaultReservedUsernames
+	}
+
+	apiKeyScopes := getEnvList("API_KEY_SCOPES")
+	if apiKeyScopes == nil {
+		apiKeyScopes = defaultAPIKeyScopes
+	}
+
+	corsAllowedMethods := getEnvList("CORS_ALLOWED_METHODS")
+	if corsAllowedMethods == nil {
+		corsAllowedMethods = defaultCORSAllowedMethods
+	}
+
+	corsAllowedHeaders := getEnvList("CORS_ALLOWED_HEADERS")
+	if corsAllowedHeaders == nil {
+		corsAllowedHeaders = defaultCORSAllowedHeaders
+	}
+
+	compressionExcludedTypes := getEnvList("COMPRESSION_EXCLUDED_TYPES")
+	if compressionExcludedTypes == nil {
+		compressionExcludedTypes = defaultCompressionExcludedTypes
+	}
+
+	loadShedExemptPaths := getEnvList("LOAD_SHED_EXEMPT_PATHS")
+	if loadShedExemptPaths == nil {
+		loadShedExemptPaths = defaultLoadShedExemptPaths
+	}
+
+	statsSignupWindows := getEnvList("STATS_SIGNUP_WINDOWS")
+	if statsSignupWindows == nil {
+		statsSignupWindows = defaultStatsSignupWindows
+	}
+
+	// Production must set its own key (checked by Validate); elsewhere a development key is used
+	totpEncryptionKey := getEnv("TOTP_ENCRYPTION_KEY", "")
+	if totpEncryptionKey == "" && appEnv != "production" {
+		totpEncryptionKey = developmentTOTPEncryptionKey
+	}
+
+	cfg := &Config{
 		AppName: getEnv("APP_NAME", ""),
-		AppEnv:  getEnv("APP_ENV", "development"),
+		AppEnv:  appEnv,
 		Port:    getEnv("PORT", "8080"),
+
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second),
+		HealthCheckTimeout: getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+
+		AuthDisabled:       getEnvBool("AUTH_DISABLED", false),
+		AuthDisabledUserID: int64(getEnvInt("AUTH_DISABLED_USER_ID", 1)),
+		AuthDisabledEmail:  getEnv("AUTH_DISABLED_EMAIL", "test@example.com"),
+		AuthDisabledRoleID: int64(getEnvInt("AUTH_DISABLED_ROLE_ID", 1)),
+
+		RequestIDHeader: getEnv("REQUEST_ID_HEADER", "X-Request-ID"),
+
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+
+		LogSlowOnly:      getEnvBool("LOG_SLOW_ONLY", false),
+		LogSlowThreshold: getEnvDuration("LOG_SLOW_THRESHOLD", 500*time.Millisecond),
+
+		LogHeaders:          logHeaders,
+		LogSensitiveHeaders: getEnvBool("LOG_SENSITIVE_HEADERS_DANGEROUS", false),
+
+		UsernameRequired:  getEnvBool("USERNAME_REQUIRED", false),
+		UsernameLowercase: getEnvBool("USERNAME_LOWERCASE", true),
+		ReservedUsernames: reservedUsernames,
+
+		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:3000"),
+
+		RequestTxGroups: getEnvList("REQUEST_TX_GROUPS"),
+
+		NoStoreAuthenticated: getEnvBool("NO_STORE_AUTHENTICATED", true),
+
+		HeadRequests: getEnvBool("HEAD_REQUESTS", true),
+
+		StripPathPrefix: getEnv("STRIP_PATH_PREFIX", ""),
+
+		JSONPretty:   getEnvBool("JSON_PRETTY", appEnv == "development"),
+		ResponseMeta: getEnvBool("RESPONSE_META", false),
+
+		EmailDomainDenyList:     getEnvList("EMAIL_DOMAIN_DENYLIST"),
+		EmailDomainDenyListFile: getEnv("EMAIL_DOMAIN_DENYLIST_FILE", ""),
+		EmailDomainAllowList:    getEnvList("EMAIL_DOMAIN_ALLOWLIST"),
+
+		StrictEmailMX:     getEnvBool("STRICT_EMAIL_MX", false),
+		EmailMXTimeout:    getEnvDuration("EMAIL_MX_TIMEOUT", 2*time.Second),
+		EmailMXFailClosed: getEnvBool("EMAIL_MX_FAIL_CLOSED", false),
+
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		OutboxBatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 100),
+		OutboxMaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxRetryBackoff: getEnvDuration("OUTBOX_RETRY_BACKOFF", 30*time.Second),
+		OutboxLease:        getEnvDuration("OUTBOX_LEASE", 5*time.Minute),
+
+		SessionIdleTimeout:           getEnvDuration("SESSION_IDLE_TIMEOUT", 0),
+		SessionActivityWriteInterval: getEnvDuration("SESSION_ACTIVITY_WRITE_INTERVAL", time.Minute),
+
+		AuthCheckUserStatus: getEnvBool("AUTH_CHECK_USER_STATUS", false),
+
+		SuspiciousLoginNewDevice:     getEnvBool("SUSPICIOUS_LOGIN_NEW_DEVICE", true),
+		SuspiciousLoginLookback:      getEnvDuration("SUSPICIOUS_LOGIN_LOOKBACK", 30*24*time.Hour),
+		SuspiciousLoginMaxSessions:   getEnvInt("SUSPICIOUS_LOGIN_MAX_SESSIONS", 5),
+		SuspiciousLoginSessionWindow: getEnvDuration("SUSPICIOUS_LOGIN_SESSION_WINDOW", 24*time.Hour),
+
+		LoginHistoryMaskIP: getEnvBool("LOGIN_HISTORY_MASK_IP", false),
+
+		PasswordMinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 6),
+		PasswordRequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:  getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+
+		StatsSignupWindows: statsSignupWindows,
+
+		PaginationMaxOffset: int64(getEnvInt("PAGINATION_MAX_OFFSET", 10000)),
+
+		ValidationMaxErrors: getEnvInt("VALIDATION_MAX_ERRORS", 0),
+
+		ValidationLocales:       getEnvList("VALIDATION_LOCALES"),
+		ValidationDefaultLocale: getEnv("VALIDATION_DEFAULT_LOCALE", "en"),
+
+		TOTPIssuer:        getEnv("TOTP_ISSUER", "echo-base"),
+		TOTPEncryptionKey: totpEncryptionKey,
+
+		RefreshTokenRotate: getEnvBool("REFRESH_TOKEN_ROTATE", true),
+		TrustedDeviceTTL:   getEnvDuration("TRUSTED_DEVICE_TTL", 30*24*time.Hour),
+
+		APIKeysEnabled:    getEnvBool("API_KEYS_ENABLED", false),
+		APIKeysMaxPerUser: getEnvInt("API_KEYS_MAX_PER_USER", 5),
+		APIKeyScopes:      apiKeyScopes,
+
+		RolesPublic:   getEnvBool("ROLES_PUBLIC", false),
+		RolesCacheTTL: getEnvDuration("ROLES_CACHE_TTL", 5*time.Minute),
+
+		RolesAutoCreate: getEnvBool("ROLES_AUTO_CREATE", false),
+
+		StatsCountersPersist:      getEnvBool("STATS_COUNTERS_PERSIST", false),
+		StatsCountersResetOnStart: getEnvBool("STATS_COUNTERS_RESET_ON_START", false),
+
+		SessionRetention:       getEnvDuration("SESSION_RETENTION", 30*24*time.Hour),
+		SessionCleanupInterval: getEnvDuration("SESSION_CLEANUP_INTERVAL", time.Hour),
+
+		MetricsEnabled: getEnvBool("METRICS_ENABLED", false),
+
+		SeedAdmin:     getEnvBool("SEED_ADMIN", false),
+		AdminName:     getEnv("ADMIN_NAME", "Admin"),
+		AdminEmail:    getEnv("ADMIN_EMAIL", ""),
+		AdminPassword: getEnv("ADMIN_PASSWORD", ""),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS"),
+		CORSAllowedMethods: corsAllowedMethods,
+		CORSAllowedHeaders: corsAllowedHeaders,
+
+		BodyLimit:       getEnv("BODY_LIMIT", "1M"),
+		BodyLimitRoutes: getEnvList("BODY_LIMIT_ROUTES"),
+
+		CompressionEnabled:       getEnvBool("COMPRESSION_ENABLED", true),
+		CompressionLevel:         getEnvInt("COMPRESSION_LEVEL", -1),
+		CompressionMinLength:     getEnvInt("COMPRESSION_MIN_LENGTH", 1024),
+		CompressionExcludedTypes: compressionExcludedTypes,
+
+		ErrorReportURL:     getEnv("ERROR_REPORT_URL", ""),
+		ErrorReportTimeout: getEnvDuration("ERROR_REPORT_TIMEOUT", 5*time.Second),
+
+		AlertWebhookURL:           getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertEmails:               getEnvList("ALERT_EMAILS"),
+		AlertErrorRatePercent:     getEnvInt("ALERT_ERROR_RATE_PERCENT", 10),
+		AlertErrorRateWindow:      getEnvDuration("ALERT_ERROR_RATE_WINDOW", 5*time.Minute),
+		AlertErrorRateMinRequests: getEnvInt("ALERT_ERROR_RATE_MIN_REQUESTS", 20),
+		AlertHealthCheckInterval:  getEnvDuration("ALERT_HEALTH_CHECK_INTERVAL", 30*time.Second),
+		AlertHealthCheckFailures:  getEnvInt("ALERT_HEALTH_CHECK_FAILURES", 3),
+		AlertCooldown:             getEnvDuration("ALERT_COOLDOWN", 15*time.Minute),
+
+		SMTPHost:          getEnv("SMTP_HOST", ""),
+		SMTPPort:          getEnv("SMTP_PORT", "587"),
+		SMTPUsername:      getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:      getEnv("SMTP_PASSWORD", ""),
+		MailFrom:          getEnv("MAIL_FROM", "no-reply@localhost"),
+		EmailTemplatesDir: getEnv("EMAIL_TEMPLATES_DIR", ""),
+
+		WelcomeEmailEnabled: getEnvBool("WELCOME_EMAIL_ENABLED", true),
+
+		RequireEmailVerification:    getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		EmailChangeConfirm:          getEnvBool("EMAIL_CHANGE_CONFIRM", false),
+		EmailVerificationExpiration: getEnvDuration("EMAIL_VERIFICATION_EXPIRATION", 24*time.Hour),
+		PasswordResetExpiration:     getEnvDuration("PASSWORD_RESET_EXPIRATION", time.Hour),
+
+		RateLimitPerMinute: getEnvInt("RATE_LIMIT_PER_MINUTE", 0),
+		RateLimitBurst:     getEnvInt("RATE_LIMIT_BURST", 0),
+
+		AuthRateLimitPerMinute: getEnvInt("AUTH_RATE_LIMIT_PER_MINUTE", 10),
+		AuthRateLimitBurst:     getEnvInt("AUTH_RATE_LIMIT_BURST", 0),
+		AuthRateLimitByEmail:   getEnvBool("AUTH_RATE_LIMIT_BY_EMAIL", true),
+
+		LoadShedLatency:     getEnvDuration("LOAD_SHED_LATENCY", 0),
+		LoadShedMaxInFlight: getEnvInt("LOAD_SHED_MAX_IN_FLIGHT", 0),
+		LoadShedWindow:      getEnvDuration("LOAD_SHED_WINDOW", 10*time.Second),
+		LoadShedExemptPaths: loadShedExemptPaths,
 	}
+	cfg.loadErrs = takeEnvErrors()
+	return cfg
 }
 
 // getEnv gets environment variable with default value
@@ -38,11 +546,72 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
+		recordEnvError(key, value, "an integer")
 		return defaultValue
 	}
 	return intValue
 }
 
+// getEnvBool gets boolean environment variable with default value
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		recordEnvError(key, value, "true or false")
+		return defaultValue
+	}
+	return boolValue
+}
+
+// getEnvDuration gets duration environment variable (e.g. "500ms", "24h") with default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	durationValue, err := time.ParseDuration(value)
+	if err != nil {
+		recordEnvError(key, value, `a duration such as "500ms" or "24h"`)
+		return defaultValue
+	}
+	return durationValue
+}
+
+// recordEnvError records that the variable key holds a value that is not of the expected kind
+func recordEnvError(key, value, expected string) {
+	envErrorsMu.Lock()
+	defer envErrorsMu.Unlock()
+	envErrors = append(envErrors, fmt.Errorf("invalid %s %q: must be %s", key, value, expected))
+}
+
+// takeEnvErrors returns the parse errors recorded since the last call and clears them
+func takeEnvErrors() []error {
+	envErrorsMu.Lock()
+	defer envErrorsMu.Unlock()
+	errs := envErrors
+	envErrors = nil
+	return errs
+}
+
+// getEnvList gets a comma-separated environment variable as a list of trimmed, non-empty values
+func getEnvList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // IsDevelopment checks if app is in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.AppEnv == "development"
@@ -52,3 +621,131 @@ func (c *Config) IsDevelopment() bool {
 func (c *Config) IsProduction() bool {
 	return c.AppEnv == "production"
 }
+
+// Validate checks the configuration, returning every problem found joined into one error
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.loadErrs...)
+
+	if !slices.Contains(appEnvs, c.AppEnv) {
+		errs = append(errs, fmt.Errorf("invalid APP_ENV %q: must be one of %s", c.AppEnv, strings.Join(appEnvs, ", ")))
+	}
+	if c.AppName == "" && c.IsProduction() {
+		errs = append(errs, errors.New("APP_NAME is required in production"))
+	}
+	if err := validatePort("PORT", c.Port); err != nil {
+		errs = append(errs, err)
+	}
+
+	if u, err := url.Parse(c.FrontendURL); err != nil {
+		errs = append(errs, fmt.Errorf("invalid FRONTEND_URL: %w", err))
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("invalid FRONTEND_URL %q: must be an absolute http(s) URL", c.FrontendURL))
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("invalid LOG_FORMAT %q: must be text or json", c.LogFormat))
+	}
+	if c.TOTPEncryptionKey == "" && c.IsProduction() {
+		errs = append(errs, errors.New("TOTP_ENCRYPTION_KEY is required in production"))
+	} else if c.TOTPEncryptionKey == developmentTOTPEncryptionKey && c.IsProduction() {
+		errs = append(errs, errors.New("TOTP_ENCRYPTION_KEY must not be the development default in production"))
+	}
+	if c.AuthDisabled && c.IsProduction() {
+		errs = append(errs, errors.New("AUTH_DISABLED must not be set when APP_ENV is production"))
+	}
+	if _, err := ParseWindows(c.StatsSignupWindows); err != nil {
+		errs = append(errs, fmt.Errorf("invalid STATS_SIGNUP_WINDOWS: %w", err))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be a positive duration"))
+	}
+	if c.HealthCheckTimeout <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_TIMEOUT must be a positive duration"))
+	}
+	if c.SeedAdmin && (c.AdminEmail == "" || c.AdminPassword == "") {
+		errs = append(errs, errors.New("ADMIN_EMAIL and ADMIN_PASSWORD are required to seed the admin user"))
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS entry %q: must be * or an http(s) origin without a path", origin))
+		}
+	}
+	if _, _, err := c.BodyLimits(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CompressionLevel < -1 || c.CompressionLevel > 9 {
+		errs = append(errs, fmt.Errorf("invalid COMPRESSION_LEVEL %d: must be between -1 and 9", c.CompressionLevel))
+	}
+	if c.CompressionMinLength < 0 {
+		errs = append(errs, errors.New("COMPRESSION_MIN_LENGTH must not be negative"))
+	}
+	for _, interval := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"OUTBOX_POLL_INTERVAL", c.OutboxPollInterval},
+		{"OUTBOX_RETRY_BACKOFF", c.OutboxRetryBackoff},
+		{"OUTBOX_LEASE", c.OutboxLease},
+		{"SESSION_CLEANUP_INTERVAL", c.SessionCleanupInterval},
+		{"ALERT_HEALTH_CHECK_INTERVAL", c.AlertHealthCheckInterval},
+		{"LOAD_SHED_WINDOW", c.LoadShedWindow},
+	} {
+		if interval.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration", interval.key))
+		}
+	}
+	for _, count := range []struct {
+		key   string
+		value int
+	}{
+		{"OUTBOX_BATCH_SIZE", c.OutboxBatchSize},
+		{"OUTBOX_MAX_ATTEMPTS", c.OutboxMaxAttempts},
+		{"PASSWORD_MIN_LENGTH", c.PasswordMinLength},
+	} {
+		if count.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive number", count.key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePort checks that the variable named key holds a TCP port number
+func validatePort(key, port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid %s %q: must be a number between 1 and 65535", key, port)
+	}
+	return nil
+}
+
+// LoadEmailDomainDenyListFile appends the domains listed in EmailDomainDenyListFile
+// (one per line, # comments allowed) to EmailDomainDenyList
+func (c *Config) LoadEmailDomainDenyListFile() error {
+	if c.EmailDomainDenyListFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(c.EmailDomainDenyListFile)
+	if err != nil {
+		return fmt.Errorf("error opening EMAIL_DOMAIN_DENYLIST_FILE: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.EmailDomainDenyList = append(c.EmailDomainDenyList, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading EMAIL_DOMAIN_DENYLIST_FILE: %w", err)
+	}
+	return nil
+}
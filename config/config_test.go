@@ -0,0 +1,191 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFrontendURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://app.example.com", wantErr: false},
+		{url: "http://localhost:3000", wantErr: false},
+		{url: "app.example.com", wantErr: true},
+		{url: "ftp://app.example.com", wantErr: true},
+		{url: "://bad", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			cfg := Load()
+			cfg.FrontendURL = tt.url
+
+			err := cfg.Validate()
+			gotErr := err != nil && strings.Contains(err.Error(), "FRONTEND_URL")
+			if gotErr != tt.wantErr {
+				t.Errorf("FRONTEND_URL error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		wantErr bool
+	}{
+		{name: "unset", origins: nil},
+		{name: "wildcard", origins: []string{"*"}},
+		{name: "origins", origins: []string{"https://app.example.com", "http://localhost:3000"}},
+		{name: "with path", origins: []string{"https://app.example.com/"}, wantErr: true},
+		{name: "without scheme", origins: []string{"app.example.com"}, wantErr: true},
+		{name: "other scheme", origins: []string{"ftp://app.example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Load()
+			cfg.CORSAllowedOrigins = tt.origins
+
+			err := cfg.Validate()
+			gotErr := err != nil && strings.Contains(err.Error(), "CORS_ALLOWED_ORIGINS")
+			if gotErr != tt.wantErr {
+				t.Errorf("CORS_ALLOWED_ORIGINS error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONPrettyDefault(t *testing.T) {
+	tests := []struct {
+		appEnv string
+		pretty string
+		want   bool
+	}{
+		{appEnv: "development", want: true},
+		{appEnv: "production", want: false},
+		{appEnv: "staging", want: false},
+		{appEnv: "production", pretty: "true", want: true},
+		{appEnv: "development", pretty: "false", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.appEnv+"/"+tt.pretty, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.appEnv)
+			t.Setenv("JSON_PRETTY", tt.pretty)
+
+			if got := Load().JSONPretty; got != tt.want {
+				t.Errorf("JSONPretty = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseMetaDefault(t *testing.T) {
+	tests := []struct {
+		env  string
+		want bool
+	}{
+		{env: "", want: false},
+		{env: "true", want: true},
+		{env: "false", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("RESPONSE_META", tt.env)
+
+			if got := Load().ResponseMeta; got != tt.want {
+				t.Errorf("ResponseMeta = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEmailDomainDenyListFile(t *testing.T) {
+	path := t.TempDir() + "/denylist.txt"
+	if err := os.WriteFile(path, []byte("# disposable\nmailinator.com\n\n  tempmail.org  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{EmailDomainDenyList: []string{"example.net"}, EmailDomainDenyListFile: path}
+	if err := cfg.LoadEmailDomainDenyListFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"example.net", "mailinator.com", "tempmail.org"}
+	if !reflect.DeepEqual(cfg.EmailDomainDenyList, want) {
+		t.Errorf("deny list = %v, want %v", cfg.EmailDomainDenyList, want)
+	}
+
+	cfg.EmailDomainDenyListFile = path + ".missing"
+	if err := cfg.LoadEmailDomainDenyListFile(); err == nil {
+		t.Error("missing file: error = nil, want an error")
+	}
+}
+
+func TestValidateAuthDisabled(t *testing.T) {
+	tests := []struct {
+		appEnv       string
+		authDisabled bool
+		wantErr      bool
+	}{
+		{appEnv: "development", authDisabled: true, wantErr: false},
+		{appEnv: "staging", authDisabled: true, wantErr: false},
+		{appEnv: "production", authDisabled: true, wantErr: true},
+		{appEnv: "production", authDisabled: false, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.appEnv, func(t *testing.T) {
+			cfg := Load()
+			cfg.AppEnv = tt.appEnv
+			cfg.AuthDisabled = tt.authDisabled
+
+			err := cfg.Validate()
+			gotErr := err != nil && strings.Contains(err.Error(), "AUTH_DISABLED")
+			if gotErr != tt.wantErr {
+				t.Errorf("AUTH_DISABLED error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePositiveSettings(t *testing.T) {
+	tests := []struct {
+		key string
+		set func(cfg *Config)
+	}{
+		{key: "OUTBOX_POLL_INTERVAL", set: func(cfg *Config) { cfg.OutboxPollInterval = 0 }},
+		{key: "OUTBOX_RETRY_BACKOFF", set: func(cfg *Config) { cfg.OutboxRetryBackoff = -time.Second }},
+		{key: "OUTBOX_LEASE", set: func(cfg *Config) { cfg.OutboxLease = 0 }},
+		{key: "SESSION_CLEANUP_INTERVAL", set: func(cfg *Config) { cfg.SessionCleanupInterval = -time.Minute }},
+		{key: "ALERT_HEALTH_CHECK_INTERVAL", set: func(cfg *Config) { cfg.AlertHealthCheckInterval = 0 }},
+		{key: "LOAD_SHED_WINDOW", set: func(cfg *Config) { cfg.LoadShedWindow = 0 }},
+		{key: "OUTBOX_BATCH_SIZE", set: func(cfg *Config) { cfg.OutboxBatchSize = 0 }},
+		{key: "OUTBOX_MAX_ATTEMPTS", set: func(cfg *Config) { cfg.OutboxMaxAttempts = -1 }},
+		{key: "PASSWORD_MIN_LENGTH", set: func(cfg *Config) { cfg.PasswordMinLength = 0 }},
+	}
+
+	if err := Load().Validate(); err != nil {
+		t.Fatalf("default config error = %v, want nil", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			cfg := Load()
+			tt.set(cfg)
+
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("error = %v, want it to name %s", err, tt.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultEnvFile is read from the working directory when ENV_FILE is unset
+const defaultEnvFile = ".env"
+
+// LoadEnvFile sets environment variables from the file named by ENV_FILE (default .env),
+// so it must run before any config is loaded. Variables already set in the environment
+// win over the file. A missing default file is ignored; a missing ENV_FILE is an error.
+// It returns the path it loaded, or "" when there was no file.
+func LoadEnvFile() (string, error) {
+	path, explicit := os.LookupEnv("ENV_FILE")
+	if !explicit || path == "" {
+		path = defaultEnvFile
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return "", nil
+		}
+		return "", fmt.Errorf("error opening ENV_FILE: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		key, value, ok, err := parseEnvLine(scanner.Text())
+		if err != nil {
+			return "", fmt.Errorf("error parsing %s line %d: %w", path, lineNo, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return "", fmt.Errorf("error setting %s: %w", key, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// parseEnvLine parses a KEY=VALUE line, with an optional "export " prefix. Values may be
+// wrapped in single or double quotes; unquoted values end at " #". Blank lines and
+// # comments report ok=false.
+func parseEnvLine(line string) (key, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	key, value, found := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false, errors.New("expected KEY=VALUE")
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		quote := value[0]
+		end := closingQuote(value, quote)
+		if end < 0 {
+			return "", "", false, errors.New("unterminated quoted value")
+		}
+		value = value[1:end]
+		if quote == '"' {
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value)
+		}
+		return key, value, true, nil
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return key, value, true, nil
+}
+
+// closingQuote returns the index of the quote closing value[0], skipping backslash-escaped
+// quotes inside double quotes, or -1 when there is none
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// developmentJWTSecret signs tokens outside production when JWT_SECRET is unset
+const developmentJWTSecret = "your-secret-key-change-in-production"
+
+// JWTConfig holds access token signing configuration
+type JWTConfig struct {
+	// Secret signs new tokens; PreviousSecrets are still accepted for
+	// validation so live tokens survive a secret rotation
+	Secret          string
+	PreviousSecrets []string
+
+	// Expiration is how long an access token stays valid
+	Expiration time.Duration
+
+	// RefreshExpiration is how long a refresh token stays valid
+	RefreshExpiration time.Duration
+
+	// Issuer is set as the iss claim and required on validation when not empty
+	Issuer string
+
+	// loadErrs are the values LoadJWTConfig could not parse; Validate reports them
+	loadErrs []error
+}
+
+// LoadJWTConfig loads JWT configuration from environment. Outside production an unset
+// JWT_SECRET falls back to a development secret; Validate rejects it in production.
+func LoadJWTConfig(appEnv string) *JWTConfig {
+	secret := getEnv("JWT_SECRET", "")
+	if secret == "" && appEnv != "production" {
+		secret = developmentJWTSecret
+	}
+
+	return &JWTConfig{
+		Secret:            secret,
+		PreviousSecrets:   getEnvList("JWT_SECRETS_PREVIOUS"),
+		Expiration:        getEnvDuration("JWT_EXPIRATION", 24*time.Hour),
+		RefreshExpiration: getEnvDuration("JWT_REFRESH_EXPIRATION", 30*24*time.Hour),
+		Issuer:            getEnv("JWT_ISSUER", ""),
+		loadErrs:          takeEnvErrors(),
+	}
+}
+
+// Validate checks the JWT configuration for appEnv, returning every problem found
+// joined into one error
+func (c *JWTConfig) Validate(appEnv string) error {
+	errs := append([]error(nil), c.loadErrs...)
+
+	if appEnv == "production" {
+		if c.Secret == "" {
+			errs = append(errs, errors.New("JWT_SECRET is required in production"))
+		} else if c.Secret == developmentJWTSecret {
+			errs = append(errs, errors.New("JWT_SECRET must not be the development default in production"))
+		}
+	}
+	if c.Expiration <= 0 {
+		errs = append(errs, errors.New("JWT_EXPIRATION must be a positive duration"))
+	} else if c.RefreshExpiration <= c.Expiration {
+		errs = append(errs, errors.New("JWT_REFRESH_EXPIRATION must be longer than JWT_EXPIRATION"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJWTConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		appEnv   string
+		env      map[string]string
+		wantErrs []string
+	}{
+		{name: "development default secret", appEnv: "development"},
+		{name: "production secret", appEnv: "production", env: map[string]string{"JWT_SECRET": "s3cret"}},
+		{
+			name:     "production without secret",
+			appEnv:   "production",
+			wantErrs: []string{"JWT_SECRET is required"},
+		},
+		{
+			name:     "production with development secret",
+			appEnv:   "production",
+			env:      map[string]string{"JWT_SECRET": developmentJWTSecret},
+			wantErrs: []string{"JWT_SECRET must not be the development default"},
+		},
+		{
+			name:     "every problem reported",
+			appEnv:   "production",
+			env:      map[string]string{"JWT_EXPIRATION": "0s", "JWT_REFRESH_EXPIRATION": "soon"},
+			wantErrs: []string{"JWT_SECRET is required", "JWT_EXPIRATION must be a positive duration", "JWT_REFRESH_EXPIRATION"},
+		},
+		{
+			name:     "refresh not longer than access",
+			appEnv:   "development",
+			env:      map[string]string{"JWT_EXPIRATION": "1h", "JWT_REFRESH_EXPIRATION": "1h"},
+			wantErrs: []string{"JWT_REFRESH_EXPIRATION must be longer than JWT_EXPIRATION"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"JWT_SECRET", "JWT_EXPIRATION", "JWT_REFRESH_EXPIRATION"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			err := LoadJWTConfig(tt.appEnv).Validate(tt.appEnv)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			for _, want := range tt.wantErrs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package config
+
+// Version is the application version, set at build time with
+// -ldflags "-X echo-base/config.Version=v1.2.3"
+var Version = "dev"
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxStatsWindows bounds how many signup-stats windows can be requested at once
+const MaxStatsWindows = 10
+
+// ParseWindow parses a positive window such as "90m", "24h" or "7d" (days are 24h)
+func ParseWindow(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		d = parsed
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid window %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// ParseWindows parses a list of windows, rejecting empty lists and more than MaxStatsWindows
+func ParseWindows(labels []string) ([]time.Duration, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("at least one window is required")
+	}
+	if len(labels) > MaxStatsWindows {
+		return nil, fmt.Errorf("at most %d windows are allowed", MaxStatsWindows)
+	}
+
+	windows := make([]time.Duration, 0, len(labels))
+	for _, label := range labels {
+		window, err := ParseWindow(label)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		window  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{window: "24h", want: 24 * time.Hour},
+		{window: "90m", want: 90 * time.Minute},
+		{window: "7d", want: 7 * 24 * time.Hour},
+		{window: "30d", want: 30 * 24 * time.Hour},
+		{window: "0d", wantErr: true},
+		{window: "-1h", wantErr: true},
+		{window: "0s", wantErr: true},
+		{window: "xd", wantErr: true},
+		{window: "week", wantErr: true},
+		{window: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			got, err := ParseWindow(tt.window)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseWindow(%q) = %v, want an error", tt.window, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseWindow(%q) = %v, %v; want %v", tt.window, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	tooMany := make([]string, MaxStatsWindows+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%dd", i+1)
+	}
+
+	tests := []struct {
+		name    string
+		labels  []string
+		want    []time.Duration
+		wantErr string
+	}{
+		{name: "custom windows", labels: []string{"1h", "2d"}, want: []time.Duration{time.Hour, 48 * time.Hour}},
+		{name: "maximum count", labels: tooMany[:MaxStatsWindows], want: nil},
+		{name: "empty", labels: nil, wantErr: "at least one"},
+		{name: "too many", labels: tooMany, wantErr: "at most"},
+		{name: "one invalid", labels: []string{"1h", "-2d"}, wantErr: "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWindows(tt.labels)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("windows = %v, want %v", got, tt.want)
+			}
+			if len(got) != len(tt.labels) {
+				t.Errorf("got %d windows, want %d", len(got), len(tt.labels))
+			}
+		})
+	}
+}
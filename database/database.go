@@ -17,6 +17,8 @@ func Connect(dbConfig *config.DatabaseConfig) (*sql.DB, error) {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
 
+	configurePool(db, dbConfig)
+
 	// Test the connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("error connecting to database: %w", err)
@@ -26,6 +28,14 @@ func Connect(dbConfig *config.DatabaseConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// configurePool bounds the pool so load cannot exhaust the server's connections
+func configurePool(db *sql.DB, dbConfig *config.DatabaseConfig) {
+	db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	db.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime)
+}
+
 // Close closes the database connection
 func Close(db *sql.DB) error {
 	if db != nil {
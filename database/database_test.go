@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"echo-base/config"
+)
+
+// poolDriver hands out connections that do nothing, so pool behaviour can be observed
+// through db.Stats() without a database
+type poolDriver struct{}
+
+func (poolDriver) Open(name string) (driver.Conn, error) { return poolConn{}, nil }
+
+// poolConn is a connection that supports nothing but being opened and closed
+type poolConn struct{}
+
+func (poolConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("pooltest: statements are not supported")
+}
+
+func (poolConn) Close() error { return nil }
+
+func (poolConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("pooltest: transactions are not supported")
+}
+
+var registerPoolDriver sync.Once
+
+// openPool opens a pool over poolDriver configured from dbConfig
+func openPool(t *testing.T, dbConfig *config.DatabaseConfig) *sql.DB {
+	t.Helper()
+
+	registerPoolDriver.Do(func() { sql.Register("pooltest", poolDriver{}) })
+	db, err := sql.Open("pooltest", "")
+	if err != nil {
+		t.Fatalf("error opening pool: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	configurePool(db, dbConfig)
+	return db
+}
+
+// hold checks out n connections at once and returns them to the pool
+func hold(t *testing.T, db *sql.DB, n int) {
+	t.Helper()
+
+	conns := make([]*sql.Conn, 0, n)
+	for range n {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("error checking out connection: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.DatabaseConfig
+
+		// use runs against the pool before its stats are read
+		use func(t *testing.T, db *sql.DB)
+
+		want func(stats sql.DBStats) bool
+	}{
+		{
+			name:   "max open connections",
+			config: config.DatabaseConfig{MaxOpenConns: 7, MaxIdleConns: 2},
+			use:    func(t *testing.T, db *sql.DB) {},
+			want:   func(stats sql.DBStats) bool { return stats.MaxOpenConnections == 7 },
+		},
+		{
+			name:   "unlimited open connections",
+			config: config.DatabaseConfig{MaxIdleConns: 2},
+			use:    func(t *testing.T, db *sql.DB) {},
+			want:   func(stats sql.DBStats) bool { return stats.MaxOpenConnections == 0 },
+		},
+		{
+			name:   "max idle connections",
+			config: config.DatabaseConfig{MaxOpenConns: 10, MaxIdleConns: 2},
+			use:    func(t *testing.T, db *sql.DB) { hold(t, db, 5) },
+			want: func(stats sql.DBStats) bool {
+				return stats.Idle == 2 && stats.OpenConnections == 2 && stats.MaxIdleClosed == 3
+			},
+		},
+		{
+			name:   "connection max lifetime",
+			config: config.DatabaseConfig{MaxOpenConns: 10, MaxIdleConns: 2, ConnMaxLifetime: 10 * time.Millisecond},
+			use: func(t *testing.T, db *sql.DB) {
+				hold(t, db, 1)
+				time.Sleep(20 * time.Millisecond)
+				hold(t, db, 1)
+			},
+			want: func(stats sql.DBStats) bool { return stats.MaxLifetimeClosed >= 1 },
+		},
+		{
+			// The pool's cleaner runs at most once a second
+			name:   "connection max idle time",
+			config: config.DatabaseConfig{MaxOpenConns: 10, MaxIdleConns: 2, ConnMaxIdleTime: 10 * time.Millisecond},
+			use: func(t *testing.T, db *sql.DB) {
+				hold(t, db, 2)
+				time.Sleep(1200 * time.Millisecond)
+			},
+			want: func(stats sql.DBStats) bool { return stats.MaxIdleTimeClosed == 2 && stats.Idle == 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openPool(t, &tt.config)
+			tt.use(t, db)
+
+			if stats := db.Stats(); !tt.want(stats) {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
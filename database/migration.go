@@ -1,60 +1,481 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"strings"
 )
 
-// RunMigrations runs all database migrations
+// migration is one step of the schema, identified by its name. Once applied a
+// migration must never be edited; changes go in a new migration appended to the list.
+// down reverts sql and is run by RollbackMigrations.
+type migration struct {
+	name string
+	sql  string
+	down string
+}
+
+// checksum fingerprints the migration's SQL so edits to applied migrations are detected.
+// Whitespace is normalized first, so reindenting a migration does not count as an edit.
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(m.sql), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// migrations are applied in order. They predate schema_migrations and stay idempotent,
+// so databases created before it was introduced simply record them on the next start.
+var migrations = []migration{
+	{
+		name: "create_roles_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS roles (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL UNIQUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_roles_name ON roles(name);
+		`,
+		down: `
+			DROP TABLE IF EXISTS roles;
+		`,
+	},
+	{
+		name: "create_users_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				email VARCHAR(255) NOT NULL UNIQUE,
+				password VARCHAR(255) NOT NULL,
+				role_id INTEGER NOT NULL DEFAULT 1 REFERENCES roles(id),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+			CREATE INDEX IF NOT EXISTS idx_users_role_id ON users(role_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS users;
+		`,
+	},
+	{
+		name: "insert_default_roles",
+		sql: `
+			INSERT INTO roles (name) VALUES ('user') ON CONFLICT (name) DO NOTHING;
+			INSERT INTO roles (name) VALUES ('admin') ON CONFLICT (name) DO NOTHING;
+		`,
+		down: `
+			DELETE FROM roles WHERE name IN ('user', 'admin');
+		`,
+	},
+	{
+		name: "add_username_to_users",
+		sql: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(30);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
+		`,
+		down: `
+			DROP INDEX IF EXISTS idx_users_username;
+			ALTER TABLE users DROP COLUMN IF EXISTS username;
+		`,
+	},
+	{
+		name: "add_referral_source_to_users",
+		sql: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_source VARCHAR(100);
+		`,
+		down: `
+			ALTER TABLE users DROP COLUMN IF EXISTS referral_source;
+		`,
+	},
+	{
+		name: "create_outbox_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS outbox (
+				id BIGSERIAL PRIMARY KEY,
+				event_name VARCHAR(255) NOT NULL,
+				payload JSONB NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				sent_at TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+		`,
+		down: `
+			DROP TABLE IF EXISTS outbox;
+		`,
+	},
+	{
+		name: "create_sessions_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS sessions (
+				id VARCHAR(64) PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				last_activity_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS sessions;
+		`,
+	},
+	{
+		name: "create_login_history_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS login_history (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				ip VARCHAR(64) NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				success BOOLEAN NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_login_history_user_id_created_at ON login_history(user_id, created_at DESC);
+		`,
+		down: `
+			DROP TABLE IF EXISTS login_history;
+		`,
+	},
+	{
+		name: "create_trusted_devices_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS trusted_devices (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash VARCHAR(64) NOT NULL UNIQUE,
+				ip VARCHAR(64) NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices(user_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS trusted_devices;
+		`,
+	},
+	{
+		name: "create_user_totp_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS user_totp (
+				user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				secret_encrypted TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				enabled_at TIMESTAMP
+			);
+		`,
+		down: `
+			DROP TABLE IF EXISTS user_totp;
+		`,
+	},
+	{
+		name: "create_recovery_codes_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS recovery_codes (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				code_hash VARCHAR(64) NOT NULL,
+				used_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS recovery_codes;
+		`,
+	},
+	{
+		name: "add_case_insensitive_username_index",
+		sql: `
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
+		`,
+		down: `
+			DROP INDEX IF EXISTS idx_users_username_lower;
+		`,
+	},
+	{
+		name: "create_counters_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS counters (
+				name VARCHAR(64) PRIMARY KEY,
+				value BIGINT NOT NULL DEFAULT 0,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+		down: `
+			DROP TABLE IF EXISTS counters;
+		`,
+	},
+	{
+		name: "create_api_keys_table",
+		sql: `
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				prefix VARCHAR(16) NOT NULL,
+				key_hash VARCHAR(64) NOT NULL UNIQUE,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP,
+				last_used_at TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS api_keys;
+		`,
+	},
+	{
+		name: "add_soft_delete_to_users",
+		sql: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+			-- Emails only need to be unique among live users so a deleted user's email can register again
+			ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users(email) WHERE deleted_at IS NULL;
+		`,
+		down: `
+			-- Deleted users are purged: without deleted_at they would come back to life
+			DELETE FROM users WHERE deleted_at IS NOT NULL;
+			DROP INDEX IF EXISTS idx_users_email_active;
+			ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+			ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+		`,
+	},
+	{
+		name: "add_email_verified_to_users",
+		sql: `
+			-- Existing accounts are treated as verified; only new registrations start unverified
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+			ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT FALSE;
+		`,
+		down: `
+			ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
+		`,
+	},
+	{
+		name: "add_status_to_users",
+		sql: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'
+				CHECK (status IN ('active', 'suspended'));
+		`,
+		down: `
+			ALTER TABLE users DROP COLUMN IF EXISTS status;
+		`,
+	},
+	{
+		name: "add_login_challenge_to_user_totp",
+		sql: `
+			ALTER TABLE user_totp ADD COLUMN IF NOT EXISTS challenge_id VARCHAR(64);
+			ALTER TABLE user_totp ADD COLUMN IF NOT EXISTS challenge_failures INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE user_totp ADD COLUMN IF NOT EXISTS last_used_step BIGINT NOT NULL DEFAULT 0;
+		`,
+		down: `
+			ALTER TABLE user_totp DROP COLUMN IF EXISTS last_used_step;
+			ALTER TABLE user_totp DROP COLUMN IF EXISTS challenge_failures;
+			ALTER TABLE user_totp DROP COLUMN IF EXISTS challenge_id;
+		`,
+	},
+	{
+		name: "add_retry_state_to_outbox",
+		sql: `
+			ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;
+			ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP;
+			DROP INDEX IF EXISTS idx_outbox_pending;
+			CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL AND failed_at IS NULL;
+		`,
+		down: `
+			DROP INDEX IF EXISTS idx_outbox_pending;
+			CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+			ALTER TABLE outbox DROP COLUMN IF EXISTS failed_at;
+			ALTER TABLE outbox DROP COLUMN IF EXISTS next_attempt_at;
+		`,
+	},
+}
+
+// migrationLock serializes migrations across instances starting at the same time
+const migrationLock = "schema_migrations"
+
+// RunMigrations applies the migrations not yet recorded in schema_migrations, each in
+// its own transaction. It fails without applying anything when an applied migration
+// has been edited since.
 func RunMigrations(db *sql.DB) error {
-	migrations := []struct {
-		name string
-		sql  string
-	}{
-		{
-			name: "create_roles_table",
-			sql: `
-				CREATE TABLE IF NOT EXISTS roles (
-					id SERIAL PRIMARY KEY,
-					name VARCHAR(255) NOT NULL UNIQUE,
-					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-				);
-				CREATE INDEX IF NOT EXISTS idx_roles_name ON roles(name);
-			`,
-		},
-		{
-			name: "create_users_table",
-			sql: `
-				CREATE TABLE IF NOT EXISTS users (
-					id SERIAL PRIMARY KEY,
-					name VARCHAR(255) NOT NULL,
-					email VARCHAR(255) NOT NULL UNIQUE,
-					password VARCHAR(255) NOT NULL,
-					role_id INTEGER NOT NULL DEFAULT 1 REFERENCES roles(id),
-					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-				);
-				CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-				CREATE INDEX IF NOT EXISTS idx_users_role_id ON users(role_id);
-			`,
-		},
-		{
-			name: "insert_default_roles",
-			sql: `
-				INSERT INTO roles (name) VALUES ('user') ON CONFLICT (name) DO NOTHING;
-				INSERT INTO roles (name) VALUES ('admin') ON CONFLICT (name) DO NOTHING;
-			`,
-		},
-	}
-
-	for _, migration := range migrations {
-		log.Printf("Running migration: %s\n", migration.name)
-		if _, err := db.Exec(migration.sql); err != nil {
+	return withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
 			return err
 		}
+		return applyPending(ctx, conn, applied)
+	})
+}
+
+// applyPending runs the migrations missing from applied in order
+func applyPending(ctx context.Context, conn *sql.Conn, applied map[string]string) error {
+	pending := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.name]; ok {
+			continue
+		}
+
+		log.Printf("Running migration: %s\n", m.name)
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("error running migration %s: %w", m.name, err)
+		}
+		pending++
 	}
 
-	log.Println("All migrations completed successfully")
+	log.Printf("Migrations up to date (%d applied now, %d total)\n", pending, len(migrations))
 	return nil
 }
+
+// RollbackMigrations reverts the last steps applied migrations, newest first, each in its
+// own transaction. It refuses to run when the database has migrations this build does
+// not know, since those would have to be reverted first.
+func RollbackMigrations(db *sql.DB, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+
+	return withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[string]bool, len(migrations))
+		for _, m := range migrations {
+			known[m.name] = true
+		}
+		for name := range applied {
+			if !known[name] {
+				return fmt.Errorf("migration %s is applied but unknown to this version; roll back with the version that added it", name)
+			}
+		}
+
+		reverted := 0
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.name]; !ok {
+				continue
+			}
+			if m.down == "" {
+				return fmt.Errorf("migration %s cannot be rolled back", m.name)
+			}
+
+			log.Printf("Rolling back migration: %s\n", m.name)
+			if err := revertMigration(ctx, conn, m); err != nil {
+				return fmt.Errorf("error rolling back migration %s: %w", m.name, err)
+			}
+			reverted++
+		}
+
+		log.Printf("Rolled back %d migrations\n", reverted)
+		return nil
+	})
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory
+// lock, after making sure schema_migrations exists
+func withMigrationLock(db *sql.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", migrationLock); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", migrationLock); err != nil {
+			log.Printf("error releasing migration lock: %v\n", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name VARCHAR(255) PRIMARY KEY,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	return fn(ctx, conn)
+}
+
+// appliedMigrations returns the checksum of every recorded migration, keyed by name.
+// It fails when a known migration was edited after it was applied.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT name, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]string)
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		applied[name] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if checksum, ok := applied[m.name]; ok && checksum != m.checksum() {
+			return nil, fmt.Errorf("migration %s was edited after it was applied; add a new migration instead", m.name)
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration runs a migration and records it in one transaction
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (name, checksum) VALUES ($1, $2)", m.name, m.checksum()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revertMigration runs a migration's down SQL and removes its record in one transaction
+func revertMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.down); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE name = $1", m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
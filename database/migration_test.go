@@ -0,0 +1,394 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// migrationDB is a database behind the "migrationtest" driver that keeps a
+// schema_migrations table and the statements migrations ran. Changes made in a
+// transaction take effect only when it commits.
+type migrationDB struct {
+	mu sync.Mutex
+
+	// applied maps the recorded migration names to their checksums
+	applied map[string]string
+
+	// executed lists the committed migration statements in order
+	executed []string
+
+	// failOn fails every migration statement equal to it
+	failOn string
+}
+
+var (
+	migrationDBs         sync.Map
+	migrationDBSeq       atomic.Int64
+	registerMigrationsDB sync.Once
+)
+
+// openMigrationDB opens a *sql.DB backed by fake, closed when the test ends
+func openMigrationDB(t *testing.T, fake *migrationDB) *sql.DB {
+	t.Helper()
+
+	registerMigrationsDB.Do(func() { sql.Register("migrationtest", migrationDriver{}) })
+	name := fmt.Sprintf("migrations-%d", migrationDBSeq.Add(1))
+	migrationDBs.Store(name, fake)
+	if fake.applied == nil {
+		fake.applied = map[string]string{}
+	}
+
+	db, err := sql.Open("migrationtest", name)
+	if err != nil {
+		t.Fatalf("error opening fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		migrationDBs.Delete(name)
+	})
+	return db
+}
+
+// useMigrations replaces the migration list for the duration of the test
+func useMigrations(t *testing.T, list []migration) {
+	t.Helper()
+
+	saved := migrations
+	migrations = list
+	t.Cleanup(func() { migrations = saved })
+}
+
+// migrationDriver opens connections to the migrationDB registered under the DSN
+type migrationDriver struct{}
+
+func (migrationDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := migrationDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return &migrationConn{db: fake.(*migrationDB)}, nil
+}
+
+// migrationConn is a connection holding at most one transaction
+type migrationConn struct {
+	db      *migrationDB
+	pending []func()
+	inTx    bool
+}
+
+func (c *migrationConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("migrationtest: prepared statements are not supported")
+}
+
+func (c *migrationConn) Close() error { return nil }
+
+func (c *migrationConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	c.pending = nil
+	return c, nil
+}
+
+func (c *migrationConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	for _, apply := range c.pending {
+		apply()
+	}
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *migrationConn) Rollback() error {
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *migrationConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var apply func()
+	switch {
+	case strings.Contains(query, "pg_advisory"), strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		name, checksum := args[0].Value.(string), args[1].Value.(string)
+		apply = func() { c.db.applied[name] = checksum }
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		name := args[0].Value.(string)
+		apply = func() { delete(c.db.applied, name) }
+	default:
+		if query == c.db.failOn {
+			return nil, fmt.Errorf("migrationtest: %q failed", query)
+		}
+		apply = func() { c.db.executed = append(c.db.executed, query) }
+	}
+
+	if c.inTx {
+		c.pending = append(c.pending, apply)
+	} else {
+		c.db.mu.Lock()
+		apply()
+		c.db.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *migrationConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query != "SELECT name, checksum FROM schema_migrations" {
+		return nil, fmt.Errorf("migrationtest: unexpected query %q", query)
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	rows := &migrationRows{}
+	for name, checksum := range c.db.applied {
+		rows.rows = append(rows.rows, [2]string{name, checksum})
+	}
+	return rows, nil
+}
+
+// migrationRows iterates over schema_migrations rows
+type migrationRows struct {
+	rows [][2]string
+	next int
+}
+
+func (r *migrationRows) Columns() []string { return []string{"name", "checksum"} }
+
+func (r *migrationRows) Close() error { return nil }
+
+func (r *migrationRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[r.next][0], r.rows[r.next][1]
+	r.next++
+	return nil
+}
+
+// testMigrations are three migrations creating tables a, b and c
+var testMigrations = []migration{
+	{name: "create_a", sql: "CREATE TABLE a (id INT)", down: "DROP TABLE a"},
+	{name: "create_b", sql: "CREATE TABLE b (id INT)", down: "DROP TABLE b"},
+	{name: "create_c", sql: "CREATE TABLE c (id INT)", down: "DROP TABLE c"},
+}
+
+// appliedChecksums records the named test migrations as applied
+func appliedChecksums(names ...string) map[string]string {
+	applied := map[string]string{}
+	for _, m := range testMigrations {
+		for _, name := range names {
+			if m.name == name {
+				applied[name] = m.checksum()
+			}
+		}
+	}
+	return applied
+}
+
+func TestRunMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied map[string]string
+		failOn  string
+
+		wantErr      string
+		wantExecuted []string
+		wantApplied  []string
+	}{
+		{
+			name:         "fresh database",
+			wantExecuted: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)", "CREATE TABLE c (id INT)"},
+			wantApplied:  []string{"create_a", "create_b", "create_c"},
+		},
+		{
+			name:         "applied migrations are skipped",
+			applied:      appliedChecksums("create_a", "create_b"),
+			wantExecuted: []string{"CREATE TABLE c (id INT)"},
+			wantApplied:  []string{"create_a", "create_b", "create_c"},
+		},
+		{
+			name:        "up to date",
+			applied:     appliedChecksums("create_a", "create_b", "create_c"),
+			wantApplied: []string{"create_a", "create_b", "create_c"},
+		},
+		{
+			name: "reindented migration still matches",
+			applied: map[string]string{
+				"create_a": migration{sql: "\n\tCREATE TABLE a\n\t\t(id INT)\n"}.checksum(),
+			},
+			wantExecuted: []string{"CREATE TABLE b (id INT)", "CREATE TABLE c (id INT)"},
+			wantApplied:  []string{"create_a", "create_b", "create_c"},
+		},
+		{
+			name: "edited migration",
+			applied: map[string]string{
+				"create_a": migration{sql: "CREATE TABLE a (id BIGINT)"}.checksum(),
+			},
+			wantErr:     "create_a was edited",
+			wantApplied: []string{"create_a"},
+		},
+		{
+			name:         "failed migration is not recorded",
+			failOn:       "CREATE TABLE b (id INT)",
+			wantErr:      "error running migration create_b",
+			wantExecuted: []string{"CREATE TABLE a (id INT)"},
+			wantApplied:  []string{"create_a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMigrations(t, testMigrations)
+			fake := &migrationDB{applied: tt.applied, failOn: tt.failOn}
+			db := openMigrationDB(t, fake)
+
+			err := RunMigrations(db)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(fake.executed, tt.wantExecuted) {
+				t.Errorf("executed = %q, want %q", fake.executed, tt.wantExecuted)
+			}
+			for _, name := range tt.wantApplied {
+				if _, ok := fake.applied[name]; !ok {
+					t.Errorf("%s is not recorded as applied", name)
+				}
+			}
+			if len(fake.applied) != len(tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", fake.applied, tt.wantApplied)
+			}
+			for _, m := range testMigrations {
+				if _, ok := tt.applied[m.name]; ok {
+					continue
+				}
+				if checksum, ok := fake.applied[m.name]; ok && checksum != m.checksum() {
+					t.Errorf("%s recorded with checksum %s, want %s", m.name, checksum, m.checksum())
+				}
+			}
+
+			// A second run has nothing left to do
+			if tt.wantErr == "" {
+				executed := len(fake.executed)
+				if err := RunMigrations(db); err != nil || len(fake.executed) != executed {
+					t.Errorf("second run executed %q (err %v), want nothing", fake.executed[executed:], err)
+				}
+			}
+		})
+	}
+}
+
+func TestRollbackMigrations(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []migration
+		applied    map[string]string
+		steps      int
+		failOn     string
+
+		wantErr      string
+		wantExecuted []string
+		wantApplied  []string
+	}{
+		{
+			name:         "newest first",
+			applied:      appliedChecksums("create_a", "create_b", "create_c"),
+			steps:        2,
+			wantExecuted: []string{"DROP TABLE c", "DROP TABLE b"},
+			wantApplied:  []string{"create_a"},
+		},
+		{
+			name:         "unapplied migrations are skipped",
+			applied:      appliedChecksums("create_a", "create_b"),
+			steps:        1,
+			wantExecuted: []string{"DROP TABLE b"},
+			wantApplied:  []string{"create_a"},
+		},
+		{
+			name:         "more steps than applied",
+			applied:      appliedChecksums("create_a", "create_b"),
+			steps:        5,
+			wantExecuted: []string{"DROP TABLE b", "DROP TABLE a"},
+		},
+		{
+			name:        "no steps",
+			applied:     appliedChecksums("create_a"),
+			steps:       0,
+			wantErr:     "steps must be at least 1",
+			wantApplied: []string{"create_a"},
+		},
+		{
+			name:        "unknown applied migration",
+			applied:     map[string]string{"create_a": testMigrations[0].checksum(), "create_z": "unknown"},
+			steps:       1,
+			wantErr:     "create_z is applied but unknown",
+			wantApplied: []string{"create_a", "create_z"},
+		},
+		{
+			name: "migration without down",
+			migrations: []migration{
+				testMigrations[0],
+				{name: "create_b", sql: testMigrations[1].sql},
+			},
+			applied:     appliedChecksums("create_a", "create_b"),
+			steps:       2,
+			wantErr:     "create_b cannot be rolled back",
+			wantApplied: []string{"create_a", "create_b"},
+		},
+		{
+			name:         "failed rollback keeps the record",
+			applied:      appliedChecksums("create_a", "create_b", "create_c"),
+			steps:        2,
+			failOn:       "DROP TABLE b",
+			wantErr:      "error rolling back migration create_b",
+			wantExecuted: []string{"DROP TABLE c"},
+			wantApplied:  []string{"create_a", "create_b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := tt.migrations
+			if list == nil {
+				list = testMigrations
+			}
+			useMigrations(t, list)
+			fake := &migrationDB{applied: tt.applied, failOn: tt.failOn}
+
+			err := RollbackMigrations(openMigrationDB(t, fake), tt.steps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(fake.executed, tt.wantExecuted) {
+				t.Errorf("executed = %q, want %q", fake.executed, tt.wantExecuted)
+			}
+			if len(fake.applied) != len(tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", fake.applied, tt.wantApplied)
+			}
+			for _, name := range tt.wantApplied {
+				if _, ok := fake.applied[name]; !ok {
+					t.Errorf("%s is no longer recorded as applied", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// APIKey is a long-lived credential a user creates for scripts and services.
+// Only a hash of the key is stored; Prefix identifies the key in listings.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APIKeyCreatePayload represents the request to create an API key
+type APIKeyCreatePayload struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes"`
+
+	// ExpiresAt is optional; keys without it never expire
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreatedResponse carries a new API key; the plaintext Key is shown only once
+type APIKeyCreatedResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// LoginHistory represents a recorded login attempt
+type LoginHistory struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaginatedLoginHistoryResponse represents a page of login history with metadata
+type PaginatedLoginHistoryResponse struct {
+	Data       []*LoginHistory `json:"data"`
+	Pagination PaginationMeta  `json:"pagination"`
+}
+
+// LoginMetadata carries request details recorded with a login attempt
+type LoginMetadata struct {
+	IP        string
+	UserAgent string
+
+	// DeviceToken is the trusted device token presented with the login, if any
+	DeviceToken string
+}
@@ -2,6 +2,20 @@ package entity
 
 import "time"
 
+const (
+	// RoleIDUser is the default role assigned to new users
+	RoleIDUser int64 = 1
+
+	// RoleIDAdmin is the administrator role
+	RoleIDAdmin int64 = 2
+)
+
+// RequiredRoles are the roles the application depends on, seeded by migrations
+var RequiredRoles = []Role{
+	{ID: RoleIDUser, Name: "user"},
+	{ID: RoleIDAdmin, Name: "admin"},
+}
+
 // Role represents a role in the system
 type Role struct {
 	ID        int64     `json:"id"`
@@ -15,3 +29,28 @@ type RoleResponse struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
 }
+
+// RolePayload represents role create and rename request payload
+type RolePayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// UserRoleUpdatePayload represents an admin's role change for one user
+type UserRoleUpdatePayload struct {
+	RoleID int64 `json:"role_id" validate:"required,gt=0"`
+}
+
+// BulkRoleAssignPayload represents bulk role assignment request payload
+type BulkRoleAssignPayload struct {
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1,max=1000,dive,gt=0"`
+	RoleID  int64   `json:"role_id" validate:"required,gt=0"`
+}
+
+// BulkRoleAssignResponse represents the result of a bulk role assignment. For a dry run
+// it describes the changes that would have been made.
+type BulkRoleAssignResponse struct {
+	DryRun      bool    `json:"dry_run"`
+	Updated     int64   `json:"updated"`
+	AffectedIDs []int64 `json:"affected_ids"`
+	InvalidIDs  []int64 `json:"invalid_ids"`
+}
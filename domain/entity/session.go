@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// Session represents a login session identified by the token's sid claim
+type Session struct {
+	ID             string    `json:"id"`
+	UserID         int64     `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
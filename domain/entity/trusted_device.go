@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// TrustedDevice is a device a user chose to remember at login. Only a hash of
+// the device token is stored.
+type TrustedDevice struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	TokenHash  string    `json:"-"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// TwoFactor holds a user's TOTP enrollment. The secret is stored encrypted and
+// the enrollment only takes effect once Enabled is set.
+type TwoFactor struct {
+	UserID          int64
+	SecretEncrypted string
+	Enabled         bool
+	CreatedAt       time.Time
+	EnabledAt       *time.Time
+
+	// ChallengeID identifies the login challenge that can still be completed (empty when
+	// none is open); ChallengeFailures counts the wrong codes entered against it
+	ChallengeID       string
+	ChallengeFailures int
+
+	// LastUsedStep is the TOTP time step of the last accepted code, so a code cannot be used twice
+	LastUsedStep int64
+}
+
+// TwoFactorSetupResponse represents the TOTP secret returned at setup
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorEnablePayload represents the request to confirm TOTP enrollment
+type TwoFactorEnablePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// RecoveryCodesResponse carries newly issued recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// RecoveryLoginPayload represents the second step of a login using a recovery code instead of a TOTP code
+type RecoveryLoginPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	RecoveryCode   string `json:"recovery_code" validate:"required"`
+
+	// RememberDevice issues a device token that marks this device as trusted
+	RememberDevice bool `json:"remember_device"`
+}
+
+// TwoFactorLoginPayload represents the second step of a login with two-factor authentication
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,len=6,numeric"`
+
+	// RememberDevice issues a device token that marks this device as trusted
+	RememberDevice bool `json:"remember_device"`
+}
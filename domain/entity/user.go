@@ -2,51 +2,197 @@ package entity
 
 import "time"
 
+// Account statuses of a user
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+)
+
 // User represents a user in the system
 type User struct {
 	ID        int64     `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Username  string    `json:"username,omitempty"`
 	Password  string    `json:"-"`
 	RoleID    int64     `json:"role_id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// RoleName is read from the roles table; it is empty when the role is missing
+	RoleName string `json:"role_name,omitempty"`
+
+	// EmailVerified is set once the user opens the link emailed on registration
+	EmailVerified bool `json:"email_verified"`
+
+	// Status is UserStatusActive or UserStatusSuspended; suspended users cannot log in
+	Status string `json:"status"`
+
+	// ReferralSource records where the signup came from (only set at creation)
+	ReferralSource string `json:"-"`
+
+	// DeletedAt is set when the user is soft-deleted; only admin listings read it
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // UserLoginPayload represents login request payload
 type UserLoginPayload struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+
+	// RememberDevice issues a device token that marks this device as trusted
+	RememberDevice bool `json:"remember_device"`
+}
+
+// UserPatchPayload represents a partial user update; absent (nil) fields are left
+// unchanged. Only admins may change RoleID.
+type UserPatchPayload struct {
+	Name   *string `json:"name" validate:"omitnil,min=3"`
+	RoleID *int64  `json:"role_id" validate:"omitnil,gt=0"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *UserPatchPayload) IsEmpty() bool {
+	return p.Name == nil && p.RoleID == nil
+}
+
+// ChangePasswordPayload represents the request to change the caller's password
+type ChangePasswordPayload struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
 }
 
 // UserCreatePayload represents create user request payload
 type UserCreatePayload struct {
 	Name     string `json:"name" validate:"required,min=3"`
 	Email    string `json:"email" validate:"required,email"`
+	Username string `json:"username" validate:"omitempty,username"`
 	Password string `json:"password" validate:"required,min=6"`
+
+	// ReferralSource is the signup attribution (falls back to the X-Referral-Source header)
+	ReferralSource string `json:"referral_source" validate:"omitempty,max=100"`
 }
 
-// UserResponse represents user response
+// UserResponse represents user response. Fields tagged `visible` are hidden
+// from viewers other than the owner and admins (see utils.ApplyVisibility).
 type UserResponse struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	RoleID    int64     `json:"role_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email" visible:"owner"`
+	Username      string     `json:"username,omitempty"`
+	RoleID        int64      `json:"role_id" visible:"owner"`
+	RoleName      string     `json:"role_name,omitempty" visible:"owner"`
+	EmailVerified bool       `json:"email_verified" visible:"owner"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" visible:"admin"`
+	Status        string     `json:"status,omitempty" visible:"admin"`
+}
+
+// OwnerID returns the ID of the user the response describes
+func (u UserResponse) OwnerID() int64 {
+	return u.ID
+}
+
+// UserStatusPayload represents the admin request to activate or suspend a user
+type UserStatusPayload struct {
+	Status string `json:"status" validate:"required,oneof=active suspended"`
+}
+
+// ResendVerificationPayload represents the request for a new email verification link
+type ResendVerificationPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ChangeEmailPayload represents the request to change the caller's email address
+type ChangeEmailPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotPasswordPayload represents the request for a password reset link
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordPayload represents the request to set a new password with a reset token
+type ResetPasswordPayload struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// RefreshTokenPayload represents the request to exchange a refresh token for a new access token
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 // LoginResponse represents login response with token
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token string        `json:"token,omitempty"`
+	User  *UserResponse `json:"user,omitempty"`
+
+	// RefreshToken is exchanged at /auth/refresh for a new access token
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// TwoFactorRequired is set instead of Token when the login must be completed
+	// at /auth/login/2fa with ChallengeToken and a TOTP code
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+
+	// DeviceToken is returned once when a device is remembered; send it back as X-Device-Token
+	DeviceToken string `json:"device_token,omitempty"`
 }
 
+// Pagination defaults and bounds shared by the query parser, usecases and repositories
+const (
+	DefaultPageLimit = int64(10)
+	MaxPageLimit     = int64(100)
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// UserSortFields are the fields user listings can be sorted by
+var UserSortFields = []string{"id", "name", "email", "username", "created_at", "updated_at"}
+
 // PaginationParams represents pagination request parameters
 type PaginationParams struct {
 	Page   int64  `query:"page"`
 	Limit  int64  `query:"limit"`
 	Search string `query:"search"`
+	Sort   string `query:"sort"`
+	Order  string `query:"order"`
+
+	// RoleID filters users by role (0 matches any role)
+	RoleID int64 `query:"role_id"`
+
+	// CreatedAfter and CreatedBefore limit users to those created at or after and
+	// strictly before the given RFC3339 times (nil leaves the range open)
+	CreatedAfter  *time.Time `query:"created_after"`
+	CreatedBefore *time.Time `query:"created_before"`
+
+	// IncludeDeleted also lists soft-deleted users (admins only)
+	IncludeDeleted bool `query:"include_deleted"`
+}
+
+// UserSearchFilter is the parsed form of a user search
+type UserSearchFilter struct {
+	Search string `json:"search,omitempty"`
+	RoleID int64  `json:"role_id,omitempty"`
+
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+
+	// IncludeDeleted also matches soft-deleted users
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
+}
+
+// UserSearchExplain describes how a user search is executed: the WHERE clause with
+// parameter placeholders, what each placeholder holds, and the planner's row estimate
+type UserSearchExplain struct {
+	Filter        UserSearchFilter `json:"filter"`
+	Where         string           `json:"where"`
+	Parameters    []string         `json:"parameters"`
+	EstimatedRows int64            `json:"estimated_rows"`
 }
 
 // PaginationMeta represents pagination metadata
@@ -62,3 +208,12 @@ type PaginatedUserResponse struct {
 	Data       []*UserResponse `json:"data"`
 	Pagination PaginationMeta  `json:"pagination"`
 }
+
+// UserStats represents aggregate user statistics for admins
+type UserStats struct {
+	TotalUsers      int64            `json:"total_users"`
+	SignupsBySource map[string]int64 `json:"signups_by_source"`
+
+	// SignupsByWindow counts signups within each requested window, keyed by its label (e.g. "7d")
+	SignupsByWindow map[string]int64 `json:"signups_by_window"`
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"echo-base/domain/entity"
+)
+
+// ErrAPIKeyNotFound is returned when an API key does not exist for the user
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository defines the interface for API key repository
+type APIKeyRepository interface {
+	// Create stores an API key
+	Create(ctx context.Context, key *entity.APIKey) error
+
+	// ListByUser lists a user's unexpired API keys, newest first
+	ListByUser(ctx context.Context, userID int64) ([]*entity.APIKey, error)
+
+	// CountActive counts a user's unexpired API keys
+	CountActive(ctx context.Context, userID int64) (int64, error)
+
+	// GetActiveByHash gets the unexpired API key with the hash, or nil when there is none
+	GetActiveByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+
+	// Touch records that an API key was used at at
+	Touch(ctx context.Context, id int64, at time.Time) error
+
+	// Delete revokes a user's API key
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// apiKeyRepository is a PostgreSQL implementation of APIKeyRepository
+type apiKeyRepository struct {
+	db DBExecutor
+}
+
+// NewAPIKeyRepository creates a new PostgreSQL API key repository
+func NewAPIKeyRepository(db DBExecutor) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create stores an API key in PostgreSQL
+func (r *apiKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	key.CreatedAt = time.Now()
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.CreatedAt,
+		key.ExpiresAt,
+	).Scan(&key.ID)
+
+	if err != nil {
+		return fmt.Errorf("error creating api key: %w", err)
+	}
+	return nil
+}
+
+// ListByUser lists a user's unexpired API keys from PostgreSQL
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at
+		FROM api_keys
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error querying api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*entity.APIKey, 0)
+	for rows.Next() {
+		key := &entity.APIKey{}
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.KeyHash,
+			pq.Array(&key.Scopes),
+			&key.CreatedAt,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning api key row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CountActive counts a user's unexpired API keys in PostgreSQL
+func (r *apiKeyRepository) CountActive(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM api_keys
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+	`
+
+	var count int64
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, time.Now()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting api keys: %w", err)
+	}
+	return count, nil
+}
+
+// GetActiveByHash gets the unexpired API key with the hash from PostgreSQL
+func (r *apiKeyRepository) GetActiveByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > $2)
+	`
+
+	key := &entity.APIKey{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, keyHash, time.Now()).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting api key: %w", err)
+	}
+	return key, nil
+}
+
+// Touch records that an API key was used in PostgreSQL
+func (r *apiKeyRepository) Touch(ctx context.Context, id int64, at time.Time) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", at, id); err != nil {
+		return fmt.Errorf("error updating api key: %w", err)
+	}
+	return nil
+}
+
+// Delete revokes a user's API key in PostgreSQL
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id int64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
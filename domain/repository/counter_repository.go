@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+)
+
+// CounterRepository defines the interface for persisted analytics counters
+type CounterRepository interface {
+	// Load returns the stored value of every counter
+	Load() (map[string]int64, error)
+
+	// Add increments a counter by delta, creating it when missing
+	Add(name string, delta int64) error
+
+	// Reset zeroes every counter
+	Reset() error
+}
+
+// counterRepository is a PostgreSQL implementation of CounterRepository
+type counterRepository struct {
+	db DBExecutor
+}
+
+// NewCounterRepository creates a new PostgreSQL counter repository
+func NewCounterRepository(db DBExecutor) CounterRepository {
+	return &counterRepository{db: db}
+}
+
+// Load returns the stored value of every counter from PostgreSQL
+func (r *counterRepository) Load() (map[string]int64, error) {
+	rows, err := r.db.Query("SELECT name, value FROM counters")
+	if err != nil {
+		return nil, fmt.Errorf("error getting counters: %w", err)
+	}
+	defer rows.Close()
+
+	counters := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("error scanning counter: %w", err)
+		}
+		counters[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating counters: %w", err)
+	}
+
+	return counters, nil
+}
+
+// Add increments a counter in PostgreSQL, creating it when missing
+func (r *counterRepository) Add(name string, delta int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO counters (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = counters.value + EXCLUDED.value, updated_at = CURRENT_TIMESTAMP
+	`, name, delta)
+	if err != nil {
+		return fmt.Errorf("error incrementing counter: %w", err)
+	}
+	return nil
+}
+
+// Reset zeroes every counter in PostgreSQL
+func (r *counterRepository) Reset() error {
+	if _, err := r.db.Exec("UPDATE counters SET value = 0, updated_at = CURRENT_TIMESTAMP"); err != nil {
+		return fmt.Errorf("error resetting counters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDB is a scripted database behind the "fakedb" database/sql driver. Writes made in
+// a transaction only take effect when it commits, so tests can observe rollbacks.
+// Savepoints are handled by the driver and never reach exec.
+type fakeDB struct {
+	mu sync.Mutex
+
+	// query answers a query with its columns and rows
+	query func(query string, args []driver.Value) ([]string, [][]driver.Value, error)
+
+	// exec answers a statement; apply makes its change and runs at once outside a
+	// transaction and at commit inside one
+	exec func(query string, args []driver.Value) (apply func(), rowsAffected int64, err error)
+
+	// commitErr makes every commit fail, as when the connection drops before it completes
+	commitErr error
+
+	begins, commits, rollbacks int
+	statements                 []string
+}
+
+var (
+	fakeDBs      sync.Map
+	fakeDBSeq    atomic.Int64
+	registerOnce sync.Once
+)
+
+// openFakeDB opens a *sql.DB backed by fake, closed when the test ends
+func openFakeDB(t *testing.T, fake *fakeDB) *sql.DB {
+	t.Helper()
+
+	registerOnce.Do(func() { sql.Register("fakedb", fakeDriver{}) })
+	name := fmt.Sprintf("fake-%d", fakeDBSeq.Add(1))
+	fakeDBs.Store(name, fake)
+
+	db, err := sql.Open("fakedb", name)
+	if err != nil {
+		t.Fatalf("error opening fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBs.Delete(name)
+	})
+	return db
+}
+
+// ran reports whether a statement containing fragment was run
+func (f *fakeDB) ran(fragment string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, statement := range f.statements {
+		if strings.Contains(statement, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeDriver opens connections to the fakeDB registered under the DSN
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+// fakeConn is a connection to a fakeDB holding at most one transaction
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.db.mu.Lock()
+	c.db.begins++
+	c.db.mu.Unlock()
+
+	c.tx = &fakeTx{conn: c}
+	return c.tx, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.record(query)
+	if c.db.query == nil {
+		return nil, fmt.Errorf("fakedb: unexpected query %q", query)
+	}
+
+	columns, rows, err := c.db.query(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	// Like lib/pq, abort a statement whose context was cancelled while it ran
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.record(query)
+	if c.tx != nil && c.tx.savepoint(query) {
+		return driver.RowsAffected(0), nil
+	}
+	if c.db.exec == nil {
+		return nil, fmt.Errorf("fakedb: unexpected statement %q", query)
+	}
+
+	apply, affected, err := c.db.exec(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if apply != nil {
+		if c.tx != nil {
+			c.tx.pending = append(c.tx.pending, apply)
+		} else {
+			c.db.mu.Lock()
+			apply()
+			c.db.mu.Unlock()
+		}
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+// record logs a statement run on the connection
+func (c *fakeConn) record(query string) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.statements = append(c.db.statements, strings.Join(strings.Fields(query), " "))
+}
+
+// fakeTx applies its writes only when committed
+type fakeTx struct {
+	conn       *fakeConn
+	pending    []func()
+	savepoints map[string]int
+}
+
+// savepoint runs query if it creates or rolls back to a savepoint, reporting whether it did
+func (tx *fakeTx) savepoint(query string) bool {
+	fields := strings.Fields(query)
+	switch {
+	case len(fields) == 2 && fields[0] == "SAVEPOINT":
+		if tx.savepoints == nil {
+			tx.savepoints = map[string]int{}
+		}
+		tx.savepoints[fields[1]] = len(tx.pending)
+		return true
+	case len(fields) == 4 && strings.Join(fields[:3], " ") == "ROLLBACK TO SAVEPOINT":
+		if mark, ok := tx.savepoints[fields[3]]; ok {
+			tx.pending = tx.pending[:mark]
+		}
+		return true
+	}
+	return false
+}
+
+func (tx *fakeTx) Commit() error {
+	db := tx.conn.db
+	tx.conn.tx = nil
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.commitErr != nil {
+		db.rollbacks++
+		return db.commitErr
+	}
+	for _, apply := range tx.pending {
+		apply()
+	}
+	db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	db := tx.conn.db
+	tx.conn.tx = nil
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.rollbacks++
+	return nil
+}
+
+// fakeRows iterates over scripted rows
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// values drops the names of statement arguments
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"echo-base/domain/entity"
+)
+
+// LoginHistoryRepository defines the interface for login history repository
+type LoginHistoryRepository interface {
+	// Create records a login attempt
+	Create(ctx context.Context, entry *entity.LoginHistory) error
+
+	// CountSuccessful counts a user's successful logins since the given time
+	CountSuccessful(ctx context.Context, userID int64, since time.Time) (int64, error)
+
+	// ExistsForDevice checks whether a user successfully logged in from the IP and user agent since the given time
+	ExistsForDevice(ctx context.Context, userID int64, ip, userAgent string, since time.Time) (bool, error)
+
+	// ListByUser gets a page of a user's login attempts, newest first, with the total count
+	ListByUser(ctx context.Context, userID int64, page, limit int64) ([]*entity.LoginHistory, int64, error)
+}
+
+// loginHistoryRepository is a PostgreSQL implementation of LoginHistoryRepository
+type loginHistoryRepository struct {
+	db DBExecutor
+}
+
+// NewLoginHistoryRepository creates a new PostgreSQL login history repository
+func NewLoginHistoryRepository(db DBExecutor) LoginHistoryRepository {
+	return &loginHistoryRepository{db: db}
+}
+
+// Create records a login attempt in PostgreSQL
+func (r *loginHistoryRepository) Create(ctx context.Context, entry *entity.LoginHistory) error {
+	query := `
+		INSERT INTO login_history (user_id, ip, user_agent, success, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	entry.CreatedAt = time.Now()
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		entry.UserID,
+		entry.IP,
+		entry.UserAgent,
+		entry.Success,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return fmt.Errorf("error creating login history: %w", err)
+	}
+	return nil
+}
+
+// CountSuccessful counts a user's successful logins since the given time
+func (r *loginHistoryRepository) CountSuccessful(ctx context.Context, userID int64, since time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM login_history
+		WHERE user_id = $1 AND success = TRUE AND created_at >= $2
+	`
+
+	var count int64
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting login history: %w", err)
+	}
+	return count, nil
+}
+
+// ExistsForDevice checks whether a user successfully logged in from the IP and user agent since the given time
+func (r *loginHistoryRepository) ExistsForDevice(ctx context.Context, userID int64, ip, userAgent string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM login_history
+			WHERE user_id = $1 AND ip = $2 AND user_agent = $3 AND success = TRUE AND created_at >= $4
+		)
+	`
+
+	var exists bool
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, ip, userAgent, since).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking login history: %w", err)
+	}
+	return exists, nil
+}
+
+// ListByUser gets a page of a user's login attempts, newest first, with the total count
+func (r *loginHistoryRepository) ListByUser(ctx context.Context, userID int64, page, limit int64) ([]*entity.LoginHistory, int64, error) {
+	var total int64
+	if err := executor(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM login_history WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting login history: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, ip, user_agent, success, created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting login history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.LoginHistory, 0)
+	for rows.Next() {
+		entry := &entity.LoginHistory{}
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.IP, &entry.UserAgent, &entry.Success, &entry.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("error scanning login history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating login history: %w", err)
+	}
+
+	return entries, total, nil
+}
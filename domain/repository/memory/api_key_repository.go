@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+// apiKeyRepository is an in-memory implementation of repository.APIKeyRepository
+type apiKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[int64]*entity.APIKey
+	nextID int64
+}
+
+// NewAPIKeyRepository creates a new in-memory API key repository
+func NewAPIKeyRepository() repository.APIKeyRepository {
+	return &apiKeyRepository{
+		keys: make(map[int64]*entity.APIKey),
+	}
+}
+
+// Create stores an API key
+func (r *apiKeyRepository) Create(_ context.Context, key *entity.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	key.ID = r.nextID
+	key.CreatedAt = time.Now()
+	c := *key
+	r.keys[key.ID] = &c
+	return nil
+}
+
+// ListByUser lists a user's unexpired API keys, newest first
+func (r *apiKeyRepository) ListByUser(_ context.Context, userID int64) ([]*entity.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*entity.APIKey, 0)
+	for _, key := range r.keys {
+		if key.UserID == userID && apiKeyActive(key, now) {
+			c := *key
+			keys = append(keys, &c)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, nil
+}
+
+// CountActive counts a user's unexpired API keys
+func (r *apiKeyRepository) CountActive(_ context.Context, userID int64) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var count int64
+	for _, key := range r.keys {
+		if key.UserID == userID && apiKeyActive(key, now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetActiveByHash gets the unexpired API key with the hash, or nil when there is none
+func (r *apiKeyRepository) GetActiveByHash(_ context.Context, keyHash string) (*entity.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash && apiKeyActive(key, now) {
+			c := *key
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// Touch records that an API key was used at at
+func (r *apiKeyRepository) Touch(_ context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+// Delete revokes a user's API key
+func (r *apiKeyRepository) Delete(_ context.Context, userID, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID {
+		return repository.ErrAPIKeyNotFound
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// apiKeyActive reports whether key has not expired at now
+func apiKeyActive(key *entity.APIKey, now time.Time) bool {
+	return key.ExpiresAt == nil || key.ExpiresAt.After(now)
+}
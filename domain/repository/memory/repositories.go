@@ -0,0 +1,533 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+	"echo-base/events"
+)
+
+// store is an in-memory repository.Store. The memory store has no transactions:
+// fn runs directly against the shared repositories and its writes are not rolled back on error.
+type store struct {
+	repos repository.Repositories
+}
+
+// NewStore creates a new in-memory store over the given repositories
+func NewStore(repos repository.Repositories) repository.Store {
+	return store{repos: repos}
+}
+
+// WithTx runs fn with the shared repositories, without a transaction
+func (s store) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return fn(s.repos)
+}
+
+// DryRun is unsupported: without transactions the memory store cannot undo fn's writes
+func (s store) DryRun(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return repository.ErrDryRunUnsupported
+}
+
+// outboxRepository is an in-memory implementation of repository.OutboxRepository
+type outboxRepository struct {
+	mu      sync.Mutex
+	pending []*outboxEntry
+	failed  []events.Event
+	nextID  int64
+}
+
+// outboxEntry is an unsent event with its retry state
+type outboxEntry struct {
+	event         events.Event
+	attempts      int
+	nextAttemptAt time.Time
+}
+
+// NewOutboxRepository creates a new in-memory outbox repository
+func NewOutboxRepository() repository.OutboxRepository {
+	return &outboxRepository{}
+}
+
+// Enqueue stores an event to be relayed
+func (r *outboxRepository) Enqueue(_ context.Context, event events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	event.ID = r.nextID
+	r.pending = append(r.pending, &outboxEntry{event: event})
+	return nil
+}
+
+// DispatchPending takes due events off the outbox and passes them to dispatch without
+// holding the lock, then schedules the failed ones for a retry or dead-letters them
+func (r *outboxRepository) DispatchPending(ctx context.Context, limit int, retry events.RetryPolicy, dispatch func(event events.Event) error) (int, error) {
+	claimed := r.claim(limit)
+
+	sent := 0
+	var retries []*outboxEntry
+	for i, entry := range claimed {
+		if err := ctx.Err(); err != nil {
+			r.release(append(retries, claimed[i:]...))
+			return sent, err
+		}
+		if err := dispatch(entry.event); err != nil {
+			entry.attempts++
+			entry.nextAttemptAt = time.Now().Add(retry.Delay(entry.attempts))
+			retries = append(retries, entry)
+			continue
+		}
+		sent++
+	}
+
+	r.mu.Lock()
+	for _, entry := range retries {
+		if retry.Exhausted(entry.attempts) {
+			r.failed = append(r.failed, entry.event)
+			continue
+		}
+		r.pending = append(r.pending, entry)
+	}
+	r.sortPending()
+	r.mu.Unlock()
+
+	return sent, nil
+}
+
+// claim removes up to limit due events from the outbox
+func (r *outboxRepository) claim(limit int) []*outboxEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var claimed []*outboxEntry
+	remaining := make([]*outboxEntry, 0, len(r.pending))
+	for _, entry := range r.pending {
+		if len(claimed) < limit && !entry.nextAttemptAt.After(now) {
+			claimed = append(claimed, entry)
+			continue
+		}
+		remaining = append(remaining, entry)
+	}
+	r.pending = remaining
+	return claimed
+}
+
+// release puts claimed events back in the outbox
+func (r *outboxRepository) release(entries []*outboxEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(r.pending, entries...)
+	r.sortPending()
+}
+
+// sortPending restores the enqueue order of the outbox after events are put back
+func (r *outboxRepository) sortPending() {
+	sort.Slice(r.pending, func(i, j int) bool { return r.pending[i].event.ID < r.pending[j].event.ID })
+}
+
+// sessionRepository is an in-memory implementation of repository.SessionRepository
+type sessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]*entity.Session
+}
+
+// NewSessionRepository creates a new in-memory session repository
+func NewSessionRepository() repository.SessionRepository {
+	return &sessionRepository{
+		sessions: make(map[string]*entity.Session),
+	}
+}
+
+// Create creates a new session
+func (r *sessionRepository) Create(_ context.Context, session *entity.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastActivityAt = now
+	c := *session
+	r.sessions[session.ID] = &c
+	return nil
+}
+
+// GetByID gets a session by ID
+func (r *sessionRepository) GetByID(_ context.Context, id string) (*entity.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if session, ok := r.sessions[id]; ok {
+		c := *session
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// Touch updates the session's last activity time
+func (r *sessionRepository) Touch(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastActivityAt = at
+	}
+	return nil
+}
+
+// CountActive counts a user's sessions active since the given time
+func (r *sessionRepository) CountActive(_ context.Context, userID int64, since time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, session := range r.sessions {
+		if session.UserID == userID && !session.LastActivityAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteInactive deletes sessions with no activity since the given time
+func (r *sessionRepository) DeleteInactive(_ context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, session := range r.sessions {
+		if session.LastActivityAt.Before(before) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// loginHistoryRepository is an in-memory implementation of repository.LoginHistoryRepository
+type loginHistoryRepository struct {
+	mu      sync.RWMutex
+	entries []entity.LoginHistory
+	nextID  int64
+}
+
+// NewLoginHistoryRepository creates a new in-memory login history repository
+func NewLoginHistoryRepository() repository.LoginHistoryRepository {
+	return &loginHistoryRepository{}
+}
+
+// Create records a login attempt
+func (r *loginHistoryRepository) Create(_ context.Context, entry *entity.LoginHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	entry.ID = r.nextID
+	entry.CreatedAt = time.Now()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// CountSuccessful counts a user's successful logins since the given time
+func (r *loginHistoryRepository) CountSuccessful(_ context.Context, userID int64, since time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, entry := range r.entries {
+		if entry.UserID == userID && entry.Success && !entry.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ExistsForDevice checks whether a user successfully logged in from the IP and user agent since the given time
+func (r *loginHistoryRepository) ExistsForDevice(_ context.Context, userID int64, ip, userAgent string, since time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, entry := range r.entries {
+		if entry.UserID == userID && entry.Success && entry.IP == ip &&
+			entry.UserAgent == userAgent && !entry.CreatedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListByUser gets a page of a user's login attempts, newest first, with the total count
+func (r *loginHistoryRepository) ListByUser(_ context.Context, userID int64, page, limit int64) ([]*entity.LoginHistory, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Entries are appended in order, so walking backwards yields newest first
+	matched := make([]*entity.LoginHistory, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].UserID == userID {
+			entry := r.entries[i]
+			matched = append(matched, &entry)
+		}
+	}
+
+	total := int64(len(matched))
+	start := (page - 1) * limit
+	if start >= total {
+		return []*entity.LoginHistory{}, total, nil
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return matched[start:end], total, nil
+}
+
+// systemRepository is an in-memory implementation of repository.SystemRepository
+type systemRepository struct{}
+
+// NewSystemRepository creates a new in-memory system repository
+func NewSystemRepository() repository.SystemRepository {
+	return systemRepository{}
+}
+
+// ServerVersion reports the in-memory store
+func (systemRepository) ServerVersion() (string, error) {
+	return "in-memory store", nil
+}
+
+// InstalledExtensions reports no extensions; the memory store has none
+func (systemRepository) InstalledExtensions(names []string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// trustedDeviceRepository is an in-memory implementation of repository.TrustedDeviceRepository
+type trustedDeviceRepository struct {
+	mu      sync.RWMutex
+	devices map[int64]*entity.TrustedDevice
+	nextID  int64
+}
+
+// NewTrustedDeviceRepository creates a new in-memory trusted device repository
+func NewTrustedDeviceRepository() repository.TrustedDeviceRepository {
+	return &trustedDeviceRepository{
+		devices: make(map[int64]*entity.TrustedDevice),
+	}
+}
+
+// Create stores a trusted device
+func (r *trustedDeviceRepository) Create(_ context.Context, device *entity.TrustedDevice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	device.ID = r.nextID
+	device.CreatedAt = now
+	device.LastUsedAt = now
+	c := *device
+	r.devices[device.ID] = &c
+	return nil
+}
+
+// GetByTokenHash gets a user's unexpired trusted device by token hash
+func (r *trustedDeviceRepository) GetByTokenHash(_ context.Context, userID int64, tokenHash string) (*entity.TrustedDevice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, device := range r.devices {
+		if device.UserID == userID && device.TokenHash == tokenHash && device.ExpiresAt.After(now) {
+			c := *device
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListByUser lists a user's unexpired trusted devices, most recently used first
+func (r *trustedDeviceRepository) ListByUser(_ context.Context, userID int64) ([]*entity.TrustedDevice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	devices := make([]*entity.TrustedDevice, 0)
+	for _, device := range r.devices {
+		if device.UserID == userID && device.ExpiresAt.After(now) {
+			c := *device
+			devices = append(devices, &c)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LastUsedAt.After(devices[j].LastUsedAt)
+	})
+	return devices, nil
+}
+
+// Touch updates the device's last used time
+func (r *trustedDeviceRepository) Touch(_ context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if device, ok := r.devices[id]; ok {
+		device.LastUsedAt = at
+	}
+	return nil
+}
+
+// Delete revokes a user's trusted device
+func (r *trustedDeviceRepository) Delete(_ context.Context, userID, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.devices[id]
+	if !ok || device.UserID != userID {
+		return repository.ErrTrustedDeviceNotFound
+	}
+	delete(r.devices, id)
+	return nil
+}
+
+// twoFactorRepository is an in-memory implementation of repository.TwoFactorRepository
+type twoFactorRepository struct {
+	mu            sync.RWMutex
+	enrollments   map[int64]*entity.TwoFactor
+	recoveryCodes map[int64]map[string]bool
+}
+
+// NewTwoFactorRepository creates a new in-memory two-factor repository
+func NewTwoFactorRepository() repository.TwoFactorRepository {
+	return &twoFactorRepository{
+		enrollments:   make(map[int64]*entity.TwoFactor),
+		recoveryCodes: make(map[int64]map[string]bool),
+	}
+}
+
+// Get gets a user's TOTP enrollment
+func (r *twoFactorRepository) Get(_ context.Context, userID int64) (*entity.TwoFactor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if tf, ok := r.enrollments[userID]; ok {
+		c := *tf
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// SavePending stores a pending TOTP secret; enabled enrollments are left untouched
+func (r *twoFactorRepository) SavePending(_ context.Context, userID int64, secretEncrypted string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tf, ok := r.enrollments[userID]; ok && tf.Enabled {
+		return nil
+	}
+	r.enrollments[userID] = &entity.TwoFactor{
+		UserID:          userID,
+		SecretEncrypted: secretEncrypted,
+		CreatedAt:       time.Now(),
+	}
+	return nil
+}
+
+// Enable activates a user's TOTP enrollment and replaces their recovery codes
+func (r *twoFactorRepository) Enable(_ context.Context, userID int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tf, ok := r.enrollments[userID]; ok {
+		now := time.Now()
+		tf.Enabled = true
+		tf.EnabledAt = &now
+	}
+
+	r.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates a user's recovery codes and stores new ones
+func (r *twoFactorRepository) ReplaceRecoveryCodes(_ context.Context, userID int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+// ConsumeRecoveryCode removes an unused recovery code, reporting whether one matched
+func (r *twoFactorRepository) ConsumeRecoveryCode(_ context.Context, userID int64, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recoveryCodes[userID][codeHash] {
+		return false, nil
+	}
+	delete(r.recoveryCodes[userID], codeHash)
+	return true, nil
+}
+
+// StartChallenge replaces the user's open login challenge
+func (r *twoFactorRepository) StartChallenge(_ context.Context, userID int64, challengeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tf, ok := r.enrollments[userID]; ok {
+		tf.ChallengeID = challengeID
+		tf.ChallengeFailures = 0
+	}
+	return nil
+}
+
+// FailChallenge counts a wrong code against an open login challenge
+func (r *twoFactorRepository) FailChallenge(_ context.Context, userID int64, challengeID string, maxFailures int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tf, ok := r.enrollments[userID]; ok && tf.ChallengeID != "" && tf.ChallengeID == challengeID {
+		tf.ChallengeFailures++
+		if tf.ChallengeFailures >= maxFailures {
+			tf.ChallengeID = ""
+		}
+	}
+	return nil
+}
+
+// CompleteChallenge closes an open login challenge, reporting whether it was still open
+func (r *twoFactorRepository) CompleteChallenge(_ context.Context, userID int64, challengeID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf, ok := r.enrollments[userID]
+	if !ok || tf.ChallengeID == "" || tf.ChallengeID != challengeID {
+		return false, nil
+	}
+	tf.ChallengeID = ""
+	return true, nil
+}
+
+// UseStep records the TOTP time step of an accepted code, reporting false when it was already used
+func (r *twoFactorRepository) UseStep(_ context.Context, userID int64, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf, ok := r.enrollments[userID]
+	if !ok || step <= tf.LastUsedStep {
+		return false, nil
+	}
+	tf.LastUsedStep = step
+	return true, nil
+}
+
+// replaceRecoveryCodes stores the unused codes for a user. Callers must hold the lock.
+func (r *twoFactorRepository) replaceRecoveryCodes(userID int64, recoveryCodeHashes []string) {
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = true
+	}
+	r.recoveryCodes[userID] = codes
+}
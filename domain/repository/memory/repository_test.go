@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"echo-base/domain/repository"
+	"echo-base/domain/repository/repositorytest"
+	"echo-base/events"
+)
+
+// newRepos returns fresh in-memory user and role repositories
+func newRepos(t *testing.T) (repository.UserRepository, repository.RoleRepository) {
+	users := NewUserRepository()
+	return users, NewRoleRepository(users)
+}
+
+func TestUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, newRepos)
+}
+
+func TestRoleRepository(t *testing.T) {
+	repositorytest.RunRoleRepositoryTests(t, newRepos)
+}
+
+func TestOutboxRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		retry         events.RetryPolicy
+		wantRetried   bool
+		wantRemaining int
+	}{
+		{name: "retried when due", retry: events.RetryPolicy{MaxAttempts: 3}, wantRetried: true},
+		{name: "held back during backoff", retry: events.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}, wantRemaining: 1},
+		{name: "dead-lettered", retry: events.RetryPolicy{MaxAttempts: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			outbox := NewOutboxRepository()
+			event, err := events.New("test.event", map[string]int{"n": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := outbox.Enqueue(ctx, event); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, err := outbox.DispatchPending(ctx, 10, tt.retry, func(event events.Event) error {
+				return errors.New("smtp unavailable")
+			})
+			if err != nil || sent != 0 {
+				t.Fatalf("first run = %d, %v; want 0, nil", sent, err)
+			}
+
+			retried := false
+			if _, err := outbox.DispatchPending(ctx, 10, tt.retry, func(event events.Event) error {
+				retried = true
+				return nil
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if retried != tt.wantRetried {
+				t.Errorf("retried = %v, want %v", retried, tt.wantRetried)
+			}
+			if remaining := len(outbox.(*outboxRepository).pending); remaining != tt.wantRemaining {
+				t.Errorf("pending events = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+// roleRepository is an in-memory implementation of repository.RoleRepository
+// serving the default roles seeded by migrations. It keeps the in-memory user
+// repository's set of valid roles in sync, standing in for the foreign key.
+type roleRepository struct {
+	mu     sync.RWMutex
+	roles  []entity.Role
+	nextID int64
+	users  *userRepository
+}
+
+// NewRoleRepository creates a new in-memory role repository with the default roles.
+// users is the in-memory user repository whose role assignments it constrains.
+func NewRoleRepository(users repository.UserRepository) repository.RoleRepository {
+	now := time.Now()
+	userRepo, _ := users.(*userRepository)
+	return &roleRepository{
+		roles: []entity.Role{
+			{ID: entity.RoleIDUser, Name: "user", CreatedAt: now, UpdatedAt: now},
+			{ID: entity.RoleIDAdmin, Name: "admin", CreatedAt: now, UpdatedAt: now},
+		},
+		nextID: entity.RoleIDAdmin,
+		users:  userRepo,
+	}
+}
+
+// GetAll returns all roles ordered by ID
+func (r *roleRepository) GetAll(_ context.Context) ([]entity.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make([]entity.Role, len(r.roles))
+	copy(roles, r.roles)
+	return roles, nil
+}
+
+// GetByID gets a role by ID
+func (r *roleRepository) GetByID(_ context.Context, id int64) (*entity.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, role := range r.roles {
+		if role.ID == id {
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetByName gets a role by name
+func (r *roleRepository) GetByName(_ context.Context, name string) (*entity.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, role := range r.roles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+
+// Create adds a role with the next free ID
+func (r *roleRepository) Create(_ context.Context, name string) (*entity.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.roles {
+		if existing.Name == name {
+			return nil, repository.ErrRoleNameTaken
+		}
+	}
+
+	r.nextID++
+	role := r.add(entity.Role{ID: r.nextID, Name: name})
+	return &role, nil
+}
+
+// CreateWithID adds a role with a fixed ID unless its ID or name already exists
+func (r *roleRepository) CreateWithID(_ context.Context, role entity.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.roles {
+		if existing.ID == role.ID || existing.Name == role.Name {
+			return nil
+		}
+	}
+
+	r.add(role)
+	r.nextID = max(r.nextID, role.ID)
+	return nil
+}
+
+// add stores a new role and registers it with the user repository. Callers must hold the lock.
+func (r *roleRepository) add(role entity.Role) entity.Role {
+	now := time.Now()
+	role.CreatedAt, role.UpdatedAt = now, now
+	r.roles = append(r.roles, role)
+	sort.Slice(r.roles, func(i, j int) bool { return r.roles[i].ID < r.roles[j].ID })
+
+	if r.users != nil {
+		r.users.mu.Lock()
+		r.users.roles[role.ID] = role.Name
+		r.users.mu.Unlock()
+	}
+	return role
+}
+
+// Update renames a role
+func (r *roleRepository) Update(_ context.Context, role *entity.Role) (*entity.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := -1
+	for i, existing := range r.roles {
+		if existing.ID == role.ID {
+			index = i
+		} else if existing.Name == role.Name {
+			return nil, repository.ErrRoleNameTaken
+		}
+	}
+	if index < 0 {
+		return nil, repository.ErrRoleNotFound
+	}
+
+	r.roles[index].Name = role.Name
+	r.roles[index].UpdatedAt = time.Now()
+	updated := r.roles[index]
+
+	if r.users != nil {
+		r.users.mu.Lock()
+		r.users.roles[role.ID] = role.Name
+		r.users.mu.Unlock()
+	}
+	return &updated, nil
+}
+
+// Delete deletes a role no user has
+func (r *roleRepository) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := -1
+	for i, existing := range r.roles {
+		if existing.ID == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		return repository.ErrRoleNotFound
+	}
+
+	if r.users != nil {
+		r.users.mu.Lock()
+		defer r.users.mu.Unlock()
+
+		for _, user := range r.users.users {
+			if user.RoleID == id {
+				return repository.ErrRoleInUse
+			}
+		}
+		delete(r.users.roles, id)
+	}
+
+	r.roles = append(r.roles[:index], r.roles[index+1:]...)
+	return nil
+}
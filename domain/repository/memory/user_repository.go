@@ -0,0 +1,509 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+// userRepository is an in-memory implementation of repository.UserRepository
+type userRepository struct {
+	mu     sync.RWMutex
+	users  map[int64]*entity.User
+	roles  map[int64]string // role ID -> name, standing in for the roles table
+	nextID int64
+}
+
+// NewUserRepository creates a new in-memory user repository seeded with the default roles
+func NewUserRepository() repository.UserRepository {
+	return &userRepository{
+		users: make(map[int64]*entity.User),
+		roles: map[int64]string{
+			entity.RoleIDUser:  "user",
+			entity.RoleIDAdmin: "admin",
+		},
+	}
+}
+
+// GetByID gets a user by ID
+func (r *userRepository) GetByID(_ context.Context, id int64) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if user, ok := r.active(id); ok {
+		return r.copyUser(user), nil
+	}
+	return nil, nil
+}
+
+// GetByIDs gets the users with the given IDs in the order of ids
+func (r *userRepository) GetByIDs(_ context.Context, ids []int64) ([]*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*entity.User, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if user, ok := r.active(id); ok {
+			users = append(users, r.copyUser(user))
+		}
+	}
+	return users, nil
+}
+
+// GetByEmail gets a user by email
+func (r *userRepository) GetByEmail(_ context.Context, email string) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && user.DeletedAt == nil {
+			return r.copyUser(user), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetByUsername gets a user by username, ignoring case
+func (r *userRepository) GetByUsername(_ context.Context, username string) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username != "" && strings.EqualFold(user.Username, username) && user.DeletedAt == nil {
+			return r.copyUser(user), nil
+		}
+	}
+	return nil, nil
+}
+
+// Create creates a new user
+func (r *userRepository) Create(_ context.Context, user *entity.User) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.RoleID == 0 {
+		user.RoleID = entity.RoleIDUser
+	}
+	if _, ok := r.roles[user.RoleID]; !ok {
+		return nil, repository.ErrRoleNotFound
+	}
+	if user.Status == "" {
+		user.Status = entity.UserStatusActive
+	}
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email && existing.DeletedAt == nil {
+			return nil, repository.ErrDuplicateEmail
+		}
+		if user.Username != "" && strings.EqualFold(existing.Username, user.Username) {
+			return nil, repository.ErrDuplicateUsername
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.RoleName = r.roles[user.RoleID]
+	r.users[user.ID] = r.copyUser(user)
+
+	return user, nil
+}
+
+// Update updates a user's name and role
+func (r *userRepository) Update(_ context.Context, user *entity.User) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(user.ID)
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	if _, ok := r.roles[user.RoleID]; !ok {
+		return nil, repository.ErrRoleNotFound
+	}
+
+	existing.Name = user.Name
+	existing.RoleID = user.RoleID
+	existing.UpdatedAt = time.Now()
+
+	return r.copyUser(existing), nil
+}
+
+// Patch updates the fields set in patch
+func (r *userRepository) Patch(_ context.Context, id int64, patch *entity.UserPatchPayload) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return nil, nil
+	}
+	if patch.RoleID != nil {
+		if _, ok := r.roles[*patch.RoleID]; !ok {
+			return nil, repository.ErrRoleNotFound
+		}
+		existing.RoleID = *patch.RoleID
+		existing.RoleName = r.roles[*patch.RoleID]
+	}
+	if patch.Name != nil {
+		existing.Name = *patch.Name
+	}
+	existing.UpdatedAt = time.Now()
+
+	return r.copyUser(existing), nil
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *userRepository) UpdatePassword(_ context.Context, id int64, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+
+	existing.Password = hashedPassword
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete soft-deletes a user
+func (r *userRepository) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+	now := time.Now()
+	existing.DeletedAt = &now
+	return nil
+}
+
+// Restore undoes the soft delete of a user
+func (r *userRepository) Restore(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok || existing.DeletedAt == nil {
+		return repository.ErrDeletedUserNotFound
+	}
+	for _, other := range r.users {
+		if other.Email == existing.Email && other.DeletedAt == nil {
+			return repository.ErrDuplicateEmail
+		}
+	}
+
+	existing.DeletedAt = nil
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (r *userRepository) MarkEmailVerified(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+
+	existing.EmailVerified = true
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateStatus sets a user's account status
+func (r *userRepository) UpdateStatus(_ context.Context, id int64, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+
+	existing.Status = status
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateEmail replaces a user's email address and its verified state
+func (r *userRepository) UpdateEmail(_ context.Context, id int64, email string, verified bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+	for _, other := range r.users {
+		if other.ID != id && other.Email == email && other.DeletedAt == nil {
+			return repository.ErrDuplicateEmail
+		}
+	}
+
+	existing.Email = email
+	existing.EmailVerified = verified
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// GetAll gets all users, newest first
+func (r *userRepository) GetAll(_ context.Context) ([]*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(entity.UserSearchFilter{}), nil
+}
+
+// GetAllPagination gets all users with pagination, optional search and sorting
+func (r *userRepository) GetAllPagination(_ context.Context, params entity.PaginationParams) ([]*entity.User, int64, error) {
+	page, limit := params.Page, params.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > entity.MaxPageLimit {
+		limit = entity.DefaultPageLimit
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.sorted(entity.UserSearchFilter{
+		Search:         params.Search,
+		RoleID:         params.RoleID,
+		CreatedAfter:   params.CreatedAfter,
+		CreatedBefore:  params.CreatedBefore,
+		IncludeDeleted: params.IncludeDeleted,
+	})
+	sortUsers(users, params.Sort, params.Order)
+	total := int64(len(users))
+
+	offset := (page - 1) * limit
+	if offset >= total {
+		return make([]*entity.User, 0), total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return users[offset:end], total, nil
+}
+
+// ExplainSearch returns the PostgreSQL WHERE clause for the search and the exact number of matches
+func (r *userRepository) ExplainSearch(_ context.Context, filter entity.UserSearchFilter) (*entity.UserSearchExplain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	where, _, params := repository.BuildUserFilter(filter)
+	return &entity.UserSearchExplain{
+		Filter:        filter,
+		Where:         where,
+		Parameters:    params,
+		EstimatedRows: int64(len(r.sorted(filter))),
+	}, nil
+}
+
+// CountBySignupSource counts users grouped by referral source
+func (r *userRepository) CountBySignupSource(_ context.Context) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		source := user.ReferralSource
+		if source == "" {
+			source = "direct"
+		}
+		counts[source]++
+	}
+	return counts, nil
+}
+
+// CountCreatedSince counts users created since each of the given times
+func (r *userRepository) CountCreatedSince(_ context.Context, since []time.Time) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make([]int64, len(since))
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		for i, t := range since {
+			if !user.CreatedAt.Before(t) {
+				counts[i]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// BulkUpdateRole sets the role of many users at once
+func (r *userRepository) BulkUpdateRole(_ context.Context, ids []int64, roleID int64) (int64, []int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[roleID]; !ok {
+		return 0, nil, repository.ErrRoleNotFound
+	}
+
+	targets := make(map[int64]bool, len(ids))
+	invalidIDs := make([]int64, 0)
+	for _, id := range ids {
+		if _, ok := r.active(id); ok {
+			targets[id] = true
+		} else {
+			invalidIDs = append(invalidIDs, id)
+		}
+	}
+
+	// Prevent demoting every remaining admin
+	if roleID != entity.RoleIDAdmin {
+		var demoted, remaining int
+		for id, user := range r.users {
+			if user.RoleID != entity.RoleIDAdmin || user.DeletedAt != nil {
+				continue
+			}
+			if targets[id] {
+				demoted++
+			} else {
+				remaining++
+			}
+		}
+		if demoted > 0 && remaining == 0 {
+			return 0, nil, repository.ErrLastAdmin
+		}
+	}
+
+	now := time.Now()
+	for id := range targets {
+		r.users[id].RoleID = roleID
+		r.users[id].UpdatedAt = now
+	}
+
+	return int64(len(targets)), invalidIDs, nil
+}
+
+// LockActiveAdmins returns the IDs of active admins in ascending order. The memory store
+// has no transactions, so nothing stays locked.
+func (r *userRepository) LockActiveAdmins(_ context.Context) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int64, 0)
+	for id, user := range r.users {
+		if user.RoleID == entity.RoleIDAdmin && user.Status == entity.UserStatusActive && user.DeletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// sorted returns copies of the users matching filter, newest first. Callers must hold the lock.
+func (r *userRepository) sorted(filter entity.UserSearchFilter) []*entity.User {
+	search := strings.ToLower(filter.Search)
+
+	users := make([]*entity.User, 0, len(r.users))
+	for _, user := range r.users {
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		if filter.RoleID > 0 && user.RoleID != filter.RoleID {
+			continue
+		}
+		if filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if !filter.IncludeDeleted && user.DeletedAt != nil {
+			continue
+		}
+		users = append(users, r.copyUser(user))
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].ID > users[j].ID
+		}
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+	return users
+}
+
+// sortUsers orders users by field (created_at when unknown), breaking ties by ID
+func sortUsers(users []*entity.User, field, order string) {
+	compare := func(a, b *entity.User) int {
+		switch field {
+		case "id":
+			return 0
+		case "name":
+			return strings.Compare(a.Name, b.Name)
+		case "email":
+			return strings.Compare(a.Email, b.Email)
+		case "username":
+			return strings.Compare(a.Username, b.Username)
+		case "updated_at":
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		c := compare(users[i], users[j])
+		if c == 0 {
+			c = cmp.Compare(users[i].ID, users[j].ID)
+		}
+		if order == entity.SortAsc {
+			return c < 0
+		}
+		return c > 0
+	})
+}
+
+// active returns the user with id unless it does not exist or is soft-deleted.
+// Callers must hold the lock.
+func (r *userRepository) active(id int64) (*entity.User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, false
+	}
+	return user, true
+}
+
+// copyUser returns a copy with its role name so callers cannot mutate stored users.
+// Callers must hold the lock.
+func (r *userRepository) copyUser(user *entity.User) *entity.User {
+	c := *user
+	c.RoleName = r.roles[user.RoleID]
+	return &c
+}
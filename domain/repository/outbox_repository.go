@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"echo-base/events"
+)
+
+// OutboxRepository defines the interface for the transactional event outbox
+type OutboxRepository interface {
+	// Enqueue stores an event to be relayed
+	Enqueue(ctx context.Context, event events.Event) error
+
+	// DispatchPending passes due events to dispatch, marking the successful ones as sent
+	// and scheduling or dead-lettering the failed ones under retry
+	DispatchPending(ctx context.Context, limit int, retry events.RetryPolicy, dispatch func(event events.Event) error) (int, error)
+}
+
+// outboxRepository is a PostgreSQL implementation of OutboxRepository
+type outboxRepository struct {
+	db DBExecutor
+}
+
+// NewOutboxRepository creates a new PostgreSQL outbox repository
+func NewOutboxRepository(db DBExecutor) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Enqueue stores an event in the outbox table
+func (r *outboxRepository) Enqueue(ctx context.Context, event events.Event) error {
+	query := `
+		INSERT INTO outbox (event_name, payload, created_at)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, event.Name, []byte(event.Payload), event.OccurredAt); err != nil {
+		return fmt.Errorf("error enqueuing event: %w", err)
+	}
+	return nil
+}
+
+// DispatchPending claims a batch of due events by pushing their next attempt past the
+// lease in one statement, so no transaction or row lock is held while they are
+// dispatched. Each outcome is then recorded on its own: successes are marked sent and
+// failures are scheduled for a retry or dead-lettered. Claimed events whose outcome is
+// never recorded, because the run fails or the process dies, are redelivered once the
+// lease expires.
+func (r *outboxRepository) DispatchPending(ctx context.Context, limit int, retry events.RetryPolicy, dispatch func(event events.Event) error) (int, error) {
+	db := executor(ctx, r.db)
+	now := time.Now()
+
+	rows, err := db.QueryContext(ctx, `
+		UPDATE outbox SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL
+				AND (next_attempt_at IS NULL OR next_attempt_at <= $2)
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_name, payload, created_at, attempts
+	`, now.Add(retry.Lease), now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error claiming outbox events: %w", err)
+	}
+
+	type claimedEvent struct {
+		event    events.Event
+		attempts int
+	}
+	claimed := make([]claimedEvent, 0, limit)
+	for rows.Next() {
+		var c claimedEvent
+		var payload []byte
+		if err := rows.Scan(&c.event.ID, &c.event.Name, &payload, &c.event.OccurredAt, &c.attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning outbox row: %w", err)
+		}
+		c.event.Payload = payload
+		claimed = append(claimed, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading rows: %w", err)
+	}
+	// RETURNING does not keep the subquery's order
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].event.ID < claimed[j].event.ID })
+
+	sent := 0
+	for _, c := range claimed {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		if dispatchErr := dispatch(c.event); dispatchErr != nil {
+			attempts := c.attempts + 1
+			var failedAt *time.Time
+			if retry.Exhausted(attempts) {
+				t := time.Now()
+				failedAt = &t
+			}
+			_, err := db.ExecContext(ctx,
+				"UPDATE outbox SET attempts = $1, last_error = $2, next_attempt_at = $3, failed_at = $4 WHERE id = $5",
+				attempts, dispatchErr.Error(), time.Now().Add(retry.Delay(attempts)), failedAt, c.event.ID,
+			)
+			if err != nil {
+				return sent, fmt.Errorf("error recording outbox failure: %w", err)
+			}
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, "UPDATE outbox SET sent_at = $1, attempts = attempts + 1 WHERE id = $2", time.Now(), c.event.ID); err != nil {
+			return sent, fmt.Errorf("error marking outbox event sent: %w", err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
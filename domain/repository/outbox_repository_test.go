@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"echo-base/events"
+)
+
+// fakeOutboxRow is a row of the fake outbox table
+type fakeOutboxRow struct {
+	id          int64
+	name        string
+	payload     []byte
+	created     time.Time
+	sent        bool
+	attempts    int
+	nextAttempt time.Time
+	failed      bool
+}
+
+// fakeOutbox is an outbox table scripted onto a fakeDB
+type fakeOutbox struct {
+	*fakeDB
+	rows []*fakeOutboxRow
+
+	// markSentErr fails the next statement marking an event sent
+	markSentErr error
+}
+
+// newFakeOutbox creates an empty fake outbox table
+func newFakeOutbox() *fakeOutbox {
+	o := &fakeOutbox{fakeDB: &fakeDB{}}
+
+	o.query = func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if !strings.Contains(query, "UPDATE outbox SET next_attempt_at") {
+			return nil, nil, errors.New("unexpected query")
+		}
+		lease, now, limit := args[0].(time.Time), args[1].(time.Time), int(args[2].(int64))
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		var rows [][]driver.Value
+		for _, row := range o.rows {
+			if row.sent || row.failed || row.nextAttempt.After(now) || len(rows) == limit {
+				continue
+			}
+			row.nextAttempt = lease
+			rows = append(rows, []driver.Value{row.id, row.name, row.payload, row.created, int64(row.attempts)})
+		}
+		return []string{"id", "event_name", "payload", "created_at", "attempts"}, rows, nil
+	}
+
+	o.exec = func(query string, args []driver.Value) (func(), int64, error) {
+		switch {
+		case strings.Contains(query, "INSERT INTO outbox"):
+			return func() {
+				o.rows = append(o.rows, &fakeOutboxRow{
+					id:      int64(len(o.rows) + 1),
+					name:    args[0].(string),
+					payload: args[1].([]byte),
+					created: args[2].(time.Time),
+				})
+			}, 1, nil
+		case strings.Contains(query, "SET sent_at"):
+			if err := o.markSentErr; err != nil {
+				o.markSentErr = nil
+				return nil, 0, err
+			}
+			id := args[1].(int64)
+			return func() {
+				row := o.rows[id-1]
+				row.sent = true
+				row.attempts++
+			}, 1, nil
+		case strings.Contains(query, "last_error"):
+			id := args[4].(int64)
+			return func() {
+				row := o.rows[id-1]
+				row.attempts = int(args[0].(int64))
+				row.nextAttempt = args[2].(time.Time)
+				row.failed = args[3] != nil
+			}, 1, nil
+		}
+		return nil, 0, errors.New("unexpected statement")
+	}
+
+	return o
+}
+
+// row returns the outbox row with the ID
+func (o *fakeOutbox) row(id int64) fakeOutboxRow {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return *o.rows[id-1]
+}
+
+// makeDue moves the next attempt of the outbox row with the ID into the past
+func (o *fakeOutbox) makeDue(id int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.rows[id-1].nextAttempt = time.Now().Add(-time.Second)
+}
+
+// enqueueTestEvents enqueues n test events through repo
+func enqueueTestEvents(t *testing.T, repo OutboxRepository, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		event, err := events.New("test.event", map[string]int{"n": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Enqueue(context.Background(), event); err != nil {
+			t.Fatalf("error enqueuing event: %v", err)
+		}
+	}
+}
+
+func TestOutboxRedelivery(t *testing.T) {
+	// Failed events are due again at once so the second run retries them
+	retry := events.RetryPolicy{MaxAttempts: 3}
+
+	tests := []struct {
+		name string
+
+		// firstRun runs the relay once in a way that may fail or crash midway
+		firstRun func(t *testing.T, outbox *fakeOutbox, repo OutboxRepository, relay *events.Relay)
+
+		// wantFirstDeliveries is how often each event reached the test subscriber in firstRun
+		wantFirstDeliveries [2]int
+
+		// wantDeliveries is how often each event reached the test subscriber after the
+		// next run, which delivers again every event whose outcome was not recorded
+		wantDeliveries [2]int
+	}{
+		{
+			name: "delivered and recorded",
+			firstRun: func(t *testing.T, outbox *fakeOutbox, repo OutboxRepository, relay *events.Relay) {
+				if sent, err := relay.RunOnce(context.Background()); err != nil || sent != 2 {
+					t.Fatalf("RunOnce = %d, %v; want 2, nil", sent, err)
+				}
+			},
+			wantFirstDeliveries: [2]int{1, 1},
+			wantDeliveries:      [2]int{1, 1},
+		},
+		{
+			name: "marking sent fails after delivery",
+			firstRun: func(t *testing.T, outbox *fakeOutbox, repo OutboxRepository, relay *events.Relay) {
+				outbox.markSentErr = errors.New("connection reset")
+				if _, err := relay.RunOnce(context.Background()); err == nil {
+					t.Fatal("RunOnce succeeded although marking the event sent failed")
+				}
+			},
+			wantFirstDeliveries: [2]int{1, 0},
+			wantDeliveries:      [2]int{2, 1},
+		},
+		{
+			name: "process crash mid-batch",
+			firstRun: func(t *testing.T, outbox *fakeOutbox, repo OutboxRepository, relay *events.Relay) {
+				defer func() {
+					if recover() == nil {
+						t.Fatal("relay did not crash")
+					}
+				}()
+				crashing := events.NewBus()
+				crashing.Subscribe("test.event", func(ctx context.Context, event events.Event) error {
+					if event.ID == 2 {
+						panic("process killed")
+					}
+					return nil
+				})
+				events.NewRelay(repo, crashing, 10, retry).RunOnce(context.Background())
+			},
+			// The crashing run delivered and recorded event 1 through its own subscriber
+			wantFirstDeliveries: [2]int{0, 0},
+			wantDeliveries:      [2]int{0, 1},
+		},
+		{
+			name: "subscriber failure",
+			firstRun: func(t *testing.T, outbox *fakeOutbox, repo OutboxRepository, relay *events.Relay) {
+				failing := events.NewBus()
+				failing.Subscribe("test.event", func(ctx context.Context, event events.Event) error {
+					return errors.New("smtp unavailable")
+				})
+				if sent, err := events.NewRelay(repo, failing, 10, retry).RunOnce(context.Background()); err != nil || sent != 0 {
+					t.Fatalf("RunOnce = %d, %v; want 0, nil", sent, err)
+				}
+				if row := outbox.row(1); row.sent || row.failed || row.attempts != 1 {
+					t.Fatalf("failed event row = %+v, want pending with 1 attempt", row)
+				}
+			},
+			wantFirstDeliveries: [2]int{0, 0},
+			wantDeliveries:      [2]int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newFakeOutbox()
+			repo := NewOutboxRepository(openFakeDB(t, outbox.fakeDB))
+			enqueueTestEvents(t, repo, 2)
+
+			var deliveries [2]int
+			bus := events.NewBus()
+			bus.Subscribe("test.event", func(ctx context.Context, event events.Event) error {
+				deliveries[event.ID-1]++
+				return nil
+			})
+			relay := events.NewRelay(repo, bus, 10, retry)
+
+			tt.firstRun(t, outbox, repo, relay)
+			if deliveries != tt.wantFirstDeliveries {
+				t.Errorf("deliveries in the first run = %v, want %v", deliveries, tt.wantFirstDeliveries)
+			}
+
+			// Every event is eventually delivered and marked sent, and then not again
+			if _, err := relay.RunOnce(context.Background()); err != nil {
+				t.Fatalf("error in the second run: %v", err)
+			}
+			if deliveries != tt.wantDeliveries {
+				t.Errorf("deliveries = %v, want %v", deliveries, tt.wantDeliveries)
+			}
+			for id := int64(1); id <= 2; id++ {
+				if row := outbox.row(id); !row.sent {
+					t.Errorf("event %d is not marked sent", id)
+				}
+			}
+
+			before := deliveries
+			if sent, err := relay.RunOnce(context.Background()); err != nil || sent != 0 {
+				t.Errorf("third run = %d, %v; want 0, nil", sent, err)
+			}
+			if deliveries != before {
+				t.Errorf("sent events were delivered again")
+			}
+
+			if outbox.begins != 0 {
+				t.Errorf("relay began %d transactions, want none", outbox.begins)
+			}
+		})
+	}
+}
+
+func TestOutboxRetryBackoff(t *testing.T) {
+	retry := events.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute, Lease: time.Minute}
+
+	outbox := newFakeOutbox()
+	repo := NewOutboxRepository(openFakeDB(t, outbox.fakeDB))
+	enqueueTestEvents(t, repo, 1)
+
+	deliveries := 0
+	bus := events.NewBus()
+	bus.Subscribe("test.event", func(ctx context.Context, event events.Event) error {
+		deliveries++
+		return errors.New("smtp unavailable")
+	})
+	relay := events.NewRelay(repo, bus, 10, retry)
+
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		started := time.Now()
+		if _, err := relay.RunOnce(context.Background()); err != nil {
+			t.Fatalf("attempt %d: unexpected error: %v", attempt, err)
+		}
+		if deliveries != attempt {
+			t.Fatalf("attempt %d: deliveries = %d, want %d", attempt, deliveries, attempt)
+		}
+
+		row := outbox.row(1)
+		if row.attempts != attempt {
+			t.Errorf("attempt %d: attempts = %d, want %d", attempt, row.attempts, attempt)
+		}
+		if wantFailed := attempt == retry.MaxAttempts; row.failed != wantFailed {
+			t.Errorf("attempt %d: dead-lettered = %v, want %v", attempt, row.failed, wantFailed)
+		}
+		if due := started.Add(retry.Delay(attempt)); row.nextAttempt.Before(due) {
+			t.Errorf("attempt %d: next attempt at %v, want after %v", attempt, row.nextAttempt, due)
+		}
+
+		// Not retried before the backoff has passed
+		if _, err := relay.RunOnce(context.Background()); err != nil || deliveries != attempt {
+			t.Fatalf("attempt %d: retried during backoff: deliveries = %d, err = %v", attempt, deliveries, err)
+		}
+		outbox.makeDue(1)
+	}
+
+	// A dead-lettered event is never retried
+	if _, err := relay.RunOnce(context.Background()); err != nil || deliveries != retry.MaxAttempts {
+		t.Errorf("dead-lettered event retried: deliveries = %d, err = %v", deliveries, err)
+	}
+}
+
+func TestOutboxLease(t *testing.T) {
+	retry := events.RetryPolicy{MaxAttempts: 3, Lease: time.Minute}
+
+	outbox := newFakeOutbox()
+	repo := NewOutboxRepository(openFakeDB(t, outbox.fakeDB))
+	enqueueTestEvents(t, repo, 2)
+
+	// A concurrent run while the batch is dispatched finds nothing to claim
+	concurrent := -1
+	bus := events.NewBus()
+	bus.Subscribe("test.event", func(ctx context.Context, event events.Event) error {
+		if event.ID == 1 {
+			sent, err := events.NewRelay(repo, events.NewBus(), 10, retry).RunOnce(ctx)
+			if err != nil {
+				return err
+			}
+			concurrent = sent
+		}
+		return nil
+	})
+
+	if sent, err := events.NewRelay(repo, bus, 10, retry).RunOnce(context.Background()); err != nil || sent != 2 {
+		t.Fatalf("RunOnce = %d, %v; want 2, nil", sent, err)
+	}
+	if concurrent != 0 {
+		t.Errorf("concurrent run sent %d events, want 0", concurrent)
+	}
+}
+
+func TestOutboxDispatchCancelled(t *testing.T) {
+	outbox := newFakeOutbox()
+	repo := NewOutboxRepository(openFakeDB(t, outbox.fakeDB))
+	enqueueTestEvents(t, repo, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	delivered := false
+	_, err := repo.DispatchPending(ctx, 10, events.RetryPolicy{MaxAttempts: 3}, func(event events.Event) error {
+		delivered = true
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DispatchPending error = %v, want %v", err, context.Canceled)
+	}
+	if delivered || outbox.row(1).attempts != 0 {
+		t.Error("events were dispatched with a cancelled context")
+	}
+}
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"echo-base/config"
+	"echo-base/database"
+	"echo-base/domain/repository"
+	"echo-base/domain/repository/repositorytest"
+)
+
+// The PostgreSQL tests share one migrated database, named by TEST_DATABASE_URL
+var (
+	testDBOnce sync.Once
+	testDB     *sql.DB
+	testDBErr  error
+)
+
+// newPostgresRepos returns the PostgreSQL user and role repositories over an emptied
+// test database, skipping the test when TEST_DATABASE_URL is not set
+func newPostgresRepos(t *testing.T) (repository.UserRepository, repository.RoleRepository) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	testDBOnce.Do(func() {
+		var cfg *config.DatabaseConfig
+		if cfg, testDBErr = config.ParseDatabaseURL(url); testDBErr != nil {
+			return
+		}
+		if testDB, testDBErr = database.Connect(cfg); testDBErr != nil {
+			return
+		}
+		testDBErr = database.RunMigrations(testDB)
+	})
+	if testDBErr != nil {
+		t.Fatalf("error preparing test database: %v", testDBErr)
+	}
+
+	if _, err := testDB.Exec("TRUNCATE users RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("error emptying users: %v", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM roles WHERE id > 2"); err != nil {
+		t.Fatalf("error removing test roles: %v", err)
+	}
+
+	return repository.NewUserRepository(testDB), repository.NewRoleRepository(testDB)
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, newPostgresRepos)
+}
+
+func TestPostgresRoleRepository(t *testing.T) {
+	repositorytest.RunRoleRepositoryTests(t, newPostgresRepos)
+}
@@ -0,0 +1,648 @@
+// Package repositorytest holds the behaviour every UserRepository and RoleRepository
+// implementation must share, so the PostgreSQL and in-memory stores are held to the
+// same tests.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+// NewReposFunc returns empty user and role repositories holding only the default
+// roles. The role repository must constrain the user repository's role assignments.
+type NewReposFunc func(t *testing.T) (repository.UserRepository, repository.RoleRepository)
+
+// RunUserRepositoryTests runs the shared UserRepository tests against the
+// repositories returned by newRepos, which is called once per test
+func RunUserRepositoryTests(t *testing.T, newRepos NewReposFunc) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepos) })
+	t.Run("CreateConflicts", func(t *testing.T) { testCreateConflicts(t, newRepos) })
+	t.Run("Patch", func(t *testing.T) { testPatch(t, newRepos) })
+	t.Run("UpdateMissingUser", func(t *testing.T) { testUpdateMissingUser(t, newRepos) })
+	t.Run("SoftDeleteAndRestore", func(t *testing.T) { testSoftDeleteAndRestore(t, newRepos) })
+	t.Run("UpdateEmail", func(t *testing.T) { testUpdateEmail(t, newRepos) })
+	t.Run("GetByIDs", func(t *testing.T) { testGetByIDs(t, newRepos) })
+	t.Run("GetAllPagination", func(t *testing.T) { testGetAllPagination(t, newRepos) })
+	t.Run("BulkUpdateRole", func(t *testing.T) { testBulkUpdateRole(t, newRepos) })
+	t.Run("CountBySignupSource", func(t *testing.T) { testCountBySignupSource(t, newRepos) })
+}
+
+// RunRoleRepositoryTests runs the shared RoleRepository tests against the
+// repositories returned by newRepos, which is called once per test
+func RunRoleRepositoryTests(t *testing.T, newRepos NewReposFunc) {
+	t.Run("DefaultRoles", func(t *testing.T) { testDefaultRoles(t, newRepos) })
+	t.Run("CreateUpdateDelete", func(t *testing.T) { testRoleLifecycle(t, newRepos) })
+	t.Run("CreateWithID", func(t *testing.T) { testRoleCreateWithID(t, newRepos) })
+	t.Run("DeleteInUse", func(t *testing.T) { testRoleDeleteInUse(t, newRepos) })
+}
+
+// createUser stores user with a default name and password hash, failing the test on error
+func createUser(t *testing.T, users repository.UserRepository, user entity.User) *entity.User {
+	t.Helper()
+
+	if user.Name == "" {
+		user.Name = "Test User"
+	}
+	if user.Password == "" {
+		user.Password = "hashed-password"
+	}
+	created, err := users.Create(context.Background(), &user)
+	if err != nil {
+		t.Fatalf("error creating user %s: %v", user.Email, err)
+	}
+	return created
+}
+
+// ids returns the IDs of users in order
+func ids(users []*entity.User) []int64 {
+	result := make([]int64, len(users))
+	for i, user := range users {
+		result[i] = user.ID
+	}
+	return result
+}
+
+func testCreateAndGet(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+
+	created := createUser(t, users, entity.User{Name: "Alice", Email: "alice@example.com", Username: "Alice"})
+	if created.ID == 0 {
+		t.Fatal("created user has no ID")
+	}
+	if created.RoleID != entity.RoleIDUser || created.Status != entity.UserStatusActive {
+		t.Errorf("created user = %+v, want the user role and active status by default", created)
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Errorf("created user = %+v, want timestamps set", created)
+	}
+
+	tests := []struct {
+		name    string
+		get     func() (*entity.User, error)
+		wantNil bool
+	}{
+		{name: "by id", get: func() (*entity.User, error) { return users.GetByID(ctx, created.ID) }},
+		{name: "by email", get: func() (*entity.User, error) { return users.GetByEmail(ctx, "alice@example.com") }},
+		{name: "by username ignoring case", get: func() (*entity.User, error) { return users.GetByUsername(ctx, "aLiCe") }},
+		{name: "unknown id", get: func() (*entity.User, error) { return users.GetByID(ctx, created.ID+100) }, wantNil: true},
+		{name: "unknown email", get: func() (*entity.User, error) { return users.GetByEmail(ctx, "bob@example.com") }, wantNil: true},
+		{name: "unknown username", get: func() (*entity.User, error) { return users.GetByUsername(ctx, "bob") }, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := tt.get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if user != nil {
+					t.Errorf("user = %+v, want nil", user)
+				}
+				return
+			}
+			if user == nil {
+				t.Fatal("user = nil, want the created user")
+			}
+			if user.ID != created.ID || user.Name != "Alice" || user.Email != "alice@example.com" ||
+				user.Username != "Alice" || user.RoleName != "user" || user.Password != "hashed-password" {
+				t.Errorf("user = %+v, want the created user with its role name", user)
+			}
+		})
+	}
+}
+
+func testCreateConflicts(t *testing.T, newRepos NewReposFunc) {
+	users, _ := newRepos(t)
+	createUser(t, users, entity.User{Email: "alice@example.com", Username: "alice"})
+
+	tests := []struct {
+		name    string
+		user    entity.User
+		wantErr error
+	}{
+		{name: "duplicate email", user: entity.User{Email: "alice@example.com"}, wantErr: repository.ErrDuplicateEmail},
+		{name: "duplicate username in another case", user: entity.User{Email: "bob@example.com", Username: "ALICE"}, wantErr: repository.ErrDuplicateUsername},
+		{name: "nonexistent role", user: entity.User{Email: "carol@example.com", RoleID: 999}, wantErr: repository.ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.user.Name, tt.user.Password = "Test User", "hashed-password"
+			if _, err := users.Create(context.Background(), &tt.user); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func testPatch(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	user := createUser(t, users, entity.User{Name: "Alice", Email: "alice@example.com"})
+
+	name := "Alice Smith"
+	admin := entity.RoleIDAdmin
+	unknown := int64(999)
+
+	tests := []struct {
+		name         string
+		id           int64
+		patch        entity.UserPatchPayload
+		wantName     string
+		wantRoleName string
+		wantNil      bool
+		wantErr      error
+	}{
+		{name: "name only", id: user.ID, patch: entity.UserPatchPayload{Name: &name}, wantName: name, wantRoleName: "user"},
+		{name: "role only", id: user.ID, patch: entity.UserPatchPayload{RoleID: &admin}, wantName: name, wantRoleName: "admin"},
+		{name: "nonexistent role", id: user.ID, patch: entity.UserPatchPayload{RoleID: &unknown}, wantErr: repository.ErrRoleNotFound},
+		{name: "missing user", id: user.ID + 100, patch: entity.UserPatchPayload{Name: &name}, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := users.Patch(ctx, tt.id, &tt.patch)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if patched != nil {
+					t.Errorf("patched = %+v, want nil", patched)
+				}
+				return
+			}
+			if patched.Name != tt.wantName || patched.RoleName != tt.wantRoleName {
+				t.Errorf("patched = %+v, want name %q and role %q", patched, tt.wantName, tt.wantRoleName)
+			}
+			if stored, _ := users.GetByID(ctx, tt.id); stored.Name != tt.wantName || stored.RoleName != tt.wantRoleName {
+				t.Errorf("stored = %+v, want the patch persisted", stored)
+			}
+		})
+	}
+}
+
+func testUpdateMissingUser(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	missing := createUser(t, users, entity.User{Email: "gone@example.com"})
+	if err := users.Delete(ctx, missing.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		update func(id int64) error
+	}{
+		{name: "update", update: func(id int64) error {
+			_, err := users.Update(ctx, &entity.User{ID: id, Name: "Renamed", RoleID: entity.RoleIDUser})
+			return err
+		}},
+		{name: "update password", update: func(id int64) error { return users.UpdatePassword(ctx, id, "new-hash") }},
+		{name: "delete", update: func(id int64) error { return users.Delete(ctx, id) }},
+		{name: "mark email verified", update: func(id int64) error { return users.MarkEmailVerified(ctx, id) }},
+		{name: "update status", update: func(id int64) error { return users.UpdateStatus(ctx, id, entity.UserStatusSuspended) }},
+		{name: "update email", update: func(id int64) error { return users.UpdateEmail(ctx, id, "new@example.com", false) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, id := range []int64{missing.ID, missing.ID + 100} {
+				if err := tt.update(id); err == nil {
+					t.Errorf("updating user %d: error = nil, want a not found error", id)
+				}
+			}
+		})
+	}
+}
+
+func testSoftDeleteAndRestore(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	user := createUser(t, users, entity.User{Email: "alice@example.com"})
+
+	if err := users.Restore(ctx, user.ID); !errors.Is(err, repository.ErrDeletedUserNotFound) {
+		t.Errorf("restoring an active user: error = %v, want %v", err, repository.ErrDeletedUserNotFound)
+	}
+
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if got, _ := users.GetByID(ctx, user.ID); got != nil {
+		t.Error("deleted user is still returned by id")
+	}
+	if got, _ := users.GetByEmail(ctx, user.Email); got != nil {
+		t.Error("deleted user is still returned by email")
+	}
+	if all, _ := users.GetAll(ctx); len(all) != 0 {
+		t.Errorf("GetAll() returned %d users, want the deleted user left out", len(all))
+	}
+
+	if err := users.Restore(ctx, user.ID); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+	if got, _ := users.GetByID(ctx, user.ID); got == nil {
+		t.Fatal("restored user is not returned by id")
+	}
+
+	// The email of a deleted user may be registered again, which blocks the restore
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	createUser(t, users, entity.User{Email: "alice@example.com"})
+	if err := users.Restore(ctx, user.ID); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("restoring over a reused email: error = %v, want %v", err, repository.ErrDuplicateEmail)
+	}
+}
+
+func testUpdateEmail(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	alice := createUser(t, users, entity.User{Email: "alice@example.com", EmailVerified: true})
+	createUser(t, users, entity.User{Email: "bob@example.com"})
+
+	if err := users.UpdateEmail(ctx, alice.ID, "bob@example.com", false); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("error = %v, want %v", err, repository.ErrDuplicateEmail)
+	}
+
+	if err := users.UpdateEmail(ctx, alice.ID, "alice@new.example.com", false); err != nil {
+		t.Fatalf("error updating email: %v", err)
+	}
+	got, _ := users.GetByEmail(ctx, "alice@new.example.com")
+	if got == nil || got.ID != alice.ID || got.EmailVerified {
+		t.Fatalf("user = %+v, want alice with an unverified new email", got)
+	}
+
+	if err := users.MarkEmailVerified(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdateStatus(ctx, alice.ID, entity.UserStatusSuspended); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdatePassword(ctx, alice.ID, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = users.GetByID(ctx, alice.ID)
+	if !got.EmailVerified || got.Status != entity.UserStatusSuspended || got.Password != "new-hash" {
+		t.Errorf("user = %+v, want verified, suspended and the new password", got)
+	}
+}
+
+func testGetByIDs(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	a := createUser(t, users, entity.User{Email: "a@example.com"})
+	b := createUser(t, users, entity.User{Email: "b@example.com"})
+	deleted := createUser(t, users, entity.User{Email: "c@example.com"})
+	if err := users.Delete(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ids  []int64
+		want []int64
+	}{
+		{name: "requested order", ids: []int64{b.ID, a.ID}, want: []int64{b.ID, a.ID}},
+		{name: "duplicates once", ids: []int64{a.ID, a.ID}, want: []int64{a.ID}},
+		{name: "missing and deleted skipped", ids: []int64{a.ID, deleted.ID, 9999}, want: []int64{a.ID}},
+		{name: "empty", ids: []int64{}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := users.GetByIDs(ctx, tt.ids)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("ids = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func testGetAllPagination(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	carol := createUser(t, users, entity.User{Name: "Carol", Email: "carol@example.com"})
+	alice := createUser(t, users, entity.User{Name: "Alice", Email: "alice@example.com", RoleID: entity.RoleIDAdmin})
+	bob := createUser(t, users, entity.User{Name: "Bob", Email: "bob@example.org"})
+	dave := createUser(t, users, entity.User{Name: "Dave", Email: "dave@example.com"})
+	if err := users.Delete(ctx, dave.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		params    entity.PaginationParams
+		want      []int64
+		wantTotal int64
+	}{
+		{name: "first page by name", params: entity.PaginationParams{Page: 1, Limit: 2, Sort: "name", Order: entity.SortAsc}, want: []int64{alice.ID, bob.ID}, wantTotal: 3},
+		{name: "second page by name", params: entity.PaginationParams{Page: 2, Limit: 2, Sort: "name", Order: entity.SortAsc}, want: []int64{carol.ID}, wantTotal: 3},
+		{name: "page past the end", params: entity.PaginationParams{Page: 5, Limit: 2, Sort: "name", Order: entity.SortAsc}, want: []int64{}, wantTotal: 3},
+		{name: "search name and email", params: entity.PaginationParams{Page: 1, Limit: 10, Search: "example.org", Sort: "name"}, want: []int64{bob.ID}, wantTotal: 1},
+		{name: "search ignores case", params: entity.PaginationParams{Page: 1, Limit: 10, Search: "CAROL", Sort: "name"}, want: []int64{carol.ID}, wantTotal: 1},
+		{name: "role filter", params: entity.PaginationParams{Page: 1, Limit: 10, RoleID: entity.RoleIDAdmin, Sort: "name"}, want: []int64{alice.ID}, wantTotal: 1},
+		{name: "include deleted", params: entity.PaginationParams{Page: 1, Limit: 10, Sort: "name", Order: entity.SortAsc, IncludeDeleted: true}, want: []int64{alice.ID, bob.ID, carol.ID, dave.ID}, wantTotal: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := users.GetAllPagination(ctx, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) || total != tt.wantTotal {
+				t.Errorf("ids = %v (total %d), want %v (total %d)", ids(got), total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func testBulkUpdateRole(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		admins      int
+		users       int
+		targets     func(admins, users []int64) []int64
+		roleID      int64
+		wantUpdated int64
+		wantInvalid []int64
+		wantErr     error
+	}{
+		{
+			name: "promote users", admins: 1, users: 2, roleID: entity.RoleIDAdmin,
+			targets:     func(admins, users []int64) []int64 { return users },
+			wantUpdated: 2, wantInvalid: []int64{},
+		},
+		{
+			name: "unknown ids reported", admins: 1, users: 1, roleID: entity.RoleIDAdmin,
+			targets:     func(admins, users []int64) []int64 { return []int64{users[0], 9999} },
+			wantUpdated: 1, wantInvalid: []int64{9999},
+		},
+		{
+			name: "demote one of two admins", admins: 2, roleID: entity.RoleIDUser,
+			targets:     func(admins, users []int64) []int64 { return admins[:1] },
+			wantUpdated: 1, wantInvalid: []int64{},
+		},
+		{
+			name: "demote every admin", admins: 2, roleID: entity.RoleIDUser,
+			targets: func(admins, users []int64) []int64 { return admins },
+			wantErr: repository.ErrLastAdmin,
+		},
+		{
+			name: "nonexistent role", users: 1, roleID: 999,
+			targets: func(admins, users []int64) []int64 { return users },
+			wantErr: repository.ErrRoleNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _ := newRepos(t)
+			var adminIDs, userIDs []int64
+			for i := range tt.admins {
+				adminIDs = append(adminIDs, createUser(t, repo, entity.User{Email: fmt.Sprintf("admin%d@example.com", i), RoleID: entity.RoleIDAdmin}).ID)
+			}
+			for i := range tt.users {
+				userIDs = append(userIDs, createUser(t, repo, entity.User{Email: fmt.Sprintf("user%d@example.com", i)}).ID)
+			}
+			targets := tt.targets(adminIDs, userIDs)
+
+			updated, invalid, err := repo.BulkUpdateRole(ctx, targets, tt.roleID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				for _, id := range append(adminIDs, userIDs...) {
+					if user, _ := repo.GetByID(ctx, id); user.RoleID == tt.roleID && tt.roleID != entity.RoleIDAdmin {
+						t.Errorf("user %d changed role despite the error", id)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated != tt.wantUpdated || !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("updated %d with invalid %v, want %d with invalid %v", updated, invalid, tt.wantUpdated, tt.wantInvalid)
+			}
+			for _, id := range targets {
+				if user, _ := repo.GetByID(ctx, id); user != nil && user.RoleID != tt.roleID {
+					t.Errorf("user %d has role %d, want %d", id, user.RoleID, tt.roleID)
+				}
+			}
+		})
+	}
+}
+
+func testCountBySignupSource(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, _ := newRepos(t)
+	createUser(t, users, entity.User{Email: "a@example.com", ReferralSource: "newsletter"})
+	createUser(t, users, entity.User{Email: "b@example.com", ReferralSource: "newsletter"})
+	createUser(t, users, entity.User{Email: "c@example.com"})
+	deleted := createUser(t, users, entity.User{Email: "d@example.com", ReferralSource: "ads"})
+	if err := users.Delete(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := users.CountBySignupSource(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]int64{"newsletter": 2, "direct": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
+func testDefaultRoles(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	_, roles := newRepos(t)
+
+	all, err := roles.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := make([]string, len(all))
+	for i, role := range all {
+		names[i] = role.Name
+	}
+	if want := []string{"user", "admin"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("roles = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name    string
+		get     func() (*entity.Role, error)
+		wantID  int64
+		wantNil bool
+	}{
+		{name: "by id", get: func() (*entity.Role, error) { return roles.GetByID(ctx, entity.RoleIDAdmin) }, wantID: entity.RoleIDAdmin},
+		{name: "by name", get: func() (*entity.Role, error) { return roles.GetByName(ctx, "user") }, wantID: entity.RoleIDUser},
+		{name: "unknown id", get: func() (*entity.Role, error) { return roles.GetByID(ctx, 999) }, wantNil: true},
+		{name: "unknown name", get: func() (*entity.Role, error) { return roles.GetByName(ctx, "owner") }, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := tt.get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if role != nil {
+					t.Errorf("role = %+v, want nil", role)
+				}
+				return
+			}
+			if role == nil || role.ID != tt.wantID {
+				t.Errorf("role = %+v, want id %d", role, tt.wantID)
+			}
+		})
+	}
+}
+
+func testRoleLifecycle(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, roles := newRepos(t)
+
+	editor, err := roles.Create(ctx, "editor")
+	if err != nil {
+		t.Fatalf("error creating role: %v", err)
+	}
+	if editor.ID <= entity.RoleIDAdmin {
+		t.Errorf("created role id = %d, want one after the default roles", editor.ID)
+	}
+	if _, err := roles.Create(ctx, "editor"); !errors.Is(err, repository.ErrRoleNameTaken) {
+		t.Errorf("creating a taken name: error = %v, want %v", err, repository.ErrRoleNameTaken)
+	}
+
+	// Users can be given the new role
+	user := createUser(t, users, entity.User{Email: "alice@example.com", RoleID: editor.ID})
+	if got, _ := users.GetByID(ctx, user.ID); got.RoleName != "editor" {
+		t.Errorf("role name = %q, want %q", got.RoleName, "editor")
+	}
+
+	tests := []struct {
+		name    string
+		role    entity.Role
+		wantErr error
+	}{
+		{name: "rename", role: entity.Role{ID: editor.ID, Name: "writer"}},
+		{name: "name taken", role: entity.Role{ID: editor.ID, Name: "admin"}, wantErr: repository.ErrRoleNameTaken},
+		{name: "missing role", role: entity.Role{ID: 999, Name: "ghost"}, wantErr: repository.ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := roles.Update(ctx, &tt.role)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Name != tt.role.Name {
+				t.Errorf("name = %q, want %q", updated.Name, tt.role.Name)
+			}
+		})
+	}
+
+	if got, _ := users.GetByID(ctx, user.ID); got.RoleName != "writer" {
+		t.Errorf("role name after rename = %q, want %q", got.RoleName, "writer")
+	}
+
+	// Move the user off the role so it can be deleted
+	role := entity.RoleIDUser
+	if _, err := users.Patch(ctx, user.ID, &entity.UserPatchPayload{RoleID: &role}); err != nil {
+		t.Fatal(err)
+	}
+	if err := roles.Delete(ctx, editor.ID); err != nil {
+		t.Fatalf("error deleting role: %v", err)
+	}
+	if got, _ := roles.GetByID(ctx, editor.ID); got != nil {
+		t.Error("deleted role is still returned")
+	}
+	if err := roles.Delete(ctx, editor.ID); !errors.Is(err, repository.ErrRoleNotFound) {
+		t.Errorf("deleting a missing role: error = %v, want %v", err, repository.ErrRoleNotFound)
+	}
+	if _, err := users.Create(ctx, &entity.User{Name: "Test User", Email: "bob@example.com", Password: "hash", RoleID: editor.ID}); !errors.Is(err, repository.ErrRoleNotFound) {
+		t.Errorf("assigning a deleted role: error = %v, want %v", err, repository.ErrRoleNotFound)
+	}
+}
+
+func testRoleCreateWithID(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	_, roles := newRepos(t)
+
+	tests := []struct {
+		name     string
+		role     entity.Role
+		wantID   int64
+		wantName string
+	}{
+		{name: "new role", role: entity.Role{ID: 10, Name: "auditor"}, wantID: 10, wantName: "auditor"},
+		{name: "existing id is kept", role: entity.Role{ID: entity.RoleIDAdmin, Name: "superuser"}, wantID: entity.RoleIDAdmin, wantName: "admin"},
+		{name: "existing name is kept", role: entity.Role{ID: 11, Name: "user"}, wantID: entity.RoleIDUser, wantName: "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := roles.CreateWithID(ctx, tt.role); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := roles.GetByName(ctx, tt.wantName)
+			if err != nil || got == nil || got.ID != tt.wantID {
+				t.Errorf("role %q = %+v (err %v), want id %d", tt.wantName, got, err, tt.wantID)
+			}
+		})
+	}
+
+	if got, _ := roles.GetByID(ctx, 11); got != nil {
+		t.Errorf("role 11 = %+v, want nothing created for a taken name", got)
+	}
+}
+
+func testRoleDeleteInUse(t *testing.T, newRepos NewReposFunc) {
+	ctx := context.Background()
+	users, roles := newRepos(t)
+
+	editor, err := roles.Create(ctx, "editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createUser(t, users, entity.User{Email: "alice@example.com", RoleID: editor.ID})
+
+	if err := roles.Delete(ctx, editor.ID); !errors.Is(err, repository.ErrRoleInUse) {
+		t.Errorf("error = %v, want %v", err, repository.ErrRoleInUse)
+	}
+
+	// Soft-deleted users still hold their role
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := roles.Delete(ctx, editor.ID); !errors.Is(err, repository.ErrRoleInUse) {
+		t.Errorf("error with a deleted user = %v, want %v", err, repository.ErrRoleInUse)
+	}
+}
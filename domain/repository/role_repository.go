@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"echo-base/domain/entity"
+)
+
+var (
+	// ErrRoleNameTaken is returned when a role name is already in use
+	ErrRoleNameTaken = errors.New("role name is already taken")
+
+	// ErrRoleInUse is returned when deleting a role that users still have
+	ErrRoleInUse = errors.New("role is still assigned to users")
+)
+
+// RoleRepository defines the interface for role data operations
+type RoleRepository interface {
+	// GetAll returns all roles ordered by ID
+	GetAll(ctx context.Context) ([]entity.Role, error)
+
+	// GetByID gets a role by ID, returning nil when it does not exist
+	GetByID(ctx context.Context, id int64) (*entity.Role, error)
+
+	// GetByName gets a role by name, returning nil when it does not exist
+	GetByName(ctx context.Context, name string) (*entity.Role, error)
+
+	// Create creates a role with the next free ID
+	Create(ctx context.Context, name string) (*entity.Role, error)
+
+	// CreateWithID inserts a role with a fixed ID, doing nothing if its ID or name already exists
+	CreateWithID(ctx context.Context, role entity.Role) error
+
+	// Update renames a role
+	Update(ctx context.Context, role *entity.Role) (*entity.Role, error)
+
+	// Delete deletes a role no user has
+	Delete(ctx context.Context, id int64) error
+}
+
+// roleRepository is a PostgreSQL implementation of RoleRepository
+type roleRepository struct {
+	db DBExecutor
+}
+
+// NewRoleRepository creates a new PostgreSQL role repository
+func NewRoleRepository(db DBExecutor) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// isRoleNameUniqueViolation reports whether err is a unique violation (23505) on roles.name
+func isRoleNameUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "roles_name_key"
+}
+
+// GetAll returns all roles from PostgreSQL ordered by ID
+func (r *roleRepository) GetAll(ctx context.Context) ([]entity.Role, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, "SELECT id, name, created_at, updated_at FROM roles ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error getting roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []entity.Role
+	for rows.Next() {
+		var role entity.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.CreatedAt, &role.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// GetByID gets a role by ID from PostgreSQL
+func (r *roleRepository) GetByID(ctx context.Context, id int64) (*entity.Role, error) {
+	return r.getOne(ctx, "SELECT id, name, created_at, updated_at FROM roles WHERE id = $1", id)
+}
+
+// GetByName gets a role by name from PostgreSQL
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*entity.Role, error) {
+	return r.getOne(ctx, "SELECT id, name, created_at, updated_at FROM roles WHERE name = $1", name)
+}
+
+// getOne scans the single role selected by query, returning nil when there is none
+func (r *roleRepository) getOne(ctx context.Context, query string, arg interface{}) (*entity.Role, error) {
+	role := &entity.Role{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, arg).Scan(&role.ID, &role.Name, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting role: %w", err)
+	}
+	return role, nil
+}
+
+// Create inserts a role into PostgreSQL
+func (r *roleRepository) Create(ctx context.Context, name string) (*entity.Role, error) {
+	query := `
+		INSERT INTO roles (name, created_at, updated_at)
+		VALUES ($1, $2, $2)
+		RETURNING id, name, created_at, updated_at
+	`
+
+	role := &entity.Role{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, name, time.Now()).Scan(&role.ID, &role.Name, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if isRoleNameUniqueViolation(err) {
+			return nil, ErrRoleNameTaken
+		}
+		return nil, fmt.Errorf("error creating role: %w", err)
+	}
+	return role, nil
+}
+
+// CreateWithID inserts a role with a fixed ID into PostgreSQL and moves the ID sequence past it
+func (r *roleRepository) CreateWithID(ctx context.Context, role entity.Role) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "INSERT INTO roles (id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING", role.ID, role.Name); err != nil {
+		return fmt.Errorf("error creating role: %w", err)
+	}
+
+	// Explicit IDs bypass the sequence, so later inserts must not reuse them
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "SELECT setval(pg_get_serial_sequence('roles', 'id'), (SELECT MAX(id) FROM roles))"); err != nil {
+		return fmt.Errorf("error updating role id sequence: %w", err)
+	}
+	return nil
+}
+
+// Update renames a role in PostgreSQL
+func (r *roleRepository) Update(ctx context.Context, role *entity.Role) (*entity.Role, error) {
+	query := `
+		UPDATE roles
+		SET name = $1, updated_at = $2
+		WHERE id = $3
+		RETURNING id, name, created_at, updated_at
+	`
+
+	updated := &entity.Role{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, role.Name, time.Now(), role.ID).Scan(&updated.ID, &updated.Name, &updated.CreatedAt, &updated.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		if isRoleNameUniqueViolation(err) {
+			return nil, ErrRoleNameTaken
+		}
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete deletes a role from PostgreSQL; users referencing it make the delete fail
+func (r *roleRepository) Delete(ctx context.Context, id int64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM roles WHERE id = $1", id)
+	if err != nil {
+		if isRoleForeignKeyViolation(err) {
+			return ErrRoleInUse
+		}
+		return fmt.Errorf("error deleting role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
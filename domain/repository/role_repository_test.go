@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"echo-base/domain/entity"
+)
+
+func TestRoleRepositoryContextCancellation(t *testing.T) {
+	calls := []struct {
+		name string
+		call func(ctx context.Context, repo RoleRepository) error
+	}{
+		{name: "GetAll", call: func(ctx context.Context, repo RoleRepository) error {
+			_, err := repo.GetAll(ctx)
+			return err
+		}},
+		{name: "GetByID", call: func(ctx context.Context, repo RoleRepository) error {
+			_, err := repo.GetByID(ctx, 1)
+			return err
+		}},
+		{name: "Create", call: func(ctx context.Context, repo RoleRepository) error {
+			_, err := repo.Create(ctx, "editor")
+			return err
+		}},
+		{name: "CreateWithID", call: func(ctx context.Context, repo RoleRepository) error {
+			return repo.CreateWithID(ctx, entity.Role{ID: 2, Name: "admin"})
+		}},
+		{name: "Update", call: func(ctx context.Context, repo RoleRepository) error {
+			_, err := repo.Update(ctx, &entity.Role{ID: 3, Name: "reviewer"})
+			return err
+		}},
+		{name: "Delete", call: func(ctx context.Context, repo RoleRepository) error {
+			return repo.Delete(ctx, 3)
+		}},
+	}
+
+	for _, call := range calls {
+		t.Run(call.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// The statement is cancelled while the database runs it
+			fake := &fakeDB{
+				query: func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+					cancel()
+					return []string{"id"}, [][]driver.Value{{int64(1)}}, nil
+				},
+				exec: func(string, []driver.Value) (func(), int64, error) {
+					cancel()
+					return nil, 1, nil
+				},
+			}
+			repo := NewRoleRepository(openFakeDB(t, fake))
+
+			if err := call.call(ctx, repo); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"echo-base/domain/entity"
+)
+
+// SessionRepository defines the interface for session repository
+type SessionRepository interface {
+	// Create creates a new session
+	Create(ctx context.Context, session *entity.Session) error
+
+	// GetByID gets a session by ID
+	GetByID(ctx context.Context, id string) (*entity.Session, error)
+
+	// Touch updates the session's last activity time
+	Touch(ctx context.Context, id string, at time.Time) error
+
+	// CountActive counts a user's sessions active since the given time
+	CountActive(ctx context.Context, userID int64, since time.Time) (int64, error)
+
+	// DeleteInactive deletes sessions with no activity since the given time
+	DeleteInactive(ctx context.Context, before time.Time) (int64, error)
+}
+
+// sessionRepository is a PostgreSQL implementation of SessionRepository
+type sessionRepository struct {
+	db DBExecutor
+}
+
+// NewSessionRepository creates a new PostgreSQL session repository
+func NewSessionRepository(db DBExecutor) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create creates a new session in PostgreSQL
+func (r *sessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, created_at, last_activity_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastActivityAt = now
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, session.ID, session.UserID, session.CreatedAt, session.LastActivityAt); err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	return nil
+}
+
+// GetByID gets a session by ID from PostgreSQL
+func (r *sessionRepository) GetByID(ctx context.Context, id string) (*entity.Session, error) {
+	query := `
+		SELECT id, user_id, created_at, last_activity_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	session := &entity.Session{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.CreatedAt,
+		&session.LastActivityAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting session by id: %w", err)
+	}
+
+	return session, nil
+}
+
+// Touch updates the session's last activity time in PostgreSQL
+func (r *sessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE sessions SET last_activity_at = $1 WHERE id = $2", at, id); err != nil {
+		return fmt.Errorf("error updating session activity: %w", err)
+	}
+	return nil
+}
+
+// CountActive counts a user's sessions active since the given time
+func (r *sessionRepository) CountActive(ctx context.Context, userID int64, since time.Time) (int64, error) {
+	var count int64
+	err := executor(ctx, r.db).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sessions WHERE user_id = $1 AND last_activity_at >= $2",
+		userID, since,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting active sessions: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteInactive deletes sessions with no activity since the given time
+func (r *sessionRepository) DeleteInactive(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM sessions WHERE last_activity_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting inactive sessions: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// SystemRepository reports details about the database server
+type SystemRepository interface {
+	// ServerVersion returns the database server version string
+	ServerVersion() (string, error)
+
+	// InstalledExtensions returns the installed version of each named extension that is installed
+	InstalledExtensions(names []string) (map[string]string, error)
+}
+
+// systemRepository is a PostgreSQL implementation of SystemRepository
+type systemRepository struct {
+	db DBExecutor
+}
+
+// NewSystemRepository creates a new PostgreSQL system repository
+func NewSystemRepository(db DBExecutor) SystemRepository {
+	return &systemRepository{db: db}
+}
+
+// ServerVersion returns the PostgreSQL server version
+func (r *systemRepository) ServerVersion() (string, error) {
+	var version string
+	if err := r.db.QueryRow("SELECT version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("error getting server version: %w", err)
+	}
+	return version, nil
+}
+
+// InstalledExtensions returns the installed version of each named extension present in pg_extension
+func (r *systemRepository) InstalledExtensions(names []string) (map[string]string, error) {
+	rows, err := r.db.Query("SELECT extname, extversion FROM pg_extension WHERE extname = ANY($1)", pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("error getting extensions: %w", err)
+	}
+	defer rows.Close()
+
+	installed := make(map[string]string)
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			return nil, fmt.Errorf("error scanning extension row: %w", err)
+		}
+		installed[name] = version
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return installed, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSystemRepository(t *testing.T) {
+	const version = "PostgreSQL 16.2 on x86_64-pc-linux-gnu"
+
+	tests := []struct {
+		name           string
+		extensions     [][]driver.Value
+		queryErr       error
+		wantExtensions map[string]string
+		wantErr        bool
+	}{
+		{
+			name:           "all extensions installed",
+			extensions:     [][]driver.Value{{"citext", "1.6"}, {"pgcrypto", "1.3"}},
+			wantExtensions: map[string]string{"citext": "1.6", "pgcrypto": "1.3"},
+		},
+		{
+			name:           "missing extension",
+			extensions:     [][]driver.Value{{"citext", "1.6"}},
+			wantExtensions: map[string]string{"citext": "1.6"},
+		},
+		{name: "no extensions", wantExtensions: map[string]string{}},
+		{name: "query fails", queryErr: errors.New("permission denied"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotNames driver.Value
+			fake := &fakeDB{
+				query: func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+					if strings.Contains(query, "version()") {
+						return []string{"version"}, [][]driver.Value{{version}}, nil
+					}
+					if tt.queryErr != nil {
+						return nil, nil, tt.queryErr
+					}
+					gotNames = args[0]
+					return []string{"extname", "extversion"}, tt.extensions, nil
+				},
+			}
+			repo := NewSystemRepository(openFakeDB(t, fake))
+
+			got, err := repo.ServerVersion()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != version {
+				t.Errorf("ServerVersion() = %q, want %q", got, version)
+			}
+
+			installed, err := repo.InstalledExtensions([]string{"citext", "pgcrypto"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(installed, tt.wantExtensions) {
+				t.Errorf("InstalledExtensions() = %v, want %v", installed, tt.wantExtensions)
+			}
+			if gotNames != "{\"citext\",\"pgcrypto\"}" {
+				t.Errorf("extension names = %v, want the requested names as an array", gotNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"echo-base/domain/entity"
+)
+
+// ErrTrustedDeviceNotFound is returned when a trusted device does not exist for the user
+var ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+
+// TrustedDeviceRepository defines the interface for trusted device repository
+type TrustedDeviceRepository interface {
+	// Create stores a trusted device
+	Create(ctx context.Context, device *entity.TrustedDevice) error
+
+	// GetByTokenHash gets a user's unexpired trusted device by token hash
+	GetByTokenHash(ctx context.Context, userID int64, tokenHash string) (*entity.TrustedDevice, error)
+
+	// ListByUser lists a user's unexpired trusted devices, most recently used first
+	ListByUser(ctx context.Context, userID int64) ([]*entity.TrustedDevice, error)
+
+	// Touch updates the device's last used time
+	Touch(ctx context.Context, id int64, at time.Time) error
+
+	// Delete revokes a user's trusted device
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// trustedDeviceRepository is a PostgreSQL implementation of TrustedDeviceRepository
+type trustedDeviceRepository struct {
+	db DBExecutor
+}
+
+// NewTrustedDeviceRepository creates a new PostgreSQL trusted device repository
+func NewTrustedDeviceRepository(db DBExecutor) TrustedDeviceRepository {
+	return &trustedDeviceRepository{db: db}
+}
+
+// Create stores a trusted device in PostgreSQL
+func (r *trustedDeviceRepository) Create(ctx context.Context, device *entity.TrustedDevice) error {
+	query := `
+		INSERT INTO trusted_devices (user_id, token_hash, ip, user_agent, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	now := time.Now()
+	device.CreatedAt = now
+	device.LastUsedAt = now
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		device.UserID,
+		device.TokenHash,
+		device.IP,
+		device.UserAgent,
+		device.CreatedAt,
+		device.LastUsedAt,
+		device.ExpiresAt,
+	).Scan(&device.ID)
+
+	if err != nil {
+		return fmt.Errorf("error creating trusted device: %w", err)
+	}
+	return nil
+}
+
+// GetByTokenHash gets a user's unexpired trusted device by token hash from PostgreSQL
+func (r *trustedDeviceRepository) GetByTokenHash(ctx context.Context, userID int64, tokenHash string) (*entity.TrustedDevice, error) {
+	query := `
+		SELECT id, user_id, token_hash, ip, user_agent, created_at, last_used_at, expires_at
+		FROM trusted_devices
+		WHERE user_id = $1 AND token_hash = $2 AND expires_at > $3
+	`
+
+	device := &entity.TrustedDevice{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, tokenHash, time.Now()).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.TokenHash,
+		&device.IP,
+		&device.UserAgent,
+		&device.CreatedAt,
+		&device.LastUsedAt,
+		&device.ExpiresAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting trusted device: %w", err)
+	}
+
+	return device, nil
+}
+
+// ListByUser lists a user's unexpired trusted devices from PostgreSQL
+func (r *trustedDeviceRepository) ListByUser(ctx context.Context, userID int64) ([]*entity.TrustedDevice, error) {
+	query := `
+		SELECT id, user_id, token_hash, ip, user_agent, created_at, last_used_at, expires_at
+		FROM trusted_devices
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error querying trusted devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := make([]*entity.TrustedDevice, 0)
+	for rows.Next() {
+		device := &entity.TrustedDevice{}
+		err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.TokenHash,
+			&device.IP,
+			&device.UserAgent,
+			&device.CreatedAt,
+			&device.LastUsedAt,
+			&device.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning trusted device row: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return devices, nil
+}
+
+// Touch updates the device's last used time in PostgreSQL
+func (r *trustedDeviceRepository) Touch(ctx context.Context, id int64, at time.Time) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE trusted_devices SET last_used_at = $1 WHERE id = $2", at, id); err != nil {
+		return fmt.Errorf("error updating trusted device: %w", err)
+	}
+	return nil
+}
+
+// Delete revokes a user's trusted device in PostgreSQL
+func (r *trustedDeviceRepository) Delete(ctx context.Context, userID, id int64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM trusted_devices WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting trusted device: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTrustedDeviceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"echo-base/domain/entity"
+)
+
+// TwoFactorRepository defines the interface for TOTP enrollment and recovery code storage
+type TwoFactorRepository interface {
+	// Get gets a user's TOTP enrollment
+	Get(ctx context.Context, userID int64) (*entity.TwoFactor, error)
+
+	// SavePending stores a not yet enabled TOTP secret, replacing any pending one
+	SavePending(ctx context.Context, userID int64, secretEncrypted string) error
+
+	// Enable activates a user's TOTP enrollment and replaces their recovery codes
+	Enable(ctx context.Context, userID int64, recoveryCodeHashes []string) error
+
+	// ReplaceRecoveryCodes invalidates a user's recovery codes and stores new ones
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, recoveryCodeHashes []string) error
+
+	// ConsumeRecoveryCode marks an unused recovery code as used, reporting whether one matched
+	ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+
+	// StartChallenge makes challengeID the user's only open login challenge
+	StartChallenge(ctx context.Context, userID int64, challengeID string) error
+
+	// FailChallenge counts a wrong code against an open login challenge, closing the
+	// challenge once maxFailures is reached
+	FailChallenge(ctx context.Context, userID int64, challengeID string, maxFailures int) error
+
+	// CompleteChallenge closes an open login challenge, reporting whether it was still open
+	CompleteChallenge(ctx context.Context, userID int64, challengeID string) (bool, error)
+
+	// UseStep records the TOTP time step of an accepted code, reporting false when it is
+	// not newer than the last step used (the code was already used)
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+}
+
+// twoFactorRepository is a PostgreSQL implementation of TwoFactorRepository
+type twoFactorRepository struct {
+	db DBExecutor
+}
+
+// NewTwoFactorRepository creates a new PostgreSQL two-factor repository
+func NewTwoFactorRepository(db DBExecutor) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// Get gets a user's TOTP enrollment from PostgreSQL
+func (r *twoFactorRepository) Get(ctx context.Context, userID int64) (*entity.TwoFactor, error) {
+	query := `
+		SELECT user_id, secret_encrypted, enabled, created_at, enabled_at,
+			COALESCE(challenge_id, ''), challenge_failures, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	tf := &entity.TwoFactor{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(
+		&tf.UserID,
+		&tf.SecretEncrypted,
+		&tf.Enabled,
+		&tf.CreatedAt,
+		&tf.EnabledAt,
+		&tf.ChallengeID,
+		&tf.ChallengeFailures,
+		&tf.LastUsedStep,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting two-factor enrollment: %w", err)
+	}
+
+	return tf, nil
+}
+
+// SavePending stores a pending TOTP secret in PostgreSQL; enabled enrollments are left untouched
+func (r *twoFactorRepository) SavePending(ctx context.Context, userID int64, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted, enabled, created_at)
+		VALUES ($1, $2, FALSE, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, created_at = EXCLUDED.created_at
+		WHERE user_totp.enabled = FALSE
+	`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID, secretEncrypted, time.Now()); err != nil {
+		return fmt.Errorf("error saving two-factor secret: %w", err)
+	}
+	return nil
+}
+
+// Enable activates a user's TOTP enrollment and replaces their recovery codes in one transaction
+func (r *twoFactorRepository) Enable(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	return runInTx(ctx, executor(ctx, r.db), func(tx DBExecutor) error {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE user_totp SET enabled = TRUE, enabled_at = $1 WHERE user_id = $2",
+			time.Now(), userID,
+		); err != nil {
+			return fmt.Errorf("error enabling two-factor: %w", err)
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes invalidates a user's recovery codes and stores new ones in one transaction
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, recoveryCodeHashes []string) error {
+	return runInTx(ctx, executor(ctx, r.db), func(tx DBExecutor) error {
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	})
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and inserts the given hashes
+func replaceRecoveryCodes(ctx context.Context, tx DBExecutor, userID int64, recoveryCodeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hash,
+		); err != nil {
+			return fmt.Errorf("error creating recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode atomically marks an unused recovery code as used in PostgreSQL
+func (r *twoFactorRepository) ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = $1
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL
+	`, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("error consuming recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// StartChallenge replaces the user's open login challenge in PostgreSQL
+func (r *twoFactorRepository) StartChallenge(ctx context.Context, userID int64, challengeID string) error {
+	query := "UPDATE user_totp SET challenge_id = $1, challenge_failures = 0 WHERE user_id = $2"
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, challengeID, userID); err != nil {
+		return fmt.Errorf("error starting login challenge: %w", err)
+	}
+	return nil
+}
+
+// FailChallenge counts a wrong code against an open login challenge in PostgreSQL
+func (r *twoFactorRepository) FailChallenge(ctx context.Context, userID int64, challengeID string, maxFailures int) error {
+	query := `
+		UPDATE user_totp
+		SET challenge_failures = challenge_failures + 1,
+			challenge_id = CASE WHEN challenge_failures + 1 >= $3 THEN NULL ELSE challenge_id END
+		WHERE user_id = $1 AND challenge_id = $2
+	`
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID, challengeID, maxFailures); err != nil {
+		return fmt.Errorf("error recording login challenge failure: %w", err)
+	}
+	return nil
+}
+
+// CompleteChallenge atomically closes an open login challenge in PostgreSQL
+func (r *twoFactorRepository) CompleteChallenge(ctx context.Context, userID int64, challengeID string) (bool, error) {
+	query := "UPDATE user_totp SET challenge_id = NULL WHERE user_id = $1 AND challenge_id = $2"
+	return r.execAffected(ctx, query, "error completing login challenge", userID, challengeID)
+}
+
+// UseStep atomically advances the last used TOTP time step in PostgreSQL
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	query := "UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1"
+	return r.execAffected(ctx, query, "error recording two-factor code use", step, userID)
+}
+
+// execAffected runs an update and reports whether it changed a row
+func (r *twoFactorRepository) execAffected(ctx context.Context, query, errorMessage string, args ...interface{}) (bool, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", errorMessage, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrDryRunUnsupported is returned by stores that cannot undo writes
+var ErrDryRunUnsupported = errors.New("dry run is not supported by this store")
+
+// DBExecutor is the query interface shared by *sql.DB and *sql.Tx
+type DBExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Repositories groups repositories that share one database handle or transaction
+type Repositories struct {
+	Users  UserRepository
+	Roles  RoleRepository
+	Outbox OutboxRepository
+}
+
+// Store hands out repositories bound to a shared transaction for operations spanning several of them
+type Store interface {
+	// WithTx runs fn with repositories bound to one transaction, committing if it
+	// returns nil and rolling back otherwise
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+
+	// DryRun runs fn with repositories bound to one transaction that is always rolled
+	// back, so fn can report what it would change without changing anything
+	DryRun(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// store is a PostgreSQL implementation of Store
+type store struct {
+	db *sql.DB
+}
+
+// NewStore creates a new PostgreSQL store
+func NewStore(db *sql.DB) Store {
+	return &store{db: db}
+}
+
+// WithTx runs fn with repositories bound to a new transaction, or to the
+// request-scoped transaction in ctx when there is one
+func (s *store) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return fn(newRepositories(tx))
+	}
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+// DryRun runs fn in a new transaction that is always rolled back. Inside a
+// request-scoped transaction it rolls back to a savepoint instead, leaving the
+// request's own writes in place.
+func (s *store) DryRun(ctx context.Context, fn func(repos Repositories) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT dry_run"); err != nil {
+			return fmt.Errorf("error creating savepoint: %w", err)
+		}
+		fnErr := fn(newRepositories(tx))
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT dry_run"); err != nil {
+			return fmt.Errorf("error rolling back to savepoint: %w", err)
+		}
+		return fnErr
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return fn(newRepositories(tx))
+}
+
+// newRepositories binds the store's repositories to tx
+func newRepositories(tx *sql.Tx) Repositories {
+	return Repositories{
+		Users:  NewUserRepository(tx),
+		Roles:  NewRoleRepository(tx),
+		Outbox: NewOutboxRepository(tx),
+	}
+}
+
+// txContextKey is the context key of a request-scoped transaction
+type txContextKey struct{}
+
+// ContextWithTx returns a context carrying a request-scoped transaction
+func ContextWithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the request-scoped transaction carried by ctx, if any
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// executor returns the request-scoped transaction carried by ctx, unless db is
+// already a transaction
+func executor(ctx context.Context, db DBExecutor) DBExecutor {
+	if _, ok := db.(*sql.DB); ok {
+		if tx, ok := TxFromContext(ctx); ok {
+			return tx
+		}
+	}
+	return db
+}
+
+// withTx runs fn in a transaction on db, committing if it returns nil and rolling back otherwise
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// runInTx runs fn in a new transaction on db, or directly when db is already a transaction
+func runInTx(ctx context.Context, db DBExecutor, fn func(tx DBExecutor) error) error {
+	sqlDB, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+	return withTx(ctx, sqlDB, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"echo-base/events"
+)
+
+// fakeTables is a users, roles and outbox table scripted onto a fakeDB
+type fakeTables struct {
+	*fakeDB
+	deletedUsers map[int64]bool
+	roles        map[int64]bool
+	outbox       int
+}
+
+// newFakeTables creates tables with users 1 and 2 and roles 1 and 2
+func newFakeTables() *fakeTables {
+	f := &fakeTables{
+		fakeDB:       &fakeDB{},
+		deletedUsers: map[int64]bool{},
+		roles:        map[int64]bool{1: true, 2: true},
+	}
+
+	f.exec = func(query string, args []driver.Value) (func(), int64, error) {
+		switch {
+		case strings.Contains(query, "UPDATE users SET deleted_at"):
+			id := args[1].(int64)
+			return func() { f.deletedUsers[id] = true }, 1, nil
+		case strings.Contains(query, "DELETE FROM roles"):
+			id := args[0].(int64)
+			return func() { delete(f.roles, id) }, 1, nil
+		case strings.Contains(query, "INSERT INTO outbox"):
+			return func() { f.outbox++ }, 1, nil
+		}
+		return nil, 0, errors.New("unexpected statement")
+	}
+
+	return f
+}
+
+// writeAll deletes user 1 and role 2 and enqueues an event through repos
+func writeAll(t *testing.T, repos Repositories) {
+	t.Helper()
+
+	if err := repos.Users.Delete(context.Background(), 1); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if err := repos.Roles.Delete(context.Background(), 2); err != nil {
+		t.Fatalf("error deleting role: %v", err)
+	}
+	event, err := events.New("user.deleted", map[string]int64{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Outbox.Enqueue(context.Background(), event); err != nil {
+		t.Fatalf("error enqueuing event: %v", err)
+	}
+}
+
+func TestStoreWithTx(t *testing.T) {
+	errFailed := errors.New("step failed")
+
+	tests := []struct {
+		name      string
+		fn        func(t *testing.T, repos Repositories) error
+		commitErr error
+		wantErr   error
+		wantApply bool
+
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{
+			name: "success",
+			fn: func(t *testing.T, repos Repositories) error {
+				writeAll(t, repos)
+				return nil
+			},
+			wantApply:   true,
+			wantCommits: 1,
+		},
+		{
+			name: "failure after writes",
+			fn: func(t *testing.T, repos Repositories) error {
+				writeAll(t, repos)
+				return errFailed
+			},
+			wantErr:       errFailed,
+			wantRollbacks: 1,
+		},
+		{
+			name: "failure before writes",
+			fn: func(t *testing.T, repos Repositories) error {
+				return errFailed
+			},
+			wantErr:       errFailed,
+			wantRollbacks: 1,
+		},
+		{
+			name: "failed commit",
+			fn: func(t *testing.T, repos Repositories) error {
+				writeAll(t, repos)
+				return nil
+			},
+			commitErr:     errFailed,
+			wantErr:       errFailed,
+			wantRollbacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := newFakeTables()
+			tables.commitErr = tt.commitErr
+			store := NewStore(openFakeDB(t, tables.fakeDB))
+
+			err := store.WithTx(context.Background(), func(repos Repositories) error {
+				return tt.fn(t, repos)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithTx error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tables.begins != 1 || tables.commits != tt.wantCommits || tables.rollbacks != tt.wantRollbacks {
+				t.Errorf("begins, commits, rollbacks = %d, %d, %d; want 1, %d, %d",
+					tables.begins, tables.commits, tables.rollbacks, tt.wantCommits, tt.wantRollbacks)
+			}
+
+			applied := map[string]bool{
+				"user delete": tables.deletedUsers[1],
+				"role delete": !tables.roles[2],
+				"event":       tables.outbox == 1,
+			}
+			for write, ok := range applied {
+				if ok != tt.wantApply {
+					t.Errorf("%s applied = %v, want %v", write, ok, tt.wantApply)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreWithTxJoinsRequestTx(t *testing.T) {
+	tables := newFakeTables()
+	db := openFakeDB(t, tables.fakeDB)
+	store := NewStore(db)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("error starting transaction: %v", err)
+	}
+	ctx := ContextWithTx(context.Background(), tx)
+
+	err = store.WithTx(ctx, func(repos Repositories) error {
+		writeAll(t, repos)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tables.begins != 1 || tables.commits != 0 {
+		t.Fatalf("WithTx began or committed its own transaction: begins = %d, commits = %d", tables.begins, tables.commits)
+	}
+	if tables.deletedUsers[1] {
+		t.Fatal("writes applied before the request transaction committed")
+	}
+
+	// The request transaction decides: rolling it back drops the writes made by WithTx
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("error rolling back: %v", err)
+	}
+	if tables.deletedUsers[1] || !tables.roles[2] || tables.outbox != 0 {
+		t.Error("writes applied after the request transaction rolled back")
+	}
+}
+
+func TestRepositoriesJoinRequestTx(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, db *sql.DB) error
+	}{
+		{
+			name: "sessions",
+			write: func(ctx context.Context, db *sql.DB) error {
+				return NewSessionRepository(db).Touch(ctx, "session", now)
+			},
+		},
+		{
+			name:  "API keys",
+			write: func(ctx context.Context, db *sql.DB) error { return NewAPIKeyRepository(db).Touch(ctx, 1, now) },
+		},
+		{
+			name:  "trusted devices",
+			write: func(ctx context.Context, db *sql.DB) error { return NewTrustedDeviceRepository(db).Touch(ctx, 1, now) },
+		},
+		{
+			name: "two-factor",
+			write: func(ctx context.Context, db *sql.DB) error {
+				return NewTwoFactorRepository(db).Enable(ctx, 1, []string{"code"})
+			},
+		},
+		{
+			name:  "roles",
+			write: func(ctx context.Context, db *sql.DB) error { return NewRoleRepository(db).Delete(ctx, 2) },
+		},
+		{
+			name: "outbox",
+			write: func(ctx context.Context, db *sql.DB) error {
+				event, err := events.New("user.deleted", map[string]int64{"id": 1})
+				if err != nil {
+					return err
+				}
+				return NewOutboxRepository(db).Enqueue(ctx, event)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := 0
+			fake := &fakeDB{
+				exec: func(query string, args []driver.Value) (func(), int64, error) {
+					return func() { applied++ }, 1, nil
+				},
+			}
+			db := openFakeDB(t, fake)
+
+			tx, err := db.BeginTx(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("error starting transaction: %v", err)
+			}
+			ctx := ContextWithTx(context.Background(), tx)
+
+			if err := tt.write(ctx, db); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if applied != 0 {
+				t.Fatalf("applied = %d before the request transaction resolved, want 0", applied)
+			}
+			if err := tx.Rollback(); err != nil {
+				t.Fatalf("error rolling back: %v", err)
+			}
+			if applied != 0 || fake.begins != 1 || fake.commits != 0 {
+				t.Errorf("applied, begins, commits = %d, %d, %d; want writes only in the rolled back request transaction",
+					applied, fake.begins, fake.commits)
+			}
+		})
+	}
+}
+
+func TestStoreDryRun(t *testing.T) {
+	errFailed := errors.New("step failed")
+
+	tests := []struct {
+		name    string
+		fn      func(t *testing.T, repos Repositories) error
+		wantErr error
+	}{
+		{
+			name: "success",
+			fn: func(t *testing.T, repos Repositories) error {
+				writeAll(t, repos)
+				return nil
+			},
+		},
+		{
+			name: "failure after writes",
+			fn: func(t *testing.T, repos Repositories) error {
+				writeAll(t, repos)
+				return errFailed
+			},
+			wantErr: errFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := newFakeTables()
+			store := NewStore(openFakeDB(t, tables.fakeDB))
+
+			err := store.DryRun(context.Background(), func(repos Repositories) error {
+				return tt.fn(t, repos)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DryRun error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tables.begins != 1 || tables.commits != 0 || tables.rollbacks != 1 {
+				t.Errorf("begins, commits, rollbacks = %d, %d, %d; want 1, 0, 1", tables.begins, tables.commits, tables.rollbacks)
+			}
+			if tables.deletedUsers[1] || !tables.roles[2] || tables.outbox != 0 {
+				t.Error("dry run writes were applied")
+			}
+		})
+	}
+}
+
+func TestStoreDryRunInRequestTx(t *testing.T) {
+	tables := newFakeTables()
+	db := openFakeDB(t, tables.fakeDB)
+	store := NewStore(db)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("error starting transaction: %v", err)
+	}
+	ctx := ContextWithTx(context.Background(), tx)
+
+	// A write the request makes before the dry run
+	if err := NewUserRepository(db).Delete(ctx, 2); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	err = store.DryRun(ctx, func(repos Repositories) error {
+		writeAll(t, repos)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !tables.ran("SAVEPOINT dry_run") || !tables.ran("ROLLBACK TO SAVEPOINT dry_run") {
+		t.Errorf("statements = %q, want a savepoint rolled back", tables.statements)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("error committing: %v", err)
+	}
+	if tables.begins != 1 || tables.commits != 1 {
+		t.Errorf("begins, commits = %d, %d; want only the request transaction", tables.begins, tables.commits)
+	}
+	if !tables.deletedUsers[2] {
+		t.Error("the request's own write was rolled back")
+	}
+	if tables.deletedUsers[1] || !tables.roles[2] || tables.outbox != 0 {
+		t.Error("dry run writes were applied")
+	}
+}
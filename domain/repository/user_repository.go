@@ -1,63 +1,143 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	"echo-base/domain/entity"
 )
 
+var (
+	// ErrDuplicateEmail is returned when an email is already registered
+	ErrDuplicateEmail = errors.New("email is already registered")
+
+	// ErrDuplicateUsername is returned when a username is already taken
+	ErrDuplicateUsername = errors.New("username is already taken")
+
+	// ErrRoleNotFound is returned when a referenced role does not exist
+	ErrRoleNotFound = errors.New("role not found")
+
+	// ErrLastAdmin is returned when an operation would leave the system without an admin
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+
+	// ErrDeletedUserNotFound is returned when restoring a user that is not soft-deleted
+	ErrDeletedUserNotFound = errors.New("deleted user not found")
+)
+
 // UserRepository defines the interface for user repository
 type UserRepository interface {
 	// GetByID gets a user by ID
-	GetByID(id int64) (*entity.User, error)
+	GetByID(ctx context.Context, id int64) (*entity.User, error)
+
+	// GetByIDs gets the users with the given IDs in one query, in the order of ids.
+	// IDs that do not exist are skipped and duplicate IDs return the user once.
+	GetByIDs(ctx context.Context, ids []int64) ([]*entity.User, error)
 
 	// GetByEmail gets a user by email
-	GetByEmail(email string) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+
+	// GetByUsername gets a user by username, ignoring case
+	GetByUsername(ctx context.Context, username string) (*entity.User, error)
 
 	// Create creates a new user
-	Create(user *entity.User) (*entity.User, error)
+	Create(ctx context.Context, user *entity.User) (*entity.User, error)
 
 	// Update updates a user
-	Update(user *entity.User) (*entity.User, error)
+	Update(ctx context.Context, user *entity.User) (*entity.User, error)
 
-	// Delete deletes a user
-	Delete(id int64) error
+	// Patch updates only the fields set in patch, returning nil when the user does not exist
+	Patch(ctx context.Context, id int64, patch *entity.UserPatchPayload) (*entity.User, error)
+
+	// UpdatePassword replaces a user's password hash
+	UpdatePassword(ctx context.Context, id int64, hashedPassword string) error
+
+	// Delete soft-deletes a user; deleted users are hidden from every read
+	Delete(ctx context.Context, id int64) error
+
+	// Restore undoes the soft delete of a user
+	Restore(ctx context.Context, id int64) error
+
+	// MarkEmailVerified records that the user confirmed their email address
+	MarkEmailVerified(ctx context.Context, id int64) error
+
+	// UpdateEmail replaces a user's email address and its verified state, returning
+	// ErrDuplicateEmail when another user has the address
+	UpdateEmail(ctx context.Context, id int64, email string, verified bool) error
+
+	// UpdateStatus sets a user's account status (entity.UserStatusActive or UserStatusSuspended)
+	UpdateStatus(ctx context.Context, id int64, status string) error
 
 	// GetAll gets all users
-	GetAll() ([]*entity.User, error)
+	GetAll(ctx context.Context) ([]*entity.User, error)
+
+	// GetAllPagination gets all users with pagination, optional search and sorting
+	GetAllPagination(ctx context.Context, params entity.PaginationParams) ([]*entity.User, int64, error)
+
+	// ExplainSearch describes the WHERE clause and estimated row count of a user search without running it
+	ExplainSearch(ctx context.Context, filter entity.UserSearchFilter) (*entity.UserSearchExplain, error)
+
+	// CountBySignupSource counts users grouped by referral source
+	CountBySignupSource(ctx context.Context) (map[string]int64, error)
 
-	// GetAllPagination gets all users with pagination and optional search
-	GetAllPagination(page int64, limit int64, search string) ([]*entity.User, int64, error)
+	// CountCreatedSince counts users created since each of the given times
+	CountCreatedSince(ctx context.Context, since []time.Time) ([]int64, error)
+
+	// BulkUpdateRole sets the role of many users in one transaction,
+	// returning the number of updated users and the IDs that do not exist
+	BulkUpdateRole(ctx context.Context, ids []int64, roleID int64) (int64, []int64, error)
+
+	// LockActiveAdmins returns the IDs of active admins, locking their rows until the
+	// transaction ends so concurrent changes to admins are serialized
+	LockActiveAdmins(ctx context.Context) ([]int64, error)
 }
 
+// joinRoleName joins each user's role name. The roles columns are renamed so the
+// unqualified users columns in filters and sorting stay unambiguous.
+const joinRoleName = "LEFT JOIN (SELECT id AS role_ref, name AS role_name FROM roles) AS role_names ON role_ref = role_id"
+
 // userRepository is a PostgreSQL implementation of UserRepository
 type userRepository struct {
-	db *sql.DB
+	db DBExecutor
+}
+
+// isRoleForeignKeyViolation reports whether err is a foreign key violation (23503) on users.role_id
+func isRoleForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "users_role_id_fkey"
 }
 
 // NewUserRepository creates a new PostgreSQL user repository
-func NewUserRepository(db *sql.DB) UserRepository {
+func NewUserRepository(db DBExecutor) UserRepository {
 	return &userRepository{db: db}
 }
 
 // GetByID gets a user by ID from PostgreSQL
-func (r *userRepository) GetByID(id int64) (*entity.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
 	query := `
-		SELECT id, name, email, password, role_id, created_at, updated_at
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
 		FROM users
-		WHERE id = $1
+		` + joinRoleName + `
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	user := &entity.User{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
+		&user.Username,
 		&user.Password,
 		&user.RoleID,
+		&user.RoleName,
+		&user.EmailVerified,
+		&user.Status,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -73,20 +153,25 @@ func (r *userRepository) GetByID(id int64) (*entity.User, error) {
 }
 
 // GetByEmail gets a user by email from PostgreSQL
-func (r *userRepository) GetByEmail(email string) (*entity.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
 	query := `
-		SELECT id, name, email, password, role_id, created_at, updated_at
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
 		FROM users
-		WHERE email = $1
+		` + joinRoleName + `
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	user := &entity.User{}
-	err := r.db.QueryRow(query, email).Scan(
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
+		&user.Username,
 		&user.Password,
 		&user.RoleID,
+		&user.RoleName,
+		&user.EmailVerified,
+		&user.Status,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -101,33 +186,85 @@ func (r *userRepository) GetByEmail(email string) (*entity.User, error) {
 	return user, nil
 }
 
+// GetByUsername gets a user by username from PostgreSQL, ignoring case
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	query := `
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
+		FROM users
+		` + joinRoleName + `
+		WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL
+	`
+
+	user := &entity.User{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, username).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Username,
+		&user.Password,
+		&user.RoleID,
+		&user.RoleName,
+		&user.EmailVerified,
+		&user.Status,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting user by username: %w", err)
+	}
+
+	return user, nil
+}
+
 // Create creates a new user in PostgreSQL
-func (r *userRepository) Create(user *entity.User) (*entity.User, error) {
+func (r *userRepository) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
 	query := `
-		INSERT INTO users (name, email, password, role_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
+		INSERT INTO users (name, email, username, password, role_id, status, referral_source, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, ''), $8, $9)
+		RETURNING id, created_at, updated_at, COALESCE((SELECT name FROM roles WHERE roles.id = users.role_id), '')
 	`
 
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	// Default to role_id 1 (user) if not set
+	// Default to the user role if not set
 	if user.RoleID == 0 {
-		user.RoleID = 1
+		user.RoleID = entity.RoleIDUser
+	}
+	if user.Status == "" {
+		user.Status = entity.UserStatusActive
 	}
 
-	err := r.db.QueryRow(query,
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
 		user.Name,
 		user.Email,
+		user.Username,
 		user.Password,
 		user.RoleID,
+		user.Status,
+		user.ReferralSource,
 		user.CreatedAt,
 		user.UpdatedAt,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.RoleName)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			switch pqErr.Constraint {
+			case "users_email_key", "idx_users_email_active":
+				return nil, ErrDuplicateEmail
+			case "idx_users_username", "idx_users_username_lower":
+				return nil, ErrDuplicateUsername
+			}
+		}
+		if isRoleForeignKeyViolation(err) {
+			return nil, ErrRoleNotFound
+		}
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
 
@@ -135,17 +272,21 @@ func (r *userRepository) Create(user *entity.User) (*entity.User, error) {
 }
 
 // Update updates a user in PostgreSQL
-func (r *userRepository) Update(user *entity.User) (*entity.User, error) {
+func (r *userRepository) Update(ctx context.Context, user *entity.User) (*entity.User, error) {
 	query := `
-		UPDATE users
-		SET name = $1, role_id = $2, updated_at = $3
-		WHERE id = $4
-		RETURNING id, name, email, password, role_id, created_at, updated_at
-	`
+		WITH updated AS (
+			UPDATE users
+			SET name = $1, role_id = $2, updated_at = $3
+			WHERE id = $4 AND deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
+		FROM updated
+		` + joinRoleName
 
 	user.UpdatedAt = time.Now()
 
-	err := r.db.QueryRow(query,
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
 		user.Name,
 		user.RoleID,
 		user.UpdatedAt,
@@ -154,8 +295,12 @@ func (r *userRepository) Update(user *entity.User) (*entity.User, error) {
 		&user.ID,
 		&user.Name,
 		&user.Email,
+		&user.Username,
 		&user.Password,
 		&user.RoleID,
+		&user.RoleName,
+		&user.EmailVerified,
+		&user.Status,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -164,16 +309,85 @@ func (r *userRepository) Update(user *entity.User) (*entity.User, error) {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
 		}
+		if isRoleForeignKeyViolation(err) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return user, nil
+}
+
+// Patch updates the fields set in patch in PostgreSQL; NULL parameters keep the current value
+func (r *userRepository) Patch(ctx context.Context, id int64, patch *entity.UserPatchPayload) (*entity.User, error) {
+	query := `
+		WITH updated AS (
+			UPDATE users
+			SET name = COALESCE($1, name), role_id = COALESCE($2, role_id), updated_at = $3
+			WHERE id = $4 AND deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
+		FROM updated
+		` + joinRoleName
+
+	user := &entity.User{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		patch.Name,
+		patch.RoleID,
+		time.Now(),
+		id,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Username,
+		&user.Password,
+		&user.RoleID,
+		&user.RoleName,
+		&user.EmailVerified,
+		&user.Status,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if isRoleForeignKeyViolation(err) {
+			return nil, ErrRoleNotFound
+		}
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
 
 	return user, nil
 }
 
-// Delete deletes a user from PostgreSQL
-func (r *userRepository) Delete(id int64) error {
-	query := "DELETE FROM users WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+// UpdatePassword replaces a user's password hash in PostgreSQL
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, hashedPassword string) error {
+	query := "UPDATE users SET password = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, hashedPassword, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+// Delete soft-deletes a user in PostgreSQL by setting deleted_at
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	query := "UPDATE users SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("error deleting user: %w", err)
 	}
@@ -190,15 +404,162 @@ func (r *userRepository) Delete(id int64) error {
 	return nil
 }
 
+// Restore clears a user's deleted_at in PostgreSQL. It fails with ErrDuplicateEmail
+// when the email was registered again after the delete.
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	query := "UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_users_email_active" {
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("error restoring user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeletedUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified sets email_verified for a user in PostgreSQL
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int64) error {
+	query := "UPDATE users SET email_verified = TRUE, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error verifying email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+// UpdateStatus sets a user's account status in PostgreSQL
+func (r *userRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	query := "UPDATE users SET status = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, status, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+// UpdateEmail replaces a user's email address in PostgreSQL
+func (r *userRepository) UpdateEmail(ctx context.Context, id int64, email string, verified bool) error {
+	query := "UPDATE users SET email = $1, email_verified = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL"
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, email, verified, time.Now(), id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("error updating email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+// GetByIDs gets users by ID from PostgreSQL with a single query
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int64) ([]*entity.User, error) {
+	if len(ids) == 0 {
+		return []*entity.User{}, nil
+	}
+
+	query := `
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
+		FROM users
+		` + joinRoleName + `
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error querying users: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[int64]*entity.User, len(ids))
+	for rows.Next() {
+		user := &entity.User{}
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Email,
+			&user.Username,
+			&user.Password,
+			&user.RoleID,
+			&user.RoleName,
+			&user.EmailVerified,
+			&user.Status,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user row: %w", err)
+		}
+		found[user.ID] = user
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	// Rows come back in no particular order; return them in the order requested
+	users := make([]*entity.User, 0, len(found))
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+			delete(found, id)
+		}
+	}
+	return users, nil
+}
+
 // GetAll gets all users from PostgreSQL
-func (r *userRepository) GetAll() ([]*entity.User, error) {
+func (r *userRepository) GetAll(ctx context.Context) ([]*entity.User, error) {
 	query := `
-		SELECT id, name, email, password, role_id, created_at, updated_at
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at
 		FROM users
+		` + joinRoleName + `
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("error querying users: %w", err)
 	}
@@ -211,8 +572,12 @@ func (r *userRepository) GetAll() ([]*entity.User, error) {
 			&user.ID,
 			&user.Name,
 			&user.Email,
+			&user.Username,
 			&user.Password,
 			&user.RoleID,
+			&user.RoleName,
+			&user.EmailVerified,
+			&user.Status,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -229,52 +594,103 @@ func (r *userRepository) GetAll() ([]*entity.User, error) {
 	return users, nil
 }
 
-// GetAllPagination gets all users with pagination and optional search
-func (r *userRepository) GetAllPagination(page int64, limit int64, search string) ([]*entity.User, int64, error) {
+// userSortColumns maps sortable fields to their columns; unknown fields sort by created_at
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"username":   "username",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// BuildUserFilter builds the WHERE clause for a user search with placeholders numbered from $1.
+// It returns the clause (empty when unfiltered), its arguments and a description of each placeholder.
+func BuildUserFilter(filter entity.UserSearchFilter) (string, []interface{}, []string) {
+	var conditions []string
+	var args []interface{}
+	params := []string{}
+
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		params = append(params, fmt.Sprintf("$%d: search pattern", len(args)))
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR email ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.RoleID > 0 {
+		args = append(args, filter.RoleID)
+		params = append(params, fmt.Sprintf("$%d: role_id", len(args)))
+		conditions = append(conditions, fmt.Sprintf("role_id = $%d", len(args)))
+	}
+	if filter.CreatedAfter != nil {
+		args = append(args, *filter.CreatedAfter)
+		params = append(params, fmt.Sprintf("$%d: created_after", len(args)))
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedBefore != nil {
+		args = append(args, *filter.CreatedBefore)
+		params = append(params, fmt.Sprintf("$%d: created_before", len(args)))
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, []string{}
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args, params
+}
+
+// GetAllPagination gets all users with pagination, optional search and sorting
+func (r *userRepository) GetAllPagination(ctx context.Context, params entity.PaginationParams) ([]*entity.User, int64, error) {
+	page, limit, search := params.Page, params.Limit, params.Search
+
 	// Default pagination values
 	if page < 1 {
 		page = 1
 	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	if limit < 1 || limit > entity.MaxPageLimit {
+		limit = entity.DefaultPageLimit
 	}
 
-	offset := (page - 1) * limit
+	column, ok := userSortColumns[params.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	direction := "DESC"
+	if params.Order == entity.SortAsc {
+		direction = "ASC"
+	}
 
-	// Count total users
-	countQuery := "SELECT COUNT(*) FROM users"
-	var countArgs []interface{}
+	offset := (page - 1) * limit
 
-	if search != "" {
-		countQuery += " WHERE name ILIKE $1 OR email ILIKE $1"
-		countArgs = append(countArgs, "%"+search+"%")
-	}
+	where, args, _ := BuildUserFilter(entity.UserSearchFilter{
+		Search:         search,
+		RoleID:         params.RoleID,
+		CreatedAfter:   params.CreatedAfter,
+		CreatedBefore:  params.CreatedBefore,
+		IncludeDeleted: params.IncludeDeleted,
+	})
 
+	// Count total users
 	var total int64
-	err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM users "+where, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error counting users: %w", err)
 	}
 
 	// Get data with pagination
 	query := `
-		SELECT id, name, email, password, role_id, created_at, updated_at
+		SELECT id, name, email, COALESCE(username, ''), password, role_id, COALESCE(role_name, ''), email_verified, status, created_at, updated_at, deleted_at
 		FROM users
-	`
+		` + joinRoleName + `
+	` + where
 
-	var args []interface{}
-	argNum := 1
-
-	if search != "" {
-		query += fmt.Sprintf(" WHERE name ILIKE $%d OR email ILIKE $%d", argNum, argNum)
-		args = append(args, "%"+search+"%")
-		argNum++
-	}
-
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argNum, argNum+1)
+	argNum := len(args) + 1
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d", column, direction, direction, argNum, argNum+1)
 	args = append(args, limit, offset)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying users: %w", err)
 	}
@@ -287,10 +703,15 @@ func (r *userRepository) GetAllPagination(page int64, limit int64, search string
 			&user.ID,
 			&user.Name,
 			&user.Email,
+			&user.Username,
 			&user.Password,
 			&user.RoleID,
+			&user.RoleName,
+			&user.EmailVerified,
+			&user.Status,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.DeletedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning user row: %w", err)
@@ -304,3 +725,193 @@ func (r *userRepository) GetAllPagination(page int64, limit int64, search string
 
 	return users, total, nil
 }
+
+// LockActiveAdmins selects the active admins FOR UPDATE. A transaction waiting on the
+// lock re-reads the rows once it is released, so it sees an admin demoted meanwhile.
+func (r *userRepository) LockActiveAdmins(ctx context.Context) ([]int64, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx,
+		"SELECT id FROM users WHERE role_id = $1 AND status = $2 AND deleted_at IS NULL ORDER BY id FOR UPDATE",
+		entity.RoleIDAdmin, entity.UserStatusActive,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error locking admins: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning admin id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+	return ids, nil
+}
+
+// BulkUpdateRole sets the role of many users in one PostgreSQL transaction
+func (r *userRepository) BulkUpdateRole(ctx context.Context, ids []int64, roleID int64) (int64, []int64, error) {
+	var updated int64
+	invalidIDs := make([]int64, 0)
+
+	err := runInTx(ctx, executor(ctx, r.db), func(tx DBExecutor) error {
+		// Check role exists
+		var roleExists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM roles WHERE id = $1)", roleID).Scan(&roleExists); err != nil {
+			return fmt.Errorf("error checking role: %w", err)
+		}
+		if !roleExists {
+			return ErrRoleNotFound
+		}
+
+		// Lock the target users and find which of the requested IDs exist
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM users WHERE id = ANY($1) AND deleted_at IS NULL FOR UPDATE", pq.Array(ids))
+		if err != nil {
+			return fmt.Errorf("error querying users: %w", err)
+		}
+		existing := make(map[int64]bool, len(ids))
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning user id: %w", err)
+			}
+			existing[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error reading rows: %w", err)
+		}
+
+		for _, id := range ids {
+			if !existing[id] {
+				invalidIDs = append(invalidIDs, id)
+			}
+		}
+
+		// Prevent demoting every remaining admin
+		if roleID != entity.RoleIDAdmin {
+			var demoted, remaining int64
+			err := tx.QueryRowContext(ctx, `
+				SELECT
+					COUNT(*) FILTER (WHERE id = ANY($2)),
+					COUNT(*) FILTER (WHERE NOT (id = ANY($2)))
+				FROM users
+				WHERE role_id = $1 AND deleted_at IS NULL
+			`, entity.RoleIDAdmin, pq.Array(ids)).Scan(&demoted, &remaining)
+			if err != nil {
+				return fmt.Errorf("error counting admins: %w", err)
+			}
+			if demoted > 0 && remaining == 0 {
+				return ErrLastAdmin
+			}
+		}
+
+		result, err := tx.ExecContext(ctx,
+			"UPDATE users SET role_id = $1, updated_at = $2 WHERE id = ANY($3) AND deleted_at IS NULL",
+			roleID, time.Now(), pq.Array(ids),
+		)
+		if err != nil {
+			return fmt.Errorf("error updating user roles: %w", err)
+		}
+
+		updated, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error getting rows affected: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return updated, invalidIDs, nil
+}
+
+// CountCreatedSince counts users created since each of the given times in a single query
+func (r *userRepository) CountCreatedSince(ctx context.Context, since []time.Time) ([]int64, error) {
+	if len(since) == 0 {
+		return []int64{}, nil
+	}
+
+	columns := make([]string, 0, len(since))
+	args := make([]interface{}, 0, len(since))
+	for i, t := range since {
+		columns = append(columns, fmt.Sprintf("COUNT(*) FILTER (WHERE created_at >= $%d)", i+1))
+		args = append(args, t)
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM users WHERE deleted_at IS NULL"
+
+	counts := make([]int64, len(since))
+	dest := make([]interface{}, len(since))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("error counting recent users: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ExplainSearch returns the search's WHERE clause and PostgreSQL's row estimate from EXPLAIN
+func (r *userRepository) ExplainSearch(ctx context.Context, filter entity.UserSearchFilter) (*entity.UserSearchExplain, error) {
+	where, args, params := BuildUserFilter(filter)
+
+	var plan []byte
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) SELECT id FROM users "+where, args...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("error explaining user search: %w", err)
+	}
+
+	var explained []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil || len(explained) == 0 {
+		return nil, fmt.Errorf("error reading query plan: %v", err)
+	}
+
+	return &entity.UserSearchExplain{
+		Filter:        filter,
+		Where:         where,
+		Parameters:    params,
+		EstimatedRows: int64(explained[0].Plan.PlanRows),
+	}, nil
+}
+
+// CountBySignupSource counts users grouped by referral source; users without one count as "direct"
+func (r *userRepository) CountBySignupSource(ctx context.Context) (map[string]int64, error) {
+	query := `
+		SELECT COALESCE(referral_source, 'direct') AS source, COUNT(*)
+		FROM users
+		WHERE deleted_at IS NULL
+		GROUP BY source
+	`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error counting users by source: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var count int64
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, fmt.Errorf("error scanning source row: %w", err)
+		}
+		counts[source] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rows: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"echo-base/domain/entity"
+)
+
+func TestUserRepositoryRoleNotFound(t *testing.T) {
+	fkViolation := &pq.Error{Code: "23503", Constraint: "users_role_id_fkey"}
+
+	tests := []struct {
+		name    string
+		dbErr   error
+		call    func(ctx context.Context, repo UserRepository) error
+		wantErr error
+	}{
+		{
+			name:  "create with a nonexistent role",
+			dbErr: fkViolation,
+			call: func(ctx context.Context, repo UserRepository) error {
+				_, err := repo.Create(ctx, &entity.User{Name: "Alice", Email: "alice@example.com", RoleID: 999})
+				return err
+			},
+			wantErr: ErrRoleNotFound,
+		},
+		{
+			name:  "update to a nonexistent role",
+			dbErr: fkViolation,
+			call: func(ctx context.Context, repo UserRepository) error {
+				_, err := repo.Update(ctx, &entity.User{ID: 1, Name: "Alice", RoleID: 999})
+				return err
+			},
+			wantErr: ErrRoleNotFound,
+		},
+		{
+			name:  "other foreign key violation is not a missing role",
+			dbErr: &pq.Error{Code: "23503", Constraint: "users_other_fkey"},
+			call: func(ctx context.Context, repo UserRepository) error {
+				_, err := repo.Create(ctx, &entity.User{Name: "Alice", Email: "alice@example.com", RoleID: 999})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDB{
+				query: func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+					return nil, nil, tt.dbErr
+				},
+			}
+			repo := NewUserRepository(openFakeDB(t, fake))
+
+			err := tt.call(context.Background(), repo)
+			if err == nil {
+				t.Fatal("error = nil, want a failure")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, ErrRoleNotFound) {
+				t.Errorf("error = %v, want a generic error", err)
+			}
+		})
+	}
+}
+
+func TestBuildUserFilter(t *testing.T) {
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		filter     entity.UserSearchFilter
+		wantWhere  string
+		wantArgs   []interface{}
+		wantParams []string
+	}{
+		{
+			name:       "no filter",
+			wantWhere:  "WHERE deleted_at IS NULL",
+			wantParams: []string{},
+		},
+		{
+			name:       "no filter including deleted",
+			filter:     entity.UserSearchFilter{IncludeDeleted: true},
+			wantWhere:  "",
+			wantParams: []string{},
+		},
+		{
+			name:       "search",
+			filter:     entity.UserSearchFilter{Search: "alice"},
+			wantWhere:  "WHERE (name ILIKE $1 OR email ILIKE $1) AND deleted_at IS NULL",
+			wantArgs:   []interface{}{"%alice%"},
+			wantParams: []string{"$1: search pattern"},
+		},
+		{
+			name:       "role",
+			filter:     entity.UserSearchFilter{RoleID: 2},
+			wantWhere:  "WHERE role_id = $1 AND deleted_at IS NULL",
+			wantArgs:   []interface{}{int64(2)},
+			wantParams: []string{"$1: role_id"},
+		},
+		{
+			name:       "search and role",
+			filter:     entity.UserSearchFilter{Search: "alice", RoleID: 2},
+			wantWhere:  "WHERE (name ILIKE $1 OR email ILIKE $1) AND role_id = $2 AND deleted_at IS NULL",
+			wantArgs:   []interface{}{"%alice%", int64(2)},
+			wantParams: []string{"$1: search pattern", "$2: role_id"},
+		},
+		{
+			name:       "created range including deleted",
+			filter:     entity.UserSearchFilter{CreatedAfter: &after, CreatedBefore: &before, IncludeDeleted: true},
+			wantWhere:  "WHERE created_at >= $1 AND created_at < $2",
+			wantArgs:   []interface{}{after, before},
+			wantParams: []string{"$1: created_after", "$2: created_before"},
+		},
+		{
+			name:       "injection attempt stays a parameter",
+			filter:     entity.UserSearchFilter{Search: "x' OR '1'='1"},
+			wantWhere:  "WHERE (name ILIKE $1 OR email ILIKE $1) AND deleted_at IS NULL",
+			wantArgs:   []interface{}{"%x' OR '1'='1%"},
+			wantParams: []string{"$1: search pattern"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, params := BuildUserFilter(tt.filter)
+
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryExplainSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     string
+		wantRows int64
+		wantErr  bool
+	}{
+		{name: "row estimate", plan: `[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 42}}]`, wantRows: 42},
+		{name: "fractional estimate", plan: `[{"Plan": {"Plan Rows": 7.6}}]`, wantRows: 7},
+		{name: "empty plan", plan: `[]`, wantErr: true},
+		{name: "malformed plan", plan: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery string
+			var gotArgs []driver.Value
+			fake := &fakeDB{
+				query: func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+					gotQuery, gotArgs = query, args
+					return []string{"QUERY PLAN"}, [][]driver.Value{{[]byte(tt.plan)}}, nil
+				},
+			}
+			repo := NewUserRepository(openFakeDB(t, fake))
+
+			filter := entity.UserSearchFilter{Search: "alice", RoleID: 2}
+			explain, err := repo.ExplainSearch(context.Background(), filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("error = nil, want a failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.HasPrefix(gotQuery, "EXPLAIN (FORMAT JSON) SELECT id FROM users WHERE") {
+				t.Errorf("query = %q, want an EXPLAIN of the filtered select", gotQuery)
+			}
+			if want := []driver.Value{"%alice%", int64(2)}; !reflect.DeepEqual(gotArgs, want) {
+				t.Errorf("args = %v, want %v", gotArgs, want)
+			}
+			if explain.EstimatedRows != tt.wantRows {
+				t.Errorf("EstimatedRows = %d, want %d", explain.EstimatedRows, tt.wantRows)
+			}
+			if explain.Filter != filter {
+				t.Errorf("Filter = %+v, want %+v", explain.Filter, filter)
+			}
+			if strings.Contains(explain.Where, "alice") {
+				t.Errorf("Where %q contains the raw search value", explain.Where)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryContextCancellation(t *testing.T) {
+	calls := []struct {
+		name string
+		call func(ctx context.Context, repo UserRepository) error
+	}{
+		{name: "GetByID", call: func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.GetByID(ctx, 1)
+			return err
+		}},
+		{name: "GetByEmail", call: func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.GetByEmail(ctx, "alice@example.com")
+			return err
+		}},
+		{name: "Create", call: func(ctx context.Context, repo UserRepository) error {
+			_, err := repo.Create(ctx, &entity.User{Name: "Alice", Email: "alice@example.com", RoleID: 2})
+			return err
+		}},
+		{name: "GetAllPagination", call: func(ctx context.Context, repo UserRepository) error {
+			_, _, err := repo.GetAllPagination(ctx, entity.PaginationParams{Page: 1, Limit: 10})
+			return err
+		}},
+		{name: "UpdatePassword", call: func(ctx context.Context, repo UserRepository) error {
+			return repo.UpdatePassword(ctx, 1, "hash")
+		}},
+		{name: "Delete", call: func(ctx context.Context, repo UserRepository) error {
+			return repo.Delete(ctx, 1)
+		}},
+	}
+
+	tests := []struct {
+		name string
+
+		// context returns the context to call with and a function the fake database
+		// runs while it executes the statement
+		context func() (context.Context, func())
+
+		wantErr error
+	}{
+		{
+			name: "cancelled mid-query",
+			context: func() (context.Context, func()) {
+				ctx, cancel := context.WithCancel(context.Background())
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "cancelled before the query",
+			context: func() (context.Context, func()) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, func() {}
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "deadline exceeded",
+			context: func() (context.Context, func()) {
+				ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+				return ctx, cancel
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, call := range calls {
+			t.Run(tt.name+" "+call.name, func(t *testing.T) {
+				ctx, running := tt.context()
+				defer running()
+
+				fake := &fakeDB{
+					query: func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+						running()
+						return []string{"count"}, [][]driver.Value{{int64(0)}}, nil
+					},
+					exec: func(string, []driver.Value) (func(), int64, error) {
+						running()
+						return nil, 1, nil
+					},
+				}
+				repo := NewUserRepository(openFakeDB(t, fake))
+
+				if err := call.call(ctx, repo); !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestUserRepositoryCreateUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbErr   error
+		wantErr error
+	}{
+		{name: "email constraint", dbErr: &pq.Error{Code: "23505", Constraint: "users_email_key"}, wantErr: ErrDuplicateEmail},
+		{name: "active email index", dbErr: &pq.Error{Code: "23505", Constraint: "idx_users_email_active"}, wantErr: ErrDuplicateEmail},
+		{name: "username index", dbErr: &pq.Error{Code: "23505", Constraint: "idx_users_username_lower"}, wantErr: ErrDuplicateUsername},
+		{name: "other unique constraint", dbErr: &pq.Error{Code: "23505", Constraint: "users_other_key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDB{
+				query: func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+					return nil, nil, tt.dbErr
+				},
+			}
+			store := NewStore(openFakeDB(t, fake))
+
+			err := store.WithTx(context.Background(), func(repos Repositories) error {
+				_, err := repos.Users.Create(context.Background(), &entity.User{Name: "Alice", Email: "alice@example.com", RoleID: 2})
+				return err
+			})
+			if err == nil {
+				t.Fatal("error = nil, want a failure")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (errors.Is(err, ErrDuplicateEmail) || errors.Is(err, ErrDuplicateUsername)) {
+				t.Errorf("error = %v, want a generic error", err)
+			}
+			if fake.commits != 0 || fake.rollbacks != 1 {
+				t.Errorf("commits, rollbacks = %d, %d; want 0, 1", fake.commits, fake.rollbacks)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+// apiKeyPrefix marks API keys so they are recognizable in configs and secret scanners
+const apiKeyPrefix = "ebk_"
+
+// apiKeyDisplayLength is how much of a key, including its marker, is kept to identify it
+const apiKeyDisplayLength = len(apiKeyPrefix) + 8
+
+// apiKeyTouchInterval is how often an API key's last use is written
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey creates an API key for the user. Several keys may be active at once so
+// a key can be rotated by creating its replacement before revoking it.
+func (u *UserUsecaseImpl) CreateAPIKey(ctx context.Context, userID int64, payload *entity.APIKeyCreatePayload) (*entity.APIKeyCreatedResponse, error) {
+	for _, scope := range payload.Scopes {
+		if !u.isAPIKeyScope(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyScope, scope)
+		}
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+
+	active, err := u.apiKeyRepo.CountActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error counting api keys: %w", err)
+	}
+	if u.cfg.APIKeysMaxPerUser > 0 && active >= int64(u.cfg.APIKeysMaxPerUser) {
+		return nil, ErrAPIKeyLimitReached
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating api key: %w", err)
+	}
+	plaintext := apiKeyPrefix + secret
+
+	scopes := payload.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	key := &entity.APIKey{
+		UserID:    userID,
+		Name:      payload.Name,
+		Prefix:    plaintext[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: payload.ExpiresAt,
+	}
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("error creating api key: %w", err)
+	}
+
+	return &entity.APIKeyCreatedResponse{APIKey: key, Key: plaintext}, nil
+}
+
+// ListAPIKeys lists the user's active API keys without their secrets
+func (u *UserUsecaseImpl) ListAPIKeys(ctx context.Context, userID int64) ([]*entity.APIKey, error) {
+	keys, err := u.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+func (u *UserUsecaseImpl) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	return u.apiKeyRepo.Delete(ctx, userID, keyID)
+}
+
+// AuthenticateAPIKey gets the unexpired API key key and its user. Keys of deleted users
+// are ErrInvalidAPIKey and keys of suspended users ErrAccountSuspended. Last use is
+// recorded at most once per apiKeyTouchInterval to avoid a write per request.
+func (u *UserUsecaseImpl) AuthenticateAPIKey(ctx context.Context, key string) (*entity.APIKey, *entity.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := u.apiKeyRepo.GetActiveByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting api key: %w", err)
+	}
+	if apiKey == nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := u.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if user.Status == entity.UserStatusSuspended {
+		return nil, nil, ErrAccountSuspended
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := u.apiKeyRepo.Touch(ctx, apiKey.ID, now); err != nil {
+			log.Printf("error updating api key last use: %v\n", err)
+		}
+	}
+	return apiKey, user, nil
+}
+
+// isAPIKeyScope reports whether scope is one of the configured API key scopes
+func (u *UserUsecaseImpl) isAPIKeyScope(scope string) bool {
+	for _, allowed := range u.cfg.APIKeyScopes {
+		if scope == allowed {
+			return true
+		}
+	}
+	return false
+}
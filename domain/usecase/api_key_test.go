@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		existing int
+		payload  entity.APIKeyCreatePayload
+		wantErr  error
+	}{
+		{name: "no scopes", payload: entity.APIKeyCreatePayload{Name: "ci"}},
+		{name: "scopes and expiry", payload: entity.APIKeyCreatePayload{Name: "ci", Scopes: []string{"read", "write"}, ExpiresAt: &future}},
+		{name: "rotation alongside an active key", existing: 1, payload: entity.APIKeyCreatePayload{Name: "ci v2"}},
+		{name: "unknown scope", payload: entity.APIKeyCreatePayload{Name: "ci", Scopes: []string{"read", "admin"}}, wantErr: ErrInvalidAPIKeyScope},
+		{name: "expiry in the past", payload: entity.APIKeyCreatePayload{Name: "ci", ExpiresAt: &past}, wantErr: ErrAPIKeyExpiryInPast},
+		{name: "limit reached", existing: 2, payload: entity.APIKeyCreatePayload{Name: "ci"}, wantErr: ErrAPIKeyLimitReached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.APIKeysMaxPerUser = 2
+				cfg.APIKeyScopes = []string{"read", "write"}
+			})
+			ctx := context.Background()
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+
+			for range tt.existing {
+				if _, err := env.uc.CreateAPIKey(ctx, user.ID, &entity.APIKeyCreatePayload{Name: "existing"}); err != nil {
+					t.Fatalf("error creating existing key: %v", err)
+				}
+			}
+
+			created, err := env.uc.CreateAPIKey(ctx, user.ID, &tt.payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !strings.HasPrefix(created.Key, apiKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+				t.Errorf("key %q does not start with %q and prefix %q", created.Key, apiKeyPrefix, created.Prefix)
+			}
+			if created.KeyHash != utils.HashToken(created.Key) {
+				t.Error("stored hash does not match the key")
+			}
+			if strings.Contains(created.KeyHash, created.Key) {
+				t.Error("plaintext key is stored")
+			}
+			if created.Scopes == nil {
+				t.Error("scopes = nil, want an empty list")
+			}
+
+			keys, err := env.uc.ListAPIKeys(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(keys) != tt.existing+1 {
+				t.Errorf("active keys = %d, want %d", len(keys), tt.existing+1)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// key returns the key to authenticate with, given a valid key of the user
+		key func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string
+
+		wantErr error
+	}{
+		{
+			name: "valid key",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				return valid.Key
+			},
+		},
+		{
+			name: "unknown key",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				return apiKeyPrefix + "unknown"
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "missing marker",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				return strings.TrimPrefix(valid.Key, apiKeyPrefix)
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "revoked key",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				if err := env.uc.RevokeAPIKey(context.Background(), user.ID, valid.ID); err != nil {
+					t.Fatalf("error revoking key: %v", err)
+				}
+				return valid.Key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "expired key",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				expired := time.Now().Add(-time.Minute)
+				key := apiKeyPrefix + "expired"
+				if err := env.apiKeys.Create(context.Background(), &entity.APIKey{
+					UserID: user.ID, Name: "old", KeyHash: utils.HashToken(key), ExpiresAt: &expired,
+				}); err != nil {
+					t.Fatalf("error creating key: %v", err)
+				}
+				return key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name: "suspended user",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				if err := env.users.UpdateStatus(context.Background(), user.ID, entity.UserStatusSuspended); err != nil {
+					t.Fatalf("error suspending user: %v", err)
+				}
+				return valid.Key
+			},
+			wantErr: ErrAccountSuspended,
+		},
+		{
+			name: "deleted user",
+			key: func(t *testing.T, env *testEnv, user *entity.User, valid *entity.APIKeyCreatedResponse) string {
+				if err := env.users.Delete(context.Background(), user.ID); err != nil {
+					t.Fatalf("error deleting user: %v", err)
+				}
+				return valid.Key
+			},
+			wantErr: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+
+			valid, err := env.uc.CreateAPIKey(ctx, user.ID, &entity.APIKeyCreatePayload{Name: "ci", Scopes: []string{"read"}})
+			if err != nil {
+				t.Fatalf("error creating key: %v", err)
+			}
+
+			apiKey, owner, err := env.uc.AuthenticateAPIKey(ctx, tt.key(t, env, user, valid))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if apiKey.ID != valid.ID || owner.ID != user.ID {
+				t.Errorf("authenticated key %d of user %d, want key %d of user %d", apiKey.ID, owner.ID, valid.ID, user.ID)
+			}
+
+			keys, err := env.uc.ListAPIKeys(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if keys[0].LastUsedAt == nil {
+				t.Error("last use was not recorded")
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   bool
+		keyID   func(created *entity.APIKeyCreatedResponse) int64
+		wantErr error
+	}{
+		{name: "own key", owner: true, keyID: func(created *entity.APIKeyCreatedResponse) int64 { return created.ID }},
+		{name: "another user's key", keyID: func(created *entity.APIKeyCreatedResponse) int64 { return created.ID }, wantErr: ErrAPIKeyNotFound},
+		{name: "unknown key", owner: true, keyID: func(created *entity.APIKeyCreatedResponse) int64 { return created.ID + 100 }, wantErr: ErrAPIKeyNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+			bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+
+			created, err := env.uc.CreateAPIKey(ctx, alice.ID, &entity.APIKeyCreatePayload{Name: "ci"})
+			if err != nil {
+				t.Fatalf("error creating key: %v", err)
+			}
+
+			revoker := bob
+			if tt.owner {
+				revoker = alice
+			}
+			if err := env.uc.RevokeAPIKey(ctx, revoker.ID, tt.keyID(created)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			keys, err := env.uc.ListAPIKeys(ctx, alice.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wantKeys := 0
+			if tt.wantErr != nil {
+				wantKeys = 1
+			}
+			if len(keys) != wantKeys {
+				t.Errorf("alice's active keys = %d, want %d", len(keys), wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+func TestBulkAssignRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		admins      int
+		users       int
+		ids         func(admins, users []int64) []int64
+		roleID      int64
+		wantErr     error
+		wantUpdated int64
+		wantInvalid []int64
+	}{
+		{
+			name:   "promotes users and reports unknown IDs",
+			admins: 1, users: 2,
+			ids:         func(admins, users []int64) []int64 { return []int64{users[0], 999, users[1], users[0]} },
+			roleID:      entity.RoleIDAdmin,
+			wantUpdated: 2,
+			wantInvalid: []int64{999},
+		},
+		{
+			name:   "demotes some admins",
+			admins: 2, users: 0,
+			ids:         func(admins, users []int64) []int64 { return admins[:1] },
+			roleID:      entity.RoleIDUser,
+			wantUpdated: 1,
+			wantInvalid: []int64{},
+		},
+		{
+			name:   "refuses to demote every admin",
+			admins: 2, users: 1,
+			ids:     func(admins, users []int64) []int64 { return append(admins, users...) },
+			roleID:  entity.RoleIDUser,
+			wantErr: ErrLastAdmin,
+		},
+		{
+			name:   "unknown role",
+			admins: 1, users: 1,
+			ids:     func(admins, users []int64) []int64 { return users },
+			roleID:  42,
+			wantErr: ErrRoleNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+
+			var adminIDs, userIDs []int64
+			roles := map[int64]int64{}
+			for i := 0; i < tt.admins; i++ {
+				user := env.createUser(t, "admin"+string(rune('a'+i))+"@example.com", entity.RoleIDAdmin)
+				adminIDs = append(adminIDs, user.ID)
+				roles[user.ID] = entity.RoleIDAdmin
+			}
+			for i := 0; i < tt.users; i++ {
+				user := env.createUser(t, "user"+string(rune('a'+i))+"@example.com", entity.RoleIDUser)
+				userIDs = append(userIDs, user.ID)
+				roles[user.ID] = entity.RoleIDUser
+			}
+
+			result, err := env.uc.BulkAssignRole(ctx, &entity.BulkRoleAssignPayload{
+				UserIDs: tt.ids(adminIDs, userIDs),
+				RoleID:  tt.roleID,
+			}, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				// A rejected assignment changes no one
+				for id, roleID := range roles {
+					user, _ := env.users.GetByID(ctx, id)
+					if user.RoleID != roleID {
+						t.Errorf("user %d role = %d after a rejected assignment, want %d", id, user.RoleID, roleID)
+					}
+				}
+				return
+			}
+
+			if result.Updated != tt.wantUpdated {
+				t.Errorf("updated = %d, want %d", result.Updated, tt.wantUpdated)
+			}
+			if !reflect.DeepEqual(result.InvalidIDs, tt.wantInvalid) {
+				t.Errorf("invalid IDs = %v, want %v", result.InvalidIDs, tt.wantInvalid)
+			}
+			for _, id := range result.AffectedIDs {
+				user, _ := env.users.GetByID(ctx, id)
+				if user.RoleID != tt.roleID {
+					t.Errorf("user %d role = %d, want %d", id, user.RoleID, tt.roleID)
+				}
+			}
+		})
+	}
+}
+
+// rollbackStore is a repository.Store whose dry runs undo the role changes fn makes, as
+// a rolled back transaction would
+type rollbackStore struct {
+	repository.Store
+	users   repository.UserRepository
+	dryRuns int
+}
+
+func (s *rollbackStore) DryRun(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	s.dryRuns++
+	users := &rollbackUsers{UserRepository: s.users}
+	defer users.rollback(ctx)
+	return fn(repository.Repositories{Users: users})
+}
+
+// rollbackUsers remembers the users a bulk role update touches so it can be undone
+type rollbackUsers struct {
+	repository.UserRepository
+	before []*entity.User
+}
+
+func (r *rollbackUsers) BulkUpdateRole(ctx context.Context, ids []int64, roleID int64) (int64, []int64, error) {
+	for _, id := range ids {
+		if user, err := r.UserRepository.GetByID(ctx, id); err == nil && user != nil {
+			r.before = append(r.before, user)
+		}
+	}
+	return r.UserRepository.BulkUpdateRole(ctx, ids, roleID)
+}
+
+// rollback restores the users as they were before the update
+func (r *rollbackUsers) rollback(ctx context.Context) {
+	for _, user := range r.before {
+		r.UserRepository.Update(ctx, user)
+	}
+}
+
+func TestBulkAssignRoleDryRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+
+		// unsupported keeps the memory store, which cannot roll back
+		unsupported bool
+
+		demoteAdmin  bool
+		wantErr      error
+		wantChanged  bool
+		wantDryRuns  int
+		wantAffected int
+	}{
+		{name: "dry run", dryRun: true, wantDryRuns: 1, wantAffected: 2},
+		{name: "real run", dryRun: false, wantChanged: true, wantAffected: 2},
+		{name: "dry run of a rejected assignment", dryRun: true, demoteAdmin: true, wantErr: ErrLastAdmin, wantDryRuns: 1},
+		{name: "dry run unsupported by the store", dryRun: true, unsupported: true, wantErr: ErrDryRunUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			admin := env.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+			alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+			bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+
+			uc := UserUsecase(env.uc)
+			store := &rollbackStore{Store: env.uc.store, users: env.users}
+			if !tt.unsupported {
+				uc = NewUserUsecase(env.users, env.outbox, env.sessions, env.history, env.devices, env.twoFactor, env.apiKeys, store, env.tokens, env.cfg)
+			}
+
+			payload := &entity.BulkRoleAssignPayload{UserIDs: []int64{alice.ID, bob.ID, 999}, RoleID: entity.RoleIDAdmin}
+			if tt.demoteAdmin {
+				payload = &entity.BulkRoleAssignPayload{UserIDs: []int64{admin.ID}, RoleID: entity.RoleIDUser}
+			}
+
+			result, err := uc.BulkAssignRole(ctx, payload, tt.dryRun)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if store.dryRuns != tt.wantDryRuns {
+				t.Errorf("dry runs = %d, want %d", store.dryRuns, tt.wantDryRuns)
+			}
+
+			wantRoles := map[int64]int64{admin.ID: entity.RoleIDAdmin, alice.ID: entity.RoleIDUser, bob.ID: entity.RoleIDUser}
+			if tt.wantChanged {
+				wantRoles[alice.ID] = entity.RoleIDAdmin
+				wantRoles[bob.ID] = entity.RoleIDAdmin
+			}
+			for id, roleID := range wantRoles {
+				user, _ := env.users.GetByID(ctx, id)
+				if user.RoleID != roleID {
+					t.Errorf("user %d role = %d, want %d", id, user.RoleID, roleID)
+				}
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+			if result.DryRun != tt.dryRun {
+				t.Errorf("DryRun = %v, want %v", result.DryRun, tt.dryRun)
+			}
+			if result.Updated != int64(tt.wantAffected) || !reflect.DeepEqual(result.AffectedIDs, []int64{alice.ID, bob.ID}) {
+				t.Errorf("updated %d, affected %v; want %d, %v", result.Updated, result.AffectedIDs, tt.wantAffected, []int64{alice.ID, bob.ID})
+			}
+			if !reflect.DeepEqual(result.InvalidIDs, []int64{999}) {
+				t.Errorf("invalid IDs = %v, want [999]", result.InvalidIDs)
+			}
+		})
+	}
+}
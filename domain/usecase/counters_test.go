@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"echo-base/analytics"
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/events"
+)
+
+func TestCountersFromUsecaseEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		// act performs the user actions whose events are counted
+		act  func(t *testing.T, env *testEnv)
+		want map[string]int64
+	}{
+		{
+			name: "registration",
+			act: func(t *testing.T, env *testEnv) {
+				if _, _, err := env.uc.Register(context.Background(), &entity.UserCreatePayload{
+					Name: "Alice", Email: "alice@example.com", Password: testPassword,
+				}); err != nil {
+					t.Fatalf("error registering: %v", err)
+				}
+			},
+			want: map[string]int64{analytics.CounterRegistrations: 1, analytics.CounterLogins: 0, analytics.CounterFailedLogins: 0},
+		},
+		{
+			name: "registration and logins",
+			act: func(t *testing.T, env *testEnv) {
+				if _, _, err := env.uc.Register(context.Background(), &entity.UserCreatePayload{
+					Name: "Alice", Email: "alice@example.com", Password: testPassword,
+				}); err != nil {
+					t.Fatalf("error registering: %v", err)
+				}
+				env.login(t, "alice@example.com", &entity.LoginMetadata{})
+				env.login(t, "alice@example.com", &entity.LoginMetadata{})
+			},
+			want: map[string]int64{analytics.CounterRegistrations: 1, analytics.CounterLogins: 2, analytics.CounterFailedLogins: 0},
+		},
+		{
+			name: "failed logins",
+			act: func(t *testing.T, env *testEnv) {
+				env.createUser(t, "alice@example.com", entity.RoleIDUser)
+				for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+					if _, err := env.uc.Login(context.Background(), &entity.UserLoginPayload{Email: email, Password: "wrong-password-1"}, &entity.LoginMetadata{}); err == nil {
+						t.Fatalf("login of %s with a wrong password succeeded", email)
+					}
+				}
+			},
+			want: map[string]int64{analytics.CounterRegistrations: 0, analytics.CounterLogins: 0, analytics.CounterFailedLogins: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.RequireEmailVerification = false })
+			counters := analytics.NewCounters(nil)
+			bus := events.NewBus()
+			analytics.Subscribe(bus, counters)
+
+			tt.act(t, env)
+			if _, err := events.NewRelay(env.outbox, bus, 100, events.RetryPolicy{MaxAttempts: 3}).RunOnce(context.Background()); err != nil {
+				t.Fatalf("error relaying events: %v", err)
+			}
+
+			if got := counters.Snapshot(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+)
+
+func TestRegisterEmailDomainLists(t *testing.T) {
+	tests := []struct {
+		name    string
+		deny    []string
+		allow   []string
+		email   string
+		wantErr error
+	}{
+		{name: "deny-listed domain", deny: []string{"mailinator.com"}, email: "bob@mailinator.com", wantErr: ErrEmailDomainNotAllowed},
+		{name: "deny-listed domain in another case", deny: []string{"mailinator.com"}, email: "bob@MAILINATOR.com", wantErr: ErrEmailDomainNotAllowed},
+		{name: "deny-listed subdomain", deny: []string{"mailinator.com"}, email: "bob@eu.mailinator.com", wantErr: ErrEmailDomainNotAllowed},
+		{name: "domain not deny-listed", deny: []string{"mailinator.com"}, email: "bob@example.com"},
+		{name: "allow-listed domain", allow: []string{"example.com"}, email: "bob@example.com"},
+		{name: "domain not allow-listed", allow: []string{"example.com"}, email: "bob@other.com", wantErr: ErrEmailDomainNotAllowed},
+		{name: "allow-list takes precedence", deny: []string{"example.com"}, allow: []string{"example.com"}, email: "bob@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.EmailDomainDenyList = tt.deny
+				cfg.EmailDomainAllowList = tt.allow
+			})
+
+			_, _, err := env.uc.Register(context.Background(), &entity.UserCreatePayload{
+				Name: "Bob", Email: tt.email, Password: testPassword,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChangeEmailDenyList(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.EmailDomainDenyList = []string{"mailinator.com"}
+	})
+	user := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+
+	if _, err := env.uc.ChangeEmail(context.Background(), user.ID, "bob@mailinator.com"); !errors.Is(err, ErrEmailDomainNotAllowed) {
+		t.Errorf("error = %v, want %v", err, ErrEmailDomainNotAllowed)
+	}
+}
+
+// fakeMXResolver answers MX lookups from a fixed table; lookups of slow.example block
+// until the context is done
+type fakeMXResolver map[string][]*net.MX
+
+func (r fakeMXResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	switch name {
+	case "slow.example":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "unresolvable.example":
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	return r[name], nil
+}
+
+func TestRegisterEmailMX(t *testing.T) {
+	resolver := fakeMXResolver{"example.com": {{Host: "mx1.example.com.", Pref: 10}}}
+
+	tests := []struct {
+		name       string
+		strict     bool
+		failClosed bool
+		email      string
+		wantErr    error
+	}{
+		{name: "domain with MX", strict: true, email: "bob@example.com"},
+		{name: "domain without MX", strict: true, email: "bob@no-mx.example", wantErr: ErrEmailDomainNoMX},
+		{name: "check disabled", strict: false, email: "bob@no-mx.example"},
+		{name: "lookup error allowed", strict: true, email: "bob@unresolvable.example"},
+		{name: "lookup error rejected when failing closed", strict: true, failClosed: true, email: "bob@unresolvable.example", wantErr: ErrEmailDomainNoMX},
+		{name: "lookup timeout allowed", strict: true, email: "bob@slow.example"},
+		{name: "lookup timeout rejected when failing closed", strict: true, failClosed: true, email: "bob@slow.example", wantErr: ErrEmailDomainNoMX},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.StrictEmailMX = tt.strict
+				cfg.EmailMXFailClosed = tt.failClosed
+				cfg.EmailMXTimeout = 20 * time.Millisecond
+			})
+			env.uc.mxResolver = resolver
+
+			_, _, err := env.uc.Register(context.Background(), &entity.UserCreatePayload{
+				Name: "Bob", Email: tt.email, Password: testPassword,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+	"echo-base/domain/repository/memory"
+	"echo-base/events"
+	"echo-base/utils"
+)
+
+// testPassword is the password of every user created by the test helpers
+const testPassword = "secret-password-1"
+
+// testPasswordHash is testPassword hashed once, as hashing is deliberately slow
+var (
+	testPasswordHashOnce sync.Once
+	testPasswordHash     string
+	testPasswordHashErr  error
+)
+
+// testEnv is a user usecase backed by the in-memory repositories
+type testEnv struct {
+	uc        *UserUsecaseImpl
+	cfg       *config.Config
+	tokens    *utils.TokenSigner
+	users     repository.UserRepository
+	outbox    repository.OutboxRepository
+	sessions  repository.SessionRepository
+	history   repository.LoginHistoryRepository
+	devices   repository.TrustedDeviceRepository
+	twoFactor repository.TwoFactorRepository
+	apiKeys   repository.APIKeyRepository
+}
+
+// newTestEnv creates a user usecase over fresh in-memory repositories with the default
+// configuration, which configure may adjust first
+func newTestEnv(t *testing.T, configure func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.Load()
+	if configure != nil {
+		configure(cfg)
+	}
+
+	env := &testEnv{
+		cfg:       cfg,
+		tokens:    utils.NewTokenSigner("test-secret", nil, time.Hour, 24*time.Hour, ""),
+		users:     memory.NewUserRepository(),
+		outbox:    memory.NewOutboxRepository(),
+		sessions:  memory.NewSessionRepository(),
+		history:   memory.NewLoginHistoryRepository(),
+		devices:   memory.NewTrustedDeviceRepository(),
+		twoFactor: memory.NewTwoFactorRepository(),
+		apiKeys:   memory.NewAPIKeyRepository(),
+	}
+	roles := memory.NewRoleRepository(env.users)
+	store := memory.NewStore(repository.Repositories{Users: env.users, Roles: roles, Outbox: env.outbox})
+
+	env.uc = NewUserUsecase(env.users, env.outbox, env.sessions, env.history, env.devices, env.twoFactor, env.apiKeys, store, env.tokens, cfg).(*UserUsecaseImpl)
+	return env
+}
+
+// createUser stores an active, verified user with testPassword and the role
+func (env *testEnv) createUser(t *testing.T, email string, roleID int64) *entity.User {
+	t.Helper()
+
+	testPasswordHashOnce.Do(func() {
+		testPasswordHash, testPasswordHashErr = utils.HashPassword(testPassword)
+	})
+	if testPasswordHashErr != nil {
+		t.Fatalf("error hashing password: %v", testPasswordHashErr)
+	}
+	user, err := env.users.Create(context.Background(), &entity.User{
+		Name:          "Test User",
+		Email:         email,
+		Password:      testPasswordHash,
+		RoleID:        roleID,
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("error creating user %s: %v", email, err)
+	}
+	return user
+}
+
+// login logs the user with email in with testPassword from the device in meta
+func (env *testEnv) login(t *testing.T, email string, meta *entity.LoginMetadata) *entity.LoginResponse {
+	t.Helper()
+
+	resp, err := env.uc.Login(context.Background(), &entity.UserLoginPayload{Email: email, Password: testPassword}, meta)
+	if err != nil {
+		t.Fatalf("error logging in %s: %v", email, err)
+	}
+	return resp
+}
+
+// loginRecovery completes a login challenge with a recovery code
+func (env *testEnv) loginRecovery(t *testing.T, challenge, code string) *entity.LoginResponse {
+	t.Helper()
+
+	resp, err := env.uc.LoginRecovery(context.Background(), &entity.RecoveryLoginPayload{ChallengeToken: challenge, RecoveryCode: code}, &entity.LoginMetadata{})
+	if err != nil {
+		t.Fatalf("error logging in with a recovery code: %v", err)
+	}
+	return resp
+}
+
+// pendingEvents drains the outbox and returns the events it held
+func (env *testEnv) pendingEvents(t *testing.T) []events.Event {
+	t.Helper()
+
+	var pending []events.Event
+	if _, err := env.outbox.DispatchPending(context.Background(), 100, events.RetryPolicy{MaxAttempts: 1}, func(event events.Event) error {
+		pending = append(pending, event)
+		return nil
+	}); err != nil {
+		t.Fatalf("error dispatching outbox: %v", err)
+	}
+	return pending
+}
+
+// totpCode returns the TOTP code of secret at the time
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+	if err != nil {
+		t.Fatalf("error generating TOTP code: %v", err)
+	}
+	return code
+}
+
+// enableTwoFactor sets up and enables TOTP for the user and returns the secret and
+// recovery codes. The enabling code is from the previous period, so codes of the
+// current and next periods are still unused.
+func (env *testEnv) enableTwoFactor(t *testing.T, userID int64) (string, []string) {
+	t.Helper()
+
+	setup, err := env.uc.SetupTwoFactor(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("error setting up two-factor: %v", err)
+	}
+	codes, err := env.uc.EnableTwoFactor(context.Background(), userID, &entity.TwoFactorEnablePayload{
+		Code: totpCode(t, setup.Secret, time.Now().Add(-30*time.Second)),
+	})
+	if err != nil {
+		t.Fatalf("error enabling two-factor: %v", err)
+	}
+	return setup.Secret, codes.RecoveryCodes
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+)
+
+func TestGetLoginHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		maskIP    bool
+		userID    int64
+		params    entity.PaginationParams
+		wantIDs   []int64
+		wantTotal int64
+		wantPages int64
+		wantIP    string
+	}{
+		{name: "first page", userID: 1, params: entity.PaginationParams{Page: 1, Limit: 2}, wantIDs: []int64{7, 5}, wantTotal: 5, wantPages: 3, wantIP: "203.0.113.7"},
+		{name: "middle page", userID: 1, params: entity.PaginationParams{Page: 2, Limit: 2}, wantIDs: []int64{4, 2}, wantTotal: 5, wantPages: 3},
+		{name: "last partial page", userID: 1, params: entity.PaginationParams{Page: 3, Limit: 2}, wantIDs: []int64{1}, wantTotal: 5, wantPages: 3},
+		{name: "past the end", userID: 1, params: entity.PaginationParams{Page: 4, Limit: 2}, wantIDs: []int64{}, wantTotal: 5, wantPages: 3},
+		{name: "defaults", userID: 1, wantIDs: []int64{7, 5, 4, 2, 1}, wantTotal: 5, wantPages: 1},
+		{name: "other user", userID: 2, params: entity.PaginationParams{Page: 1, Limit: 10}, wantIDs: []int64{6, 3}, wantTotal: 2, wantPages: 1},
+		{name: "no history", userID: 3, params: entity.PaginationParams{Page: 1, Limit: 10}, wantIDs: []int64{}, wantTotal: 0, wantPages: 0},
+		{name: "masked IP", maskIP: true, userID: 1, params: entity.PaginationParams{Page: 1, Limit: 1}, wantIDs: []int64{7}, wantTotal: 5, wantPages: 5, wantIP: "203.0.113.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.LoginHistoryMaskIP = tt.maskIP
+			})
+			ctx := context.Background()
+
+			// Entries 1-7 alternate between users 1 and 2
+			for i, userID := range []int64{1, 1, 2, 1, 1, 2, 1} {
+				if err := env.history.Create(ctx, &entity.LoginHistory{
+					UserID:    userID,
+					IP:        fmt.Sprintf("203.0.113.%d", i+1),
+					UserAgent: "test-agent",
+					Success:   i%2 == 0,
+				}); err != nil {
+					t.Fatalf("error recording login: %v", err)
+				}
+			}
+
+			result, err := env.uc.GetLoginHistory(ctx, tt.userID, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := make([]int64, 0, len(result.Data))
+			for _, entry := range result.Data {
+				if entry.UserID != tt.userID {
+					t.Errorf("entry %d belongs to user %d, want %d", entry.ID, entry.UserID, tt.userID)
+				}
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("entry IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if result.Pagination.Total != tt.wantTotal || result.Pagination.TotalPages != tt.wantPages {
+				t.Errorf("total, pages = %d, %d; want %d, %d", result.Pagination.Total, result.Pagination.TotalPages, tt.wantTotal, tt.wantPages)
+			}
+			if tt.wantIP != "" && result.Data[0].IP != tt.wantIP {
+				t.Errorf("IP = %q, want %q", result.Data[0].IP, tt.wantIP)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/events"
+)
+
+func TestSuspiciousLogin(t *testing.T) {
+	laptop := &entity.LoginMetadata{IP: "10.0.0.1", UserAgent: "laptop"}
+	phone := &entity.LoginMetadata{IP: "10.0.0.2", UserAgent: "phone"}
+
+	tests := []struct {
+		name        string
+		maxSessions int
+		newDevice   bool
+		earlier     []*entity.LoginMetadata
+		login       *entity.LoginMetadata
+		wantReasons []string
+	}{
+		{name: "first login is not flagged", newDevice: true, login: laptop},
+		{name: "repeat device is not flagged", newDevice: true, earlier: []*entity.LoginMetadata{laptop}, login: laptop},
+		{name: "new device is flagged", newDevice: true, earlier: []*entity.LoginMetadata{laptop}, login: phone, wantReasons: []string{"new_device"}},
+		{name: "new device detection disabled", newDevice: false, earlier: []*entity.LoginMetadata{laptop}, login: phone},
+		{name: "sessions within limit", maxSessions: 2, earlier: []*entity.LoginMetadata{laptop}, login: laptop},
+		{name: "sessions over limit", maxSessions: 2, earlier: []*entity.LoginMetadata{laptop, laptop}, login: laptop, wantReasons: []string{"too_many_sessions"}},
+		{
+			name: "new device over session limit", newDevice: true, maxSessions: 1,
+			earlier: []*entity.LoginMetadata{laptop}, login: phone, wantReasons: []string{"new_device", "too_many_sessions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.SuspiciousLoginNewDevice = tt.newDevice
+				cfg.SuspiciousLoginMaxSessions = tt.maxSessions
+			})
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+
+			for _, meta := range tt.earlier {
+				env.login(t, user.Email, meta)
+			}
+			env.pendingEvents(t)
+
+			env.login(t, user.Email, tt.login)
+
+			var got *events.SuspiciousLoginPayload
+			for _, event := range env.pendingEvents(t) {
+				if event.Name != events.SuspiciousLogin {
+					continue
+				}
+				if got != nil {
+					t.Fatal("more than one suspicious login event published")
+				}
+				got = &events.SuspiciousLoginPayload{}
+				if err := event.Decode(got); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.wantReasons == nil {
+				if got != nil {
+					t.Fatalf("suspicious login event %+v published, want none", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("no suspicious login event, want reasons %v", tt.wantReasons)
+			}
+			if !reflect.DeepEqual(got.Reasons, tt.wantReasons) {
+				t.Errorf("reasons = %v, want %v", got.Reasons, tt.wantReasons)
+			}
+			if got.UserID != user.ID || got.IP != tt.login.IP || got.UserAgent != tt.login.UserAgent {
+				t.Errorf("payload = %+v, want user %d from %s/%s", got, user.ID, tt.login.IP, tt.login.UserAgent)
+			}
+			if want := int64(len(tt.earlier) + 1); got.ActiveSessions != want {
+				t.Errorf("active sessions = %d, want %d", got.ActiveSessions, want)
+			}
+		})
+	}
+}
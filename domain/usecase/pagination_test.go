@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+)
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct {
+		total, limit int64
+		want         int64
+	}{
+		{total: 0, limit: 10, want: 0},
+		{total: 1, limit: 10, want: 1},
+		{total: 10, limit: 10, want: 1},
+		{total: 11, limit: 10, want: 2},
+		{total: 25, limit: 1, want: 25},
+		{total: 25, limit: 0, want: 0},
+		{total: 25, limit: -5, want: 0},
+		{total: -1, limit: 10, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.total, tt.limit), func(t *testing.T) {
+			if got := totalPages(tt.total, tt.limit); got != tt.want {
+				t.Errorf("totalPages(%d, %d) = %d, want %d", tt.total, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAllPaginationEdgeLimits(t *testing.T) {
+	env := newTestEnv(t, nil)
+	for i := range 25 {
+		env.createUser(t, fmt.Sprintf("user%d@example.com", i), entity.RoleIDUser)
+	}
+
+	tests := []struct {
+		name           string
+		page, limit    int64
+		wantPage       int64
+		wantLimit      int64
+		wantTotalPages int64
+		wantLen        int
+	}{
+		{name: "zero limit", page: 1, limit: 0, wantPage: 1, wantLimit: entity.DefaultPageLimit, wantTotalPages: 3, wantLen: 10},
+		{name: "negative limit", page: 1, limit: -3, wantPage: 1, wantLimit: entity.DefaultPageLimit, wantTotalPages: 3, wantLen: 10},
+		{name: "limit above max", page: 1, limit: entity.MaxPageLimit + 1, wantPage: 1, wantLimit: entity.DefaultPageLimit, wantTotalPages: 3, wantLen: 10},
+		{name: "zero page", page: 0, limit: 5, wantPage: 1, wantLimit: 5, wantTotalPages: 5, wantLen: 5},
+		{name: "negative page", page: -2, limit: 5, wantPage: 1, wantLimit: 5, wantTotalPages: 5, wantLen: 5},
+		{name: "last partial page", page: 3, limit: 10, wantPage: 3, wantLimit: 10, wantTotalPages: 3, wantLen: 5},
+		{name: "page past the end", page: 9, limit: 10, wantPage: 9, wantLimit: 10, wantTotalPages: 3, wantLen: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.uc.GetAllPagination(context.Background(), entity.PaginationParams{Page: tt.page, Limit: tt.limit})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			meta := resp.Pagination
+			if meta.Page != tt.wantPage || meta.Limit != tt.wantLimit || meta.Total != 25 || meta.TotalPages != tt.wantTotalPages {
+				t.Errorf("pagination = %+v, want page %d, limit %d, total 25, %d pages", meta, tt.wantPage, tt.wantLimit, tt.wantTotalPages)
+			}
+			if len(resp.Data) != tt.wantLen {
+				t.Errorf("got %d users, want %d", len(resp.Data), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestGetAllPaginationMaxOffset(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxOffset int64
+		page      int64
+		limit     int64
+		wantErr   error
+	}{
+		{name: "first page", maxOffset: 100, page: 1, limit: 50},
+		{name: "offset at the limit", maxOffset: 100, page: 3, limit: 50},
+		{name: "offset over the limit", maxOffset: 100, page: 4, limit: 50, wantErr: ErrOffsetTooLarge},
+		{name: "huge page", maxOffset: 10000, page: 1000000, limit: 10, wantErr: ErrOffsetTooLarge},
+		{name: "check disabled", maxOffset: 0, page: 1000000, limit: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.PaginationMaxOffset = tt.maxOffset })
+
+			_, err := env.uc.GetAllPagination(context.Background(), entity.PaginationParams{Page: tt.page, Limit: tt.limit})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
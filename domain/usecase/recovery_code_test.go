@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"echo-base/domain/entity"
+)
+
+func TestLoginRecovery(t *testing.T) {
+	meta := &entity.LoginMetadata{IP: "10.0.0.1", UserAgent: "laptop"}
+
+	tests := []struct {
+		name string
+		// code returns the recovery code to submit, given the codes issued at enrollment
+		code    func(t *testing.T, env *testEnv, userID int64, codes []string) string
+		wantErr error
+	}{
+		{
+			name: "unused code",
+			code: func(t *testing.T, env *testEnv, userID int64, codes []string) string { return codes[0] },
+		},
+		{
+			name: "code in another format",
+			code: func(t *testing.T, env *testEnv, userID int64, codes []string) string {
+				return " " + strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")) + " "
+			},
+		},
+		{
+			name:    "unknown code",
+			code:    func(t *testing.T, env *testEnv, userID int64, codes []string) string { return "aaaaa-bbbbb" },
+			wantErr: ErrInvalidRecoveryCode,
+		},
+		{
+			name: "code already used",
+			code: func(t *testing.T, env *testEnv, userID int64, codes []string) string {
+				env.loginRecovery(t, env.login(t, "alice@example.com", meta).ChallengeToken, codes[0])
+				return codes[0]
+			},
+			wantErr: ErrInvalidRecoveryCode,
+		},
+		{
+			name: "code replaced by regeneration",
+			code: func(t *testing.T, env *testEnv, userID int64, codes []string) string {
+				if _, err := env.uc.RegenerateRecoveryCodes(context.Background(), userID); err != nil {
+					t.Fatalf("error regenerating recovery codes: %v", err)
+				}
+				return codes[0]
+			},
+			wantErr: ErrInvalidRecoveryCode,
+		},
+		{
+			name: "regenerated code",
+			code: func(t *testing.T, env *testEnv, userID int64, codes []string) string {
+				resp, err := env.uc.RegenerateRecoveryCodes(context.Background(), userID)
+				if err != nil {
+					t.Fatalf("error regenerating recovery codes: %v", err)
+				}
+				return resp.RecoveryCodes[0]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+			_, codes := env.enableTwoFactor(t, user.ID)
+
+			code := tt.code(t, env, user.ID, codes)
+			challenge := env.login(t, user.Email, meta).ChallengeToken
+
+			resp, err := env.uc.LoginRecovery(context.Background(), &entity.RecoveryLoginPayload{ChallengeToken: challenge, RecoveryCode: code}, meta)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Token == "" || resp.User == nil || resp.User.ID != user.ID {
+				t.Errorf("response = %+v, want a token for user %d", resp, user.ID)
+			}
+
+			// Each code logs in once
+			challenge = env.login(t, user.Email, meta).ChallengeToken
+			if _, err := env.uc.LoginRecovery(context.Background(), &entity.RecoveryLoginPayload{ChallengeToken: challenge, RecoveryCode: code}, meta); !errors.Is(err, ErrInvalidRecoveryCode) {
+				t.Errorf("reused code error = %v, want %v", err, ErrInvalidRecoveryCode)
+			}
+		})
+	}
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+	_, enrolled := env.enableTwoFactor(t, alice.ID)
+
+	tests := []struct {
+		name    string
+		userID  int64
+		wantErr error
+	}{
+		{name: "enabled", userID: alice.ID},
+		{name: "not enabled", userID: bob.ID, wantErr: ErrTwoFactorNotEnabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.uc.RegenerateRecoveryCodes(context.Background(), tt.userID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(resp.RecoveryCodes) != recoveryCodeCount {
+				t.Fatalf("recovery codes = %d, want %d", len(resp.RecoveryCodes), recoveryCodeCount)
+			}
+			seen := make(map[string]bool)
+			for _, code := range append(resp.RecoveryCodes, enrolled...) {
+				if seen[code] {
+					t.Errorf("recovery code %q issued twice", code)
+				}
+				seen[code] = true
+			}
+
+			// Only hashes are stored, so the plaintext code is not a stored value
+			if consumed, err := env.twoFactor.ConsumeRecoveryCode(context.Background(), tt.userID, resp.RecoveryCodes[0]); err != nil || consumed {
+				t.Errorf("plaintext code consumed = %v (err %v), want only hashes stored", consumed, err)
+			}
+		})
+	}
+}
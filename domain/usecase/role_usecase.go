@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+)
+
+var (
+	// ErrRolesUnavailable is returned when the required roles cannot be resolved
+	ErrRolesUnavailable = errors.New("required roles are unavailable")
+
+	// ErrRoleNameTaken is returned when a role name is already in use
+	ErrRoleNameTaken = repository.ErrRoleNameTaken
+
+	// ErrRoleInUse is returned when deleting a role that users still have
+	ErrRoleInUse = repository.ErrRoleInUse
+
+	// ErrRoleProtected is returned when deleting a role the application depends on
+	ErrRoleProtected = errors.New("role is required by the application and cannot be deleted")
+)
+
+// RoleUsecase defines the interface for role business logic
+type RoleUsecase interface {
+	// GetAll returns all roles as public-safe responses
+	GetAll(ctx context.Context) ([]entity.RoleResponse, error)
+
+	// GetByID returns a role
+	GetByID(ctx context.Context, id int64) (*entity.RoleResponse, error)
+
+	// Create creates a role
+	Create(ctx context.Context, payload *entity.RolePayload) (*entity.RoleResponse, error)
+
+	// Update renames a role
+	Update(ctx context.Context, id int64, payload *entity.RolePayload) (*entity.RoleResponse, error)
+
+	// Delete deletes a role that no user has; required roles cannot be deleted
+	Delete(ctx context.Context, id int64) error
+
+	// EnsureRequired checks that every required role exists, creating the missing ones
+	// when create is set and failing otherwise
+	EnsureRequired(ctx context.Context, create bool) error
+
+	// CheckRequired returns ErrRolesUnavailable when the roles cannot be read or a required
+	// role is missing. It uses the cached role list.
+	CheckRequired(ctx context.Context) error
+}
+
+// RoleUsecaseImpl implements RoleUsecase, caching the role list for cacheTTL
+type RoleUsecaseImpl struct {
+	roleRepo repository.RoleRepository
+	cacheTTL time.Duration
+
+	mu       sync.Mutex
+	cached   []entity.RoleResponse
+	cachedAt time.Time
+}
+
+// NewRoleUsecase creates a new role usecase. A cacheTTL of 0 disables caching.
+func NewRoleUsecase(roleRepo repository.RoleRepository, cacheTTL time.Duration) RoleUsecase {
+	return &RoleUsecaseImpl{
+		roleRepo: roleRepo,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// GetAll returns all roles, served from cache while it is fresh
+func (u *RoleUsecaseImpl) GetAll(ctx context.Context) ([]entity.RoleResponse, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.cached != nil && time.Since(u.cachedAt) < u.cacheTTL {
+		return u.cached, nil
+	}
+
+	roles, err := u.roleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]entity.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		responses = append(responses, entity.RoleResponse{
+			ID:   role.ID,
+			Name: role.Name,
+		})
+	}
+
+	if u.cacheTTL > 0 {
+		u.cached = responses
+		u.cachedAt = time.Now()
+	}
+
+	return responses, nil
+}
+
+// GetByID returns a role
+func (u *RoleUsecaseImpl) GetByID(ctx context.Context, id int64) (*entity.RoleResponse, error) {
+	role, err := u.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return toRoleResponse(role), nil
+}
+
+// Create creates a role
+func (u *RoleUsecaseImpl) Create(ctx context.Context, payload *entity.RolePayload) (*entity.RoleResponse, error) {
+	role, err := u.roleRepo.Create(ctx, strings.TrimSpace(payload.Name))
+	if err != nil {
+		return nil, err
+	}
+	u.invalidate()
+	return toRoleResponse(role), nil
+}
+
+// Update renames a role
+func (u *RoleUsecaseImpl) Update(ctx context.Context, id int64, payload *entity.RolePayload) (*entity.RoleResponse, error) {
+	role, err := u.roleRepo.Update(ctx, &entity.Role{ID: id, Name: strings.TrimSpace(payload.Name)})
+	if err != nil {
+		return nil, err
+	}
+	u.invalidate()
+	return toRoleResponse(role), nil
+}
+
+// Delete deletes a role, refusing required roles and roles still assigned to users
+func (u *RoleUsecaseImpl) Delete(ctx context.Context, id int64) error {
+	for _, role := range entity.RequiredRoles {
+		if role.ID == id {
+			return ErrRoleProtected
+		}
+	}
+
+	if err := u.roleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.invalidate()
+	return nil
+}
+
+// invalidate drops the cached role list after a change
+func (u *RoleUsecaseImpl) invalidate() {
+	u.mu.Lock()
+	u.cached = nil
+	u.mu.Unlock()
+}
+
+// toRoleResponse converts a role to its public-safe response
+func toRoleResponse(role *entity.Role) *entity.RoleResponse {
+	return &entity.RoleResponse{
+		ID:   role.ID,
+		Name: role.Name,
+	}
+}
+
+// EnsureRequired checks the required roles against the database, bypassing the cache
+func (u *RoleUsecaseImpl) EnsureRequired(ctx context.Context, create bool) error {
+	missing, err := u.missingRoles(ctx)
+	if err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("%w: missing %s; run the migrations or set ROLES_AUTO_CREATE=true",
+			ErrRolesUnavailable, describeRoles(missing))
+	}
+
+	for _, role := range missing {
+		if err := u.roleRepo.CreateWithID(ctx, role); err != nil {
+			return err
+		}
+	}
+
+	// A role whose name is taken under another ID cannot be recreated
+	if missing, err = u.missingRoles(ctx); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: could not create %s; another role uses its name",
+			ErrRolesUnavailable, describeRoles(missing))
+	}
+
+	u.invalidate()
+	return nil
+}
+
+// CheckRequired reports whether the required roles are present in the role list
+func (u *RoleUsecaseImpl) CheckRequired(ctx context.Context) error {
+	roles, err := u.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRolesUnavailable, err)
+	}
+
+	present := make(map[int64]bool, len(roles))
+	for _, role := range roles {
+		present[role.ID] = true
+	}
+	for _, role := range entity.RequiredRoles {
+		if !present[role.ID] {
+			return ErrRolesUnavailable
+		}
+	}
+	return nil
+}
+
+// missingRoles returns the required roles absent from the database
+func (u *RoleUsecaseImpl) missingRoles(ctx context.Context) ([]entity.Role, error) {
+	roles, err := u.roleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRolesUnavailable, err)
+	}
+
+	present := make(map[int64]bool, len(roles))
+	for _, role := range roles {
+		present[role.ID] = true
+	}
+
+	var missing []entity.Role
+	for _, role := range entity.RequiredRoles {
+		if !present[role.ID] {
+			missing = append(missing, role)
+		}
+	}
+	return missing, nil
+}
+
+// describeRoles lists roles as "1 (user), 2 (admin)"
+func describeRoles(roles []entity.Role) string {
+	parts := make([]string, 0, len(roles))
+	for _, role := range roles {
+		parts = append(parts, fmt.Sprintf("%d (%s)", role.ID, role.Name))
+	}
+	return strings.Join(parts, ", ")
+}
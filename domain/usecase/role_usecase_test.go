@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+	"echo-base/domain/repository/memory"
+)
+
+func TestRoleUsecaseGetAllCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		wait     time.Duration
+		wantNew  bool
+	}{
+		{name: "caching disabled", cacheTTL: 0, wantNew: true},
+		{name: "fresh cache", cacheTTL: time.Hour, wantNew: false},
+		{name: "expired cache", cacheTTL: 20 * time.Millisecond, wait: 40 * time.Millisecond, wantNew: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			roles := memory.NewRoleRepository(memory.NewUserRepository())
+			uc := NewRoleUsecase(roles, tt.cacheTTL)
+
+			before, err := uc.GetAll(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(before) != len(entity.RequiredRoles) {
+				t.Fatalf("roles = %v, want the %d required roles", before, len(entity.RequiredRoles))
+			}
+
+			// Changes made around the usecase only show once the cache is refreshed
+			if _, err := roles.Create(ctx, "editor"); err != nil {
+				t.Fatalf("error creating role: %v", err)
+			}
+			time.Sleep(tt.wait)
+
+			after, err := uc.GetAll(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotNew := len(after) == len(before)+1; gotNew != tt.wantNew {
+				t.Errorf("new role listed = %v, want %v (roles %v)", gotNew, tt.wantNew, after)
+			}
+		})
+	}
+}
+
+func TestRoleUsecaseWritesInvalidateCache(t *testing.T) {
+	ctx := context.Background()
+	uc := NewRoleUsecase(memory.NewRoleRepository(memory.NewUserRepository()), time.Hour)
+	if _, err := uc.GetAll(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created, err := uc.Create(ctx, &entity.RolePayload{Name: " editor "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := uc.Update(ctx, created.ID, &entity.RolePayload{Name: "reviewer"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	roles, err := uc.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, role := range roles {
+		if role.ID == created.ID {
+			found = role.Name == "reviewer"
+		}
+	}
+	if !found {
+		t.Errorf("roles = %v, want the renamed role %d listed", roles, created.ID)
+	}
+}
+
+// missingTableRoles is a role repository whose table does not exist
+type missingTableRoles struct {
+	repository.RoleRepository
+}
+
+func (missingTableRoles) GetAll(ctx context.Context) ([]entity.Role, error) {
+	return nil, errors.New(`pq: relation "roles" does not exist`)
+}
+
+func TestRoleUsecaseEnsureRequired(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// setup breaks the seeded roles before the check
+		setup func(t *testing.T, roles repository.RoleRepository) repository.RoleRepository
+
+		create    bool
+		wantErr   error
+		wantMsg   string
+		wantRoles bool
+	}{
+		{
+			name:      "roles present",
+			setup:     func(t *testing.T, roles repository.RoleRepository) repository.RoleRepository { return roles },
+			wantRoles: true,
+		},
+		{
+			name:    "missing role fails fast",
+			setup:   deleteAdminRole,
+			wantErr: ErrRolesUnavailable,
+			wantMsg: "missing 2 (admin)",
+		},
+		{
+			name:      "missing role recreated",
+			setup:     deleteAdminRole,
+			create:    true,
+			wantRoles: true,
+		},
+		{
+			name: "name taken by another role",
+			setup: func(t *testing.T, roles repository.RoleRepository) repository.RoleRepository {
+				deleteAdminRole(t, roles)
+				if _, err := roles.Create(context.Background(), "admin"); err != nil {
+					t.Fatalf("error creating role: %v", err)
+				}
+				return roles
+			},
+			create:  true,
+			wantErr: ErrRolesUnavailable,
+			wantMsg: "could not create 2 (admin)",
+		},
+		{
+			name: "missing table",
+			setup: func(t *testing.T, roles repository.RoleRepository) repository.RoleRepository {
+				return missingTableRoles{roles}
+			},
+			create:  true,
+			wantErr: ErrRolesUnavailable,
+			wantMsg: `relation "roles" does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			roles := tt.setup(t, memory.NewRoleRepository(memory.NewUserRepository()))
+			uc := NewRoleUsecase(roles, time.Hour)
+
+			err := uc.EnsureRequired(ctx, tt.create)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantMsg)
+			}
+
+			if gotRoles := uc.CheckRequired(ctx) == nil; gotRoles != tt.wantRoles {
+				t.Errorf("required roles available = %v, want %v", gotRoles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestRoleUsecaseCheckRequired(t *testing.T) {
+	ctx := context.Background()
+	roles := memory.NewRoleRepository(memory.NewUserRepository())
+	uc := NewRoleUsecase(roles, time.Hour)
+
+	if err := uc.CheckRequired(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// CheckRequired reads the cached role list, so a role deleted behind its back only
+	// shows once the cache expires; EnsureRequired always reads the repository
+	deleteAdminRole(t, roles)
+	if err := uc.CheckRequired(ctx); err != nil {
+		t.Fatalf("CheckRequired error = %v with a fresh cache, want nil", err)
+	}
+	if err := uc.EnsureRequired(ctx, false); !errors.Is(err, ErrRolesUnavailable) {
+		t.Fatalf("EnsureRequired error = %v, want %v", err, ErrRolesUnavailable)
+	}
+
+	uncached := NewRoleUsecase(roles, 0)
+	if err := uncached.CheckRequired(ctx); !errors.Is(err, ErrRolesUnavailable) {
+		t.Errorf("CheckRequired error = %v, want %v", err, ErrRolesUnavailable)
+	}
+
+	// Recreating the roles refreshes the cache
+	if err := uc.EnsureRequired(ctx, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := uc.CheckRequired(ctx); err != nil {
+		t.Errorf("CheckRequired error = %v after recreating the roles, want nil", err)
+	}
+
+	if err := NewRoleUsecase(missingTableRoles{roles}, 0).CheckRequired(ctx); !errors.Is(err, ErrRolesUnavailable) {
+		t.Errorf("CheckRequired error = %v with no roles table, want %v", err, ErrRolesUnavailable)
+	}
+}
+
+// deleteAdminRole deletes the seeded admin role
+func deleteAdminRole(t *testing.T, roles repository.RoleRepository) repository.RoleRepository {
+	t.Helper()
+
+	if err := roles.Delete(context.Background(), entity.RoleIDAdmin); err != nil {
+		t.Fatalf("error deleting role: %v", err)
+	}
+	return roles
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+)
+
+func TestGetStatsWindows(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.StatsSignupWindows = []string{"24h", "7d"} })
+	env.createUser(t, "old1@example.com", entity.RoleIDUser)
+	env.createUser(t, "old2@example.com", entity.RoleIDUser)
+	time.Sleep(300 * time.Millisecond)
+	env.createUser(t, "new@example.com", entity.RoleIDUser)
+
+	tests := []struct {
+		name    string
+		windows []string
+		want    map[string]int64
+		wantErr error
+	}{
+		{name: "configured defaults", windows: nil, want: map[string]int64{"24h": 3, "7d": 3}},
+		{name: "custom windows", windows: []string{"150ms", "1h"}, want: map[string]int64{"150ms": 1, "1h": 3}},
+		{name: "negative window", windows: []string{"-1h"}, wantErr: ErrInvalidStatsWindows},
+		{name: "malformed window", windows: []string{"soon"}, wantErr: ErrInvalidStatsWindows},
+		{name: "too many windows", windows: make([]string, config.MaxStatsWindows+1), wantErr: ErrInvalidStatsWindows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := env.uc.GetStats(context.Background(), tt.windows)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(stats.SignupsByWindow, tt.want) {
+				t.Errorf("signups by window = %v, want %v", stats.SignupsByWindow, tt.want)
+			}
+			if stats.TotalUsers != 3 {
+				t.Errorf("total users = %d, want 3", stats.TotalUsers)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+)
+
+func TestRememberDevice(t *testing.T) {
+	laptop := &entity.LoginMetadata{IP: "10.0.0.1", UserAgent: "laptop"}
+
+	tests := []struct {
+		name           string
+		ttl            time.Duration
+		rememberDevice bool
+		wantDevice     bool
+	}{
+		{name: "remembered", ttl: time.Hour, rememberDevice: true, wantDevice: true},
+		{name: "not requested", ttl: time.Hour, rememberDevice: false, wantDevice: false},
+		{name: "remembering disabled", ttl: 0, rememberDevice: true, wantDevice: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.TrustedDeviceTTL = tt.ttl })
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+
+			resp, err := env.uc.Login(context.Background(), &entity.UserLoginPayload{
+				Email: user.Email, Password: testPassword, RememberDevice: tt.rememberDevice,
+			}, laptop)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resp.DeviceToken != ""; got != tt.wantDevice {
+				t.Fatalf("device token returned = %v, want %v", got, tt.wantDevice)
+			}
+
+			devices, err := env.uc.ListTrustedDevices(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantDevice {
+				if len(devices) != 0 {
+					t.Errorf("devices = %d, want none", len(devices))
+				}
+				return
+			}
+			if len(devices) != 1 {
+				t.Fatalf("devices = %d, want 1", len(devices))
+			}
+			device := devices[0]
+			if device.IP != laptop.IP || device.UserAgent != laptop.UserAgent {
+				t.Errorf("device = %s/%s, want %s/%s", device.IP, device.UserAgent, laptop.IP, laptop.UserAgent)
+			}
+			if device.TokenHash == "" || device.TokenHash == resp.DeviceToken {
+				t.Errorf("token hash = %q, want a hash of the device token", device.TokenHash)
+			}
+			if wantExpiry := time.Now().Add(tt.ttl); device.ExpiresAt.After(wantExpiry) || device.ExpiresAt.Before(wantExpiry.Add(-time.Minute)) {
+				t.Errorf("expires at = %v, want about %v", device.ExpiresAt, wantExpiry)
+			}
+		})
+	}
+}
+
+func TestTrustedDeviceSkipsTwoFactor(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.TrustedDeviceTTL = time.Hour })
+	alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+	secret, _ := env.enableTwoFactor(t, alice.ID)
+
+	// Complete a two-factor login that remembers the device
+	challenge := env.login(t, alice.Email, &entity.LoginMetadata{IP: "10.0.0.1"})
+	resp, err := env.uc.LoginTwoFactor(context.Background(), &entity.TwoFactorLoginPayload{
+		ChallengeToken: challenge.ChallengeToken,
+		Code:           totpCode(t, secret, time.Now()),
+		RememberDevice: true,
+	}, &entity.LoginMetadata{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.DeviceToken == "" {
+		t.Fatal("no device token returned")
+	}
+	deviceToken := resp.DeviceToken
+
+	devices, err := env.uc.ListTrustedDevices(context.Background(), alice.ID)
+	if err != nil || len(devices) != 1 {
+		t.Fatalf("devices = %v (err %v), want 1", devices, err)
+	}
+
+	tests := []struct {
+		name              string
+		deviceToken       string
+		revoke            bool
+		wantTwoFactorStep bool
+	}{
+		{name: "no device token", deviceToken: "", wantTwoFactorStep: true},
+		{name: "unknown device token", deviceToken: "not-a-device", wantTwoFactorStep: true},
+		{name: "trusted device", deviceToken: deviceToken, wantTwoFactorStep: false},
+		{name: "revoked device", deviceToken: deviceToken, revoke: true, wantTwoFactorStep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.revoke {
+				if err := env.uc.RevokeTrustedDevice(context.Background(), alice.ID, devices[0].ID); err != nil {
+					t.Fatalf("error revoking device: %v", err)
+				}
+			}
+
+			resp := env.login(t, alice.Email, &entity.LoginMetadata{IP: "10.0.0.1", DeviceToken: tt.deviceToken})
+			if resp.TwoFactorRequired != tt.wantTwoFactorStep {
+				t.Errorf("two-factor required = %v, want %v", resp.TwoFactorRequired, tt.wantTwoFactorStep)
+			}
+			if got := resp.Token != ""; got == tt.wantTwoFactorStep {
+				t.Errorf("access token issued = %v, want %v", got, !tt.wantTwoFactorStep)
+			}
+		})
+	}
+
+	t.Run("device token of another user", func(t *testing.T) {
+		if trusted, err := env.uc.isTrustedDevice(context.Background(), bob.ID, deviceToken); err != nil || trusted {
+			t.Errorf("trusted = %v (err %v), want false", trusted, err)
+		}
+	})
+}
+
+func TestRevokeTrustedDevice(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.TrustedDeviceTTL = time.Hour })
+	alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+
+	if _, err := env.uc.Login(context.Background(), &entity.UserLoginPayload{
+		Email: alice.Email, Password: testPassword, RememberDevice: true,
+	}, &entity.LoginMetadata{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	devices, err := env.uc.ListTrustedDevices(context.Background(), alice.ID)
+	if err != nil || len(devices) != 1 {
+		t.Fatalf("devices = %v (err %v), want 1", devices, err)
+	}
+	deviceID := devices[0].ID
+
+	tests := []struct {
+		name     string
+		userID   int64
+		deviceID int64
+		wantErr  error
+	}{
+		{name: "another user's device", userID: bob.ID, deviceID: deviceID, wantErr: ErrTrustedDeviceNotFound},
+		{name: "unknown device", userID: alice.ID, deviceID: deviceID + 100, wantErr: ErrTrustedDeviceNotFound},
+		{name: "own device", userID: alice.ID, deviceID: deviceID},
+		{name: "already revoked", userID: alice.ID, deviceID: deviceID, wantErr: ErrTrustedDeviceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.uc.RevokeTrustedDevice(context.Background(), tt.userID, tt.deviceID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if devices, _ := env.uc.ListTrustedDevices(context.Background(), alice.ID); len(devices) != 0 {
+		t.Errorf("devices after revoking = %d, want none", len(devices))
+	}
+}
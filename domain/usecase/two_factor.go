@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+// recoveryCodeCount is how many recovery codes are issued when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+// maxChallengeFailures is how many wrong codes a login challenge accepts before it is
+// closed and the user has to enter their password again
+const maxChallengeFailures = 5
+
+// totpOpts are the TOTP parameters of authenticator apps: 6 digits every 30 seconds,
+// accepting the code of the previous and next period for clock drift
+var totpOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// SetupTwoFactor generates a new pending TOTP secret for the user
+func (u *UserUsecaseImpl) SetupTwoFactor(ctx context.Context, userID int64) (*entity.TwoFactorSetupResponse, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	tf, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking two-factor: %w", err)
+	}
+	if tf != nil && tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      u.cfg.TOTPIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error generating two-factor secret: %w", err)
+	}
+
+	encrypted, err := utils.EncryptString(u.cfg.TOTPEncryptionKey, key.Secret())
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting two-factor secret: %w", err)
+	}
+	if err := u.twoFactorRepo.SavePending(ctx, userID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &entity.TwoFactorSetupResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+	}, nil
+}
+
+// EnableTwoFactor confirms the pending TOTP secret with a code and issues recovery codes
+func (u *UserUsecaseImpl) EnableTwoFactor(ctx context.Context, userID int64, payload *entity.TwoFactorEnablePayload) (*entity.RecoveryCodesResponse, error) {
+	tf, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking two-factor: %w", err)
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	valid, err := u.useTOTP(ctx, userID, tf.SecretEncrypted, payload.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.twoFactorRepo.Enable(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &entity.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// LoginTwoFactor completes a login challenge with a TOTP code
+func (u *UserUsecaseImpl) LoginTwoFactor(ctx context.Context, payload *entity.TwoFactorLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error) {
+	user, tf, challengeID, err := u.openChallenge(ctx, payload.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := u.useTOTP(ctx, user.ID, tf.SecretEncrypted, payload.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		u.failChallenge(ctx, user.ID, challengeID, meta)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	return u.finishChallenge(ctx, user, challengeID, meta, payload.RememberDevice)
+}
+
+// LoginRecovery completes a login challenge by consuming a recovery code
+func (u *UserUsecaseImpl) LoginRecovery(ctx context.Context, payload *entity.RecoveryLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error) {
+	user, _, challengeID, err := u.openChallenge(ctx, payload.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	consumed, err := u.twoFactorRepo.ConsumeRecoveryCode(ctx, user.ID, hashRecoveryCode(payload.RecoveryCode))
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		u.failChallenge(ctx, user.ID, challengeID, meta)
+		return nil, ErrInvalidRecoveryCode
+	}
+
+	return u.finishChallenge(ctx, user, challengeID, meta, payload.RememberDevice)
+}
+
+// openChallenge returns the user and enrollment of a login challenge token, refusing
+// tokens whose challenge was completed, replaced by a newer login or closed after too
+// many wrong codes
+func (u *UserUsecaseImpl) openChallenge(ctx context.Context, token string) (*entity.User, *entity.TwoFactor, string, error) {
+	userID, challengeID, err := u.tokens.ValidateLoginChallenge(token)
+	if err != nil {
+		return nil, nil, "", ErrInvalidLoginChallenge
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error getting user: %w", err)
+	}
+	tf, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error checking two-factor: %w", err)
+	}
+	if user == nil || tf == nil || !tf.Enabled || tf.ChallengeID != challengeID {
+		return nil, nil, "", ErrInvalidLoginChallenge
+	}
+
+	return user, tf, challengeID, nil
+}
+
+// failChallenge records a wrong code against a login challenge; errors are only logged
+func (u *UserUsecaseImpl) failChallenge(ctx context.Context, userID int64, challengeID string, meta *entity.LoginMetadata) {
+	if err := u.twoFactorRepo.FailChallenge(ctx, userID, challengeID, maxChallengeFailures); err != nil {
+		log.Printf("error recording login challenge failure: %v\n", err)
+	}
+	u.recordFailedLogin(ctx, userID, meta)
+}
+
+// finishChallenge closes a login challenge and logs the user in; a challenge that was
+// completed concurrently is refused
+func (u *UserUsecaseImpl) finishChallenge(ctx context.Context, user *entity.User, challengeID string, meta *entity.LoginMetadata, rememberDevice bool) (*entity.LoginResponse, error) {
+	completed, err := u.twoFactorRepo.CompleteChallenge(ctx, user.ID, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	return u.completeLogin(ctx, user, meta, false, rememberDevice)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with a fresh set
+func (u *UserUsecaseImpl) RegenerateRecoveryCodes(ctx context.Context, userID int64) (*entity.RecoveryCodesResponse, error) {
+	tf, err := u.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking two-factor: %w", err)
+	}
+	if tf == nil || !tf.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &entity.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// useTOTP checks a code against an encrypted TOTP secret and records its time step,
+// so each code is accepted at most once per user
+func (u *UserUsecaseImpl) useTOTP(ctx context.Context, userID int64, secretEncrypted, code string) (bool, error) {
+	secret, err := utils.DecryptString(u.cfg.TOTPEncryptionKey, secretEncrypted)
+	if err != nil {
+		return false, fmt.Errorf("error decrypting two-factor secret: %w", err)
+	}
+
+	step, ok, err := totpStep(secret, code, time.Now())
+	if err != nil || !ok {
+		return false, err
+	}
+	return u.twoFactorRepo.UseStep(ctx, userID, step)
+}
+
+// totpStep returns the time step whose code matches among the steps accepted around now
+func totpStep(secret, code string, now time.Time) (int64, bool, error) {
+	period := time.Duration(totpOpts.Period) * time.Second
+	skew := int(totpOpts.Skew)
+	for offset := -skew; offset <= skew; offset++ {
+		at := now.Add(time.Duration(offset) * period)
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+		if err != nil {
+			return 0, false, fmt.Errorf("error generating two-factor code: %w", err)
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / int64(totpOpts.Period), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// generateRecoveryCodes returns plaintext recovery codes formatted as xxxxx-xxxxx and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery code: %w", err)
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(normalized)
+}
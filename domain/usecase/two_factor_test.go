@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"echo-base/domain/entity"
+)
+
+// wrongCode returns a six-digit code that matches none of the periods accepted now
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	accepted := make(map[string]bool)
+	for _, offset := range []time.Duration{-time.Minute, -30 * time.Second, 0, 30 * time.Second, time.Minute} {
+		accepted[totpCode(t, secret, time.Now().Add(offset))] = true
+	}
+	for _, code := range []string{"000000", "111111", "222222", "333333", "444444", "555555"} {
+		if !accepted[code] {
+			return code
+		}
+	}
+	t.Fatal("no wrong code found")
+	return ""
+}
+
+func TestSetupTwoFactor(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := env.createUser(t, "bob@example.com", entity.RoleIDUser)
+	env.enableTwoFactor(t, bob.ID)
+
+	tests := []struct {
+		name    string
+		userID  int64
+		wantErr error
+	}{
+		{name: "new enrollment", userID: alice.ID},
+		{name: "pending enrollment replaced", userID: alice.ID},
+		{name: "already enabled", userID: bob.ID, wantErr: ErrTwoFactorAlreadyEnabled},
+		{name: "unknown user", userID: 999, wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup, err := env.uc.SetupTwoFactor(context.Background(), tt.userID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			otpURL, err := url.Parse(setup.OTPAuthURL)
+			if err != nil {
+				t.Fatalf("error parsing otpauth URL: %v", err)
+			}
+			if otpURL.Scheme != "otpauth" || otpURL.Query().Get("secret") != setup.Secret {
+				t.Errorf("otpauth URL = %q, want an otpauth URL with the secret", setup.OTPAuthURL)
+			}
+			if !strings.Contains(otpURL.Path, "alice@example.com") || otpURL.Query().Get("issuer") != env.cfg.TOTPIssuer {
+				t.Errorf("otpauth URL = %q, want the account and issuer %q", setup.OTPAuthURL, env.cfg.TOTPIssuer)
+			}
+
+			tf, err := env.twoFactor.Get(context.Background(), tt.userID)
+			if err != nil || tf == nil {
+				t.Fatalf("enrollment = %v (err %v), want a pending enrollment", tf, err)
+			}
+			if tf.Enabled {
+				t.Error("enrollment enabled before a code was confirmed")
+			}
+			if tf.SecretEncrypted == "" || strings.Contains(tf.SecretEncrypted, setup.Secret) {
+				t.Errorf("stored secret = %q, want the secret encrypted", tf.SecretEncrypted)
+			}
+		})
+	}
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         bool
+		code          func(secret string) string
+		wantErr       error
+		wantRecovered int
+	}{
+		{name: "not set up", setup: false, code: func(string) string { return "123456" }, wantErr: ErrTwoFactorNotSetUp},
+		{name: "wrong code", setup: true, code: func(secret string) string { return wrongCode(t, secret) }, wantErr: ErrInvalidTwoFactorCode},
+		{name: "expired code", setup: true, code: func(secret string) string { return totpCode(t, secret, time.Now().Add(-5*time.Minute)) }, wantErr: ErrInvalidTwoFactorCode},
+		{name: "current code", setup: true, code: func(secret string) string { return totpCode(t, secret, time.Now()) }, wantRecovered: recoveryCodeCount},
+		{name: "code of the next period", setup: true, code: func(secret string) string { return totpCode(t, secret, time.Now().Add(30*time.Second)) }, wantRecovered: recoveryCodeCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+
+			var secret string
+			if tt.setup {
+				setup, err := env.uc.SetupTwoFactor(context.Background(), user.ID)
+				if err != nil {
+					t.Fatalf("error setting up two-factor: %v", err)
+				}
+				secret = setup.Secret
+			}
+
+			resp, err := env.uc.EnableTwoFactor(context.Background(), user.ID, &entity.TwoFactorEnablePayload{Code: tt.code(secret)})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				if tf, _ := env.twoFactor.Get(context.Background(), user.ID); tf != nil && tf.Enabled {
+					t.Error("two-factor enabled after a failed confirmation")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.RecoveryCodes) != tt.wantRecovered {
+				t.Errorf("recovery codes = %d, want %d", len(resp.RecoveryCodes), tt.wantRecovered)
+			}
+
+			tf, err := env.twoFactor.Get(context.Background(), user.ID)
+			if err != nil || tf == nil || !tf.Enabled {
+				t.Fatalf("enrollment = %+v (err %v), want it enabled", tf, err)
+			}
+			if _, err := env.uc.EnableTwoFactor(context.Background(), user.ID, &entity.TwoFactorEnablePayload{Code: tt.code(secret)}); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+				t.Errorf("second enable error = %v, want %v", err, ErrTwoFactorAlreadyEnabled)
+			}
+		})
+	}
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	meta := &entity.LoginMetadata{IP: "10.0.0.1", UserAgent: "laptop"}
+
+	tests := []struct {
+		name string
+		// attempt starts any logins it needs and returns the challenge token and code to submit
+		attempt func(t *testing.T, env *testEnv, email, secret string) (string, string)
+		wantErr error
+	}{
+		{
+			name: "current code",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				return env.login(t, email, meta).ChallengeToken, totpCode(t, secret, time.Now())
+			},
+		},
+		{
+			name: "wrong code",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				return env.login(t, email, meta).ChallengeToken, wrongCode(t, secret)
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "code reused on a new challenge",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				code := totpCode(t, secret, time.Now())
+				first := env.login(t, email, meta).ChallengeToken
+				if _, err := env.uc.LoginTwoFactor(context.Background(), &entity.TwoFactorLoginPayload{ChallengeToken: first, Code: code}, meta); err != nil {
+					t.Fatalf("error completing the first login: %v", err)
+				}
+				return env.login(t, email, meta).ChallengeToken, code
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "completed challenge",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				challenge := env.login(t, email, meta).ChallengeToken
+				if _, err := env.uc.LoginTwoFactor(context.Background(), &entity.TwoFactorLoginPayload{ChallengeToken: challenge, Code: totpCode(t, secret, time.Now())}, meta); err != nil {
+					t.Fatalf("error completing the login: %v", err)
+				}
+				return challenge, totpCode(t, secret, time.Now().Add(30*time.Second))
+			},
+			wantErr: ErrInvalidLoginChallenge,
+		},
+		{
+			name: "superseded challenge",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				first := env.login(t, email, meta).ChallengeToken
+				env.login(t, email, meta)
+				return first, totpCode(t, secret, time.Now())
+			},
+			wantErr: ErrInvalidLoginChallenge,
+		},
+		{
+			name: "challenge closed after too many wrong codes",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				challenge := env.login(t, email, meta).ChallengeToken
+				wrong := wrongCode(t, secret)
+				for range maxChallengeFailures {
+					env.uc.LoginTwoFactor(context.Background(), &entity.TwoFactorLoginPayload{ChallengeToken: challenge, Code: wrong}, meta)
+				}
+				return challenge, totpCode(t, secret, time.Now())
+			},
+			wantErr: ErrInvalidLoginChallenge,
+		},
+		{
+			name: "access token as challenge",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				token, err := env.tokens.GenerateToken(1, email, entity.RoleIDUser, "", "")
+				if err != nil {
+					t.Fatalf("error generating token: %v", err)
+				}
+				return token, totpCode(t, secret, time.Now())
+			},
+			wantErr: ErrInvalidLoginChallenge,
+		},
+		{
+			name: "forged challenge",
+			attempt: func(t *testing.T, env *testEnv, email, secret string) (string, string) {
+				return "not-a-challenge", totpCode(t, secret, time.Now())
+			},
+			wantErr: ErrInvalidLoginChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+			secret, _ := env.enableTwoFactor(t, user.ID)
+
+			challenge, code := tt.attempt(t, env, user.Email, secret)
+			resp, err := env.uc.LoginTwoFactor(context.Background(), &entity.TwoFactorLoginPayload{ChallengeToken: challenge, Code: code}, meta)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Token == "" || resp.RefreshToken == "" || resp.User == nil || resp.User.ID != user.ID {
+				t.Errorf("response = %+v, want tokens for user %d", resp, user.ID)
+			}
+			if _, err := env.tokens.ValidateToken(resp.Token); err != nil {
+				t.Errorf("issued token is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginRequiresTwoFactorStep(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice@example.com", entity.RoleIDUser)
+	env.enableTwoFactor(t, user.ID)
+
+	resp := env.login(t, user.Email, &entity.LoginMetadata{IP: "10.0.0.1"})
+	if !resp.TwoFactorRequired || resp.ChallengeToken == "" {
+		t.Fatalf("response = %+v, want a two-factor challenge", resp)
+	}
+	if resp.Token != "" || resp.RefreshToken != "" || resp.User != nil {
+		t.Errorf("response = %+v, want no tokens or user before the second step", resp)
+	}
+	if _, err := env.tokens.ValidateToken(resp.ChallengeToken); err == nil {
+		t.Error("challenge token is accepted as an access token")
+	}
+}
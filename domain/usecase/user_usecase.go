@@ -1,234 +1,1494 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
 
+	"echo-base/config"
 	"echo-base/domain/entity"
 	"echo-base/domain/repository"
+	"echo-base/events"
 	"echo-base/utils"
 )
 
+var (
+	// ErrUsernameRequired is returned when a username is required but missing
+	ErrUsernameRequired = errors.New("username is required")
+
+	// ErrUserNotFound is returned when a user does not exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrEmailTaken is returned when an email is already registered
+	ErrEmailTaken = errors.New("email is already registered")
+
+	// ErrUsernameTaken is returned when a username is already in use
+	ErrUsernameTaken = errors.New("username is already taken")
+
+	// ErrUsernameReserved is returned when a username is on the reserved list
+	ErrUsernameReserved = errors.New("username is reserved")
+
+	// ErrEmailDomainNotAllowed is returned when the email domain is blocked for registration
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+
+	// ErrEmailDomainNoMX is returned when strict MX checking finds the email domain cannot receive mail
+	ErrEmailDomainNoMX = errors.New("email domain cannot receive mail")
+
+	// ErrRoleNotFound is returned when a referenced role does not exist
+	ErrRoleNotFound = repository.ErrRoleNotFound
+
+	// ErrDryRunUnsupported is returned when a dry run is requested from a store that cannot roll back
+	ErrDryRunUnsupported = repository.ErrDryRunUnsupported
+
+	// ErrInvalidStatsWindows is returned when requested stats windows are malformed or too many
+	ErrInvalidStatsWindows = errors.New("invalid stats windows")
+
+	// ErrWeakPassword is returned when a password does not meet the configured policy
+	ErrWeakPassword = errors.New("password does not meet the password policy")
+
+	// ErrIncorrectPassword is returned when the current password given for a password change does not match
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+
+	// ErrOffsetTooLarge is returned when a requested page lies beyond the configured maximum offset
+	ErrOffsetTooLarge = errors.New("requested page exceeds the maximum pagination offset")
+
+	// ErrAPIKeyNotFound is returned when an API key does not exist for the user
+	ErrAPIKeyNotFound = repository.ErrAPIKeyNotFound
+
+	// ErrAPIKeyLimitReached is returned when creating an API key beyond the per-user limit
+	ErrAPIKeyLimitReached = errors.New("api key limit reached; revoke an existing key first")
+
+	// ErrInvalidAPIKeyScope is returned when an API key requests an unknown scope
+	ErrInvalidAPIKeyScope = errors.New("invalid api key scope")
+
+	// ErrAPIKeyExpiryInPast is returned when an API key's expiry is not in the future
+	ErrAPIKeyExpiryInPast = errors.New("api key expiry must be in the future")
+
+	// ErrInvalidAPIKey is returned for unknown, revoked or expired API keys
+	ErrInvalidAPIKey = errors.New("invalid or expired api key")
+
+	// ErrTrustedDeviceNotFound is returned when a trusted device does not exist for the user
+	ErrTrustedDeviceNotFound = repository.ErrTrustedDeviceNotFound
+
+	// ErrTwoFactorAlreadyEnabled is returned when setting up two-factor authentication that is already enabled
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrTwoFactorNotSetUp is returned when enabling two-factor authentication before setup
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+
+	// ErrTwoFactorNotEnabled is returned when an operation requires enabled two-factor authentication
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrInvalidRecoveryCode is returned when a recovery code does not match an unused code
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+
+	// ErrInvalidTwoFactorCode is returned when a TOTP code does not match
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+	// ErrInvalidLoginChallenge is returned when a login challenge token is invalid or expired
+	ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+
+	// ErrInvalidRefreshToken is returned when a refresh token is invalid, expired, or its user or session is gone
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrLastAdmin is returned when an operation would leave the system without an admin
+	ErrLastAdmin = repository.ErrLastAdmin
+
+	// ErrDeletedUserNotFound is returned when restoring a user that is not soft-deleted
+	ErrDeletedUserNotFound = repository.ErrDeletedUserNotFound
+
+	// ErrEmailNotVerified is returned when logging in before verifying the email address
+	// while RequireEmailVerification is enabled
+	ErrEmailNotVerified = errors.New("email address has not been verified; check your inbox for the verification link")
+
+	// ErrAccountSuspended is returned when a suspended user logs in or refreshes a token
+	ErrAccountSuspended = errors.New("account is suspended; contact an administrator")
+
+	// ErrEmailAlreadyVerified is returned when a verification link is opened again
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+
+	// ErrInvalidVerificationToken is returned for malformed, forged or superseded verification tokens
+	ErrInvalidVerificationToken = errors.New("invalid verification link")
+
+	// ErrVerificationTokenExpired is returned when a verification link is used after it expired
+	ErrVerificationTokenExpired = errors.New("verification link has expired; request a new one")
+
+	// ErrInvalidResetToken is returned for malformed, forged or already used password reset tokens
+	ErrInvalidResetToken = errors.New("invalid or already used password reset link")
+
+	// ErrResetTokenExpired is returned when a password reset link is used after it expired
+	ErrResetTokenExpired = errors.New("password reset link has expired; request a new one")
+)
+
 // UserUsecase defines the interface for user usecase
 type UserUsecase interface {
-	// Register registers a new user
-	Register(payload *entity.UserCreatePayload) (*entity.UserResponse, error)
+	// Register registers a new user, returning non-fatal warnings about the input
+	Register(ctx context.Context, payload *entity.UserCreatePayload) (*entity.UserResponse, []string, error)
+
+	// VerifyEmail marks the email address carried by a verification token as verified,
+	// or applies the pending email change carried by an email change token
+	VerifyEmail(ctx context.Context, token string) error
+
+	// ChangeEmail changes the user's email address, or only emails a confirmation link to
+	// the new address when EmailChangeConfirm is set
+	ChangeEmail(ctx context.Context, id int64, email string) (*entity.UserResponse, error)
+
+	// ResendVerification emails a new verification link when the email belongs to an
+	// unverified user; it succeeds silently otherwise so accounts cannot be enumerated
+	ResendVerification(ctx context.Context, email string) error
+
+	// ForgotPassword emails a password reset link when the email belongs to a user; it
+	// succeeds silently otherwise so accounts cannot be enumerated
+	ForgotPassword(ctx context.Context, email string) error
 
-	// Login logs in a user and returns a token
-	Login(payload *entity.UserLoginPayload) (*entity.LoginResponse, error)
+	// ResetPassword sets a new password using a password reset token
+	ResetPassword(ctx context.Context, payload *entity.ResetPasswordPayload) error
+
+	// Login logs in a user and returns a token, or a challenge when two-factor authentication is required
+	Login(ctx context.Context, payload *entity.UserLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error)
+
+	// LoginTwoFactor completes a login challenge with a TOTP code
+	LoginTwoFactor(ctx context.Context, payload *entity.TwoFactorLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error)
+
+	// RefreshToken exchanges a refresh token for a new access token, rotating the
+	// refresh token when configured
+	RefreshToken(ctx context.Context, payload *entity.RefreshTokenPayload) (*entity.LoginResponse, error)
+
+	// SetupTwoFactor generates a new pending TOTP secret for the user
+	SetupTwoFactor(ctx context.Context, userID int64) (*entity.TwoFactorSetupResponse, error)
+
+	// EnableTwoFactor confirms the pending TOTP secret with a code and issues recovery codes
+	EnableTwoFactor(ctx context.Context, userID int64, payload *entity.TwoFactorEnablePayload) (*entity.RecoveryCodesResponse, error)
+
+	// LoginRecovery completes a login challenge by consuming a recovery code
+	LoginRecovery(ctx context.Context, payload *entity.RecoveryLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error)
+
+	// RegenerateRecoveryCodes replaces the user's recovery codes with a fresh set
+	RegenerateRecoveryCodes(ctx context.Context, userID int64) (*entity.RecoveryCodesResponse, error)
 
 	// GetByID gets a user by ID
-	GetByID(id int64) (*entity.UserResponse, error)
+	GetByID(ctx context.Context, id int64) (*entity.UserResponse, error)
+
+	// GetByIDs gets the users with the given IDs in one lookup, in the order requested;
+	// unknown IDs are skipped
+	GetByIDs(ctx context.Context, ids []int64) ([]*entity.UserResponse, error)
+
+	// GetByUsername gets a user by username
+	GetByUsername(ctx context.Context, username string) (*entity.UserResponse, error)
 
 	// GetAll gets all users
-	GetAll() ([]*entity.UserResponse, error)
+	GetAll(ctx context.Context) ([]*entity.UserResponse, error)
 
 	// GetAllPagination gets all users with pagination and optional search
-	GetAllPagination(page int64, limit int64, search string) (*entity.PaginatedUserResponse, error)
+	GetAllPagination(ctx context.Context, params entity.PaginationParams) (*entity.PaginatedUserResponse, error)
+
+	// ExplainSearch describes how a user search would be executed without running it
+	ExplainSearch(ctx context.Context, filter entity.UserSearchFilter) (*entity.UserSearchExplain, error)
 
 	// Update updates a user
-	Update(id int64, name string) (*entity.UserResponse, error)
+	Update(ctx context.Context, id int64, name string) (*entity.UserResponse, error)
+
+	// Patch updates only the fields set in the payload, refusing to demote the last admin
+	Patch(ctx context.Context, id int64, payload *entity.UserPatchPayload) (*entity.UserResponse, error)
+
+	// ChangePassword replaces the user's password after verifying the current one
+	ChangePassword(ctx context.Context, id int64, payload *entity.ChangePasswordPayload) error
+
+	// Delete deletes a user, refusing to delete the last active admin
+	Delete(ctx context.Context, id int64) error
 
-	// Delete deletes a user
-	Delete(id int64) error
+	// UpdateRole sets any user's role; it is for admins and skips ownership checks
+	UpdateRole(ctx context.Context, id, roleID int64) (*entity.UserResponse, error)
+
+	// AdminDelete deletes any user, refusing to delete the last active admin; it is for
+	// admins and skips ownership checks
+	AdminDelete(ctx context.Context, id int64) error
+
+	// Restore undeletes a soft-deleted user
+	Restore(ctx context.Context, id int64) (*entity.UserResponse, error)
+
+	// SetStatus activates or suspends a user, refusing to suspend the last active admin;
+	// it is for admins and skips ownership checks
+	SetStatus(ctx context.Context, id int64, status string) (*entity.UserResponse, error)
+
+	// SeedAdmin creates a verified admin with the given credentials unless an admin
+	// already exists, reporting whether one was created
+	SeedAdmin(ctx context.Context, name, email, password string) (bool, error)
+
+	// GetStats gets aggregate user statistics; windows default to the configured signup windows
+	GetStats(ctx context.Context, windows []string) (*entity.UserStats, error)
+
+	// BulkAssignRole assigns a role to many users at once. With dryRun the assignment is
+	// rolled back and only its would-be result is returned.
+	BulkAssignRole(ctx context.Context, payload *entity.BulkRoleAssignPayload, dryRun bool) (*entity.BulkRoleAssignResponse, error)
+
+	// SuggestPassword generates a random password that meets the password policy
+	SuggestPassword() (string, error)
+
+	// GetLoginHistory gets a page of the user's login attempts, newest first
+	GetLoginHistory(ctx context.Context, userID int64, params entity.PaginationParams) (*entity.PaginatedLoginHistoryResponse, error)
+
+	// CreateAPIKey creates an API key for the user, returning its plaintext once
+	CreateAPIKey(ctx context.Context, userID int64, payload *entity.APIKeyCreatePayload) (*entity.APIKeyCreatedResponse, error)
+
+	// ListAPIKeys lists the user's active API keys without their secrets
+	ListAPIKeys(ctx context.Context, userID int64) ([]*entity.APIKey, error)
+
+	// RevokeAPIKey revokes one of the user's API keys
+	RevokeAPIKey(ctx context.Context, userID, keyID int64) error
+
+	// AuthenticateAPIKey gets an active API key and the user it belongs to
+	AuthenticateAPIKey(ctx context.Context, key string) (*entity.APIKey, *entity.User, error)
+
+	// ListTrustedDevices lists the user's trusted devices
+	ListTrustedDevices(ctx context.Context, userID int64) ([]*entity.TrustedDevice, error)
+
+	// RevokeTrustedDevice revokes one of the user's trusted devices
+	RevokeTrustedDevice(ctx context.Context, userID, deviceID int64) error
 }
 
 // UserUsecaseImpl implements UserUsecase
 type UserUsecaseImpl struct {
-	userRepo repository.UserRepository
+	userRepo         repository.UserRepository
+	outboxRepo       repository.OutboxRepository
+	sessionRepo      repository.SessionRepository
+	loginHistoryRepo repository.LoginHistoryRepository
+	deviceRepo       repository.TrustedDeviceRepository
+	twoFactorRepo    repository.TwoFactorRepository
+	apiKeyRepo       repository.APIKeyRepository
+	store            repository.Store
+	tokens           *utils.TokenSigner
+	cfg              *config.Config
+
+	// mxResolver looks up MX records when StrictEmailMX is enabled
+	mxResolver utils.MXResolver
 }
 
 // NewUserUsecase creates a new user usecase
-func NewUserUsecase(userRepo repository.UserRepository) UserUsecase {
+func NewUserUsecase(
+	userRepo repository.UserRepository,
+	outboxRepo repository.OutboxRepository,
+	sessionRepo repository.SessionRepository,
+	loginHistoryRepo repository.LoginHistoryRepository,
+	deviceRepo repository.TrustedDeviceRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	store repository.Store,
+	tokens *utils.TokenSigner,
+	cfg *config.Config,
+) UserUsecase {
 	return &UserUsecaseImpl{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		outboxRepo:       outboxRepo,
+		sessionRepo:      sessionRepo,
+		loginHistoryRepo: loginHistoryRepo,
+		deviceRepo:       deviceRepo,
+		twoFactorRepo:    twoFactorRepo,
+		apiKeyRepo:       apiKeyRepo,
+		store:            store,
+		tokens:           tokens,
+		cfg:              cfg,
+		mxResolver:       net.DefaultResolver,
 	}
 }
 
-// Register registers a new user
-func (u *UserUsecaseImpl) Register(payload *entity.UserCreatePayload) (*entity.UserResponse, error) {
-	// Check if email is already registered
-	existingUser, err := u.userRepo.GetByEmail(payload.Email)
+// toUserResponse maps a user entity to its response DTO
+func toUserResponse(user *entity.User) *entity.UserResponse {
+	return &entity.UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Username:      user.Username,
+		RoleID:        user.RoleID,
+		RoleName:      user.RoleName,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		DeletedAt:     user.DeletedAt,
+		Status:        user.Status,
+	}
+}
+
+// checkEmailDomain rejects emails whose domain is deny-listed or, in allow-list mode, not allow-listed
+func (u *UserUsecaseImpl) checkEmailDomain(email string) error {
+	domain := utils.EmailDomain(email)
+
+	if len(u.cfg.EmailDomainAllowList) > 0 {
+		if !utils.DomainMatches(domain, u.cfg.EmailDomainAllowList) {
+			return ErrEmailDomainNotAllowed
+		}
+		return nil
+	}
+
+	if utils.DomainMatches(domain, u.cfg.EmailDomainDenyList) {
+		return ErrEmailDomainNotAllowed
+	}
+	return nil
+}
+
+// checkEmailMX rejects emails whose domain has no MX record when StrictEmailMX is enabled.
+// Lookup errors (timeouts, resolver failures) only reject when EmailMXFailClosed is set.
+func (u *UserUsecaseImpl) checkEmailMX(ctx context.Context, email string) error {
+	if !u.cfg.StrictEmailMX {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.cfg.EmailMXTimeout)
+	defer cancel()
+
+	domain := utils.EmailDomain(email)
+	ok, err := utils.HasMX(ctx, u.mxResolver, domain)
 	if err != nil {
-		return nil, fmt.Errorf("error checking existing user: %w", err)
+		if u.cfg.EmailMXFailClosed {
+			return fmt.Errorf("%w: %v", ErrEmailDomainNoMX, err)
+		}
+		log.Printf("MX lookup for %s failed, allowing registration: %v", domain, err)
+		return nil
+	}
+	if !ok {
+		return ErrEmailDomainNoMX
+	}
+	return nil
+}
+
+// isReservedUsername reports whether username matches a reserved name, ignoring case
+func (u *UserUsecaseImpl) isReservedUsername(username string) bool {
+	for _, reserved := range u.cfg.ReservedUsernames {
+		if strings.EqualFold(username, strings.TrimSpace(reserved)) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordPolicy builds the password policy from config
+func (u *UserUsecaseImpl) passwordPolicy() utils.PasswordPolicy {
+	return utils.PasswordPolicy{
+		MinLength:     u.cfg.PasswordMinLength,
+		RequireUpper:  u.cfg.PasswordRequireUpper,
+		RequireLower:  u.cfg.PasswordRequireLower,
+		RequireDigit:  u.cfg.PasswordRequireDigit,
+		RequireSymbol: u.cfg.PasswordRequireSymbol,
+	}
+}
+
+// SuggestPassword generates a random password that meets the password policy
+func (u *UserUsecaseImpl) SuggestPassword() (string, error) {
+	return utils.GeneratePassword(u.passwordPolicy())
+}
+
+// Register registers a new user. Warnings flag accepted but weak input, such as a weak password.
+func (u *UserUsecaseImpl) Register(ctx context.Context, payload *entity.UserCreatePayload) (*entity.UserResponse, []string, error) {
+	payload.Username = strings.TrimSpace(payload.Username)
+	if u.cfg.UsernameLowercase {
+		payload.Username = strings.ToLower(payload.Username)
+	}
+	if u.cfg.UsernameRequired && payload.Username == "" {
+		return nil, nil, ErrUsernameRequired
 	}
-	if existingUser != nil {
-		return nil, errors.New("email is already registered")
+	if u.isReservedUsername(payload.Username) {
+		return nil, nil, ErrUsernameReserved
+	}
+
+	if err := u.checkEmailDomain(payload.Email); err != nil {
+		return nil, nil, err
 	}
 
-	// Hash password
+	if err := u.checkEmailMX(ctx, payload.Email); err != nil {
+		return nil, nil, err
+	}
+
+	if err := utils.ValidatePassword(payload.Password, u.passwordPolicy()); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+
+	// Hash password before the transaction so it is not held open during hashing
 	hashedPassword, err := utils.HashPassword(payload.Password)
 	if err != nil {
-		return nil, fmt.Errorf("error hashing password: %w", err)
+		return nil, nil, fmt.Errorf("error hashing password: %w", err)
 	}
 
 	// Create user
 	user := &entity.User{
-		Name:     payload.Name,
-		Email:    payload.Email,
-		Password: hashedPassword,
+		Name:           payload.Name,
+		Email:          payload.Email,
+		Username:       payload.Username,
+		Password:       hashedPassword,
+		ReferralSource: payload.ReferralSource,
 	}
 
-	createdUser, err := u.userRepo.Create(user)
+	// Check availability, create the user and enqueue its event atomically. A concurrent
+	// registration can still pass the checks; the unique constraints then reject the insert.
+	var createdUser *entity.User
+	err = u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		existingUser, err := repos.Users.GetByEmail(ctx, payload.Email)
+		if err != nil {
+			return fmt.Errorf("error checking existing user: %w", err)
+		}
+		if existingUser != nil {
+			return ErrEmailTaken
+		}
+
+		if payload.Username != "" {
+			existingUser, err = repos.Users.GetByUsername(ctx, payload.Username)
+			if err != nil {
+				return fmt.Errorf("error checking existing user: %w", err)
+			}
+			if existingUser != nil {
+				return ErrUsernameTaken
+			}
+		}
+
+		createdUser, err = repos.Users.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		event, err := events.New(events.UserRegistered, events.UserRegisteredPayload{
+			UserID: createdUser.ID,
+			Name:   createdUser.Name,
+			Email:  createdUser.Email,
+		})
+		if err != nil {
+			return err
+		}
+		return repos.Outbox.Enqueue(ctx, event)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating user: %w", err)
+		switch {
+		case errors.Is(err, repository.ErrDuplicateEmail):
+			return nil, nil, ErrEmailTaken
+		case errors.Is(err, repository.ErrDuplicateUsername):
+			return nil, nil, ErrUsernameTaken
+		case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrRoleNotFound):
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("error creating user: %w", err)
 	}
 
-	return &entity.UserResponse{
-		ID:        createdUser.ID,
-		Name:      createdUser.Name,
-		Email:     createdUser.Email,
-		CreatedAt: createdUser.CreatedAt,
-		UpdatedAt: createdUser.UpdatedAt,
-	}, nil
+	return toUserResponse(createdUser), utils.PasswordWarnings(payload.Password), nil
+}
+
+// VerifyEmail validates a verification token and marks its user's email as verified.
+// Tokens carry the email they were issued for, so a link stops working once the
+// address changes, and verifying makes every later use report ErrEmailAlreadyVerified.
+func (u *UserUsecaseImpl) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := u.tokens.ValidateToken(token)
+	if err != nil {
+		if utils.IsTokenExpired(err) {
+			return ErrVerificationTokenExpired
+		}
+		return ErrInvalidVerificationToken
+	}
+	if claims.TokenScope() == utils.ScopeEmailChange {
+		return u.confirmEmailChange(ctx, claims)
+	}
+	if claims.TokenScope() != utils.ScopeVerify {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := u.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil || user.Email != claims.Email {
+		return ErrInvalidVerificationToken
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	return u.userRepo.MarkEmailVerified(ctx, user.ID)
+}
+
+// confirmEmailChange applies a pending email change once its link is opened. Opening
+// the link proves the user owns the new address, so it is stored as verified. The link
+// is bound to the address the change was requested from, so an older link cannot revert
+// a change applied since.
+func (u *UserUsecaseImpl) confirmEmailChange(ctx context.Context, claims *utils.JWTClaims) error {
+	user, err := u.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return ErrInvalidVerificationToken
+	}
+	if user.Email == claims.Email {
+		return ErrEmailAlreadyVerified
+	}
+	if claims.PreviousEmail == "" || user.Email != claims.PreviousEmail {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := u.userRepo.UpdateEmail(ctx, user.ID, claims.Email, true); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("error updating email: %w", err)
+	}
+	return nil
+}
+
+// ChangeEmail changes the user's email address after the same domain checks as
+// registration. With EmailChangeConfirm the change waits for the link sent to the new
+// address; otherwise it is applied now, and when verification is required the account
+// is unverified until the new address is confirmed. The verification email is enqueued
+// in the same transaction as the change.
+func (u *UserUsecaseImpl) ChangeEmail(ctx context.Context, id int64, email string) (*entity.UserResponse, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Email == email {
+		return toUserResponse(user), nil
+	}
+
+	if err := u.checkEmailDomain(email); err != nil {
+		return nil, err
+	}
+	if err := u.checkEmailMX(ctx, email); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing user: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrEmailTaken
+	}
+
+	if u.cfg.EmailChangeConfirm {
+		u.enqueueEvent(ctx, events.EmailChangeRequested, events.EmailChangeRequestedPayload{
+			UserID:       user.ID,
+			Name:         user.Name,
+			Email:        email,
+			CurrentEmail: user.Email,
+		})
+		return toUserResponse(user), nil
+	}
+
+	verified := !u.cfg.RequireEmailVerification
+	err = u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.UpdateEmail(ctx, user.ID, email, verified); err != nil {
+			return err
+		}
+		if verified {
+			return nil
+		}
+
+		event, err := events.New(events.EmailVerificationRequested, events.EmailVerificationRequestedPayload{
+			UserID: user.ID,
+			Name:   user.Name,
+			Email:  email,
+		})
+		if err != nil {
+			return err
+		}
+		return repos.Outbox.Enqueue(ctx, event)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("error updating email: %w", err)
+	}
+
+	return u.GetByID(ctx, id)
+}
+
+// ResendVerification enqueues a new verification email for an unverified user
+func (u *UserUsecaseImpl) ResendVerification(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil || user.EmailVerified {
+		return nil
+	}
+
+	u.enqueueEvent(ctx, events.EmailVerificationRequested, events.EmailVerificationRequestedPayload{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+	})
+	return nil
+}
+
+// ForgotPassword enqueues a password reset email for the user with the email.
+//
+// Reset tokens are not stored, so there is no per-user cap on outstanding ones: each
+// token is signed with a key derived from the user's current password hash, so all of
+// a user's outstanding tokens are invalidated together by the first successful reset,
+// and each one expires after PasswordResetExpiration anyway. Repeated requests only
+// cost emails, which the auth rate limiter on this route bounds.
+func (u *UserUsecaseImpl) ForgotPassword(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	u.enqueueEvent(ctx, events.PasswordResetRequested, events.PasswordResetRequestedPayload{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+	})
+	return nil
+}
+
+// ResetPassword validates a reset token against the user's current password hash and
+// replaces the password. The new hash invalidates the token, so it cannot be reused.
+func (u *UserUsecaseImpl) ResetPassword(ctx context.Context, payload *entity.ResetPasswordPayload) error {
+	var lookupErr error
+	userID, err := u.tokens.ValidatePasswordReset(payload.Token, func(userID int64) (string, error) {
+		user, err := u.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			lookupErr = err
+			return "", err
+		}
+		if user == nil {
+			return "", ErrInvalidResetToken
+		}
+		return user.Password, nil
+	})
+	if lookupErr != nil {
+		return fmt.Errorf("error getting user: %w", lookupErr)
+	}
+	if err != nil {
+		if utils.IsTokenExpired(err) {
+			return ErrResetTokenExpired
+		}
+		return ErrInvalidResetToken
+	}
+
+	if err := utils.ValidatePassword(payload.NewPassword, u.passwordPolicy()); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.NewPassword)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	return u.userRepo.UpdatePassword(ctx, userID, hashedPassword)
 }
 
 // Login logs in a user and returns a token
-func (u *UserUsecaseImpl) Login(payload *entity.UserLoginPayload) (*entity.LoginResponse, error) {
+func (u *UserUsecaseImpl) Login(ctx context.Context, payload *entity.UserLoginPayload, meta *entity.LoginMetadata) (*entity.LoginResponse, error) {
 	// Get user by email
-	user, err := u.userRepo.GetByEmail(payload.Email)
+	user, err := u.userRepo.GetByEmail(ctx, payload.Email)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 	if user == nil {
+		u.enqueueEvent(ctx, events.LoginFailed, events.LoginFailedPayload{IP: meta.IP})
 		return nil, errors.New("invalid email or password")
 	}
 
 	// Check password
 	if !utils.CheckPassword(user.Password, payload.Password) {
+		u.recordFailedLogin(ctx, user.ID, meta)
 		return nil, errors.New("invalid email or password")
 	}
 
+	if u.cfg.RequireEmailVerification && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	if user.Status == entity.UserStatusSuspended {
+		return nil, ErrAccountSuspended
+	}
+
+	trusted, err := u.isTrustedDevice(ctx, user.ID, meta.DeviceToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users with two-factor authentication confirm a code first, unless the device is trusted
+	if !trusted {
+		tf, err := u.twoFactorRepo.Get(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking two-factor: %w", err)
+		}
+		if tf != nil && tf.Enabled {
+			// Only the latest challenge can be completed, and only once
+			challengeID, err := utils.GenerateRandomToken(16)
+			if err != nil {
+				return nil, fmt.Errorf("error creating login challenge: %w", err)
+			}
+			if err := u.twoFactorRepo.StartChallenge(ctx, user.ID, challengeID); err != nil {
+				return nil, err
+			}
+			challenge, err := u.tokens.GenerateLoginChallenge(user.ID, challengeID)
+			if err != nil {
+				return nil, err
+			}
+			return &entity.LoginResponse{
+				TwoFactorRequired: true,
+				ChallengeToken:    challenge,
+			}, nil
+		}
+	}
+
+	return u.completeLogin(ctx, user, meta, trusted, payload.RememberDevice)
+}
+
+// recordFailedLogin records a failed login attempt; errors are only logged
+func (u *UserUsecaseImpl) recordFailedLogin(ctx context.Context, userID int64, meta *entity.LoginMetadata) {
+	if err := u.loginHistoryRepo.Create(ctx, &entity.LoginHistory{
+		UserID:    userID,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Success:   false,
+	}); err != nil {
+		log.Printf("error recording failed login: %v\n", err)
+	}
+
+	u.enqueueEvent(ctx, events.LoginFailed, events.LoginFailedPayload{UserID: userID, IP: meta.IP})
+}
+
+// enqueueEvent stores an event in the outbox outside the usecase's own transactions,
+// joining the request-scoped one in ctx if any; errors are only logged
+func (u *UserUsecaseImpl) enqueueEvent(ctx context.Context, name string, payload interface{}) {
+	event, err := events.New(name, payload)
+	if err == nil {
+		err = u.outboxRepo.Enqueue(ctx, event)
+	}
+	if err != nil {
+		log.Printf("error publishing %s event: %v\n", name, err)
+	}
+}
+
+// completeLogin records a successful login, starts a session and issues the access token
+func (u *UserUsecaseImpl) completeLogin(ctx context.Context, user *entity.User, meta *entity.LoginMetadata, trusted, rememberDevice bool) (*entity.LoginResponse, error) {
+	// The user may have been suspended between the password and the two-factor step
+	if user.Status == entity.UserStatusSuspended {
+		return nil, ErrAccountSuspended
+	}
+
+	// Compare against recent history before recording this login
+	suspicious, err := u.detectSuspiciousLogin(ctx, user, meta, trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.loginHistoryRepo.Create(ctx, &entity.LoginHistory{
+		UserID:    user.ID,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Success:   true,
+	}); err != nil {
+		return nil, fmt.Errorf("error recording login: %w", err)
+	}
+
+	// Start a new session
+	sessionID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+	if err := u.sessionRepo.Create(ctx, &entity.Session{ID: sessionID, UserID: user.ID}); err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	u.enqueueEvent(ctx, events.UserLoggedIn, events.UserLoggedInPayload{UserID: user.ID})
+	if suspicious != nil {
+		u.enqueueEvent(ctx, events.SuspiciousLogin, suspicious)
+	}
+
 	// Generate JWT token with role
-	token, err := utils.GenerateToken(user.ID, user.Email, user.RoleID)
+	token, err := u.tokens.GenerateToken(user.ID, user.Email, user.RoleID, sessionID, utils.ScopeAccess)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	refreshToken, err := u.tokens.GenerateRefreshToken(user.ID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	response := &entity.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         toUserResponse(user),
+	}
+
+	if rememberDevice && !trusted && u.cfg.TrustedDeviceTTL > 0 {
+		response.DeviceToken, err = u.rememberDevice(ctx, user.ID, meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// RefreshToken issues a new access token for a valid refresh token whose user and
+// session still exist. Without rotation the presented refresh token is returned as is.
+func (u *UserUsecaseImpl) RefreshToken(ctx context.Context, payload *entity.RefreshTokenPayload) (*entity.LoginResponse, error) {
+	claims, err := u.tokens.ValidateRefreshToken(payload.RefreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := u.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if user.Status == entity.UserStatusSuspended {
+		return nil, ErrAccountSuspended
+	}
+
+	// Sessions removed for inactivity can no longer be refreshed
+	if claims.SessionID != "" {
+		session, err := u.sessionRepo.GetByID(ctx, claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting session: %w", err)
+		}
+		if session == nil || session.UserID != user.ID {
+			return nil, ErrInvalidRefreshToken
+		}
+		if err := u.sessionRepo.Touch(ctx, session.ID, time.Now()); err != nil {
+			return nil, fmt.Errorf("error updating session: %w", err)
+		}
+	}
+
+	token, err := u.tokens.GenerateToken(user.ID, user.Email, user.RoleID, claims.SessionID, utils.ScopeAccess)
 	if err != nil {
 		return nil, fmt.Errorf("error generating token: %w", err)
 	}
 
+	refreshToken := payload.RefreshToken
+	if u.cfg.RefreshTokenRotate {
+		refreshToken, err = u.tokens.GenerateRefreshToken(user.ID, claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("error generating refresh token: %w", err)
+		}
+	}
+
 	return &entity.LoginResponse{
-		Token: token,
-		User: entity.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			RoleID:    user.RoleID,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         toUserResponse(user),
+	}, nil
+}
+
+// isTrustedDevice reports whether token is an unexpired trusted device token of the user,
+// recording its use
+func (u *UserUsecaseImpl) isTrustedDevice(ctx context.Context, userID int64, token string) (bool, error) {
+	if token == "" || u.cfg.TrustedDeviceTTL <= 0 {
+		return false, nil
+	}
+
+	device, err := u.deviceRepo.GetByTokenHash(ctx, userID, utils.HashToken(token))
+	if err != nil {
+		return false, fmt.Errorf("error checking trusted device: %w", err)
+	}
+	if device == nil {
+		return false, nil
+	}
+
+	if err := u.deviceRepo.Touch(ctx, device.ID, time.Now()); err != nil {
+		log.Printf("error updating trusted device: %v\n", err)
+	}
+	return true, nil
+}
+
+// rememberDevice stores a new trusted device for the user and returns its plaintext token
+func (u *UserUsecaseImpl) rememberDevice(ctx context.Context, userID int64, meta *entity.LoginMetadata) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("error creating device token: %w", err)
+	}
+
+	err = u.deviceRepo.Create(ctx, &entity.TrustedDevice{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		ExpiresAt: time.Now().Add(u.cfg.TrustedDeviceTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error remembering device: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetLoginHistory gets a page of the user's login attempts, newest first, masking IPs when configured
+func (u *UserUsecaseImpl) GetLoginHistory(ctx context.Context, userID int64, params entity.PaginationParams) (*entity.PaginatedLoginHistoryResponse, error) {
+	params = clampPagination(params)
+
+	entries, total, err := u.loginHistoryRepo.ListByUser(ctx, userID, params.Page, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting login history: %w", err)
+	}
+
+	if u.cfg.LoginHistoryMaskIP {
+		for _, entry := range entries {
+			entry.IP = utils.MaskIP(entry.IP)
+		}
+	}
+
+	return &entity.PaginatedLoginHistoryResponse{
+		Data: entries,
+		Pagination: entity.PaginationMeta{
+			Page:       params.Page,
+			Limit:      params.Limit,
+			Total:      total,
+			TotalPages: totalPages(total, params.Limit),
 		},
 	}, nil
 }
 
+// ListTrustedDevices lists the user's trusted devices
+func (u *UserUsecaseImpl) ListTrustedDevices(ctx context.Context, userID int64) ([]*entity.TrustedDevice, error) {
+	devices, err := u.deviceRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting trusted devices: %w", err)
+	}
+	return devices, nil
+}
+
+// RevokeTrustedDevice revokes one of the user's trusted devices
+func (u *UserUsecaseImpl) RevokeTrustedDevice(ctx context.Context, userID, deviceID int64) error {
+	return u.deviceRepo.Delete(ctx, userID, deviceID)
+}
+
+// detectSuspiciousLogin checks a login against the user's recent history, returning
+// the event payload when it comes from a new device or exceeds the concurrent session limit.
+// Trusted devices are never flagged as new.
+func (u *UserUsecaseImpl) detectSuspiciousLogin(ctx context.Context, user *entity.User, meta *entity.LoginMetadata, trusted bool) (*events.SuspiciousLoginPayload, error) {
+	now := time.Now()
+	reasons := make([]string, 0)
+
+	if u.cfg.SuspiciousLoginNewDevice && !trusted {
+		since := now.Add(-u.cfg.SuspiciousLoginLookback)
+
+		// A user's first login is never flagged as a new device
+		previous, err := u.loginHistoryRepo.CountSuccessful(ctx, user.ID, since)
+		if err != nil {
+			return nil, fmt.Errorf("error checking login history: %w", err)
+		}
+		if previous > 0 {
+			known, err := u.loginHistoryRepo.ExistsForDevice(ctx, user.ID, meta.IP, meta.UserAgent, since)
+			if err != nil {
+				return nil, fmt.Errorf("error checking login history: %w", err)
+			}
+			if !known {
+				reasons = append(reasons, "new_device")
+			}
+		}
+	}
+
+	// Count sessions including the one about to be created
+	activeSessions, err := u.sessionRepo.CountActive(ctx, user.ID, now.Add(-u.cfg.SuspiciousLoginSessionWindow))
+	if err != nil {
+		return nil, fmt.Errorf("error counting active sessions: %w", err)
+	}
+	activeSessions++
+	if u.cfg.SuspiciousLoginMaxSessions > 0 && activeSessions > int64(u.cfg.SuspiciousLoginMaxSessions) {
+		reasons = append(reasons, "too_many_sessions")
+	}
+
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+
+	return &events.SuspiciousLoginPayload{
+		UserID:         user.ID,
+		Email:          user.Email,
+		IP:             meta.IP,
+		UserAgent:      meta.UserAgent,
+		Reasons:        reasons,
+		ActiveSessions: activeSessions,
+	}, nil
+}
+
 // GetByID gets a user by ID
-func (u *UserUsecaseImpl) GetByID(id int64) (*entity.UserResponse, error) {
-	user, err := u.userRepo.GetByID(id)
+func (u *UserUsecaseImpl) GetByID(ctx context.Context, id int64) (*entity.UserResponse, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
-	return &entity.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		RoleID:    user.RoleID,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	return toUserResponse(user), nil
+}
+
+// GetByIDs gets the users with the given IDs
+func (u *UserUsecaseImpl) GetByIDs(ctx context.Context, ids []int64) ([]*entity.UserResponse, error) {
+	users, err := u.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting users: %w", err)
+	}
+
+	responses := make([]*entity.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, toUserResponse(user))
+	}
+
+	return responses, nil
+}
+
+// GetByUsername gets a user by username
+func (u *UserUsecaseImpl) GetByUsername(ctx context.Context, username string) (*entity.UserResponse, error) {
+	user, err := u.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return toUserResponse(user), nil
 }
 
 // GetAll gets all users
-func (u *UserUsecaseImpl) GetAll() ([]*entity.UserResponse, error) {
-	users, err := u.userRepo.GetAll()
+func (u *UserUsecaseImpl) GetAll(ctx context.Context) ([]*entity.UserResponse, error) {
+	users, err := u.userRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting users: %w", err)
 	}
 
 	responses := make([]*entity.UserResponse, 0, len(users))
 	for _, user := range users {
-		responses = append(responses, &entity.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			RoleID:    user.RoleID,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		})
+		responses = append(responses, toUserResponse(user))
 	}
 
 	return responses, nil
 }
 
 // Update updates a user
-func (u *UserUsecaseImpl) Update(id int64, name string) (*entity.UserResponse, error) {
-	user, err := u.userRepo.GetByID(id)
+func (u *UserUsecaseImpl) Update(ctx context.Context, id int64, name string) (*entity.UserResponse, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	user.Name = name
 
-	updatedUser, err := u.userRepo.Update(user)
+	updatedUser, err := u.userRepo.Update(ctx, user)
 	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
 
-	return &entity.UserResponse{
-		ID:        updatedUser.ID,
-		Name:      updatedUser.Name,
-		Email:     updatedUser.Email,
-		RoleID:    updatedUser.RoleID,
-		CreatedAt: updatedUser.CreatedAt,
-		UpdatedAt: updatedUser.UpdatedAt,
-	}, nil
+	return toUserResponse(updatedUser), nil
+}
+
+// Patch updates the fields set in the payload. The last-admin check locks the admin rows
+// in the transaction that writes, so two concurrent demotions cannot both pass it.
+func (u *UserUsecaseImpl) Patch(ctx context.Context, id int64, payload *entity.UserPatchPayload) (*entity.UserResponse, error) {
+	if payload.IsEmpty() {
+		return u.GetByID(ctx, id)
+	}
+
+	var updated *entity.User
+	err := u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		user, err := repos.Users.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error getting user: %w", err)
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+
+		if payload.RoleID != nil {
+			role, err := repos.Roles.GetByID(ctx, *payload.RoleID)
+			if err != nil {
+				return fmt.Errorf("error getting role: %w", err)
+			}
+			if role == nil {
+				return ErrRoleNotFound
+			}
+		}
+
+		if payload.RoleID != nil && *payload.RoleID != entity.RoleIDAdmin {
+			if err := ensureOtherActiveAdmin(ctx, repos, id); err != nil {
+				return err
+			}
+		}
+
+		updated, err = repos.Users.Patch(ctx, id, payload)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrRoleNotFound), errors.Is(err, ErrLastAdmin):
+			return nil, err
+		}
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return toUserResponse(updated), nil
+}
+
+// ensureOtherActiveAdmin returns ErrLastAdmin when id is the only active admin, so
+// demoting, suspending or deleting it would leave nobody able to administer the system.
+// It locks the admin rows and must run in the transaction that makes the change.
+func ensureOtherActiveAdmin(ctx context.Context, repos repository.Repositories, id int64) error {
+	admins, err := repos.Users.LockActiveAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if len(admins) == 1 && admins[0] == id {
+		return ErrLastAdmin
+	}
+	return nil
 }
 
-// Delete deletes a user
-func (u *UserUsecaseImpl) Delete(id int64) error {
-	user, err := u.userRepo.GetByID(id)
+// ChangePassword replaces the user's password after verifying the current one.
+// The new password must meet the same policy as at registration.
+func (u *UserUsecaseImpl) ChangePassword(ctx context.Context, id int64, payload *entity.ChangePasswordPayload) error {
+	user, err := u.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("error getting user: %w", err)
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
+	}
+
+	if !utils.CheckPassword(user.Password, payload.OldPassword) {
+		return ErrIncorrectPassword
+	}
+
+	if err := utils.ValidatePassword(payload.NewPassword, u.passwordPolicy()); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+
+	hashedPassword, err := utils.HashPassword(payload.NewPassword)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	return u.userRepo.UpdatePassword(ctx, id, hashedPassword)
+}
+
+// Delete deletes a user, refusing to delete the last active admin
+func (u *UserUsecaseImpl) Delete(ctx context.Context, id int64) error {
+	return u.deleteUser(ctx, id)
+}
+
+// UpdateRole sets a user's role, refusing unknown roles and demoting the last admin
+func (u *UserUsecaseImpl) UpdateRole(ctx context.Context, id, roleID int64) (*entity.UserResponse, error) {
+	_, invalidIDs, err := u.userRepo.BulkUpdateRole(ctx, []int64{id}, roleID)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) || errors.Is(err, ErrLastAdmin) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+	if len(invalidIDs) > 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return u.GetByID(ctx, id)
+}
+
+// AdminDelete deletes a user, refusing to delete the last active admin
+func (u *UserUsecaseImpl) AdminDelete(ctx context.Context, id int64) error {
+	return u.deleteUser(ctx, id)
+}
+
+// deleteUser deletes a user unless it is the last active admin, checking and deleting
+// in one transaction
+func (u *UserUsecaseImpl) deleteUser(ctx context.Context, id int64) error {
+	err := u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		user, err := repos.Users.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error getting user: %w", err)
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+
+		if err := ensureOtherActiveAdmin(ctx, repos, id); err != nil {
+			return err
+		}
+
+		return repos.Users.Delete(ctx, id)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrLastAdmin):
+			return err
+		}
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	return nil
+}
+
+// Restore undeletes a soft-deleted user, refusing when its email was registered again since
+func (u *UserUsecaseImpl) Restore(ctx context.Context, id int64) (*entity.UserResponse, error) {
+	if err := u.userRepo.Restore(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, ErrDeletedUserNotFound):
+			return nil, err
+		case errors.Is(err, repository.ErrDuplicateEmail):
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("error restoring user: %w", err)
+	}
+
+	return u.GetByID(ctx, id)
+}
+
+// SetStatus sets a user's account status, refusing to suspend the last active admin.
+// Suspending takes effect at the next login or token refresh, or on the next request
+// when AuthCheckUserStatus is enabled.
+func (u *UserUsecaseImpl) SetStatus(ctx context.Context, id int64, status string) (*entity.UserResponse, error) {
+	err := u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		user, err := repos.Users.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error getting user: %w", err)
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+
+		if status == entity.UserStatusSuspended {
+			if err := ensureOtherActiveAdmin(ctx, repos, id); err != nil {
+				return err
+			}
+		}
+
+		return repos.Users.UpdateStatus(ctx, id, status)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrLastAdmin):
+			return nil, err
+		}
+		return nil, fmt.Errorf("error updating status: %w", err)
+	}
+
+	return u.GetByID(ctx, id)
+}
+
+// SeedAdmin creates the first admin so a fresh deployment can be managed. It does nothing
+// once any admin exists, so it is safe to run on every start.
+func (u *UserUsecaseImpl) SeedAdmin(ctx context.Context, name, email, password string) (bool, error) {
+	_, admins, err := u.userRepo.GetAllPagination(ctx, entity.PaginationParams{Page: 1, Limit: 1, RoleID: entity.RoleIDAdmin})
+	if err != nil {
+		return false, fmt.Errorf("error checking existing admins: %w", err)
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	if err := utils.ValidatePassword(password, u.passwordPolicy()); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return false, fmt.Errorf("error hashing password: %w", err)
 	}
 
-	return u.userRepo.Delete(id)
+	// Both writes share a transaction so a failed start never leaves an unverified admin
+	// behind, which would stop every later start from seeding a usable one
+	err = u.store.WithTx(ctx, func(repos repository.Repositories) error {
+		admin, err := repos.Users.Create(ctx, &entity.User{
+			Name:     name,
+			Email:    email,
+			Password: hashedPassword,
+			RoleID:   entity.RoleIDAdmin,
+		})
+		if err != nil {
+			if errors.Is(err, repository.ErrDuplicateEmail) {
+				return fmt.Errorf("%w: %s belongs to a user who is not an admin", ErrEmailTaken, email)
+			}
+			return fmt.Errorf("error creating admin: %w", err)
+		}
+
+		// The operator chose the address, so there is nothing to verify
+		if err := repos.Users.MarkEmailVerified(ctx, admin.ID); err != nil {
+			return fmt.Errorf("error verifying admin email: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExplainSearch describes how a user search would be executed without running it
+func (u *UserUsecaseImpl) ExplainSearch(ctx context.Context, filter entity.UserSearchFilter) (*entity.UserSearchExplain, error) {
+	explain, err := u.userRepo.ExplainSearch(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error explaining search: %w", err)
+	}
+	return explain, nil
 }
 
 // GetAllPagination gets all users with pagination and optional search
-func (u *UserUsecaseImpl) GetAllPagination(page int64, limit int64, search string) (*entity.PaginatedUserResponse, error) {
-	users, total, err := u.userRepo.GetAllPagination(page, limit, search)
+func (u *UserUsecaseImpl) GetAllPagination(ctx context.Context, params entity.PaginationParams) (*entity.PaginatedUserResponse, error) {
+	params = clampPagination(params)
+
+	// Deep offsets scan and discard every preceding row
+	if u.cfg.PaginationMaxOffset > 0 && (params.Page-1)*params.Limit > u.cfg.PaginationMaxOffset {
+		return nil, ErrOffsetTooLarge
+	}
+
+	users, total, err := u.userRepo.GetAllPagination(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("error getting users: %w", err)
 	}
 
 	responses := make([]*entity.UserResponse, 0, len(users))
 	for _, user := range users {
-		responses = append(responses, &entity.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			RoleID:    user.RoleID,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		})
+		responses = append(responses, toUserResponse(user))
 	}
 
-	// Calculate total pages
-	totalPages := (total + limit - 1) / limit
-
 	return &entity.PaginatedUserResponse{
 		Data: responses,
 		Pagination: entity.PaginationMeta{
-			Page:       page,
-			Limit:      limit,
+			Page:       params.Page,
+			Limit:      params.Limit,
 			Total:      total,
-			TotalPages: totalPages,
+			TotalPages: totalPages(total, params.Limit),
 		},
 	}, nil
 }
+
+// clampPagination normalizes params so callers never depend on the repository's clamp
+func clampPagination(params entity.PaginationParams) entity.PaginationParams {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 || params.Limit > entity.MaxPageLimit {
+		params.Limit = entity.DefaultPageLimit
+	}
+	if params.Order != entity.SortAsc {
+		params.Order = entity.SortDesc
+	}
+	return params
+}
+
+// totalPages returns the number of pages needed for total items, or 0 when limit is not positive
+func totalPages(total, limit int64) int64 {
+	if limit <= 0 || total <= 0 {
+		return 0
+	}
+	return (total + limit - 1) / limit
+}
+
+// BulkAssignRole assigns a role to many users at once. A dry run performs the same
+// checks and update in a transaction that is rolled back.
+func (u *UserUsecaseImpl) BulkAssignRole(ctx context.Context, payload *entity.BulkRoleAssignPayload, dryRun bool) (*entity.BulkRoleAssignResponse, error) {
+	// Remove duplicate IDs, keeping the request order
+	seen := make(map[int64]bool, len(payload.UserIDs))
+	ids := make([]int64, 0, len(payload.UserIDs))
+	for _, id := range payload.UserIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var (
+		updated    int64
+		invalidIDs []int64
+		err        error
+	)
+	if dryRun {
+		err = u.store.DryRun(ctx, func(repos repository.Repositories) error {
+			updated, invalidIDs, err = repos.Users.BulkUpdateRole(ctx, ids, payload.RoleID)
+			return err
+		})
+	} else {
+		updated, invalidIDs, err = u.userRepo.BulkUpdateRole(ctx, ids, payload.RoleID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) || errors.Is(err, ErrLastAdmin) || errors.Is(err, ErrDryRunUnsupported) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error assigning role: %w", err)
+	}
+
+	// The affected users are the requested ones that exist
+	invalid := make(map[int64]bool, len(invalidIDs))
+	for _, id := range invalidIDs {
+		invalid[id] = true
+	}
+	affectedIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !invalid[id] {
+			affectedIDs = append(affectedIDs, id)
+		}
+	}
+
+	return &entity.BulkRoleAssignResponse{
+		DryRun:      dryRun,
+		Updated:     updated,
+		AffectedIDs: affectedIDs,
+		InvalidIDs:  invalidIDs,
+	}, nil
+}
+
+// GetStats gets aggregate user statistics
+func (u *UserUsecaseImpl) GetStats(ctx context.Context, windows []string) (*entity.UserStats, error) {
+	if len(windows) == 0 {
+		windows = u.cfg.StatsSignupWindows
+	}
+	durations, err := config.ParseWindows(windows)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatsWindows, err)
+	}
+
+	bySource, err := u.userRepo.CountBySignupSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stats: %w", err)
+	}
+
+	now := time.Now()
+	since := make([]time.Time, 0, len(durations))
+	for _, d := range durations {
+		since = append(since, now.Add(-d))
+	}
+	counts, err := u.userRepo.CountCreatedSince(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stats: %w", err)
+	}
+
+	byWindow := make(map[string]int64, len(windows))
+	for i, label := range windows {
+		byWindow[label] = counts[i]
+	}
+
+	var total int64
+	for _, count := range bySource {
+		total += count
+	}
+
+	return &entity.UserStats{
+		TotalUsers:      total,
+		SignupsBySource: bySource,
+		SignupsByWindow: byWindow,
+	}, nil
+}
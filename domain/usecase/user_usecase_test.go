@@ -0,0 +1,754 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+	"echo-base/events"
+)
+
+func TestRegisterUsername(t *testing.T) {
+	tests := []struct {
+		name         string
+		required     bool
+		username     string
+		wantUsername string
+		wantErr      error
+	}{
+		{name: "optional and omitted", username: "", wantUsername: ""},
+		{name: "stored lowercase", username: "  Alice.Smith ", wantUsername: "alice.smith"},
+		{name: "required and omitted", required: true, username: "", wantErr: ErrUsernameRequired},
+		{name: "reserved", username: "Admin", wantErr: ErrUsernameReserved},
+		{name: "taken", username: "taken_name", wantErr: ErrUsernameTaken},
+		{name: "taken regardless of case", username: "TAKEN_NAME", wantErr: ErrUsernameTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.UsernameRequired = tt.required
+				cfg.UsernameLowercase = true
+			})
+			ctx := context.Background()
+
+			_, _, err := env.uc.Register(ctx, &entity.UserCreatePayload{
+				Name: "Existing", Email: "existing@example.com", Username: "taken_name", Password: testPassword,
+			})
+			if err != nil {
+				t.Fatalf("error registering existing user: %v", err)
+			}
+
+			user, _, err := env.uc.Register(ctx, &entity.UserCreatePayload{
+				Name: "New User", Email: "new@example.com", Username: tt.username, Password: testPassword,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if user.Username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUsername)
+			}
+		})
+	}
+}
+
+func TestRegisterUsernameConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		lowercase    bool
+		reserved     []string
+		username     string
+		wantUsername string
+		wantErr      error
+	}{
+		{name: "case kept", username: "JohnDoe", wantUsername: "JohnDoe"},
+		{name: "case kept but unique regardless of case", username: "TAKEN_NAME", wantErr: ErrUsernameTaken},
+		{name: "lowercased", lowercase: true, username: "JohnDoe", wantUsername: "johndoe"},
+		{name: "configured reserved name", reserved: []string{"staff"}, username: "Staff", wantErr: ErrUsernameReserved},
+		{name: "default name not reserved when overridden", reserved: []string{"staff"}, username: "admin", wantUsername: "admin"},
+		{name: "nothing reserved", reserved: []string{}, username: "support", wantUsername: "support"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.UsernameLowercase = tt.lowercase
+				if tt.reserved != nil {
+					cfg.ReservedUsernames = tt.reserved
+				}
+			})
+			ctx := context.Background()
+
+			_, _, err := env.uc.Register(ctx, &entity.UserCreatePayload{
+				Name: "Existing", Email: "existing@example.com", Username: "taken_name", Password: testPassword,
+			})
+			if err != nil {
+				t.Fatalf("error registering existing user: %v", err)
+			}
+
+			user, _, err := env.uc.Register(ctx, &entity.UserCreatePayload{
+				Name: "New User", Email: "new@example.com", Username: tt.username, Password: testPassword,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if user.Username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUsername)
+			}
+		})
+	}
+}
+
+func TestGetByUsername(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+
+	if _, _, err := env.uc.Register(ctx, &entity.UserCreatePayload{
+		Name: "Alice", Email: "alice@example.com", Username: "alice", Password: testPassword,
+	}); err != nil {
+		t.Fatalf("error registering user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "exact", username: "alice"},
+		{name: "different case", username: "ALICE"},
+		{name: "unknown", username: "bob", wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := env.uc.GetByUsername(ctx, tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.Email != "alice@example.com" {
+				t.Errorf("email = %q, want alice@example.com", user.Email)
+			}
+		})
+	}
+}
+
+func TestRegisterEnqueuesEventWithUser(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	user, _, err := env.uc.Register(context.Background(), &entity.UserCreatePayload{
+		Name: "Alice", Email: "alice@example.com", Password: testPassword,
+	})
+	if err != nil {
+		t.Fatalf("error registering user: %v", err)
+	}
+
+	pending := env.pendingEvents(t)
+	if len(pending) != 1 || pending[0].Name != events.UserRegistered {
+		t.Fatalf("outbox = %+v, want one %s event", pending, events.UserRegistered)
+	}
+	var payload events.UserRegisteredPayload
+	if err := pending[0].Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != user.ID || payload.Email != user.Email {
+		t.Errorf("payload = %+v, want the registered user %d", payload, user.ID)
+	}
+}
+
+// stagingStore is a repository.Store whose transactions stage user inserts and outbox
+// events and apply them to the underlying repositories only on commit, with faults
+// injected into the staged writes
+type stagingStore struct {
+	repos repository.Repositories
+
+	createErr  error
+	enqueueErr error
+	verifyErr  error
+
+	commits, rollbacks int
+}
+
+func (s *stagingStore) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	users := &stagedUsers{UserRepository: s.repos.Users, err: s.createErr, verifyErr: s.verifyErr}
+	outbox := &stagedOutbox{OutboxRepository: s.repos.Outbox, err: s.enqueueErr}
+
+	if err := fn(repository.Repositories{Users: users, Roles: s.repos.Roles, Outbox: outbox}); err != nil {
+		s.rollbacks++
+		return err
+	}
+
+	for _, user := range users.staged {
+		if _, err := s.repos.Users.Create(ctx, user); err != nil {
+			return err
+		}
+	}
+	for _, event := range outbox.staged {
+		if err := s.repos.Outbox.Enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
+	s.commits++
+	return nil
+}
+
+func (s *stagingStore) DryRun(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return repository.ErrDryRunUnsupported
+}
+
+// stagedUsers stages the users created in a stagingStore transaction
+type stagedUsers struct {
+	repository.UserRepository
+	err       error
+	verifyErr error
+	staged    []*entity.User
+}
+
+func (r *stagedUsers) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	staged := *user
+	staged.ID = int64(1000 + len(r.staged))
+	r.staged = append(r.staged, &staged)
+	return &staged, nil
+}
+
+func (r *stagedUsers) MarkEmailVerified(ctx context.Context, id int64) error {
+	if r.verifyErr != nil {
+		return r.verifyErr
+	}
+	for _, user := range r.staged {
+		if user.ID == id {
+			user.EmailVerified = true
+			return nil
+		}
+	}
+	return r.UserRepository.MarkEmailVerified(ctx, id)
+}
+
+// stagedOutbox stages the events enqueued in a stagingStore transaction
+type stagedOutbox struct {
+	repository.OutboxRepository
+	err    error
+	staged []events.Event
+}
+
+func (r *stagedOutbox) Enqueue(ctx context.Context, event events.Event) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.staged = append(r.staged, event)
+	return nil
+}
+
+func TestRegisterTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		createErr  error
+		enqueueErr error
+		email      string
+		wantErr    error
+		wantUser   bool
+	}{
+		{name: "committed", email: "new@example.com", wantUser: true},
+		{name: "outbox failure after insert", email: "new@example.com", enqueueErr: errors.New("outbox unavailable")},
+		{name: "email taken by a concurrent registration", email: "new@example.com", createErr: repository.ErrDuplicateEmail, wantErr: ErrEmailTaken},
+		{name: "username taken by a concurrent registration", email: "new@example.com", createErr: repository.ErrDuplicateUsername, wantErr: ErrUsernameTaken},
+		{name: "email already registered", email: "existing@example.com", wantErr: ErrEmailTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			env.createUser(t, "existing@example.com", entity.RoleIDUser)
+
+			store := &stagingStore{
+				repos:      repository.Repositories{Users: env.users, Outbox: env.outbox},
+				createErr:  tt.createErr,
+				enqueueErr: tt.enqueueErr,
+			}
+			uc := NewUserUsecase(env.users, env.outbox, env.sessions, env.history, env.devices, env.twoFactor, env.apiKeys, store, env.tokens, env.cfg)
+
+			_, _, err := uc.Register(ctx, &entity.UserCreatePayload{
+				Name: "New User", Email: tt.email, Username: "new_user", Password: testPassword,
+			})
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if (err == nil) != tt.wantUser {
+				t.Fatalf("error = %v, want success %v", err, tt.wantUser)
+			}
+
+			wantCommits, wantRollbacks := 0, 1
+			if tt.wantUser {
+				wantCommits, wantRollbacks = 1, 0
+			}
+			if store.commits != wantCommits || store.rollbacks != wantRollbacks {
+				t.Errorf("commits, rollbacks = %d, %d; want %d, %d", store.commits, store.rollbacks, wantCommits, wantRollbacks)
+			}
+
+			// A rolled back registration leaves neither the user nor its event behind
+			user, err := env.users.GetByUsername(ctx, "new_user")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (user != nil) != tt.wantUser {
+				t.Errorf("user stored = %v, want %v", user != nil, tt.wantUser)
+			}
+			registered := 0
+			for _, event := range env.pendingEvents(t) {
+				if event.Name == events.UserRegistered {
+					registered++
+				}
+			}
+			if registered != wantCommits {
+				t.Errorf("registration events = %d, want %d", registered, wantCommits)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	name := func(s string) *string { return &s }
+	role := func(id int64) *int64 { return &id }
+
+	tests := []struct {
+		name    string
+		target  string
+		payload entity.UserPatchPayload
+
+		wantErr    error
+		wantName   string
+		wantRoleID int64
+	}{
+		{
+			name:       "name only",
+			target:     "user@example.com",
+			payload:    entity.UserPatchPayload{Name: name("Renamed User")},
+			wantName:   "Renamed User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "role only",
+			target:     "user@example.com",
+			payload:    entity.UserPatchPayload{RoleID: role(entity.RoleIDAdmin)},
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDAdmin,
+		},
+		{
+			name:       "both fields",
+			target:     "user@example.com",
+			payload:    entity.UserPatchPayload{Name: name("Renamed User"), RoleID: role(entity.RoleIDAdmin)},
+			wantName:   "Renamed User",
+			wantRoleID: entity.RoleIDAdmin,
+		},
+		{
+			name:       "empty patch",
+			target:     "user@example.com",
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "unknown role",
+			target:     "user@example.com",
+			payload:    entity.UserPatchPayload{Name: name("Renamed User"), RoleID: role(999)},
+			wantErr:    ErrRoleNotFound,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "demoting the last admin",
+			target:     "admin@example.com",
+			payload:    entity.UserPatchPayload{RoleID: role(entity.RoleIDUser)},
+			wantErr:    ErrLastAdmin,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			users := map[string]*entity.User{
+				"admin@example.com": env.createUser(t, "admin@example.com", entity.RoleIDAdmin),
+				"user@example.com":  env.createUser(t, "user@example.com", entity.RoleIDUser),
+			}
+			target := users[tt.target]
+
+			payload := tt.payload
+			resp, err := env.uc.Patch(ctx, target.ID, &payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (resp.Name != tt.wantName || resp.RoleID != tt.wantRoleID) {
+				t.Errorf("response name, role = %q, %d; want %q, %d", resp.Name, resp.RoleID, tt.wantName, tt.wantRoleID)
+			}
+
+			stored, err := env.users.GetByID(ctx, target.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Name != tt.wantName || stored.RoleID != tt.wantRoleID {
+				t.Errorf("stored name, role = %q, %d; want %q, %d", stored.Name, stored.RoleID, tt.wantName, tt.wantRoleID)
+			}
+			if stored.Email != target.Email || stored.Password != target.Password {
+				t.Error("patch changed fields it was not given")
+			}
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		env := newTestEnv(t, nil)
+		if _, err := env.uc.Patch(context.Background(), 404, &entity.UserPatchPayload{Name: name("Nobody")}); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		confirm       bool
+		requireVerify bool
+		email         string
+
+		wantErr      error
+		wantEmail    string
+		wantVerified bool
+		wantEvent    string
+	}{
+		{
+			name:         "immediate without verification",
+			email:        "new@example.com",
+			wantEmail:    "new@example.com",
+			wantVerified: true,
+		},
+		{
+			name:          "immediate with verification",
+			requireVerify: true,
+			email:         "new@example.com",
+			wantEmail:     "new@example.com",
+			wantEvent:     events.EmailVerificationRequested,
+		},
+		{
+			name:         "pending confirmation",
+			confirm:      true,
+			email:        "new@example.com",
+			wantEmail:    "user@example.com",
+			wantVerified: true,
+			wantEvent:    events.EmailChangeRequested,
+		},
+		{
+			name:         "unchanged",
+			email:        "user@example.com",
+			wantEmail:    "user@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "taken",
+			email:        "taken@example.com",
+			wantErr:      ErrEmailTaken,
+			wantEmail:    "user@example.com",
+			wantVerified: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.EmailChangeConfirm = tt.confirm
+				cfg.RequireEmailVerification = tt.requireVerify
+			})
+			ctx := context.Background()
+			user := env.createUser(t, "user@example.com", entity.RoleIDUser)
+			env.createUser(t, "taken@example.com", entity.RoleIDUser)
+
+			_, err := env.uc.ChangeEmail(ctx, user.ID, tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := env.users.GetByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Email != tt.wantEmail || stored.EmailVerified != tt.wantVerified {
+				t.Errorf("email, verified = %q, %v; want %q, %v", stored.Email, stored.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+
+			pending := env.pendingEvents(t)
+			if tt.wantEvent == "" {
+				if len(pending) != 0 {
+					t.Errorf("events = %v, want none", pending)
+				}
+				return
+			}
+			if len(pending) != 1 || pending[0].Name != tt.wantEvent {
+				t.Fatalf("events = %v, want one %s", pending, tt.wantEvent)
+			}
+			var payload events.EmailChangeRequestedPayload
+			if err := json.Unmarshal(pending[0].Payload, &payload); err != nil {
+				t.Fatalf("error decoding payload: %v", err)
+			}
+			if payload.Email != tt.email {
+				t.Errorf("event email = %q, want the new address %q", payload.Email, tt.email)
+			}
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		env := newTestEnv(t, nil)
+		if _, err := env.uc.ChangeEmail(context.Background(), 404, "new@example.com"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// token issues the confirmation link for the user
+		token func(env *testEnv, user *entity.User) (string, error)
+
+		// before runs before the link is opened
+		before func(t *testing.T, env *testEnv, user *entity.User)
+
+		wantErr   error
+		wantEmail string
+	}{
+		{
+			name: "confirmed",
+			token: func(env *testEnv, user *entity.User) (string, error) {
+				return env.tokens.GenerateEmailChangeToken(user.ID, user.Email, "new@example.com", time.Hour)
+			},
+			wantEmail: "new@example.com",
+		},
+		{
+			name: "opened twice",
+			token: func(env *testEnv, user *entity.User) (string, error) {
+				return env.tokens.GenerateEmailChangeToken(user.ID, user.Email, "new@example.com", time.Hour)
+			},
+			before: func(t *testing.T, env *testEnv, user *entity.User) {
+				if err := env.users.UpdateEmail(context.Background(), user.ID, "new@example.com", true); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:   ErrEmailAlreadyVerified,
+			wantEmail: "new@example.com",
+		},
+		{
+			name: "superseded by a later change",
+			token: func(env *testEnv, user *entity.User) (string, error) {
+				return env.tokens.GenerateEmailChangeToken(user.ID, user.Email, "new@example.com", time.Hour)
+			},
+			before: func(t *testing.T, env *testEnv, user *entity.User) {
+				if err := env.users.UpdateEmail(context.Background(), user.ID, "later@example.com", true); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:   ErrInvalidVerificationToken,
+			wantEmail: "later@example.com",
+		},
+		{
+			name: "new address taken meanwhile",
+			token: func(env *testEnv, user *entity.User) (string, error) {
+				return env.tokens.GenerateEmailChangeToken(user.ID, user.Email, "new@example.com", time.Hour)
+			},
+			before: func(t *testing.T, env *testEnv, user *entity.User) {
+				env.createUser(t, "new@example.com", entity.RoleIDUser)
+			},
+			wantErr:   ErrEmailTaken,
+			wantEmail: "user@example.com",
+		},
+		{
+			name: "expired",
+			token: func(env *testEnv, user *entity.User) (string, error) {
+				return env.tokens.GenerateEmailChangeToken(user.ID, user.Email, "new@example.com", -time.Minute)
+			},
+			wantErr:   ErrVerificationTokenExpired,
+			wantEmail: "user@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.EmailChangeConfirm = true })
+			ctx := context.Background()
+			user := env.createUser(t, "user@example.com", entity.RoleIDUser)
+
+			token, err := tt.token(env, user)
+			if err != nil {
+				t.Fatalf("error issuing token: %v", err)
+			}
+			if tt.before != nil {
+				tt.before(t, env, user)
+			}
+
+			if err := env.uc.VerifyEmail(ctx, token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := env.users.GetByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Email != tt.wantEmail {
+				t.Errorf("email = %q, want %q", stored.Email, tt.wantEmail)
+			}
+			if tt.wantErr == nil && !stored.EmailVerified {
+				t.Error("confirmed address is not verified")
+			}
+		})
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		status string
+
+		wantErr    error
+		wantStatus string
+		wantLogin  error
+	}{
+		{name: "suspend user", target: "user@example.com", status: entity.UserStatusSuspended, wantStatus: entity.UserStatusSuspended, wantLogin: ErrAccountSuspended},
+		{name: "reactivate user", target: "suspended@example.com", status: entity.UserStatusActive, wantStatus: entity.UserStatusActive},
+		{name: "suspend last admin", target: "admin@example.com", status: entity.UserStatusSuspended, wantErr: ErrLastAdmin, wantStatus: entity.UserStatusActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			users := map[string]*entity.User{
+				"admin@example.com":     env.createUser(t, "admin@example.com", entity.RoleIDAdmin),
+				"user@example.com":      env.createUser(t, "user@example.com", entity.RoleIDUser),
+				"suspended@example.com": env.createUser(t, "suspended@example.com", entity.RoleIDUser),
+			}
+			if err := env.users.UpdateStatus(ctx, users["suspended@example.com"].ID, entity.UserStatusSuspended); err != nil {
+				t.Fatal(err)
+			}
+			target := users[tt.target]
+
+			resp, err := env.uc.SetStatus(ctx, target.ID, tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && resp.Status != tt.wantStatus {
+				t.Errorf("response status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+
+			stored, err := env.users.GetByID(ctx, target.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("stored status = %q, want %q", stored.Status, tt.wantStatus)
+			}
+
+			_, err = env.uc.Login(ctx, &entity.UserLoginPayload{Email: tt.target, Password: testPassword}, &entity.LoginMetadata{})
+			if !errors.Is(err, tt.wantLogin) {
+				t.Errorf("login error = %v, want %v", err, tt.wantLogin)
+			}
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		env := newTestEnv(t, nil)
+		if _, err := env.uc.SetStatus(context.Background(), 404, entity.UserStatusSuspended); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+}
+
+func TestRefreshTokenSuspended(t *testing.T) {
+	env := newTestEnv(t, nil)
+	ctx := context.Background()
+	env.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+	user := env.createUser(t, "user@example.com", entity.RoleIDUser)
+	login := env.login(t, user.Email, &entity.LoginMetadata{})
+
+	if _, err := env.uc.SetStatus(ctx, user.ID, entity.UserStatusSuspended); err != nil {
+		t.Fatalf("error suspending user: %v", err)
+	}
+	_, err := env.uc.RefreshToken(ctx, &entity.RefreshTokenPayload{RefreshToken: login.RefreshToken})
+	if !errors.Is(err, ErrAccountSuspended) {
+		t.Errorf("refresh error = %v, want %v", err, ErrAccountSuspended)
+	}
+}
+
+func TestSeedAdminTransaction(t *testing.T) {
+	tests := []struct {
+		name          string
+		existingAdmin bool
+		createErr     error
+		verifyErr     error
+		wantSeeded    bool
+		wantErr       error
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{name: "committed", wantSeeded: true, wantCommits: 1},
+		{name: "verification failure", verifyErr: errors.New("connection reset"), wantRollbacks: 1},
+		{name: "email taken by a user", createErr: repository.ErrDuplicateEmail, wantErr: ErrEmailTaken, wantRollbacks: 1},
+		{name: "admin exists", existingAdmin: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			ctx := context.Background()
+			if tt.existingAdmin {
+				env.createUser(t, "root@example.com", entity.RoleIDAdmin)
+			}
+
+			store := &stagingStore{
+				repos:     repository.Repositories{Users: env.users, Outbox: env.outbox},
+				createErr: tt.createErr,
+				verifyErr: tt.verifyErr,
+			}
+			uc := NewUserUsecase(env.users, env.outbox, env.sessions, env.history, env.devices, env.twoFactor, env.apiKeys, store, env.tokens, env.cfg)
+
+			seeded, err := uc.SeedAdmin(ctx, "Admin", "admin@example.com", testPassword)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if (err == nil) != (tt.wantRollbacks == 0) {
+				t.Fatalf("error = %v, want failure %v", err, tt.wantRollbacks > 0)
+			}
+			if seeded != tt.wantSeeded {
+				t.Errorf("seeded = %v, want %v", seeded, tt.wantSeeded)
+			}
+			if store.commits != tt.wantCommits || store.rollbacks != tt.wantRollbacks {
+				t.Errorf("commits, rollbacks = %d, %d; want %d, %d", store.commits, store.rollbacks, tt.wantCommits, tt.wantRollbacks)
+			}
+
+			// The admin is stored verified or not at all
+			admin, err := env.users.GetByEmail(ctx, "admin@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (admin != nil) != tt.wantSeeded {
+				t.Fatalf("admin stored = %v, want %v", admin != nil, tt.wantSeeded)
+			}
+			if admin != nil && !admin.EmailVerified {
+				t.Error("seeded admin is not verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Handler handles a published event
+type Handler func(ctx context.Context, event Event) error
+
+// Bus dispatches events to their subscribers
+type Bus interface {
+	// Subscribe registers a handler for events with the given name
+	Subscribe(name string, handler Handler)
+
+	// Publish delivers an event to every subscriber of its name
+	Publish(ctx context.Context, event Event) error
+}
+
+// inMemoryBus is an in-process, synchronous implementation of Bus
+type inMemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a new in-memory event bus
+func NewBus() Bus {
+	return &inMemoryBus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for events with the given name
+func (b *inMemoryBus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish calls every subscriber of the event, returning the joined handler errors
+func (b *inMemoryBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.Name]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("error handling %s: %w", event.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// UserRegistered is published after a user successfully registers
+	UserRegistered = "user.registered"
+
+	// SuspiciousLogin is published when a login comes from a new device or
+	// the user has too many concurrent sessions
+	SuspiciousLogin = "user.suspicious_login"
+
+	// UserLoggedIn is published after a successful login
+	UserLoggedIn = "user.logged_in"
+
+	// LoginFailed is published after a login attempt with wrong credentials
+	LoginFailed = "user.login_failed"
+
+	// EmailVerificationRequested is published when an unverified user asks for a new verification email
+	EmailVerificationRequested = "user.email_verification_requested"
+
+	// PasswordResetRequested is published when a user asks for a password reset link
+	PasswordResetRequested = "user.password_reset_requested"
+
+	// EmailChangeRequested is published when a user asks to change their email and the
+	// change waits for confirmation from the new address
+	EmailChangeRequested = "user.email_change_requested"
+)
+
+// Event represents a domain event delivered through the bus
+type Event struct {
+	ID         int64           `json:"id,omitempty"`
+	Name       string          `json:"name"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// UserRegisteredPayload is the payload of a UserRegistered event
+type UserRegisteredPayload struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// EmailVerificationRequestedPayload is the payload of an EmailVerificationRequested event
+type EmailVerificationRequestedPayload struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// EmailChangeRequestedPayload is the payload of an EmailChangeRequested event; Email is
+// the new address and CurrentEmail the one it replaces
+type EmailChangeRequestedPayload struct {
+	UserID       int64  `json:"user_id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	CurrentEmail string `json:"current_email"`
+}
+
+// PasswordResetRequestedPayload is the payload of a PasswordResetRequested event
+type PasswordResetRequestedPayload struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// SuspiciousLoginPayload is the payload of a SuspiciousLogin event
+type SuspiciousLoginPayload struct {
+	UserID         int64    `json:"user_id"`
+	Email          string   `json:"email"`
+	IP             string   `json:"ip"`
+	UserAgent      string   `json:"user_agent"`
+	Reasons        []string `json:"reasons"`
+	ActiveSessions int64    `json:"active_sessions"`
+}
+
+// UserLoggedInPayload is the payload of a UserLoggedIn event
+type UserLoggedInPayload struct {
+	UserID int64 `json:"user_id"`
+}
+
+// LoginFailedPayload is the payload of a LoginFailed event; UserID is 0 for unknown emails
+type LoginFailedPayload struct {
+	UserID int64  `json:"user_id"`
+	IP     string `json:"ip"`
+}
+
+// New creates an event with the JSON-encoded payload
+func New(name string, payload interface{}) (Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("error encoding event payload: %w", err)
+	}
+
+	return Event{
+		Name:       name,
+		Payload:    raw,
+		OccurredAt: time.Now(),
+	}, nil
+}
+
+// Decode decodes the event payload into v
+func (e Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("error decoding %s payload: %w", e.Name, err)
+	}
+	return nil
+}
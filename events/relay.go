@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// maxRetryBackoff caps the doubling delay between retries of a failed event
+const maxRetryBackoff = time.Hour
+
+// RetryPolicy controls how the outbox retries events whose dispatch failed
+type RetryPolicy struct {
+	// MaxAttempts is how many failed dispatches move an event to the dead-letter state
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry; it doubles with every further failure
+	Backoff time.Duration
+
+	// Lease hides claimed events from other relay runs while they are dispatched. Events
+	// left unmarked by a run that dies are redelivered once it expires.
+	Lease time.Duration
+}
+
+// Delay returns how long to wait before retrying an event that has failed attempts times
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// Exhausted reports whether an event that has failed attempts times is dead-lettered
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return attempts >= p.MaxAttempts
+}
+
+// OutboxStore is the durable event storage drained by the relay
+type OutboxStore interface {
+	// DispatchPending claims up to limit unsent events that are due, passes them to
+	// dispatch outside any transaction and records the outcome of each under retry.
+	// It returns how many were sent.
+	DispatchPending(ctx context.Context, limit int, retry RetryPolicy, dispatch func(event Event) error) (int, error)
+}
+
+// Relay publishes pending outbox events on the bus; it is run periodically as a background job.
+// Events are marked sent only after a successful publish, giving at-least-once delivery.
+type Relay struct {
+	store     OutboxStore
+	bus       Bus
+	batchSize int
+	retry     RetryPolicy
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(store OutboxStore, bus Bus, batchSize int, retry RetryPolicy) *Relay {
+	return &Relay{
+		store:     store,
+		bus:       bus,
+		batchSize: batchSize,
+		retry:     retry,
+	}
+}
+
+// RunOnce dispatches one batch of pending events
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	return r.store.DispatchPending(ctx, r.batchSize, r.retry, func(event Event) error {
+		return r.bus.Publish(ctx, event)
+	})
+}
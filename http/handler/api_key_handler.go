@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/usecase"
+	"echo-base/utils"
+)
+
+// CreateAPIKey creates an API key for the caller; the key itself is only returned here
+// POST /api/profile/api-keys
+func (h *UserHandler) CreateAPIKey(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	payload := new(entity.APIKeyCreatePayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Validate payload
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.CreateAPIKey(c.Request().Context(), userID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidAPIKeyScope):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"scopes": err.Error()}))
+		case errors.Is(err, usecase.ErrAPIKeyExpiryInPast):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"expires_at": err.Error()}))
+		case errors.Is(err, usecase.ErrAPIKeyLimitReached):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, utils.SuccessResponse("api key created; store it now, it will not be shown again", result))
+}
+
+// GetAPIKeys lists the caller's active API keys without their secrets
+// GET /api/profile/api-keys
+func (h *UserHandler) GetAPIKeys(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	result, err := h.userUsecase.ListAPIKeys(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("api keys retrieved successfully", result))
+}
+
+// RevokeAPIKey revokes one of the caller's API keys
+// DELETE /api/profile/api-keys/:id
+func (h *UserHandler) RevokeAPIKey(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid api key ID"))
+	}
+
+	if err := h.userUsecase.RevokeAPIKey(c.Request().Context(), userID, keyID); err != nil {
+		if errors.Is(err, usecase.ErrAPIKeyNotFound) {
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("api key revoked successfully", nil))
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/http/middleware"
+)
+
+// newAPIKeyServer registers the API key routes and a /whoami route that accepts bearer
+// tokens and API keys
+func newAPIKeyServer(t *testing.T) *testServer {
+	t.Helper()
+
+	s := newTestServer(t, nil)
+	auth := middleware.APIKeyAuthMiddleware(s.uc, s.auth)
+	s.e.POST("/profile/api-keys", s.h.CreateAPIKey, s.auth)
+	s.e.GET("/profile/api-keys", s.h.GetAPIKeys, s.auth)
+	s.e.DELETE("/profile/api-keys/:id", s.h.RevokeAPIKey, s.auth)
+	s.e.GET("/whoami", func(c echo.Context) error {
+		return c.String(http.StatusOK, fmt.Sprint(c.Get("user_id")))
+	}, auth)
+	return s
+}
+
+// createAPIKey creates an API key through the API and returns it with its plaintext
+func (s *testServer) createAPIKey(t *testing.T, token, body string) entity.APIKeyCreatedResponse {
+	t.Helper()
+
+	rec := s.do(http.MethodPost, "/profile/api-keys", body, token)
+	expectStatus(t, rec, http.StatusCreated)
+
+	var created entity.APIKeyCreatedResponse
+	decodeData(t, rec, &created)
+	return created
+}
+
+// withAPIKey serves GET /whoami authenticated with key
+func (s *testServer) withAPIKey(key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set(middleware.APIKeyHeader, key)
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{name: "read key", body: `{"name":"ci","scopes":["read"]}`, wantStatus: http.StatusCreated},
+		{name: "expiring key", body: `{"name":"ci","scopes":["read","write"],"expires_at":"2100-01-01T00:00:00Z"}`, wantStatus: http.StatusCreated},
+		{name: "missing name", body: `{"scopes":["read"]}`, wantStatus: http.StatusBadRequest, wantField: "name"},
+		{name: "unknown scope", body: `{"name":"ci","scopes":["root"]}`, wantStatus: http.StatusBadRequest, wantField: "scopes"},
+		{name: "expired", body: `{"name":"ci","expires_at":"2000-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest, wantField: "expires_at"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAPIKeyServer(t)
+			token := s.token(t, s.createUser(t, "alice@example.com", entity.RoleIDUser))
+
+			rec := s.do(http.MethodPost, "/profile/api-keys", tt.body, token)
+			expectStatus(t, rec, tt.wantStatus)
+
+			if tt.wantField != "" {
+				if !strings.Contains(rec.Body.String(), `"`+tt.wantField+`"`) {
+					t.Errorf("response %s has no %s error", rec.Body.String(), tt.wantField)
+				}
+				return
+			}
+
+			var created entity.APIKeyCreatedResponse
+			decodeData(t, rec, &created)
+			if created.Key == "" || !strings.HasPrefix(created.Key, created.Prefix) {
+				t.Fatalf("key = %q with prefix %q, want the plaintext key", created.Key, created.Prefix)
+			}
+			if strings.Contains(rec.Body.String(), "key_hash") {
+				t.Errorf("response %s exposes the key hash", rec.Body.String())
+			}
+			if rec := s.withAPIKey(created.Key); rec.Code != http.StatusOK {
+				t.Errorf("new key rejected with status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	s := newAPIKeyServer(t)
+	alice := s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := s.createUser(t, "bob@example.com", entity.RoleIDUser)
+	aliceToken, bobToken := s.token(t, alice), s.token(t, bob)
+
+	// Rotation: the replacement is created while the old key is still active
+	old := s.createAPIKey(t, aliceToken, `{"name":"ci","scopes":["read"]}`)
+	replacement := s.createAPIKey(t, aliceToken, `{"name":"ci v2","scopes":["read"]}`)
+
+	rec := s.do(http.MethodGet, "/profile/api-keys", "", aliceToken)
+	expectStatus(t, rec, http.StatusOK)
+	for _, secret := range []string{old.Key, replacement.Key} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Fatalf("listing %s contains a key secret", rec.Body.String())
+		}
+	}
+	var keys []map[string]interface{}
+	decodeData(t, rec, &keys)
+	if len(keys) != 2 {
+		t.Fatalf("listed %d keys, want 2", len(keys))
+	}
+	for _, key := range keys {
+		if _, ok := key["key"]; ok {
+			t.Errorf("listed key %v has a key field", key)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		wantOld    int
+		wantNew    int
+	}{
+		{name: "both keys active during rotation", wantOld: http.StatusOK, wantNew: http.StatusOK},
+		{name: "another user cannot revoke", method: http.MethodDelete, path: fmt.Sprintf("/profile/api-keys/%d", old.ID), token: bobToken, wantStatus: http.StatusNotFound, wantOld: http.StatusOK, wantNew: http.StatusOK},
+		{name: "invalid ID", method: http.MethodDelete, path: "/profile/api-keys/abc", token: aliceToken, wantStatus: http.StatusBadRequest, wantOld: http.StatusOK, wantNew: http.StatusOK},
+		{name: "revoke the old key", method: http.MethodDelete, path: fmt.Sprintf("/profile/api-keys/%d", old.ID), token: aliceToken, wantStatus: http.StatusOK, wantOld: http.StatusUnauthorized, wantNew: http.StatusOK},
+		{name: "revoke again", method: http.MethodDelete, path: fmt.Sprintf("/profile/api-keys/%d", old.ID), token: aliceToken, wantStatus: http.StatusNotFound, wantOld: http.StatusUnauthorized, wantNew: http.StatusOK},
+	}
+
+	// Each step builds on the previous ones
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.method != "" {
+				expectStatus(t, s.do(tt.method, tt.path, "", tt.token), tt.wantStatus)
+			}
+			if rec := s.withAPIKey(old.Key); rec.Code != tt.wantOld {
+				t.Errorf("old key status = %d, want %d", rec.Code, tt.wantOld)
+			}
+			if rec := s.withAPIKey(replacement.Key); rec.Code != tt.wantNew {
+				t.Errorf("replacement key status = %d, want %d", rec.Code, tt.wantNew)
+			}
+		})
+	}
+}
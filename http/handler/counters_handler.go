@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/analytics"
+	"echo-base/utils"
+)
+
+// CountersHandler reports the lifetime analytics counters
+type CountersHandler struct {
+	counters *analytics.Counters
+}
+
+// NewCountersHandler creates a new counters handler
+func NewCountersHandler(counters *analytics.Counters) *CountersHandler {
+	return &CountersHandler{
+		counters: counters,
+	}
+}
+
+// GetCounters returns the total logins, registrations and failed logins
+// GET /api/v1/admin/stats/counters
+func (h *CountersHandler) GetCounters(c echo.Context) error {
+	return c.JSON(http.StatusOK, utils.SuccessResponse("counters retrieved successfully", h.counters.Snapshot()))
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/repository"
+	"echo-base/utils"
+)
+
+// checkedExtensions are the PostgreSQL extensions reported by GetDeps
+var checkedExtensions = []string{"citext", "pgcrypto"}
+
+// DepsHandler reports the versions of the service's dependencies
+type DepsHandler struct {
+	systemRepo repository.SystemRepository
+}
+
+// NewDepsHandler creates a new dependencies handler
+func NewDepsHandler(systemRepo repository.SystemRepository) *DepsHandler {
+	return &DepsHandler{
+		systemRepo: systemRepo,
+	}
+}
+
+// ExtensionStatus reports whether a database extension is installed
+type ExtensionStatus struct {
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"`
+}
+
+// DepsInfo represents the dependency versions response
+type DepsInfo struct {
+	AppVersion      string                     `json:"app_version"`
+	GoVersion       string                     `json:"go_version"`
+	EchoVersion     string                     `json:"echo_version"`
+	DatabaseVersion string                     `json:"database_version"`
+	Extensions      map[string]ExtensionStatus `json:"extensions"`
+}
+
+// GetDeps returns the database server, Go and Echo versions and the status of checked extensions
+// GET /api/v1/admin/deps
+func (h *DepsHandler) GetDeps(c echo.Context) error {
+	dbVersion, err := h.systemRepo.ServerVersion()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	installed, err := h.systemRepo.InstalledExtensions(checkedExtensions)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	extensions := make(map[string]ExtensionStatus, len(checkedExtensions))
+	for _, name := range checkedExtensions {
+		version, ok := installed[name]
+		extensions[name] = ExtensionStatus{Installed: ok, Version: version}
+	}
+
+	result := DepsInfo{
+		AppVersion:      config.Version,
+		GoVersion:       runtime.Version(),
+		EchoVersion:     echo.Version,
+		DatabaseVersion: dbVersion,
+		Extensions:      extensions,
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("dependency versions retrieved successfully", result))
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fakeSystemRepository reports a fixed server version and set of installed extensions
+type fakeSystemRepository struct {
+	version    string
+	extensions map[string]string
+	err        error
+}
+
+func (r fakeSystemRepository) ServerVersion() (string, error) {
+	return r.version, r.err
+}
+
+func (r fakeSystemRepository) InstalledExtensions(names []string) (map[string]string, error) {
+	installed := make(map[string]string)
+	for _, name := range names {
+		if version, ok := r.extensions[name]; ok {
+			installed[name] = version
+		}
+	}
+	return installed, r.err
+}
+
+func TestGetDeps(t *testing.T) {
+	const version = "PostgreSQL 16.2 on x86_64-pc-linux-gnu"
+
+	tests := []struct {
+		name           string
+		repo           fakeSystemRepository
+		wantStatus     int
+		wantExtensions map[string]ExtensionStatus
+	}{
+		{
+			name:       "all extensions installed",
+			repo:       fakeSystemRepository{version: version, extensions: map[string]string{"citext": "1.6", "pgcrypto": "1.3"}},
+			wantStatus: http.StatusOK,
+			wantExtensions: map[string]ExtensionStatus{
+				"citext":   {Installed: true, Version: "1.6"},
+				"pgcrypto": {Installed: true, Version: "1.3"},
+			},
+		},
+		{
+			name:       "missing extension",
+			repo:       fakeSystemRepository{version: version, extensions: map[string]string{"citext": "1.6"}},
+			wantStatus: http.StatusOK,
+			wantExtensions: map[string]ExtensionStatus{
+				"citext":   {Installed: true, Version: "1.6"},
+				"pgcrypto": {Installed: false},
+			},
+		},
+		{
+			name:       "database unavailable",
+			repo:       fakeSystemRepository{err: errors.New("connection refused")},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/admin/deps", NewDepsHandler(tt.repo).GetDeps)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/deps", nil))
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var info DepsInfo
+			decodeData(t, rec, &info)
+			if info.DatabaseVersion != version {
+				t.Errorf("database version = %q, want %q", info.DatabaseVersion, version)
+			}
+			if info.GoVersion != runtime.Version() || info.EchoVersion != echo.Version {
+				t.Errorf("go/echo versions = %q/%q, want %q/%q", info.GoVersion, info.EchoVersion, runtime.Version(), echo.Version)
+			}
+			if !reflect.DeepEqual(info.Extensions, tt.wantExtensions) {
+				t.Errorf("extensions = %+v, want %+v", info.Extensions, tt.wantExtensions)
+			}
+		})
+	}
+}
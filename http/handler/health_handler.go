@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewHealthHandler creates a health handler. db is nil with the in-memory store, in which
+// case readiness does not depend on a database. Pings give up after timeout.
+func NewHealthHandler(db *sql.DB, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+// HealthStatus represents a probe response
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Live reports that the process is up and serving requests
+// GET /health/live
+func (h *HealthHandler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Ready reports whether the service can serve requests, responding 503 with the failed
+// checks when the database is unreachable
+// GET /health/ready
+func (h *HealthHandler) Ready(c echo.Context) error {
+	if h.db == nil {
+		return c.JSON(http.StatusOK, HealthStatus{Status: "ok", Checks: map[string]string{"database": "in-memory"}})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+
+	if err := h.db.PingContext(ctx); err != nil {
+		return c.JSON(http.StatusServiceUnavailable, HealthStatus{Status: "unavailable", Checks: map[string]string{"database": err.Error()}})
+	}
+
+	return c.JSON(http.StatusOK, HealthStatus{Status: "ok", Checks: map[string]string{"database": "ok"}})
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/domain/repository"
+	"echo-base/domain/repository/memory"
+	"echo-base/domain/usecase"
+	"echo-base/http/middleware"
+	"echo-base/utils"
+)
+
+// testPassword is the password of every user created by the test helpers
+const testPassword = "secret-password-1"
+
+// testPasswordHash is testPassword hashed once, as hashing is deliberately slow
+var (
+	testPasswordHashOnce sync.Once
+	testPasswordHash     string
+	testPasswordHashErr  error
+)
+
+// testServer is a user handler over the in-memory repositories
+type testServer struct {
+	e       *echo.Echo
+	h       *UserHandler
+	uc      usecase.UserUsecase
+	cfg     *config.Config
+	signer  *utils.TokenSigner
+	users   repository.UserRepository
+	apiKeys repository.APIKeyRepository
+
+	// auth authenticates protected test routes with bearer tokens
+	auth echo.MiddlewareFunc
+}
+
+// newTestServer creates a user handler with the default configuration, which configure
+// may adjust first. Routes are registered by each test.
+func newTestServer(t *testing.T, configure func(cfg *config.Config)) *testServer {
+	t.Helper()
+
+	cfg := config.Load()
+	if configure != nil {
+		configure(cfg)
+	}
+
+	users := memory.NewUserRepository()
+	outbox := memory.NewOutboxRepository()
+	apiKeys := memory.NewAPIKeyRepository()
+	store := memory.NewStore(repository.Repositories{Users: users, Roles: memory.NewRoleRepository(users), Outbox: outbox})
+	signer := utils.NewTokenSigner("test-secret", nil, time.Hour, 24*time.Hour, "")
+
+	uc := usecase.NewUserUsecase(users, outbox, memory.NewSessionRepository(), memory.NewLoginHistoryRepository(),
+		memory.NewTrustedDeviceRepository(), memory.NewTwoFactorRepository(), apiKeys, store, signer, cfg)
+	h, err := NewUserHandler(uc, cfg)
+	if err != nil {
+		t.Fatalf("error creating handler: %v", err)
+	}
+
+	e := echo.New()
+	e.JSONSerializer = &utils.JSONSerializer{}
+
+	return &testServer{
+		e:       e,
+		h:       h,
+		uc:      uc,
+		cfg:     cfg,
+		signer:  signer,
+		users:   users,
+		apiKeys: apiKeys,
+		auth:    middleware.BearerAuthMiddleware(signer),
+	}
+}
+
+// createUser stores an active, verified user with testPassword and the role
+func (s *testServer) createUser(t *testing.T, email string, roleID int64) *entity.User {
+	t.Helper()
+
+	testPasswordHashOnce.Do(func() {
+		testPasswordHash, testPasswordHashErr = utils.HashPassword(testPassword)
+	})
+	if testPasswordHashErr != nil {
+		t.Fatalf("error hashing password: %v", testPasswordHashErr)
+	}
+	user, err := s.users.Create(context.Background(), &entity.User{
+		Name:          "Test User",
+		Email:         email,
+		Password:      testPasswordHash,
+		RoleID:        roleID,
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("error creating user %s: %v", email, err)
+	}
+	return user
+}
+
+// token issues an access token for user
+func (s *testServer) token(t *testing.T, user *entity.User) string {
+	t.Helper()
+
+	token, err := s.signer.GenerateToken(user.ID, user.Email, user.RoleID, "", "")
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	return token
+}
+
+// do serves a request with an optional JSON body and bearer token
+func (s *testServer) do(method, path, body, token string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeData decodes the data field of a success response into v
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("error decoding response %q: %v", rec.Body.String(), err)
+	}
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		t.Fatalf("error decoding data %q: %v", resp.Data, err)
+	}
+}
+
+// expectStatus fails the test when rec does not have the status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body.String())
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/jobs"
+	"echo-base/utils"
+)
+
+// JobHandler handles background job HTTP requests
+type JobHandler struct {
+	runner *jobs.Runner
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(runner *jobs.Runner) *JobHandler {
+	return &JobHandler{
+		runner: runner,
+	}
+}
+
+// Run triggers a registered background job immediately
+// POST /api/v1/admin/jobs/:name/run
+func (h *JobHandler) Run(c echo.Context) error {
+	result, err := h.runner.RunNow(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, jobs.ErrJobLocked):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+
+		// Job errors can carry queries and hostnames, so they stay in the server log
+		log.Printf("error running job %s on demand: %v\n", c.Param("name"), err)
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse("job failed"))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("job completed successfully", result))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/jobs"
+)
+
+func TestJobRun(t *testing.T) {
+	locker := jobs.NewLocalLocker()
+	runner := jobs.NewRunner(locker)
+	err := errors.Join(
+		runner.Register(jobs.Job{
+			Name:     "cleanup",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				return "removed 3 rows", nil
+			},
+		}),
+		runner.Register(jobs.Job{
+			Name:     "broken",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				return "", errors.New("dial tcp 10.0.0.5:5432: connection refused")
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("error registering jobs: %v", err)
+	}
+
+	e := echo.New()
+	e.POST("/admin/jobs/:name/run", NewJobHandler(runner).Run)
+
+	tests := []struct {
+		name        string
+		job         string
+		lockHeld    bool
+		wantStatus  int
+		wantSummary string
+	}{
+		{name: "registered job", job: "cleanup", wantStatus: http.StatusOK, wantSummary: "removed 3 rows"},
+		{name: "lock released after the run", job: "cleanup", wantStatus: http.StatusOK, wantSummary: "removed 3 rows"},
+		{name: "unknown job", job: "missing", wantStatus: http.StatusNotFound},
+		{name: "lock held by another run", job: "cleanup", lockHeld: true, wantStatus: http.StatusConflict},
+		{name: "failing job", job: "broken", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.lockHeld {
+				unlock, ok, err := locker.TryLock(context.Background(), tt.job)
+				if err != nil || !ok {
+					t.Fatalf("error taking the job lock: ok=%v err=%v", ok, err)
+				}
+				defer unlock()
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/jobs/"+tt.job+"/run", nil))
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus == http.StatusInternalServerError && strings.Contains(rec.Body.String(), "10.0.0.5") {
+				t.Errorf("response leaks the job error: %s", rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var result jobs.Result
+			decodeData(t, rec, &result)
+			if result.Name != tt.job || result.Summary != tt.wantSummary {
+				t.Errorf("result = %+v, want job %q with summary %q", result, tt.job, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/metrics"
+)
+
+// MetricsHandler exposes metrics for Prometheus to scrape
+type MetricsHandler struct {
+	registry *metrics.Registry
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(registry *metrics.Registry) *MetricsHandler {
+	return &MetricsHandler{
+		registry: registry,
+	}
+}
+
+// GetMetrics writes the metrics in the Prometheus text exposition format
+// GET /metrics
+func (h *MetricsHandler) GetMetrics(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	_, err := h.registry.WriteTo(c.Response())
+	return err
+}
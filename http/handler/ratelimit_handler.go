@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/http/middleware"
+	"echo-base/utils"
+)
+
+// RateLimitHandler reports the caller's rate-limit quota
+type RateLimitHandler struct {
+	store middleware.RateLimitStore
+}
+
+// NewRateLimitHandler creates a new rate-limit handler reading from the middleware's store
+func NewRateLimitHandler(store middleware.RateLimitStore) *RateLimitHandler {
+	return &RateLimitHandler{
+		store: store,
+	}
+}
+
+// GetStatus returns the caller's limit, remaining requests and reset time without consuming quota
+// GET /api/v1/ratelimit
+func (h *RateLimitHandler) GetStatus(c echo.Context) error {
+	status := h.store.Peek(middleware.RateLimitKey(c))
+	return c.JSON(http.StatusOK, utils.SuccessResponse("rate limit status retrieved successfully", status))
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/http/middleware"
+)
+
+func TestRateLimitStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		consumed      int
+		wantRemaining int
+	}{
+		{name: "fresh caller", consumed: 0, wantRemaining: 5},
+		{name: "after two requests", consumed: 2, wantRemaining: 3},
+		{name: "quota exhausted", consumed: 7, wantRemaining: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := middleware.NewMemoryRateLimitStore(60, 5)
+			e := echo.New()
+			e.GET("/ping", func(c echo.Context) error {
+				return c.String(http.StatusOK, "pong")
+			}, middleware.RateLimitMiddleware(store))
+			e.GET("/ratelimit", NewRateLimitHandler(store).GetStatus)
+
+			for range tt.consumed {
+				e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+			}
+
+			// Checking the status twice shows it does not consume quota itself
+			for range 2 {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ratelimit", nil))
+				expectStatus(t, rec, http.StatusOK)
+
+				var status middleware.RateLimitStatus
+				decodeData(t, rec, &status)
+				if status.Limit != 5 || status.Remaining != tt.wantRemaining {
+					t.Errorf("status = %+v, want limit 5 and %d remaining", status, tt.wantRemaining)
+				}
+				if tt.consumed > 0 && !status.ResetAt.After(time.Now()) {
+					t.Errorf("reset at %v, want a time in the future after consumption", status.ResetAt)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitStatusKeyedByUser(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore(60, 5)
+	asUser := func(id int64) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("user_id", id)
+				return next(c)
+			}
+		}
+	}
+
+	e := echo.New()
+	e.GET("/ping", func(c echo.Context) error {
+		return c.String(http.StatusOK, "pong")
+	}, asUser(1), middleware.RateLimitMiddleware(store))
+	e.GET("/ratelimit/:user", NewRateLimitHandler(store).GetStatus, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Param("user") == "1" {
+				return asUser(1)(next)(c)
+			}
+			return asUser(2)(next)(c)
+		}
+	})
+
+	for range 3 {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	}
+
+	tests := []struct {
+		user          string
+		wantRemaining int
+	}{
+		{user: "1", wantRemaining: 2},
+		{user: "2", wantRemaining: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run("user "+tt.user, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ratelimit/"+tt.user, nil))
+			expectStatus(t, rec, http.StatusOK)
+
+			var status middleware.RateLimitStatus
+			decodeData(t, rec, &status)
+			if status.Remaining != tt.wantRemaining {
+				t.Errorf("remaining = %d, want %d", status.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/domain/usecase"
+	"echo-base/utils"
+)
+
+// RoleHandler handles role HTTP requests
+type RoleHandler struct {
+	payloadValidator
+	roleUsecase usecase.RoleUsecase
+}
+
+// NewRoleHandler creates a new role handler, registering validation translations for
+// the configured locales
+func NewRoleHandler(roleUsecase usecase.RoleUsecase, cfg *config.Config) (*RoleHandler, error) {
+	validation, err := newPayloadValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoleHandler{
+		payloadValidator: validation,
+		roleUsecase:      roleUsecase,
+	}, nil
+}
+
+// GetAll returns the available roles
+// GET /api/v1/roles
+func (h *RoleHandler) GetAll(c echo.Context) error {
+	roles, err := h.roleUsecase.GetAll(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("roles retrieved successfully", roles))
+}
+
+// GetByID returns a role
+// GET /api/v1/admin/roles/:id
+func (h *RoleHandler) GetByID(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid role ID"))
+	}
+
+	role, err := h.roleUsecase.GetByID(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrRoleNotFound) {
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("role retrieved successfully", role))
+}
+
+// Create creates a role
+// POST /api/v1/admin/roles
+func (h *RoleHandler) Create(c echo.Context) error {
+	payload := new(entity.RolePayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	role, err := h.roleUsecase.Create(c.Request().Context(), payload)
+	if err != nil {
+		if errors.Is(err, usecase.ErrRoleNameTaken) {
+			return c.JSON(http.StatusConflict, utils.ValidationErrorResponse(err.Error(), map[string]string{"name": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, utils.SuccessResponse("role created successfully", role))
+}
+
+// Update renames a role
+// PUT /api/v1/admin/roles/:id
+func (h *RoleHandler) Update(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid role ID"))
+	}
+
+	payload := new(entity.RolePayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	role, err := h.roleUsecase.Update(c.Request().Context(), id, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrRoleNameTaken):
+			return c.JSON(http.StatusConflict, utils.ValidationErrorResponse(err.Error(), map[string]string{"name": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("role updated successfully", role))
+}
+
+// Delete deletes a role that no user has
+// DELETE /api/v1/admin/roles/:id
+func (h *RoleHandler) Delete(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid role ID"))
+	}
+
+	if err := h.roleUsecase.Delete(c.Request().Context(), id); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrRoleInUse), errors.Is(err, usecase.ErrRoleProtected):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("role deleted successfully", nil))
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/repository/memory"
+	"echo-base/domain/usecase"
+	"echo-base/utils"
+)
+
+func TestRoleValidationErrorLanguage(t *testing.T) {
+	cfg := config.Load()
+	cfg.ValidationLocales = []string{"en", "es"}
+	cfg.ValidationDefaultLocale = "en"
+
+	roles := usecase.NewRoleUsecase(memory.NewRoleRepository(memory.NewUserRepository()), 0)
+	h, err := NewRoleHandler(roles, cfg)
+	if err != nil {
+		t.Fatalf("error creating handler: %v", err)
+	}
+	e := echo.New()
+	e.POST("/admin/roles", h.Create)
+	e.PUT("/admin/roles/:id", h.Update)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		acceptLanguage string
+		wantLanguage   string
+		wantNameError  string
+	}{
+		{name: "create in spanish", method: http.MethodPost, path: "/admin/roles", acceptLanguage: "es", wantLanguage: "es", wantNameError: "name es un campo requerido"},
+		{name: "create in english", method: http.MethodPost, path: "/admin/roles", wantLanguage: "en", wantNameError: "is required"},
+		{name: "update in spanish", method: http.MethodPut, path: "/admin/roles/1", acceptLanguage: "es-MX", wantLanguage: "es", wantNameError: "name es un campo requerido"},
+		{name: "update with an unsupported language", method: http.MethodPut, path: "/admin/roles/1", acceptLanguage: "de", wantLanguage: "en", wantNameError: "is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"name":""}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			expectStatus(t, rec, http.StatusBadRequest)
+
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if resp.Errors["name"] != tt.wantNameError {
+				t.Errorf("name error = %q, want %q", resp.Errors["name"], tt.wantNameError)
+			}
+		})
+	}
+}
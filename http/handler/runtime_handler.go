@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/utils"
+)
+
+// RuntimeHandler exposes process runtime statistics
+type RuntimeHandler struct {
+	startedAt time.Time
+}
+
+// NewRuntimeHandler creates a new runtime handler, recording the process start time
+func NewRuntimeHandler() *RuntimeHandler {
+	return &RuntimeHandler{
+		startedAt: time.Now(),
+	}
+}
+
+// MemoryStats represents a subset of runtime.MemStats
+type MemoryStats struct {
+	Alloc       uint64 `json:"alloc_bytes"`
+	TotalAlloc  uint64 `json:"total_alloc_bytes"`
+	Sys         uint64 `json:"sys_bytes"`
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapInuse   uint64 `json:"heap_inuse_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+}
+
+// GCStats represents garbage collector statistics
+type GCStats struct {
+	NumGC        uint32     `json:"num_gc"`
+	PauseTotalMs float64    `json:"pause_total_ms"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+}
+
+// RuntimeStats represents runtime statistics response
+type RuntimeStats struct {
+	GoVersion     string      `json:"go_version"`
+	NumCPU        int         `json:"num_cpu"`
+	Goroutines    int         `json:"goroutines"`
+	StartedAt     time.Time   `json:"started_at"`
+	UptimeSeconds float64     `json:"uptime_seconds"`
+	Memory        MemoryStats `json:"memory"`
+	GC            GCStats     `json:"gc"`
+}
+
+// GetStats returns goroutine, memory and GC statistics
+// GET /api/v1/admin/runtime
+func (h *RuntimeHandler) GetStats(c echo.Context) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	gc := GCStats{
+		NumGC:        mem.NumGC,
+		PauseTotalMs: float64(mem.PauseTotalNs) / float64(time.Millisecond),
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		gc.LastGC = &lastGC
+	}
+
+	result := RuntimeStats{
+		GoVersion:     runtime.Version(),
+		NumCPU:        runtime.NumCPU(),
+		Goroutines:    runtime.NumGoroutine(),
+		StartedAt:     h.startedAt,
+		UptimeSeconds: time.Since(h.startedAt).Seconds(),
+		Memory: MemoryStats{
+			Alloc:       mem.Alloc,
+			TotalAlloc:  mem.TotalAlloc,
+			Sys:         mem.Sys,
+			HeapAlloc:   mem.HeapAlloc,
+			HeapInuse:   mem.HeapInuse,
+			HeapObjects: mem.HeapObjects,
+		},
+		GC: gc,
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("runtime stats retrieved successfully", result))
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRuntimeHandlerGetStats(t *testing.T) {
+	h := NewRuntimeHandler()
+	e := echo.New()
+	e.GET("/runtime", h.GetStats)
+
+	get := func() RuntimeStats {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runtime", nil))
+		expectStatus(t, rec, http.StatusOK)
+
+		var stats RuntimeStats
+		decodeData(t, rec, &stats)
+		return stats
+	}
+
+	runtime.GC()
+	first := get()
+
+	checks := []struct {
+		name string
+		ok   bool
+	}{
+		{"go_version", first.GoVersion == runtime.Version()},
+		{"num_cpu", first.NumCPU > 0},
+		{"goroutines", first.Goroutines > 0},
+		{"started_at", !first.StartedAt.IsZero()},
+		{"memory.alloc_bytes", first.Memory.Alloc > 0},
+		{"memory.sys_bytes", first.Memory.Sys > 0},
+		{"memory.heap_objects", first.Memory.HeapObjects > 0},
+		{"gc.num_gc", first.GC.NumGC > 0},
+		{"gc.last_gc", first.GC.LastGC != nil},
+	}
+	for _, check := range checks {
+		if !check.ok {
+			t.Errorf("%s is not populated: %+v", check.name, first)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	second := get()
+	if second.UptimeSeconds <= first.UptimeSeconds {
+		t.Errorf("uptime did not increase: %v then %v", first.UptimeSeconds, second.UptimeSeconds)
+	}
+	if !second.StartedAt.Equal(first.StartedAt) {
+		t.Errorf("started_at changed from %v to %v", first.StartedAt, second.StartedAt)
+	}
+}
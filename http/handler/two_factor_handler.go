@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/usecase"
+	"echo-base/utils"
+)
+
+// LoginTwoFactor completes a login that requires a TOTP code
+// POST /api/auth/login/2fa
+func (h *UserHandler) LoginTwoFactor(c echo.Context) error {
+	payload := new(entity.TwoFactorLoginPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Validate payload
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.LoginTwoFactor(c.Request().Context(), payload, &entity.LoginMetadata{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidLoginChallenge), errors.Is(err, usecase.ErrInvalidTwoFactorCode):
+			return c.JSON(http.StatusUnauthorized, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrAccountSuspended):
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("login successful", result))
+}
+
+// LoginRecovery completes a login that requires two-factor authentication using a recovery code
+// POST /api/auth/login/recovery
+func (h *UserHandler) LoginRecovery(c echo.Context) error {
+	payload := new(entity.RecoveryLoginPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Validate payload
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.LoginRecovery(c.Request().Context(), payload, &entity.LoginMetadata{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidLoginChallenge), errors.Is(err, usecase.ErrInvalidRecoveryCode):
+			return c.JSON(http.StatusUnauthorized, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrAccountSuspended):
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("login successful", result))
+}
+
+// SetupTwoFactor generates a TOTP secret for the caller to add to an authenticator app
+// POST /api/profile/2fa/setup
+func (h *UserHandler) SetupTwoFactor(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	result, err := h.userUsecase.SetupTwoFactor(c.Request().Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrTwoFactorAlreadyEnabled):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, utils.SuccessResponse("two-factor setup started", result))
+}
+
+// EnableTwoFactor confirms the caller's TOTP secret with a code and returns recovery codes
+// POST /api/profile/2fa/enable
+func (h *UserHandler) EnableTwoFactor(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	payload := new(entity.TwoFactorEnablePayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Validate payload
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.EnableTwoFactor(c.Request().Context(), userID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrTwoFactorAlreadyEnabled):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrTwoFactorNotSetUp):
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrInvalidTwoFactorCode):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"code": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, utils.SuccessResponse("two-factor authentication enabled", result))
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes, invalidating the old ones
+// POST /api/profile/2fa/recovery-codes/regenerate
+func (h *UserHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	result, err := h.userUsecase.RegenerateRecoveryCodes(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrTwoFactorNotEnabled) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, utils.SuccessResponse("recovery codes regenerated", result))
+}
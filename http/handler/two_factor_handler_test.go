@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"echo-base/domain/entity"
+)
+
+// totpCode returns the TOTP code of secret at the time
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, at)
+	if err != nil {
+		t.Fatalf("error generating TOTP code: %v", err)
+	}
+	return code
+}
+
+func TestTwoFactorLoginFlow(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/login", s.h.Login)
+	s.e.POST("/auth/login/2fa", s.h.LoginTwoFactor)
+	s.e.POST("/profile/2fa/setup", s.h.SetupTwoFactor, s.auth)
+	s.e.POST("/profile/2fa/enable", s.h.EnableTwoFactor, s.auth)
+
+	user := s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	token := s.token(t, user)
+	login := fmt.Sprintf(`{"email":%q,"password":%q}`, user.Email, testPassword)
+
+	// Setup returns the secret once and is never cached
+	rec := s.do(http.MethodPost, "/profile/2fa/setup", "", token)
+	expectStatus(t, rec, http.StatusOK)
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("setup Cache-Control = %q, want no-store", cc)
+	}
+	var setup entity.TwoFactorSetupResponse
+	decodeData(t, rec, &setup)
+
+	// Logging in before 2FA is enabled needs no second step
+	rec = s.do(http.MethodPost, "/auth/login", login, "")
+	expectStatus(t, rec, http.StatusOK)
+	var before entity.LoginResponse
+	decodeData(t, rec, &before)
+	if before.TwoFactorRequired || before.Token == "" {
+		t.Fatalf("login before enabling = %+v, want a token", before)
+	}
+
+	enableCode := totpCode(t, setup.Secret, time.Now().Add(-30*time.Second))
+	enableTests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "malformed code", body: `{"code":"12ab"}`, wantStatus: http.StatusBadRequest},
+		{name: "valid code", body: fmt.Sprintf(`{"code":%q}`, enableCode), wantStatus: http.StatusOK},
+		{name: "already enabled", body: fmt.Sprintf(`{"code":%q}`, enableCode), wantStatus: http.StatusConflict},
+	}
+	for _, tt := range enableTests {
+		t.Run("enable "+tt.name, func(t *testing.T) {
+			expectStatus(t, s.do(http.MethodPost, "/profile/2fa/enable", tt.body, token), tt.wantStatus)
+		})
+	}
+
+	// The password step now returns a challenge instead of a token
+	rec = s.do(http.MethodPost, "/auth/login", login, "")
+	expectStatus(t, rec, http.StatusOK)
+	var challenge entity.LoginResponse
+	decodeData(t, rec, &challenge)
+	if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" || challenge.Token != "" {
+		t.Fatalf("login after enabling = %+v, want only a challenge", challenge)
+	}
+
+	code := totpCode(t, setup.Secret, time.Now())
+	loginTests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "missing code", body: fmt.Sprintf(`{"challenge_token":%q}`, challenge.ChallengeToken), wantStatus: http.StatusBadRequest},
+		{name: "invalid challenge", body: fmt.Sprintf(`{"challenge_token":"forged","code":%q}`, code), wantStatus: http.StatusUnauthorized},
+		{name: "valid code", body: fmt.Sprintf(`{"challenge_token":%q,"code":%q}`, challenge.ChallengeToken, code), wantStatus: http.StatusOK},
+		{name: "challenge replayed", body: fmt.Sprintf(`{"challenge_token":%q,"code":%q}`, challenge.ChallengeToken, code), wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range loginTests {
+		t.Run("login "+tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/auth/login/2fa", tt.body, "")
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp entity.LoginResponse
+			decodeData(t, rec, &resp)
+			if _, err := s.signer.ValidateToken(resp.Token); err != nil {
+				t.Errorf("issued token is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodeLogin(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/login", s.h.Login)
+	s.e.POST("/auth/login/recovery", s.h.LoginRecovery)
+	s.e.POST("/profile/2fa/setup", s.h.SetupTwoFactor, s.auth)
+	s.e.POST("/profile/2fa/enable", s.h.EnableTwoFactor, s.auth)
+	s.e.POST("/profile/2fa/recovery-codes/regenerate", s.h.RegenerateRecoveryCodes, s.auth)
+
+	user := s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	other := s.createUser(t, "bob@example.com", entity.RoleIDUser)
+	token := s.token(t, user)
+	login := fmt.Sprintf(`{"email":%q,"password":%q}`, user.Email, testPassword)
+
+	expectStatus(t, s.do(http.MethodPost, "/profile/2fa/recovery-codes/regenerate", "", s.token(t, other)), http.StatusBadRequest)
+
+	rec := s.do(http.MethodPost, "/profile/2fa/setup", "", token)
+	expectStatus(t, rec, http.StatusOK)
+	var setup entity.TwoFactorSetupResponse
+	decodeData(t, rec, &setup)
+
+	rec = s.do(http.MethodPost, "/profile/2fa/enable", fmt.Sprintf(`{"code":%q}`, totpCode(t, setup.Secret, time.Now())), token)
+	expectStatus(t, rec, http.StatusOK)
+	var enrolled entity.RecoveryCodesResponse
+	decodeData(t, rec, &enrolled)
+
+	rec = s.do(http.MethodPost, "/profile/2fa/recovery-codes/regenerate", "", token)
+	expectStatus(t, rec, http.StatusOK)
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("regenerate Cache-Control = %q, want no-store", cc)
+	}
+	var regenerated entity.RecoveryCodesResponse
+	decodeData(t, rec, &regenerated)
+
+	tests := []struct {
+		name       string
+		code       string
+		wantStatus int
+	}{
+		{name: "code replaced by regeneration", code: enrolled.RecoveryCodes[0], wantStatus: http.StatusUnauthorized},
+		{name: "regenerated code", code: regenerated.RecoveryCodes[0], wantStatus: http.StatusOK},
+		{name: "code already used", code: regenerated.RecoveryCodes[0], wantStatus: http.StatusUnauthorized},
+		{name: "another regenerated code", code: regenerated.RecoveryCodes[1], wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/auth/login", login, "")
+			expectStatus(t, rec, http.StatusOK)
+			var challenge entity.LoginResponse
+			decodeData(t, rec, &challenge)
+
+			body := fmt.Sprintf(`{"challenge_token":%q,"recovery_code":%q}`, challenge.ChallengeToken, tt.code)
+			rec = s.do(http.MethodPost, "/auth/login/recovery", body, "")
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp entity.LoginResponse
+			decodeData(t, rec, &resp)
+			if resp.Token == "" {
+				t.Errorf("response = %+v, want a token", resp)
+			}
+		})
+	}
+}
@@ -1,133 +1,459 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 
+	"echo-base/config"
 	"echo-base/domain/entity"
 	"echo-base/domain/usecase"
+	"echo-base/http/middleware"
 	"echo-base/utils"
 )
 
+var (
+	// errBodyRequired is returned by bindBody when the request has no body
+	errBodyRequired = errors.New("request body is required")
+
+	// errInvalidBody is returned by bindBody when the body cannot be decoded
+	errInvalidBody = errors.New("invalid request body")
+)
+
 // UserHandler handles user HTTP requests
 type UserHandler struct {
+	payloadValidator
 	userUsecase usecase.UserUsecase
-	validator   *validator.Validate
+	cfg         *config.Config
 }
 
-// NewUserHandler creates a new user handler
-func NewUserHandler(userUsecase usecase.UserUsecase) *UserHandler {
+// NewUserHandler creates a new user handler, registering validation translations for
+// the configured locales
+func NewUserHandler(userUsecase usecase.UserUsecase, cfg *config.Config) (*UserHandler, error) {
+	validation, err := newPayloadValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &UserHandler{
-		userUsecase: userUsecase,
-		validator:   validator.New(),
+		payloadValidator: validation,
+		userUsecase:      userUsecase,
+		cfg:              cfg,
+	}, nil
+}
+
+// bindBody binds the request body into payload, distinguishing a missing body from a malformed one
+func bindBody(c echo.Context, payload interface{}) error {
+	req := c.Request()
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return errBodyRequired
+	}
+
+	// Chunked requests have no Content-Length; peek at the body to see if it is empty
+	if req.ContentLength < 0 {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return errInvalidBody
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			return errBodyRequired
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if err := c.Bind(payload); err != nil {
+		return errInvalidBody
+	}
+	return nil
+}
+
+// resolveUserID parses the :id path param, resolving the literal "me" to the authenticated user
+func resolveUserID(c echo.Context) (int64, error) {
+	param := c.Param("id")
+	if param == "me" {
+		userID, ok := c.Get("user_id").(int64)
+		if !ok {
+			return 0, errors.New("unauthorized")
+		}
+		return userID, nil
+	}
+
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid user ID")
+	}
+	return id, nil
+}
+
+// viewerFromContext describes the authenticated caller for field visibility
+func viewerFromContext(c echo.Context) utils.Viewer {
+	userID, _ := c.Get("user_id").(int64)
+	roleID, _ := c.Get("role_id").(int64)
+	return utils.Viewer{
+		UserID:  userID,
+		IsAdmin: roleID == entity.RoleIDAdmin,
+	}
+}
+
+// selectUserFields hides fields the caller may not see, then applies the optional ?fields= query param
+func selectUserFields(c echo.Context, data interface{}) (interface{}, error) {
+	data, err := utils.ApplyVisibility(data, viewerFromContext(c))
+	if err != nil {
+		return nil, err
 	}
+
+	raw := c.QueryParam("fields")
+	if raw == "" {
+		return data, nil
+	}
+
+	fields, err := utils.ParseFields(raw, utils.JSONFieldNames(entity.UserResponse{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.SelectFields(data, fields)
 }
 
 // Register handles user registration
 // POST /api/auth/register
 func (h *UserHandler) Register(c echo.Context) error {
 	payload := new(entity.UserCreatePayload)
-	if err := c.Bind(payload); err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid request body"))
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if payload.ReferralSource == "" {
+		payload.ReferralSource = c.Request().Header.Get("X-Referral-Source")
 	}
 
 	// Validate payload
 	if err := h.validator.Struct(payload); err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
 	}
 
-	result, err := h.userUsecase.Register(payload)
+	result, warnings, err := h.userUsecase.Register(c.Request().Context(), payload)
 	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrEmailDomainNotAllowed), errors.Is(err, usecase.ErrEmailDomainNoMX):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"email": err.Error()}))
+		case errors.Is(err, usecase.ErrUsernameRequired), errors.Is(err, usecase.ErrUsernameReserved):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"username": err.Error()}))
+		case errors.Is(err, usecase.ErrEmailTaken):
+			return c.JSON(http.StatusConflict, utils.ValidationErrorResponse(err.Error(), map[string]string{"email": err.Error()}))
+		case errors.Is(err, usecase.ErrUsernameTaken):
+			return c.JSON(http.StatusConflict, utils.ValidationErrorResponse(err.Error(), map[string]string{"username": err.Error()}))
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"role_id": err.Error()}))
+		case errors.Is(err, usecase.ErrWeakPassword):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"password": err.Error()}))
+		}
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(http.StatusCreated, utils.SuccessResponse("user registered successfully", result))
+	return c.JSON(http.StatusCreated, utils.SuccessResponse("user registered successfully", result).WithWarnings(warnings))
+}
+
+// SuggestPassword returns a strong random password meeting the password policy
+// GET /api/auth/suggest-password
+func (h *UserHandler) SuggestPassword(c echo.Context) error {
+	password, err := h.userUsecase.SuggestPassword()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, utils.SuccessResponse("password generated successfully", map[string]string{
+		"password": password,
+	}))
+}
+
+// VerifyEmail confirms the email address carried by a verification token
+// GET /api/v1/auth/verify?token=...
+func (h *UserHandler) VerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse("token is required", map[string]string{"token": "token is required"}))
+	}
+
+	if err := h.userUsecase.VerifyEmail(c.Request().Context(), token); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrEmailAlreadyVerified):
+			return c.JSON(http.StatusOK, utils.SuccessResponse(err.Error(), nil))
+		case errors.Is(err, usecase.ErrInvalidVerificationToken), errors.Is(err, usecase.ErrVerificationTokenExpired):
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrEmailTaken):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("email verified successfully", nil))
+}
+
+// ResendVerification emails a new verification link. It responds the same way whether
+// or not the email belongs to an unverified account.
+// POST /api/v1/auth/verify/resend
+func (h *UserHandler) ResendVerification(c echo.Context) error {
+	payload := new(entity.ResendVerificationPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	if err := h.userUsecase.ResendVerification(c.Request().Context(), payload.Email); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("if the account needs verification, a new link has been sent", nil))
+}
+
+// ForgotPassword emails a password reset link. It responds the same way whether or not
+// the email belongs to an account.
+// POST /api/v1/auth/forgot-password
+func (h *UserHandler) ForgotPassword(c echo.Context) error {
+	payload := new(entity.ForgotPasswordPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	if err := h.userUsecase.ForgotPassword(c.Request().Context(), payload.Email); err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("if the email is registered, a password reset link has been sent", nil))
+}
+
+// ResetPassword sets a new password using the token from a password reset link
+// POST /api/v1/auth/reset-password
+func (h *UserHandler) ResetPassword(c echo.Context) error {
+	payload := new(entity.ResetPasswordPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	if err := h.userUsecase.ResetPassword(c.Request().Context(), payload); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidResetToken), errors.Is(err, usecase.ErrResetTokenExpired):
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrWeakPassword):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"new_password": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("password reset successfully", nil))
 }
 
 // Login handles user login
 // POST /api/auth/login
 func (h *UserHandler) Login(c echo.Context) error {
 	payload := new(entity.UserLoginPayload)
-	if err := c.Bind(payload); err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid request body"))
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
 	// Validate payload
 	if err := h.validator.Struct(payload); err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
 	}
 
-	result, err := h.userUsecase.Login(payload)
+	result, err := h.userUsecase.Login(c.Request().Context(), payload, &entity.LoginMetadata{
+		IP:          c.RealIP(),
+		UserAgent:   c.Request().UserAgent(),
+		DeviceToken: c.Request().Header.Get("X-Device-Token"),
+	})
 	if err != nil {
+		if errors.Is(err, usecase.ErrEmailNotVerified) || errors.Is(err, usecase.ErrAccountSuspended) {
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse(err.Error()))
+		}
 		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse(err.Error()))
 	}
 
+	if result.TwoFactorRequired {
+		return c.JSON(http.StatusOK, utils.SuccessResponse("two-factor code required", result))
+	}
 	return c.JSON(http.StatusOK, utils.SuccessResponse("login successful", result))
 }
 
-// GetByID gets user by ID
+// RefreshToken exchanges a refresh token for a new access token
+// POST /api/auth/refresh
+func (h *UserHandler) RefreshToken(c echo.Context) error {
+	payload := new(entity.RefreshTokenPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Validate payload
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.RefreshToken(c.Request().Context(), payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidRefreshToken):
+			return c.JSON(http.StatusUnauthorized, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrAccountSuspended):
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("token refreshed", result))
+}
+
+// GetByID gets user by ID ("me" resolves to the caller)
 // GET /api/users/:id
 func (h *UserHandler) GetByID(c echo.Context) error {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	result, err := h.userUsecase.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+	}
+
+	data, err := selectUserFields(c, result)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("user retrieved successfully", data))
+}
+
+// GetVCard downloads a user's profile as a vCard ("me" resolves to the caller)
+// GET /api/users/:id/vcard
+func (h *UserHandler) GetVCard(c echo.Context) error {
+	id, err := resolveUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid user ID"))
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
-	result, err := h.userUsecase.GetByID(id)
+	result, err := h.userUsecase.GetByID(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, utils.SuccessResponse("user retrieved successfully", result))
+	// The vCard follows the profile's visibility rules, so only the owner and admins get the email
+	visible, err := utils.ApplyVisibility(result, viewerFromContext(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+	email, _ := visible.(map[string]interface{})["email"].(string)
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="user-%d.vcf"`, result.ID))
+	return c.Blob(http.StatusOK, "text/vcard; charset=utf-8", []byte(utils.BuildVCard(result.Name, email, result.Username)))
+}
+
+// GetByUsername gets user by username
+// GET /api/users/by-username/:username
+func (h *UserHandler) GetByUsername(c echo.Context) error {
+	result, err := h.userUsecase.GetByUsername(c.Request().Context(), c.Param("username"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+	}
+
+	data, err := selectUserFields(c, result)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("user retrieved successfully", data))
 }
 
 // GetAll gets all users
 // GET /api/users
 func (h *UserHandler) GetAll(c echo.Context) error {
-	result, err := h.userUsecase.GetAll()
+	result, err := h.userUsecase.GetAll(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, utils.SuccessResponse("users retrieved successfully", result))
+	data, err := selectUserFields(c, result)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("users retrieved successfully", data))
 }
 
-// GetAllPagination gets all users with pagination and optional search
-// GET /api/users/pagination?page=1&limit=10&search=john
+// GetAllPagination gets all users with pagination, optional search and role and
+// creation date filters; created_after and created_before take RFC3339 times
+// GET /api/users/pagination?page=1&limit=10&search=john&role_id=2&created_after=2026-01-01T00:00:00Z&sort=name&order=asc&fields=id,name
 func (h *UserHandler) GetAllPagination(c echo.Context) error {
-	// Parse pagination parameters
-	page := int64(1)
-	limit := int64(10)
-	search := ""
+	// Pagination params are parsed and validated by PaginationMiddleware
+	params := middleware.GetPaginationParams(c)
 
-	if p := c.QueryParam("page"); p != "" {
-		if parsed, err := strconv.ParseInt(p, 10, 64); err == nil && parsed > 0 {
-			page = parsed
-		}
+	// Only admins may list soft-deleted users
+	if !viewerFromContext(c).IsAdmin {
+		params.IncludeDeleted = false
 	}
 
-	if l := c.QueryParam("limit"); l != "" {
-		if parsed, err := strconv.ParseInt(l, 10, 64); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
+	result, err := h.userUsecase.GetAllPagination(c.Request().Context(), params)
+	if err != nil {
+		if errors.Is(err, usecase.ErrOffsetTooLarge) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(
+				fmt.Sprintf("%s (%d rows); narrow the search instead of paging this deep", err.Error(), h.cfg.PaginationMaxOffset),
+			))
 		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
-	if s := c.QueryParam("search"); s != "" {
-		search = s
+	data, err := selectUserFields(c, result.Data)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
-	result, err := h.userUsecase.GetAllPagination(page, limit, search)
+	return c.JSON(http.StatusOK, utils.SuccessResponse("users retrieved successfully", map[string]interface{}{
+		"data":       data,
+		"pagination": result.Pagination,
+	}))
+}
+
+// ExplainSearch shows how a user search is interpreted: the parsed filter, the WHERE
+// clause with placeholders (never the values) and the estimated row count
+// GET /api/v1/admin/users/search/explain
+func (h *UserHandler) ExplainSearch(c echo.Context) error {
+	params, err := utils.ParsePagination(c, utils.DefaultPagination)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	result, err := h.userUsecase.ExplainSearch(c.Request().Context(), entity.UserSearchFilter{
+		Search:         params.Search,
+		RoleID:         params.RoleID,
+		CreatedAfter:   params.CreatedAfter,
+		CreatedBefore:  params.CreatedBefore,
+		IncludeDeleted: params.IncludeDeleted,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, utils.SuccessResponse("users retrieved successfully", result))
+	return c.JSON(http.StatusOK, utils.SuccessResponse("search explained successfully", result))
 }
 
-// Update updates user profile
+// Update updates user profile ("me" resolves to the caller)
 // PUT /api/users/:id
 func (h *UserHandler) Update(c echo.Context) error {
 	// Check authorization
@@ -136,9 +462,9 @@ func (h *UserHandler) Update(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
 	}
 
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := resolveUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid user ID"))
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
 	// Check if user is updating their own profile
@@ -149,23 +475,163 @@ func (h *UserHandler) Update(c echo.Context) error {
 	payload := new(struct {
 		Name string `json:"name" validate:"required,min=3"`
 	})
-	if err := c.Bind(payload); err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid request body"))
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
 	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.Update(c.Request().Context(), id, payload.Name)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("user updated successfully", result))
+}
+
+// Patch updates only the fields sent ("me" resolves to the caller). Users may change
+// their own name; admins may patch any user, including their role_id.
+// PATCH /api/v1/users/:id
+func (h *UserHandler) Patch(c echo.Context) error {
+	// Check authorization
+	userID := c.Get("user_id")
+	if userID == nil {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	payload := new(entity.UserPatchPayload)
+	if err := bindBody(c, payload); err != nil {
 		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
-	result, err := h.userUsecase.Update(id, payload.Name)
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	// Admins may patch anyone; other users only their own profile and never their role
+	if !viewerFromContext(c).IsAdmin {
+		if userID.(int64) != id {
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse("you can only update your own profile"))
+		}
+		if payload.RoleID != nil {
+			return c.JSON(http.StatusForbidden, utils.ErrorResponse("only admins can change roles"))
+		}
+	}
+
+	result, err := h.userUsecase.Patch(c.Request().Context(), id, payload)
 	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"role_id": err.Error()}))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
 	return c.JSON(http.StatusOK, utils.SuccessResponse("user updated successfully", result))
 }
 
-// Delete deletes a user
+// ChangeEmail changes the caller's email address ("me" resolves to the caller). With
+// EMAIL_CHANGE_CONFIRM the change only takes effect once the link emailed to the new
+// address is opened.
+// POST /api/v1/users/:id/email
+func (h *UserHandler) ChangeEmail(c echo.Context) error {
+	// Check authorization
+	userID := c.Get("user_id")
+	if userID == nil {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Check if user is changing their own email
+	if userID.(int64) != id {
+		return c.JSON(http.StatusForbidden, utils.ErrorResponse("you can only change your own email"))
+	}
+
+	payload := new(entity.ChangeEmailPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.ChangeEmail(c.Request().Context(), id, payload.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrEmailDomainNotAllowed), errors.Is(err, usecase.ErrEmailDomainNoMX):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"email": err.Error()}))
+		case errors.Is(err, usecase.ErrEmailTaken):
+			return c.JSON(http.StatusConflict, utils.ValidationErrorResponse(err.Error(), map[string]string{"email": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	if h.cfg.EmailChangeConfirm && result.Email != payload.Email {
+		return c.JSON(http.StatusAccepted, utils.SuccessResponse("check your new email address for a confirmation link", result))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("email updated successfully", result))
+}
+
+// ChangePassword changes the caller's password after verifying the current one ("me" resolves to the caller)
+// POST /api/users/:id/password
+func (h *UserHandler) ChangePassword(c echo.Context) error {
+	// Check authorization
+	userID := c.Get("user_id")
+	if userID == nil {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	// Check if user is changing their own password
+	if userID.(int64) != id {
+		return c.JSON(http.StatusForbidden, utils.ErrorResponse("you can only change your own password"))
+	}
+
+	payload := new(entity.ChangePasswordPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	if err := h.userUsecase.ChangePassword(c.Request().Context(), id, payload); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrIncorrectPassword):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"old_password": err.Error()}))
+		case errors.Is(err, usecase.ErrWeakPassword):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"new_password": err.Error()}))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("password changed successfully", nil))
+}
+
+// Delete deletes a user ("me" resolves to the caller)
 // DELETE /api/users/:id
 func (h *UserHandler) Delete(c echo.Context) error {
 	// Check authorization
@@ -174,9 +640,9 @@ func (h *UserHandler) Delete(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
 	}
 
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := resolveUserID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid user ID"))
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
 	}
 
 	// Check if user is deleting their own account
@@ -184,14 +650,131 @@ func (h *UserHandler) Delete(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, utils.ErrorResponse("you can only delete your own account"))
 	}
 
-	err = h.userUsecase.Delete(id)
+	err = h.userUsecase.Delete(c.Request().Context(), id)
 	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
 		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 	}
 
 	return c.JSON(http.StatusOK, utils.SuccessResponse("user deleted successfully", nil))
 }
 
+// AdminUpdateRole sets any user's role
+// PUT /api/v1/admin/users/:id/role
+func (h *UserHandler) AdminUpdateRole(c echo.Context) error {
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	payload := new(entity.UserRoleUpdatePayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.UpdateRole(c.Request().Context(), id, payload.RoleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusBadRequest, utils.ValidationErrorResponse(err.Error(), map[string]string{"role_id": err.Error()}))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("role updated successfully", result))
+}
+
+// AdminSetStatus activates or suspends any user; admins cannot suspend themselves
+// PUT /api/v1/admin/users/:id/status
+func (h *UserHandler) AdminSetStatus(c echo.Context) error {
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	payload := new(entity.UserStatusPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	if payload.Status == entity.UserStatusSuspended && id == viewerFromContext(c).UserID {
+		return c.JSON(http.StatusForbidden, utils.ErrorResponse("you cannot suspend your own account"))
+	}
+
+	result, err := h.userUsecase.SetStatus(c.Request().Context(), id, payload.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("status updated successfully", result))
+}
+
+// AdminDelete deletes any user
+// DELETE /api/v1/admin/users/:id
+func (h *UserHandler) AdminDelete(c echo.Context) error {
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.userUsecase.AdminDelete(c.Request().Context(), id); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("user deleted successfully", nil))
+}
+
+// AdminRestore undeletes a soft-deleted user
+// POST /api/v1/admin/users/:id/restore
+func (h *UserHandler) AdminRestore(c echo.Context) error {
+	id, err := resolveUserID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	result, err := h.userUsecase.Restore(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrDeletedUserNotFound):
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrEmailTaken):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("user restored successfully", result))
+}
+
 // GetProfile gets current user profile
 // GET /api/profile
 func (h *UserHandler) GetProfile(c echo.Context) error {
@@ -201,10 +784,142 @@ func (h *UserHandler) GetProfile(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
 	}
 
-	result, err := h.userUsecase.GetByID(userID.(int64))
+	result, err := h.userUsecase.GetByID(c.Request().Context(), userID.(int64))
 	if err != nil {
 		return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, utils.SuccessResponse("profile retrieved successfully", result))
+	data, err := selectUserFields(c, result)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("profile retrieved successfully", data))
+}
+
+// BulkAssignRole assigns a role to many users at once
+// POST /api/v1/admin/users/bulk-role?dry_run=true
+func (h *UserHandler) BulkAssignRole(c echo.Context) error {
+	dryRun, err := dryRunParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	payload := new(entity.BulkRoleAssignPayload)
+	if err := bindBody(c, payload); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+	}
+
+	if err := h.validator.Struct(payload); err != nil {
+		return c.JSON(http.StatusBadRequest, h.validationError(c, err))
+	}
+
+	result, err := h.userUsecase.BulkAssignRole(c.Request().Context(), payload, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrLastAdmin):
+			return c.JSON(http.StatusConflict, utils.ErrorResponse(err.Error()))
+		case errors.Is(err, usecase.ErrDryRunUnsupported):
+			return c.JSON(http.StatusNotImplemented, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, utils.SuccessResponse("dry run: no roles were assigned", result))
+	}
+	return c.JSON(http.StatusOK, utils.SuccessResponse("roles assigned successfully", result))
+}
+
+// dryRunParam parses the optional dry_run query parameter of destructive admin operations
+func dryRunParam(c echo.Context) (bool, error) {
+	raw := c.QueryParam("dry_run")
+	if raw == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid dry_run value %q", raw)
+	}
+	return dryRun, nil
+}
+
+// GetStats gets aggregate user statistics
+// GET /api/v1/admin/stats?windows=24h,7d,30d
+func (h *UserHandler) GetStats(c echo.Context) error {
+	var windows []string
+	if raw := c.QueryParam("windows"); raw != "" {
+		windows = strings.Split(raw, ",")
+		for i := range windows {
+			windows[i] = strings.TrimSpace(windows[i])
+		}
+	}
+
+	result, err := h.userUsecase.GetStats(c.Request().Context(), windows)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidStatsWindows) {
+			return c.JSON(http.StatusBadRequest, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("stats retrieved successfully", result))
+}
+
+// GetTrustedDevices lists the caller's trusted devices
+// GET /api/profile/devices
+func (h *UserHandler) GetTrustedDevices(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	result, err := h.userUsecase.ListTrustedDevices(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("trusted devices retrieved successfully", result))
+}
+
+// GetLoginHistory gets a page of the caller's login attempts, newest first
+// GET /api/profile/login-history
+func (h *UserHandler) GetLoginHistory(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	// Pagination params are parsed and validated by PaginationMiddleware
+	result, err := h.userUsecase.GetLoginHistory(c.Request().Context(), userID, middleware.GetPaginationParams(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("login history retrieved successfully", result))
+}
+
+// RevokeTrustedDevice revokes one of the caller's trusted devices
+// DELETE /api/profile/devices/:id
+func (h *UserHandler) RevokeTrustedDevice(c echo.Context) error {
+	userID, ok := c.Get("user_id").(int64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, utils.ErrorResponse("unauthorized"))
+	}
+
+	deviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid device ID"))
+	}
+
+	if err := h.userUsecase.RevokeTrustedDevice(c.Request().Context(), userID, deviceID); err != nil {
+		if errors.Is(err, usecase.ErrTrustedDeviceNotFound) {
+			return c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
+		}
+		return c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, utils.SuccessResponse("trusted device revoked successfully", nil))
 }
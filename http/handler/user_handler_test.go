@@ -0,0 +1,872 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/http/middleware"
+	"echo-base/utils"
+)
+
+func TestMeAlias(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.GET("/users/:id", s.h.GetByID, s.auth)
+	s.e.PUT("/users/:id", s.h.Update, s.auth)
+	s.e.DELETE("/users/:id", s.h.Delete, s.auth)
+
+	caller := s.createUser(t, "caller@example.com", entity.RoleIDUser)
+	s.createUser(t, "other@example.com", entity.RoleIDUser)
+	token := s.token(t, caller)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		token      string
+		wantStatus int
+	}{
+		{name: "get without auth", method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "update without auth", method: http.MethodPut, body: `{"name":"Renamed"}`, wantStatus: http.StatusUnauthorized},
+		{name: "delete without auth", method: http.MethodDelete, wantStatus: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, token: token, wantStatus: http.StatusOK},
+		{name: "update", method: http.MethodPut, body: `{"name":"Renamed"}`, token: token, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(tt.method, "/users/me", tt.body, tt.token)
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var user entity.UserResponse
+			decodeData(t, rec, &user)
+			if user.ID != caller.ID {
+				t.Errorf("user ID = %d, want the caller %d", user.ID, caller.ID)
+			}
+		})
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		expectStatus(t, s.do(http.MethodDelete, "/users/me", "", token), http.StatusOK)
+
+		if user, _ := s.users.GetByID(t.Context(), caller.ID); user != nil {
+			t.Error("caller still exists after DELETE /users/me")
+		}
+		if user, _ := s.users.GetByEmail(t.Context(), "other@example.com"); user == nil {
+			t.Error("another user was deleted by DELETE /users/me")
+		}
+	})
+}
+
+func TestGetByIDFieldSelection(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.GET("/users/:id", s.h.GetByID, s.auth)
+	caller := s.createUser(t, "caller@example.com", entity.RoleIDUser)
+	token := s.token(t, caller)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []string
+	}{
+		{name: "subset", query: "?fields=id,name", wantStatus: http.StatusOK, wantKeys: []string{"id", "name"}},
+		{name: "invalid field", query: "?fields=id,password", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/users/me"+tt.query, "", token)
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var data map[string]interface{}
+			decodeData(t, rec, &data)
+			if len(data) != len(tt.wantKeys) {
+				t.Errorf("fields = %v, want only %v", data, tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := data[key]; !ok {
+					t.Errorf("field %q missing from %v", key, data)
+				}
+			}
+		})
+	}
+}
+
+func TestSignupAttribution(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/register", s.h.Register)
+	s.e.GET("/admin/stats", s.h.GetStats)
+
+	register := func(email, body, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(
+			`{"name":"New User","email":"`+email+`","password":"`+testPassword+`"`+body+`}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if header != "" {
+			req.Header.Set("X-Referral-Source", header)
+		}
+		rec := httptest.NewRecorder()
+		s.e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		header     string
+		wantStatus int
+	}{
+		{name: "from payload", body: `,"referral_source":"newsletter"`, wantStatus: http.StatusCreated},
+		{name: "from header", header: "newsletter", wantStatus: http.StatusCreated},
+		{name: "payload wins over header", body: `,"referral_source":"ads"`, header: "newsletter", wantStatus: http.StatusCreated},
+		{name: "none", wantStatus: http.StatusCreated},
+		{name: "too long", body: `,"referral_source":"` + strings.Repeat("x", 101) + `"`, wantStatus: http.StatusBadRequest},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, register(fmt.Sprintf("user%d@example.com", i), tt.body, tt.header), tt.wantStatus)
+		})
+	}
+
+	rec := s.do(http.MethodGet, "/admin/stats", "", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var stats entity.UserStats
+	decodeData(t, rec, &stats)
+	want := map[string]int64{"newsletter": 2, "ads": 1, "direct": 1}
+	if !reflect.DeepEqual(stats.SignupsBySource, want) {
+		t.Errorf("signups by source = %v, want %v", stats.SignupsBySource, want)
+	}
+	if stats.TotalUsers != 4 {
+		t.Errorf("total users = %d, want 4", stats.TotalUsers)
+	}
+}
+
+func TestGetVCard(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.GET("/users/:id/vcard", s.h.GetVCard, s.auth)
+	owner := s.createUser(t, "owner@example.com", entity.RoleIDUser)
+	other := s.createUser(t, "other@example.com", entity.RoleIDUser)
+	admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+
+	tests := []struct {
+		name       string
+		path       string
+		caller     *entity.User
+		wantStatus int
+		wantEmail  bool
+	}{
+		{name: "owner sees own email", path: "/users/me/vcard", caller: owner, wantStatus: http.StatusOK, wantEmail: true},
+		{name: "admin sees email", path: fmt.Sprintf("/users/%d/vcard", owner.ID), caller: admin, wantStatus: http.StatusOK, wantEmail: true},
+		{name: "other user does not see email", path: fmt.Sprintf("/users/%d/vcard", owner.ID), caller: other, wantStatus: http.StatusOK, wantEmail: false},
+		{name: "unknown user", path: "/users/9999/vcard", caller: owner, wantStatus: http.StatusNotFound},
+		{name: "invalid id", path: "/users/abc/vcard", caller: owner, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, tt.path, "", s.token(t, tt.caller))
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != "text/vcard; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/vcard", ct)
+			}
+			wantDisposition := fmt.Sprintf(`attachment; filename="user-%d.vcf"`, owner.ID)
+			if cd := rec.Header().Get(echo.HeaderContentDisposition); cd != wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", cd, wantDisposition)
+			}
+
+			body := rec.Body.String()
+			lines := strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n")
+			if lines[0] != "BEGIN:VCARD" || lines[1] != "VERSION:4.0" || lines[len(lines)-1] != "END:VCARD" {
+				t.Errorf("vCard = %q, want a BEGIN/VERSION ... END envelope", body)
+			}
+			if !strings.Contains(body, "\r\nFN:"+owner.Name+"\r\n") {
+				t.Errorf("vCard = %q, want FN:%s", body, owner.Name)
+			}
+			if hasEmail := strings.Contains(body, "\r\nEMAIL:"+owner.Email+"\r\n"); hasEmail != tt.wantEmail {
+				t.Errorf("email shown = %v, want %v (vCard %q)", hasEmail, tt.wantEmail, body)
+			}
+		})
+	}
+}
+
+func TestPatchUnknownRole(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.PATCH("/users/:id", s.h.Patch, s.auth)
+	admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+	user := s.createUser(t, "user@example.com", entity.RoleIDUser)
+	token := s.token(t, admin)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "nonexistent role", body: `{"role_id":999}`, wantStatus: http.StatusBadRequest},
+		{name: "existing role", body: fmt.Sprintf(`{"role_id":%d}`, entity.RoleIDAdmin), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPatch, fmt.Sprintf("/users/%d", user.ID), tt.body, token)
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(rec.Body.String(), `"role_id":"role not found"`) {
+				t.Errorf("body = %s, want a role_id validation error", rec.Body)
+			}
+		})
+	}
+}
+
+func TestPatchPartialUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     string
+		target     string
+		body       string
+		wantStatus int
+		wantName   string
+		wantRoleID int64
+	}{
+		{
+			name:       "own name",
+			caller:     "user@example.com",
+			target:     "me",
+			body:       `{"name":"Renamed User"}`,
+			wantStatus: http.StatusOK,
+			wantName:   "Renamed User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "null field is left unchanged",
+			caller:     "user@example.com",
+			target:     "me",
+			body:       `{"name":null}`,
+			wantStatus: http.StatusOK,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "name too short",
+			caller:     "user@example.com",
+			target:     "me",
+			body:       `{"name":"Al"}`,
+			wantStatus: http.StatusBadRequest,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "own role",
+			caller:     "user@example.com",
+			target:     "me",
+			body:       fmt.Sprintf(`{"role_id":%d}`, entity.RoleIDAdmin),
+			wantStatus: http.StatusForbidden,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "another user",
+			caller:     "other@example.com",
+			target:     "user",
+			body:       `{"name":"Renamed User"}`,
+			wantStatus: http.StatusForbidden,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDUser,
+		},
+		{
+			name:       "admin changes role only",
+			caller:     "admin@example.com",
+			target:     "user",
+			body:       fmt.Sprintf(`{"role_id":%d}`, entity.RoleIDAdmin),
+			wantStatus: http.StatusOK,
+			wantName:   "Test User",
+			wantRoleID: entity.RoleIDAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, nil)
+			s.e.PATCH("/users/:id", s.h.Patch, s.auth)
+			callers := map[string]*entity.User{
+				"admin@example.com": s.createUser(t, "admin@example.com", entity.RoleIDAdmin),
+				"user@example.com":  s.createUser(t, "user@example.com", entity.RoleIDUser),
+				"other@example.com": s.createUser(t, "other@example.com", entity.RoleIDUser),
+			}
+			user := callers["user@example.com"]
+
+			target := tt.target
+			if target == "user" {
+				target = fmt.Sprint(user.ID)
+			}
+			rec := s.do(http.MethodPatch, "/users/"+target, tt.body, s.token(t, callers[tt.caller]))
+			expectStatus(t, rec, tt.wantStatus)
+
+			stored, err := s.users.GetByID(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Name != tt.wantName || stored.RoleID != tt.wantRoleID {
+				t.Errorf("name, role = %q, %d; want %q, %d", stored.Name, stored.RoleID, tt.wantName, tt.wantRoleID)
+			}
+		})
+	}
+}
+
+func TestChangeEmailStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		confirm    bool
+		target     string
+		body       string
+		wantStatus int
+		wantEmail  string
+	}{
+		{name: "applied", target: "me", body: `{"email":"new@example.com"}`, wantStatus: http.StatusOK, wantEmail: "new@example.com"},
+		{name: "pending confirmation", confirm: true, target: "me", body: `{"email":"new@example.com"}`, wantStatus: http.StatusAccepted, wantEmail: "user@example.com"},
+		{name: "duplicate", target: "me", body: `{"email":"taken@example.com"}`, wantStatus: http.StatusConflict, wantEmail: "user@example.com"},
+		{name: "invalid", target: "me", body: `{"email":"not-an-email"}`, wantStatus: http.StatusBadRequest, wantEmail: "user@example.com"},
+		{name: "another user", target: "other", body: `{"email":"new@example.com"}`, wantStatus: http.StatusForbidden, wantEmail: "user@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(cfg *config.Config) { cfg.EmailChangeConfirm = tt.confirm })
+			s.e.POST("/users/:id/email", s.h.ChangeEmail, s.auth)
+			user := s.createUser(t, "user@example.com", entity.RoleIDUser)
+			other := s.createUser(t, "taken@example.com", entity.RoleIDUser)
+
+			target := tt.target
+			if target == "other" {
+				target = fmt.Sprint(other.ID)
+			}
+			rec := s.do(http.MethodPost, "/users/"+target+"/email", tt.body, s.token(t, user))
+			expectStatus(t, rec, tt.wantStatus)
+
+			stored, err := s.users.GetByID(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("error getting user: %v", err)
+			}
+			if stored.Email != tt.wantEmail {
+				t.Errorf("email = %q, want %q", stored.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestBulkAssignRoleDryRunParam(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantChanged bool
+	}{
+		{name: "no dry run", query: "", wantStatus: http.StatusOK, wantChanged: true},
+		{name: "dry run disabled", query: "?dry_run=false", wantStatus: http.StatusOK, wantChanged: true},
+		{name: "dry run unsupported by the memory store", query: "?dry_run=true", wantStatus: http.StatusNotImplemented},
+		{name: "invalid value", query: "?dry_run=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, nil)
+			s.e.POST("/admin/users/bulk-role", s.h.BulkAssignRole, s.auth)
+			admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+			user := s.createUser(t, "user@example.com", entity.RoleIDUser)
+
+			body := fmt.Sprintf(`{"user_ids":[%d],"role_id":%d}`, user.ID, entity.RoleIDAdmin)
+			rec := s.do(http.MethodPost, "/admin/users/bulk-role"+tt.query, body, s.token(t, admin))
+			expectStatus(t, rec, tt.wantStatus)
+
+			wantRole := entity.RoleIDUser
+			if tt.wantChanged {
+				wantRole = entity.RoleIDAdmin
+			}
+			updated, _ := s.users.GetByID(context.Background(), user.ID)
+			if updated.RoleID != wantRole {
+				t.Errorf("role = %d, want %d", updated.RoleID, wantRole)
+			}
+		})
+	}
+}
+
+func TestGetAllPaginationMaxOffset(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) { cfg.PaginationMaxOffset = 100 })
+	s.e.GET("/users/pagination", s.h.GetAllPagination, s.auth, middleware.PaginationMiddleware(entity.UserSortFields...))
+	token := s.token(t, s.createUser(t, "caller@example.com", entity.RoleIDUser))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "within the limit", query: "?page=3&limit=50", wantStatus: http.StatusOK},
+		{name: "over the limit", query: "?page=4&limit=50", wantStatus: http.StatusBadRequest},
+		{name: "deep page", query: "?page=1000000&limit=10", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/users/pagination"+tt.query, "", token)
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "narrow the search") {
+				t.Errorf("body = %s, want a hint to narrow the search", rec.Body)
+			}
+		})
+	}
+}
+
+func TestSuggestPassword(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.PasswordMinLength = 20
+		cfg.PasswordRequireUpper = true
+		cfg.PasswordRequireDigit = true
+		cfg.PasswordRequireSymbol = true
+	})
+	s.e.GET("/auth/suggest-password", s.h.SuggestPassword)
+
+	rec := s.do(http.MethodGet, "/auth/suggest-password", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	if cc := rec.Header().Get(echo.HeaderCacheControl); cc != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", cc)
+	}
+
+	var data struct {
+		Password string `json:"password"`
+	}
+	decodeData(t, rec, &data)
+	policy := utils.PasswordPolicy{MinLength: 20, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+	if err := utils.ValidatePassword(data.Password, policy); err != nil {
+		t.Errorf("suggested password %q fails the configured policy: %v", data.Password, err)
+	}
+}
+
+func TestUserFieldVisibility(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.GET("/users/:id", s.h.GetByID, s.auth)
+	owner := s.createUser(t, "owner@example.com", entity.RoleIDUser)
+	stranger := s.createUser(t, "stranger@example.com", entity.RoleIDUser)
+	admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+
+	tests := []struct {
+		name   string
+		viewer *entity.User
+		want   []string
+	}{
+		{name: "admin", viewer: admin, want: []string{"created_at", "email", "email_verified", "id", "name", "role_id", "role_name", "status", "updated_at"}},
+		{name: "owner", viewer: owner, want: []string{"created_at", "email", "email_verified", "id", "name", "role_id", "role_name", "updated_at"}},
+		{name: "stranger", viewer: stranger, want: []string{"created_at", "id", "name", "updated_at"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, fmt.Sprintf("/users/%d", owner.ID), "", s.token(t, tt.viewer))
+			expectStatus(t, rec, http.StatusOK)
+
+			var data map[string]interface{}
+			decodeData(t, rec, &data)
+			got := make([]string, 0, len(data))
+			for key := range data {
+				got = append(got, key)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyRequestBody(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/register", s.h.Register)
+	s.e.POST("/auth/login", s.h.Login)
+	s.e.POST("/auth/forgot-password", s.h.ForgotPassword)
+	s.e.POST("/auth/reset-password", s.h.ResetPassword)
+	s.e.PUT("/users/:id", s.h.Update, s.auth)
+	s.e.PATCH("/users/:id", s.h.Patch, s.auth)
+	s.e.PUT("/users/:id/password", s.h.ChangePassword, s.auth)
+	s.e.POST("/admin/users/bulk-role", s.h.BulkAssignRole, s.auth)
+
+	admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+	token := s.token(t, admin)
+
+	endpoints := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/auth/register"},
+		{http.MethodPost, "/auth/login"},
+		{http.MethodPost, "/auth/forgot-password"},
+		{http.MethodPost, "/auth/reset-password"},
+		{http.MethodPut, "/users/me"},
+		{http.MethodPatch, "/users/me"},
+		{http.MethodPut, "/users/me/password"},
+		{http.MethodPost, "/admin/users/bulk-role"},
+	}
+
+	bodies := []struct {
+		name        string
+		body        string
+		chunked     bool
+		wantMessage string
+	}{
+		{name: "no body", wantMessage: "request body is required"},
+		{name: "chunked empty body", body: " \n", chunked: true, wantMessage: "request body is required"},
+		{name: "malformed body", body: "{", wantMessage: "invalid request body"},
+	}
+
+	for _, endpoint := range endpoints {
+		for _, tt := range bodies {
+			t.Run(endpoint.method+" "+endpoint.path+" "+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(endpoint.method, endpoint.path, strings.NewReader(tt.body))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+				if tt.chunked {
+					req.ContentLength = -1
+				}
+
+				rec := httptest.NewRecorder()
+				s.e.ServeHTTP(rec, req)
+				expectStatus(t, rec, http.StatusBadRequest)
+
+				var resp utils.APIResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("error decoding response: %v", err)
+				}
+				if resp.Message != tt.wantMessage {
+					t.Errorf("message = %q, want %q", resp.Message, tt.wantMessage)
+				}
+			})
+		}
+	}
+}
+
+func TestRegisterWarnings(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/register", s.h.Register)
+
+	tests := []struct {
+		name         string
+		password     string
+		wantStatus   int
+		wantWarnings int
+	}{
+		{name: "strong password", password: "Str0ng-Passphrase", wantStatus: http.StatusCreated, wantWarnings: 0},
+		{name: "short password", password: "Abcdef1!", wantStatus: http.StatusCreated, wantWarnings: 1},
+		{name: "weak but allowed password", password: "abcdefgh", wantStatus: http.StatusCreated, wantWarnings: 2},
+		{name: "password below the policy", password: "abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"name":"New User","email":"user%d@example.com","password":%q}`, i, tt.password)
+			rec := s.do(http.MethodPost, "/auth/register", body, "")
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if !resp.Success {
+				t.Error("success = false, want warnings not to fail the request")
+			}
+			if len(resp.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %d", resp.Warnings, tt.wantWarnings)
+			}
+			if tt.wantWarnings == 0 && strings.Contains(rec.Body.String(), `"warnings"`) {
+				t.Errorf("body %s has a warnings field, want it omitted", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHeadRequests(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.Pre(middleware.HeadMiddleware())
+	s.e.GET("/health", NewHealthHandler(nil, time.Second).Live)
+	s.e.GET("/users/:id", s.h.GetByID, s.auth)
+
+	user := s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	token := s.token(t, user)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "health", path: "/health", wantStatus: http.StatusOK},
+		{name: "user route", path: "/users/me", token: token, wantStatus: http.StatusOK},
+		{name: "user route without auth", path: "/users/me", wantStatus: http.StatusUnauthorized},
+		{name: "missing user", path: "/users/999", token: token, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodHead, tt.path, "", tt.token)
+			expectStatus(t, rec, tt.wantStatus)
+			if rec.Body.Len() != 0 {
+				t.Errorf("body = %q, want none", rec.Body.String())
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != echo.MIMEApplicationJSON {
+				t.Errorf("Content-Type = %q, want %q", ct, echo.MIMEApplicationJSON)
+			}
+		})
+	}
+}
+
+func TestExplainSearch(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.GET("/admin/users/search/explain", s.h.ExplainSearch, s.auth)
+
+	admin := s.createUser(t, "admin@example.com", entity.RoleIDAdmin)
+	s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	s.createUser(t, "alicia@example.com", entity.RoleIDAdmin)
+	token := s.token(t, admin)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantWhere  string
+		wantParams []string
+		wantRows   int64
+	}{
+		{
+			name:       "no filter",
+			wantStatus: http.StatusOK,
+			wantWhere:  "WHERE deleted_at IS NULL",
+			wantParams: []string{},
+			wantRows:   3,
+		},
+		{
+			name:       "search",
+			query:      "search=alic",
+			wantStatus: http.StatusOK,
+			wantWhere:  "WHERE (name ILIKE $1 OR email ILIKE $1) AND deleted_at IS NULL",
+			wantParams: []string{"$1: search pattern"},
+			wantRows:   2,
+		},
+		{
+			name:       "search and role",
+			query:      "search=alic&role_id=1",
+			wantStatus: http.StatusOK,
+			wantWhere:  "WHERE (name ILIKE $1 OR email ILIKE $1) AND role_id = $2 AND deleted_at IS NULL",
+			wantParams: []string{"$1: search pattern", "$2: role_id"},
+			wantRows:   1,
+		},
+		{
+			name:       "created range",
+			query:      "created_after=2000-01-01T00:00:00Z&created_before=2100-01-01T00:00:00Z",
+			wantStatus: http.StatusOK,
+			wantWhere:  "WHERE created_at >= $1 AND created_at < $2 AND deleted_at IS NULL",
+			wantParams: []string{"$1: created_after", "$2: created_before"},
+			wantRows:   3,
+		},
+		{
+			name:       "invalid role",
+			query:      "role_id=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/admin/users/search/explain?"+tt.query, "", token)
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var explain entity.UserSearchExplain
+			decodeData(t, rec, &explain)
+
+			if explain.Where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", explain.Where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(explain.Parameters, tt.wantParams) {
+				t.Errorf("parameters = %v, want %v", explain.Parameters, tt.wantParams)
+			}
+			if explain.EstimatedRows != tt.wantRows {
+				t.Errorf("estimated rows = %d, want %d", explain.EstimatedRows, tt.wantRows)
+			}
+			if strings.Contains(rec.Body.String(), "%alic%") {
+				t.Errorf("response %s contains an interpolated search pattern", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterUsernameErrors(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/register", s.h.Register)
+
+	rec := s.do(http.MethodPost, "/auth/register",
+		fmt.Sprintf(`{"name":"John","email":"john@example.com","username":"JohnDoe","password":%q}`, testPassword), "")
+	expectStatus(t, rec, http.StatusCreated)
+
+	tests := []struct {
+		name       string
+		username   string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "case collision", username: "johndoe", wantStatus: http.StatusConflict, wantError: "username is already taken"},
+		{name: "upper case collision", username: "JOHNDOE", wantStatus: http.StatusConflict, wantError: "username is already taken"},
+		{name: "reserved", username: "Support", wantStatus: http.StatusBadRequest, wantError: "username is reserved"},
+		{name: "available", username: "janedoe", wantStatus: http.StatusCreated},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"name":"Jane","email":"jane%d@example.com","username":%q,"password":%q}`, i, tt.username, testPassword)
+			rec := s.do(http.MethodPost, "/auth/register", body, "")
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantError == "" {
+				return
+			}
+
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if resp.Errors["username"] != tt.wantError {
+				t.Errorf("username error = %q, want %q", resp.Errors["username"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestGetLoginHistory(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.e.POST("/auth/login", s.h.Login)
+	s.e.GET("/profile/login-history", s.h.GetLoginHistory, s.auth, middleware.PaginationMiddleware())
+
+	alice := s.createUser(t, "alice@example.com", entity.RoleIDUser)
+	bob := s.createUser(t, "bob@example.com", entity.RoleIDUser)
+
+	// Alice: three successful logins and one failure; Bob: one successful login
+	attempts := []struct {
+		email    string
+		password string
+	}{
+		{"alice@example.com", testPassword},
+		{"bob@example.com", testPassword},
+		{"alice@example.com", "wrong-password"},
+		{"alice@example.com", testPassword},
+		{"alice@example.com", testPassword},
+	}
+	for _, attempt := range attempts {
+		s.do(http.MethodPost, "/auth/login", fmt.Sprintf(`{"email":%q,"password":%q}`, attempt.email, attempt.password), "")
+	}
+
+	tests := []struct {
+		name        string
+		user        *entity.User
+		query       string
+		wantStatus  int
+		wantEntries int
+		wantTotal   int64
+		wantSuccess []bool
+	}{
+		{name: "first page", user: alice, query: "page=1&limit=3", wantStatus: http.StatusOK, wantEntries: 3, wantTotal: 4, wantSuccess: []bool{true, true, false}},
+		{name: "second page", user: alice, query: "page=2&limit=3", wantStatus: http.StatusOK, wantEntries: 1, wantTotal: 4, wantSuccess: []bool{true}},
+		{name: "only the caller's history", user: bob, wantStatus: http.StatusOK, wantEntries: 1, wantTotal: 1, wantSuccess: []bool{true}},
+		{name: "invalid page", user: alice, query: "page=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/profile/login-history?"+tt.query, "", s.token(t, tt.user))
+			expectStatus(t, rec, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var history entity.PaginatedLoginHistoryResponse
+			decodeData(t, rec, &history)
+
+			if len(history.Data) != tt.wantEntries || history.Pagination.Total != tt.wantTotal {
+				t.Fatalf("entries, total = %d, %d; want %d, %d", len(history.Data), history.Pagination.Total, tt.wantEntries, tt.wantTotal)
+			}
+			for i, entry := range history.Data {
+				if entry.UserID != tt.user.ID {
+					t.Errorf("entry %d belongs to user %d, want %d", entry.ID, entry.UserID, tt.user.ID)
+				}
+				if entry.Success != tt.wantSuccess[i] {
+					t.Errorf("entry %d success = %v, want %v", i, entry.Success, tt.wantSuccess[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidationErrorLanguage(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.ValidationLocales = []string{"en", "es", "fr"}
+		cfg.ValidationDefaultLocale = "en"
+	})
+	s.e.POST("/auth/register", s.h.Register)
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantLanguage   string
+		wantEmailError string
+	}{
+		{name: "spanish", acceptLanguage: "es-ES", wantLanguage: "es", wantEmailError: "email debe ser una dirección de correo electrónico válida"},
+		{name: "french", acceptLanguage: "fr", wantLanguage: "fr", wantEmailError: "email doit être une adresse email valide"},
+		{name: "unsupported", acceptLanguage: "de", wantLanguage: "en", wantEmailError: "must be a valid email"},
+		{name: "no header", wantLanguage: "en", wantEmailError: "must be a valid email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"name":"Jane","email":"not-an-email","password":%q}`, testPassword)
+			req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			s.e.ServeHTTP(rec, req)
+			expectStatus(t, rec, http.StatusBadRequest)
+
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if resp.Errors["email"] != tt.wantEmailError {
+				t.Errorf("email error = %q, want %q", resp.Errors["email"], tt.wantEmailError)
+			}
+		})
+	}
+}
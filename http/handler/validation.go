@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/utils"
+)
+
+// payloadValidator validates request payloads and renders their errors, capped at the
+// configured maximum, in the language negotiated from Accept-Language
+type payloadValidator struct {
+	validator  *validator.Validate
+	translator *utils.ValidationTranslator
+	maxErrors  int
+}
+
+// newPayloadValidator creates a payload validator, registering validation translations
+// for the configured locales
+func newPayloadValidator(cfg *config.Config) (payloadValidator, error) {
+	v := utils.NewValidator()
+	translator, err := utils.NewValidationTranslator(v, cfg.ValidationLocales, cfg.ValidationDefaultLocale)
+	if err != nil {
+		return payloadValidator{}, err
+	}
+
+	return payloadValidator{
+		validator:  v,
+		translator: translator,
+		maxErrors:  cfg.ValidationMaxErrors,
+	}, nil
+}
+
+// validationError builds the response for a payload that failed validation, with field
+// messages in the language negotiated from Accept-Language
+func (v payloadValidator) validationError(c echo.Context, err error) utils.APIResponse {
+	locale, trans := v.translator.Translator(c.Request().Header.Get("Accept-Language"))
+	c.Response().Header().Set("Content-Language", locale)
+	return utils.ValidationFailedResponse(err, v.maxErrors, trans)
+}
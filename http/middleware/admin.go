@@ -1,71 +1,91 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/labstack/echo/v4"
 
+	"echo-base/domain/entity"
 	"echo-base/utils"
 )
 
-// AdminRoleMiddleware validates if user has admin role
-func AdminRoleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
-			return echo.NewHTTPError(401, "missing authorization header")
-		}
+// RequireRoles allows only users whose role is one of roleIDs. It must run after the auth middleware.
+func RequireRoles(roleIDs ...int64) echo.MiddlewareFunc {
+	allowed := make(map[int64]bool, len(roleIDs))
+	for _, id := range roleIDs {
+		allowed[id] = true
+	}
 
-		// Extract token from Bearer <token>
-		parts := len(c.Request().Header.Values("Authorization"))
-		if parts == 0 {
-			return echo.NewHTTPError(401, "missing authorization header")
-		}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Get claims from context (set by the auth middleware)
+			userID := c.Get("user_id")
+			roleID, ok := c.Get("role_id").(int64)
 
-		// Get token from context (set by BearerAuthMiddleware)
-		userID := c.Get("user_id")
-		roleID := c.Get("role_id")
+			if userID == nil || !ok {
+				return echo.NewHTTPError(401, "unauthorized")
+			}
 
-		if userID == nil || roleID == nil {
-			return echo.NewHTTPError(401, "unauthorized")
-		}
+			if !allowed[roleID] {
+				return echo.NewHTTPError(403, "you don't have permission to access this resource")
+			}
 
-		// Check if role_id is 2 (admin role)
-		if roleID != int64(2) {
-			return echo.NewHTTPError(403, "you don't have permission to access this resource")
+			return next(c)
 		}
+	}
+}
 
-		return next(c)
+// RolesAvailableMiddleware responds 503 while check reports that the roles the
+// request depends on cannot be resolved, instead of failing later in the handler
+func RolesAvailableMiddleware(check func(ctx context.Context) error) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := check(c.Request().Context()); err != nil {
+				c.Logger().Errorf("roles unavailable: %v", err)
+				return echo.NewHTTPError(503, "roles are unavailable; try again later")
+			}
+			return next(c)
+		}
 	}
 }
 
+// AdminRoleMiddleware validates if user has admin role. It must run after the auth middleware.
+func AdminRoleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return RequireRoles(entity.RoleIDAdmin)(next)
+}
+
 // BearerAuthMiddlewareWithRole validates bearer token and extracts role
-func BearerAuthMiddlewareWithRole(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
-			return echo.NewHTTPError(401, "missing authorization header")
-		}
+func BearerAuthMiddlewareWithRole(signer *utils.TokenSigner) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return echo.NewHTTPError(401, "missing authorization header")
+			}
 
-		// Parse Bearer token
-		var token string
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			return echo.NewHTTPError(401, "invalid authorization header format")
-		}
+			// Parse Bearer token
+			var token string
+			if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+				token = authHeader[7:]
+			} else {
+				return echo.NewHTTPError(401, "invalid authorization header format")
+			}
 
-		// Validate token
-		claims, err := utils.ValidateToken(token)
-		if err != nil {
-			return echo.NewHTTPError(401, fmt.Sprintf("invalid token: %v", err))
-		}
+			// Validate token
+			claims, err := signer.ValidateToken(token)
+			if err != nil {
+				return echo.NewHTTPError(401, fmt.Sprintf("invalid token: %v", err))
+			}
 
-		// Store claims in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("role_id", claims.RoleID)
+			// Store claims in context
+			c.Set("user_id", claims.UserID)
+			c.Set("user_email", claims.Email)
+			c.Set("role_id", claims.RoleID)
+			c.Set("session_id", claims.SessionID)
+			c.Set("token_scope", claims.TokenScope())
 
-		return next(c)
+			return next(c)
+		}
 	}
 }
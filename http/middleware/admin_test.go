@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+)
+
+// serveWithClaims serves GET /test behind middleware after setting the claims the auth
+// middleware would; nil claims are left unset
+func serveWithClaims(userID, roleID interface{}, middleware echo.MiddlewareFunc) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.GET("/test", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if userID != nil {
+				c.Set("user_id", userID)
+			}
+			if roleID != nil {
+				c.Set("role_id", roleID)
+			}
+			return next(c)
+		}
+	}, middleware)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	return rec
+}
+
+func TestRequireRoles(t *testing.T) {
+	const roleIDModerator int64 = 3
+
+	tests := []struct {
+		name       string
+		allowed    []int64
+		userID     interface{}
+		roleID     interface{}
+		wantStatus int
+	}{
+		{name: "allowed role", allowed: []int64{entity.RoleIDAdmin}, userID: int64(1), roleID: entity.RoleIDAdmin, wantStatus: http.StatusOK},
+		{name: "one of several allowed roles", allowed: []int64{entity.RoleIDAdmin, roleIDModerator}, userID: int64(1), roleID: roleIDModerator, wantStatus: http.StatusOK},
+		{name: "forbidden role", allowed: []int64{entity.RoleIDAdmin}, userID: int64(1), roleID: entity.RoleIDUser, wantStatus: http.StatusForbidden},
+		{name: "no roles allowed", allowed: nil, userID: int64(1), roleID: entity.RoleIDAdmin, wantStatus: http.StatusForbidden},
+		{name: "missing role in context", allowed: []int64{entity.RoleIDAdmin}, userID: int64(1), wantStatus: http.StatusUnauthorized},
+		{name: "role of the wrong type", allowed: []int64{entity.RoleIDAdmin}, userID: int64(1), roleID: "2", wantStatus: http.StatusUnauthorized},
+		{name: "missing user in context", allowed: []int64{entity.RoleIDAdmin}, roleID: entity.RoleIDAdmin, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithClaims(tt.userID, tt.roleID, RequireRoles(tt.allowed...))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAdminRoleMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		roleID     interface{}
+		wantStatus int
+	}{
+		{name: "admin", roleID: entity.RoleIDAdmin, wantStatus: http.StatusOK},
+		{name: "user", roleID: entity.RoleIDUser, wantStatus: http.StatusForbidden},
+		{name: "missing role", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithClaims(int64(1), tt.roleID, AdminRoleMiddleware)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRolesAvailableMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{name: "roles available", wantStatus: http.StatusOK},
+		{name: "roles unavailable", checkErr: errors.New("role table empty"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithClaims(nil, nil, RolesAvailableMiddleware(func(ctx context.Context) error { return tt.checkErr }))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
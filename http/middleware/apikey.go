@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/domain/usecase"
+	"echo-base/utils"
+)
+
+// APIKeyHeader carries an API key in place of a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// API key scopes needed for safe (read) and all other (write) requests
+const (
+	apiKeyScopeRead  = "read"
+	apiKeyScopeWrite = "write"
+)
+
+// APIKeyAuthenticator looks up the API key sent by a caller and the user it belongs to
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*entity.APIKey, *entity.User, error)
+}
+
+// APIKeyAuthMiddleware authenticates requests carrying an X-API-Key header as the key's
+// user, and hands every other request to bearer. GET and HEAD requests need a key with
+// the read scope and all other requests one with the write scope. API key requests get
+// the ScopeAPIKey token scope and no session.
+func APIKeyAuthMiddleware(keys APIKeyAuthenticator, bearer echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		viaBearer := bearer(next)
+
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(APIKeyHeader)
+			if key == "" {
+				return viaBearer(c)
+			}
+
+			apiKey, user, err := keys.AuthenticateAPIKey(c.Request().Context(), key)
+			if err != nil {
+				switch {
+				case errors.Is(err, usecase.ErrInvalidAPIKey):
+					return echo.NewHTTPError(401, err.Error())
+				case errors.Is(err, usecase.ErrAccountSuspended):
+					return echo.NewHTTPError(403, err.Error())
+				}
+				return echo.NewHTTPError(500, "error checking api key")
+			}
+
+			required := apiKeyScopeWrite
+			if method := c.Request().Method; method == http.MethodGet || method == http.MethodHead {
+				required = apiKeyScopeRead
+			}
+			if !hasScope(apiKey.Scopes, required) {
+				return echo.NewHTTPError(403, fmt.Sprintf("api key needs the %q scope for this request", required))
+			}
+
+			c.Set("user_id", user.ID)
+			c.Set("user_email", user.Email)
+			c.Set("role_id", user.RoleID)
+			c.Set("session_id", "")
+			c.Set("token_scope", utils.ScopeAPIKey)
+			c.Set("api_key_id", apiKey.ID)
+
+			return next(c)
+		}
+	}
+}
+
+// hasScope reports whether scopes contains scope
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -1,69 +1,181 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"echo-base/config"
+	"echo-base/domain/entity"
 	"echo-base/utils"
 )
 
+// SessionStore is the session storage used by IdleSessionMiddleware
+type SessionStore interface {
+	GetByID(ctx context.Context, id string) (*entity.Session, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// UserStore is the user lookup used by UserStatusMiddleware
+type UserStore interface {
+	GetByID(ctx context.Context, id int64) (*entity.User, error)
+}
+
 // BearerAuthMiddleware validates bearer token in Authorization header
-func BearerAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
-			return echo.NewHTTPError(401, "missing authorization header")
-		}
+func BearerAuthMiddleware(signer *utils.TokenSigner) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return echo.NewHTTPError(401, "missing authorization header")
+			}
 
-		// Extract token from Bearer <token>
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			return echo.NewHTTPError(401, "invalid authorization header format")
-		}
+			// Extract token from Bearer <token>
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				return echo.NewHTTPError(401, "invalid authorization header format")
+			}
+
+			token := parts[1]
 
-		token := parts[1]
+			// Validate token
+			claims, err := signer.ValidateToken(token)
+			if err != nil {
+				return echo.NewHTTPError(401, fmt.Sprintf("invalid token: %v", err))
+			}
 
-		// Validate token
-		claims, err := utils.ValidateToken(token)
-		if err != nil {
-			return echo.NewHTTPError(401, fmt.Sprintf("invalid token: %v", err))
+			// Store claims in context
+			c.Set("user_id", claims.UserID)
+			c.Set("user_email", claims.Email)
+			c.Set("role_id", claims.RoleID)
+			c.Set("session_id", claims.SessionID)
+			c.Set("token_scope", claims.TokenScope())
+
+			return next(c)
 		}
+	}
+}
 
-		// Store claims in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("role_id", claims.RoleID)
+// AuthMiddleware returns BearerAuthMiddleware, or outside production with AUTH_DISABLED set
+// a middleware that authenticates every request as the configured fake user
+func AuthMiddleware(cfg *config.Config, signer *utils.TokenSigner) echo.MiddlewareFunc {
+	if !cfg.AuthDisabled || cfg.IsProduction() {
+		return BearerAuthMiddleware(signer)
+	}
 
-		return next(c)
+	log.Printf("WARNING: authentication is disabled; all protected requests act as user %d (role %d)\n",
+		cfg.AuthDisabledUserID, cfg.AuthDisabledRoleID)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", cfg.AuthDisabledUserID)
+			c.Set("user_email", cfg.AuthDisabledEmail)
+			c.Set("role_id", cfg.AuthDisabledRoleID)
+			c.Set("session_id", "")
+			c.Set("token_scope", utils.ScopeAccess)
+
+			return next(c)
+		}
 	}
 }
 
 // OptionalBearerAuthMiddleware validates bearer token if provided
-func OptionalBearerAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
+func OptionalBearerAuthMiddleware(signer *utils.TokenSigner) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return next(c)
+			}
+
+			// Extract token from Bearer <token>
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				return next(c)
+			}
+
+			token := parts[1]
+
+			// Validate token; only access tokens identify the caller
+			claims, err := signer.ValidateToken(token)
+			if err == nil && claims.TokenScope() == utils.ScopeAccess {
+				c.Set("user_id", claims.UserID)
+				c.Set("user_email", claims.Email)
+				c.Set("role_id", claims.RoleID)
+				c.Set("session_id", claims.SessionID)
+				c.Set("token_scope", claims.TokenScope())
+			}
+
 			return next(c)
 		}
+	}
+}
+
+// IdleSessionMiddleware rejects tokens whose session has been idle longer than idleTimeout.
+// Last activity is written at most once per writeInterval to avoid a write per request.
+// API key requests have no session and are let through. It must run after
+// BearerAuthMiddleware.
+func IdleSessionMiddleware(sessions SessionStore, idleTimeout, writeInterval time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if scope, _ := c.Get("token_scope").(string); scope == utils.ScopeAPIKey {
+				return next(c)
+			}
+
+			sessionID, _ := c.Get("session_id").(string)
+			if sessionID == "" {
+				return echo.NewHTTPError(401, "session expired, please log in again")
+			}
+
+			session, err := sessions.GetByID(c.Request().Context(), sessionID)
+			if err != nil {
+				return echo.NewHTTPError(500, "error checking session")
+			}
+			if session == nil {
+				return echo.NewHTTPError(401, "session expired, please log in again")
+			}
+
+			now := time.Now()
+			idle := now.Sub(session.LastActivityAt)
+			if idle > idleTimeout {
+				return echo.NewHTTPError(401, "session expired due to inactivity")
+			}
+
+			if idle >= writeInterval {
+				if err := sessions.Touch(c.Request().Context(), sessionID, now); err != nil {
+					log.Printf("error updating session activity: %v\n", err)
+				}
+			}
 
-		// Extract token from Bearer <token>
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
 			return next(c)
 		}
+	}
+}
 
-		token := parts[1]
+// UserStatusMiddleware rejects tokens of users that have been deleted or suspended since
+// the token was issued. It looks the user up on every request and must run after
+// BearerAuthMiddleware.
+func UserStatusMiddleware(users UserStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, _ := c.Get("user_id").(int64)
 
-		// Validate token
-		claims, err := utils.ValidateToken(token)
-		if err == nil {
-			c.Set("user_id", claims.UserID)
-			c.Set("user_email", claims.Email)
-			c.Set("role_id", claims.RoleID)
-		}
+			user, err := users.GetByID(c.Request().Context(), userID)
+			if err != nil {
+				return echo.NewHTTPError(500, "error checking user")
+			}
+			if user == nil {
+				return echo.NewHTTPError(401, "user no longer exists, please log in again")
+			}
+			if user.Status == entity.UserStatusSuspended {
+				return echo.NewHTTPError(403, "account is suspended; contact an administrator")
+			}
 
-		return next(c)
+			return next(c)
+		}
 	}
 }
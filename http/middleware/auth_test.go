@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+// fakeSessions is an in-memory SessionStore that counts activity writes
+type fakeSessions struct {
+	sessions map[string]*entity.Session
+	touches  int
+}
+
+func (f *fakeSessions) GetByID(_ context.Context, id string) (*entity.Session, error) {
+	return f.sessions[id], nil
+}
+
+func (f *fakeSessions) Touch(_ context.Context, id string, at time.Time) error {
+	f.touches++
+	f.sessions[id].LastActivityAt = at
+	return nil
+}
+
+func TestIdleSessionMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		sessionID   string
+		idle        time.Duration
+		scope       string
+		wantStatus  int
+		wantTouches int
+	}{
+		{name: "active session within write interval", sessionID: "s1", idle: 10 * time.Second, wantStatus: http.StatusOK, wantTouches: 0},
+		{name: "active session past write interval is touched", sessionID: "s1", idle: 5 * time.Minute, wantStatus: http.StatusOK, wantTouches: 1},
+		{name: "idle session is rejected", sessionID: "s1", idle: 31 * time.Minute, wantStatus: http.StatusUnauthorized},
+		{name: "unknown session is rejected", sessionID: "missing", wantStatus: http.StatusUnauthorized},
+		{name: "token without session is rejected", sessionID: "", wantStatus: http.StatusUnauthorized},
+		{name: "api key request skips the check", scope: utils.ScopeAPIKey, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeSessions{sessions: map[string]*entity.Session{
+				"s1": {ID: "s1", UserID: 1, LastActivityAt: time.Now().Add(-tt.idle)},
+			}}
+
+			e := echo.New()
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set("session_id", tt.sessionID)
+					c.Set("token_scope", tt.scope)
+					return next(c)
+				}
+			}, IdleSessionMiddleware(store, 30*time.Minute, time.Minute))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if store.touches != tt.wantTouches {
+				t.Errorf("touches = %d, want %d", store.touches, tt.wantTouches)
+			}
+		})
+	}
+}
+
+func TestIdleSessionMiddlewareActivityKeepsSessionAlive(t *testing.T) {
+	store := &fakeSessions{sessions: map[string]*entity.Session{
+		"s1": {ID: "s1", UserID: 1, LastActivityAt: time.Now().Add(-50 * time.Millisecond)},
+	}}
+
+	e := echo.New()
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("session_id", "s1")
+			return next(c)
+		}
+	}, IdleSessionMiddleware(store, 100*time.Millisecond, 0))
+
+	// Each request lands inside the idle window measured from the one before it, so the
+	// session outlives the window measured from its first activity.
+	for i := range 4 {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, http.StatusOK)
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status after going idle = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthMiddlewareAuthDisabled(t *testing.T) {
+	signer := utils.NewTokenSigner("test-secret", nil, time.Hour, 24*time.Hour, "")
+	token, err := signer.GenerateToken(42, "real@example.com", entity.RoleIDUser, "", "")
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		appEnv       string
+		authDisabled bool
+		token        string
+		wantStatus   int
+		wantUserID   int64
+		wantRoleID   int64
+	}{
+		{name: "disabled in development", appEnv: "development", authDisabled: true, wantStatus: http.StatusOK, wantUserID: 7, wantRoleID: entity.RoleIDAdmin},
+		{name: "disabled in development ignores tokens", appEnv: "development", authDisabled: true, token: token, wantStatus: http.StatusOK, wantUserID: 7, wantRoleID: entity.RoleIDAdmin},
+		{name: "enabled in development", appEnv: "development", authDisabled: false, wantStatus: http.StatusUnauthorized},
+		{name: "ignored in production", appEnv: "production", authDisabled: true, wantStatus: http.StatusUnauthorized},
+		{name: "ignored in production with token", appEnv: "production", authDisabled: true, token: token, wantStatus: http.StatusOK, wantUserID: 42, wantRoleID: entity.RoleIDUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.AppEnv = tt.appEnv
+			cfg.AuthDisabled = tt.authDisabled
+			cfg.AuthDisabledUserID = 7
+			cfg.AuthDisabledEmail = "fake@example.com"
+			cfg.AuthDisabledRoleID = entity.RoleIDAdmin
+
+			var gotUserID, gotRoleID int64
+			e := echo.New()
+			e.GET("/protected", func(c echo.Context) error {
+				gotUserID, _ = c.Get("user_id").(int64)
+				gotRoleID, _ = c.Get("role_id").(int64)
+				return c.NoContent(http.StatusOK)
+			}, AuthMiddleware(cfg, signer))
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if gotUserID != tt.wantUserID || gotRoleID != tt.wantRoleID {
+				t.Errorf("user/role = %d/%d, want %d/%d", gotUserID, gotRoleID, tt.wantUserID, tt.wantRoleID)
+			}
+		})
+	}
+}
+
+// fakeUsers is an in-memory UserStore
+type fakeUsers struct {
+	users map[int64]*entity.User
+	err   error
+}
+
+func (f *fakeUsers) GetByID(_ context.Context, id int64) (*entity.User, error) {
+	return f.users[id], f.err
+}
+
+func TestUserStatusMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     int64
+		err        error
+		wantStatus int
+	}{
+		{name: "active", userID: 1, wantStatus: http.StatusOK},
+		{name: "suspended", userID: 2, wantStatus: http.StatusForbidden},
+		{name: "deleted", userID: 3, wantStatus: http.StatusUnauthorized},
+		{name: "lookup error", userID: 1, err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{
+				users: map[int64]*entity.User{
+					1: {ID: 1, Status: entity.UserStatusActive},
+					2: {ID: 2, Status: entity.UserStatusSuspended},
+				},
+				err: tt.err,
+			}
+
+			e := echo.New()
+			e.GET("/protected", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set("user_id", tt.userID)
+					return next(c)
+				}
+			}, UserStatusMiddleware(users))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/protected", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
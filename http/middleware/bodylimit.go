@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/utils"
+)
+
+// BodyLimitMiddleware rejects requests whose body is larger than limit bytes with 413.
+// routeLimits overrides the limit for individual routes, keyed by route path such as
+// "/api/v1/users/:id". Register it with e.Use so the route is known when it runs.
+func BodyLimitMiddleware(limit int64, routeLimits map[string]int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			max := limit
+			if routeLimit, ok := routeLimits[c.Path()]; ok {
+				max = routeLimit
+			}
+
+			req := c.Request()
+			if req.ContentLength > max {
+				return bodyTooLarge(c, max)
+			}
+
+			// Chunked bodies have no Content-Length; read up to the limit to find out
+			if req.ContentLength < 0 && req.Body != nil && req.Body != http.NoBody {
+				body, err := io.ReadAll(io.LimitReader(req.Body, max+1))
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, utils.ErrorResponse("invalid request body"))
+				}
+				if int64(len(body)) > max {
+					return bodyTooLarge(c, max)
+				}
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// bodyTooLarge responds 413 and closes the connection so the rest of the body is not read
+func bodyTooLarge(c echo.Context, max int64) error {
+	c.Response().Header().Set(echo.HeaderConnection, "close")
+	return c.JSON(http.StatusRequestEntityTooLarge,
+		utils.ErrorResponse("request body must not exceed "+strconv.FormatInt(max, 10)+" bytes"))
+}
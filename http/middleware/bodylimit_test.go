@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		size       int
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "within the limit", path: "/users", size: 64, wantStatus: http.StatusOK},
+		{name: "at the limit", path: "/users", size: 100, wantStatus: http.StatusOK},
+		{name: "oversized", path: "/users", size: 101, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked within the limit", path: "/users", size: 64, chunked: true, wantStatus: http.StatusOK},
+		{name: "chunked oversized", path: "/users", size: 500, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "route with a higher limit", path: "/uploads", size: 500, wantStatus: http.StatusOK},
+		{name: "route with a lower limit", path: "/users/:id", size: 64, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(BodyLimitMiddleware(100, map[string]int64{"/uploads": 1000, "/users/:id": 10}))
+			echoLength := func(c echo.Context) error {
+				body, err := io.ReadAll(c.Request().Body)
+				if err != nil {
+					return err
+				}
+				return c.String(http.StatusOK, strconv.Itoa(len(body)))
+			}
+			e.POST("/users", echoLength)
+			e.POST("/users/:id", echoLength)
+			e.POST("/uploads", echoLength)
+
+			path := strings.Replace(tt.path, ":id", "1", 1)
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("a", tt.size)))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge {
+				if rec.Header().Get(echo.HeaderConnection) != "close" {
+					t.Error("connection is kept open after rejecting the body")
+				}
+				return
+			}
+			// The handler still reads the whole body
+			if rec.Body.String() != strconv.Itoa(tt.size) {
+				t.Errorf("handler read %s bytes, want %d", rec.Body.String(), tt.size)
+			}
+		})
+	}
+}
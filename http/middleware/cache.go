@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// noStoreCacheControl keeps authenticated responses out of shared and browser caches
+const noStoreCacheControl = "no-store, private"
+
+// NoStoreMiddleware marks responses as not cacheable. The header is set before the
+// handler runs, so a route can override it by setting its own Cache-Control.
+func NoStoreMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, noStoreCacheControl)
+		return next(c)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNoStoreMiddleware(t *testing.T) {
+	e := echo.New()
+	protected := e.Group("/protected", NoStoreMiddleware)
+	protected.GET("/profile", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"email": "alice@example.com"})
+	})
+	protected.GET("/avatar", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=60")
+		return c.String(http.StatusOK, "image")
+	})
+	protected.GET("/error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden)
+	})
+	e.GET("/public", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       string
+	}{
+		{name: "protected response", path: "/protected/profile", wantStatus: http.StatusOK, want: "no-store, private"},
+		{name: "route override", path: "/protected/avatar", wantStatus: http.StatusOK, want: "private, max-age=60"},
+		{name: "protected error", path: "/protected/error", wantStatus: http.StatusForbidden, want: "no-store, private"},
+		{name: "public response", path: "/public", wantStatus: http.StatusOK, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(echo.HeaderCacheControl); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// CompressionMiddleware gzips responses for clients whose Accept-Encoding allows it.
+// Bodies shorter than minLength are sent uncompressed, and so are responses whose
+// Content-Type starts with one of excludedTypes or that already carry a Content-Encoding.
+func CompressionMiddleware(level, minLength int, excludedTypes []string) echo.MiddlewareFunc {
+	gzip := middleware.GzipWithConfig(middleware.GzipConfig{
+		Level:     level,
+		MinLength: minLength,
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return gzip(func(c echo.Context) error {
+			// The gzip middleware only swaps the writer when the client accepts gzip
+			if !strings.Contains(c.Request().Header.Get(echo.HeaderAcceptEncoding), "gzip") {
+				return next(c)
+			}
+
+			res := c.Response()
+			unwrapper, ok := res.Writer.(interface{ Unwrap() http.ResponseWriter })
+			if !ok {
+				return next(c)
+			}
+			res.Writer = &compressionWriter{
+				gzip:          res.Writer,
+				raw:           unwrapper.Unwrap(),
+				excludedTypes: excludedTypes,
+			}
+			return next(c)
+		})
+	}
+}
+
+// compressionWriter sits between a handler and the gzip writer and decides, once the
+// response headers are known, whether the body goes through gzip or straight to the client
+type compressionWriter struct {
+	gzip          http.ResponseWriter
+	raw           http.ResponseWriter
+	excludedTypes []string
+	target        http.ResponseWriter
+}
+
+// Header returns the response headers, shared by both writers
+func (w *compressionWriter) Header() http.Header {
+	return w.raw.Header()
+}
+
+// WriteHeader picks the writer and sends the status through it
+func (w *compressionWriter) WriteHeader(code int) {
+	w.choose().WriteHeader(code)
+}
+
+// Write picks the writer and sends the body through it
+func (w *compressionWriter) Write(b []byte) (int, error) {
+	return w.choose().Write(b)
+}
+
+// choose returns the writer for this response, deciding on the first call. Bypassing gzip
+// entirely leaves it with nothing written, so it adds no headers and sends no body.
+func (w *compressionWriter) choose() http.ResponseWriter {
+	if w.target != nil {
+		return w.target
+	}
+
+	w.target = w.gzip
+	if w.Header().Get(echo.HeaderContentEncoding) != "" || w.excluded(w.Header().Get(echo.HeaderContentType)) {
+		w.target = w.raw
+	}
+	return w.target
+}
+
+// excluded reports whether contentType starts with one of the excluded types
+func (w *compressionWriter) excluded(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range w.excludedTypes {
+		if prefix != "" && strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush flushes the chosen writer
+func (w *compressionWriter) Flush() {
+	if err := http.NewResponseController(w.choose()).Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// Hijack hands over the underlying connection
+func (w *compressionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.raw).Hijack()
+}
+
+// Unwrap returns the chosen writer for http.ResponseController
+func (w *compressionWriter) Unwrap() http.ResponseWriter {
+	return w.choose()
+}
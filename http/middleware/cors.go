@@ -1,17 +1,25 @@
 package middleware
 
 import (
+	"slices"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-// CORSMiddleware returns CORS middleware configuration
-func CORSMiddleware() echo.MiddlewareFunc {
+// CORSMiddleware returns CORS middleware allowing the given origins, methods and headers.
+// Without origins any origin is allowed, and credentials are then disabled since
+// browsers reject credentialed responses for a wildcard origin.
+func CORSMiddleware(origins, methods, headers []string) echo.MiddlewareFunc {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
 	return middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowOrigins:     origins,
+		AllowMethods:     methods,
+		AllowHeaders:     headers,
 		ExposeHeaders:    []string{"Content-Length", "Authorization"},
-		AllowCredentials: true,
+		AllowCredentials: !slices.Contains(origins, "*"),
 	})
 }
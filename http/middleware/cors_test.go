@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://admin.example.com"}
+	methods := []string{http.MethodGet, http.MethodPost}
+	headers := []string{"Content-Type", "Authorization"}
+
+	tests := []struct {
+		name            string
+		origins         []string
+		method          string
+		origin          string
+		wantOrigin      string
+		wantCredentials bool
+		wantMethods     string
+	}{
+		{name: "allowed origin", origins: allowed, method: http.MethodGet, origin: "https://admin.example.com", wantOrigin: "https://admin.example.com", wantCredentials: true},
+		{name: "disallowed origin", origins: allowed, method: http.MethodGet, origin: "https://evil.example.com", wantOrigin: ""},
+		{name: "allowed preflight", origins: allowed, method: http.MethodOptions, origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantCredentials: true, wantMethods: "GET,POST"},
+		{name: "disallowed preflight", origins: allowed, method: http.MethodOptions, origin: "https://evil.example.com", wantOrigin: ""},
+		{name: "any origin without credentials", origins: nil, method: http.MethodGet, origin: "https://evil.example.com", wantOrigin: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(CORSMiddleware(tt.origins, methods, headers))
+			e.GET("/users", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/users", nil)
+			req.Header.Set(echo.HeaderOrigin, tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get(echo.HeaderAccessControlAllowCredentials) == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+			if got := rec.Header().Get(echo.HeaderAccessControlAllowMethods); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+		})
+	}
+}
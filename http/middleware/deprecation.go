@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Deprecate marks a route as deprecated by setting the Deprecation and Sunset
+// response headers (IETF draft-ietf-httpapi-deprecation-header, RFC 8594)
+func Deprecate(sunset time.Time) echo.MiddlewareFunc {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set("Deprecation", "true")
+			c.Response().Header().Set("Sunset", sunsetHeader)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeprecate(t *testing.T) {
+	sunset := time.Date(2027, time.January, 31, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name           string
+		path           string
+		status         int
+		wantDeprecated bool
+	}{
+		{name: "deprecated route", path: "/legacy", status: http.StatusOK, wantDeprecated: true},
+		{name: "deprecated route error", path: "/legacy-error", status: http.StatusBadRequest, wantDeprecated: true},
+		{name: "current route", path: "/current", status: http.StatusOK, wantDeprecated: false},
+	}
+
+	e := echo.New()
+	e.GET("/legacy", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, Deprecate(sunset))
+	e.GET("/legacy-error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}, Deprecate(sunset))
+	e.GET("/current", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			wantDeprecation, wantSunset := "", ""
+			if tt.wantDeprecated {
+				wantDeprecation, wantSunset = "true", "Sun, 31 Jan 2027 11:00:00 GMT"
+			}
+			if got := rec.Header().Get("Deprecation"); got != wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, wantDeprecation)
+			}
+			if got := rec.Header().Get("Sunset"); got != wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, wantSunset)
+			}
+		})
+	}
+}
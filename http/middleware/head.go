@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeadMiddleware serves HEAD requests with the matching GET route, sending its
+// status and headers without a body. Register it with e.Pre so it runs before routing.
+func HeadMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodHead {
+				return next(c)
+			}
+
+			req.Method = http.MethodGet
+			res := c.Response()
+			res.Writer = &headResponseWriter{ResponseWriter: res.Writer}
+
+			err := next(c)
+			if err != nil {
+				// Commit the error response while the body is still discarded
+				c.Error(err)
+			}
+			req.Method = http.MethodHead
+			return nil
+		}
+	}
+}
+
+// headResponseWriter discards the response body of a HEAD request
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards the body, reporting it as written
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
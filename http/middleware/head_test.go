@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHeadMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Pre(HeadMiddleware())
+	e.GET("/health", func(c echo.Context) error {
+		c.Response().Header().Set("X-Checked", "yes")
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	})
+	e.POST("/write", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		wantStatus      int
+		wantBody        bool
+		wantContentType string
+	}{
+		{name: "HEAD on GET route", method: http.MethodHead, path: "/health", wantStatus: http.StatusOK, wantContentType: echo.MIMEApplicationJSON},
+		{name: "GET still has a body", method: http.MethodGet, path: "/health", wantStatus: http.StatusOK, wantBody: true, wantContentType: echo.MIMEApplicationJSON},
+		{name: "HEAD on failing GET route", method: http.MethodHead, path: "/missing", wantStatus: http.StatusNotFound, wantContentType: echo.MIMEApplicationJSON},
+		{name: "HEAD on unknown route", method: http.MethodHead, path: "/nowhere", wantStatus: http.StatusNotFound, wantContentType: echo.MIMEApplicationJSON},
+		{name: "HEAD on POST-only route", method: http.MethodHead, path: "/write", wantStatus: http.StatusMethodNotAllowed, wantContentType: echo.MIMEApplicationJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotBody := rec.Body.Len() > 0; gotBody != tt.wantBody {
+				t.Errorf("body = %q, want body %v", rec.Body.String(), tt.wantBody)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantContentType)
+			}
+			if req.Method != tt.method {
+				t.Errorf("request method = %s after serving, want %s", req.Method, tt.method)
+			}
+		})
+	}
+
+	t.Run("headers of the GET route", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/health", nil))
+		if got := rec.Header().Get("X-Checked"); got != "yes" {
+			t.Errorf("X-Checked = %q, want yes", got)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// loadShedSmoothing is the weight of each new latency sample in the moving average
+const loadShedSmoothing = 0.2
+
+// LoadShedder rejects new requests with 503 while the service is overloaded: when the
+// moving average of request latency exceeds a threshold or too many requests are in
+// flight. The average decays while no requests complete, so shedding stops once
+// slow requests drain and fast ones come back.
+type LoadShedder struct {
+	latencyThreshold time.Duration
+	maxInFlight      int64
+	window           time.Duration
+	exempt           map[string]bool
+
+	inFlight atomic.Int64
+
+	mu         sync.Mutex
+	avgLatency float64
+	lastSample time.Time
+}
+
+// NewLoadShedder creates a load shedder. A zero latencyThreshold or maxInFlight disables
+// that trigger; window is the decay time constant of the latency average. Requests to
+// exempt route paths (such as health checks) are never shed.
+func NewLoadShedder(latencyThreshold time.Duration, maxInFlight int, window time.Duration, exempt []string) *LoadShedder {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return &LoadShedder{
+		latencyThreshold: latencyThreshold,
+		maxInFlight:      int64(maxInFlight),
+		window:           window,
+		exempt:           exemptPaths,
+	}
+}
+
+// Middleware sheds requests while overloaded and records the latency of admitted ones
+func (s *LoadShedder) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.exempt[c.Path()] {
+				return next(c)
+			}
+
+			inFlight := s.inFlight.Add(1)
+			defer s.inFlight.Add(-1)
+
+			if s.overloaded(inFlight) {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.window.Seconds()))))
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "server is overloaded, try again later")
+			}
+
+			start := time.Now()
+			err := next(c)
+			s.observe(time.Since(start))
+			return err
+		}
+	}
+}
+
+// overloaded reports whether a request arriving with inFlight requests in progress should be shed
+func (s *LoadShedder) overloaded(inFlight int64) bool {
+	if s.maxInFlight > 0 && inFlight > s.maxInFlight {
+		return true
+	}
+	return s.latencyThreshold > 0 && s.AverageLatency() > s.latencyThreshold
+}
+
+// observe folds a request latency into the moving average
+func (s *LoadShedder) observe(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	avg := s.decayedLocked(now)
+	s.avgLatency = avg + loadShedSmoothing*(float64(latency)-avg)
+	s.lastSample = now
+}
+
+// AverageLatency returns the current moving average of request latency
+func (s *LoadShedder) AverageLatency() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return time.Duration(s.decayedLocked(time.Now()))
+}
+
+// decayedLocked returns the latency average decayed for the time since the last sample
+func (s *LoadShedder) decayedLocked(now time.Time) float64 {
+	if s.lastSample.IsZero() || s.window <= 0 {
+		return s.avgLatency
+	}
+	elapsed := now.Sub(s.lastSample)
+	return s.avgLatency * math.Exp(-float64(elapsed)/float64(s.window))
+}
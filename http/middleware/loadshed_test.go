@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newShedServer serves /work, which sleeps for the duration in the delay query
+// parameter or blocks until release is closed, and an exempt /health behind shedder
+func newShedServer(shedder *LoadShedder, started chan<- struct{}, release <-chan struct{}) *echo.Echo {
+	e := echo.New()
+	e.Use(shedder.Middleware())
+	e.GET("/work", func(c echo.Context) error {
+		if c.QueryParam("block") != "" {
+			started <- struct{}{}
+			<-release
+			return c.NoContent(http.StatusOK)
+		}
+		delay, _ := time.ParseDuration(c.QueryParam("delay"))
+		time.Sleep(delay)
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/health", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e
+}
+
+// serveGet serves a GET request for path
+func serveGet(e *echo.Echo, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestLoadShedderLatency(t *testing.T) {
+	tests := []struct {
+		name       string
+		slow       int
+		wait       time.Duration
+		path       string
+		wantStatus int
+	}{
+		{name: "fast traffic", slow: 0, path: "/work", wantStatus: http.StatusOK},
+		{name: "single slow request", slow: 1, path: "/work", wantStatus: http.StatusOK},
+		{name: "sustained slowness", slow: 10, path: "/work", wantStatus: http.StatusServiceUnavailable},
+		{name: "health check during slowness", slow: 10, path: "/health", wantStatus: http.StatusOK},
+		{name: "recovered after slowness", slow: 10, wait: 400 * time.Millisecond, path: "/work", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shedder := NewLoadShedder(20*time.Millisecond, 0, 500*time.Millisecond, []string{"/health"})
+			e := newShedServer(shedder, nil, nil)
+
+			for range 5 {
+				serveGet(e, "/work")
+			}
+			for range tt.slow {
+				serveGet(e, "/work?delay=40ms")
+			}
+			time.Sleep(tt.wait)
+
+			rec := serveGet(e, tt.path)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (average latency %v)", rec.Code, tt.wantStatus, shedder.AverageLatency())
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want %q", rec.Header().Get("Retry-After"), "1")
+			}
+		})
+	}
+}
+
+func TestLoadShedderInFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocked    int
+		path       string
+		wantStatus int
+	}{
+		{name: "under the limit", blocked: 1, path: "/work", wantStatus: http.StatusOK},
+		{name: "at the limit", blocked: 2, path: "/work", wantStatus: http.StatusServiceUnavailable},
+		{name: "health check at the limit", blocked: 2, path: "/health", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			e := newShedServer(NewLoadShedder(0, 2, time.Second, []string{"/health"}), started, release)
+
+			var wg sync.WaitGroup
+			for range tt.blocked {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					serveGet(e, "/work?block=1")
+				}()
+				<-started
+			}
+
+			rec := serveGet(e, tt.path)
+			close(release)
+			wg.Wait()
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,18 +1,195 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+
+	"echo-base/config"
+	"echo-base/reporting"
 )
 
+// sensitiveHeaders are redacted in access logs unless LogSensitiveHeaders is enabled
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
 // LoggerMiddleware returns logger middleware configuration
-func LoggerMiddleware() echo.MiddlewareFunc {
+func LoggerMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	if cfg.LogFormat == "json" {
+		var threshold time.Duration
+		if cfg.LogSlowOnly {
+			threshold = cfg.LogSlowThreshold
+		}
+		return JSONLoggerMiddleware(threshold, cfg.LogHeaders, cfg.LogSensitiveHeaders)
+	}
+	if cfg.LogSlowOnly {
+		return SlowRequestLoggerMiddleware(cfg.LogSlowThreshold, cfg.LogHeaders, cfg.LogSensitiveHeaders)
+	}
+
 	return middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "[${time_rfc3339}] ${status} ${method} ${path} latency=${latency_human}\n",
+		Format: "[${time_rfc3339}] ${status} ${method} ${path} latency=${latency_human}${custom}\n",
+		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) {
+			return buf.WriteString(formatRequestID(c) + formatLogHeaders(c.Request(), cfg.LogHeaders, cfg.LogSensitiveHeaders))
+		},
 	})
 }
 
-// RecoverMiddleware returns recover middleware configuration
-func RecoverMiddleware() echo.MiddlewareFunc {
-	return middleware.Recover()
+// SlowRequestLoggerMiddleware logs only requests slower than threshold, plus all failed requests
+func SlowRequestLoggerMiddleware(threshold time.Duration, headers []string, logSensitive bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			// Commit the error response so the final status is known
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			latency := time.Since(start)
+			status := c.Response().Status
+			if latency < threshold && status < 500 {
+				return err
+			}
+
+			fmt.Fprintf(c.Echo().Logger.Output(), "[%s] %d %s %s latency=%s%s%s\n",
+				start.Format(time.RFC3339),
+				status,
+				c.Request().Method,
+				c.Request().URL.Path,
+				latency,
+				formatRequestID(c),
+				formatLogHeaders(c.Request(), headers, logSensitive),
+			)
+
+			return err
+		}
+	}
+}
+
+// jsonLogEntry is one request in the JSON access log. Request bodies are never logged.
+type jsonLogEntry struct {
+	Time      string            `json:"time"`
+	Status    int               `json:"status"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	LatencyMs float64           `json:"latency_ms"`
+	RemoteIP  string            `json:"remote_ip"`
+	RequestID string            `json:"request_id,omitempty"`
+	UserID    int64             `json:"user_id,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// JSONLoggerMiddleware logs one JSON object per request. With a positive slowThreshold
+// only requests slower than it, plus all failed requests, are logged.
+func JSONLoggerMiddleware(slowThreshold time.Duration, headers []string, logSensitive bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			// Commit the error response so the final status is known
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			latency := time.Since(start)
+			status := c.Response().Status
+			if slowThreshold > 0 && latency < slowThreshold && status < 500 {
+				return err
+			}
+
+			req := c.Request()
+			userID, _ := c.Get("user_id").(int64)
+
+			line, marshalErr := json.Marshal(jsonLogEntry{
+				Time:      start.Format(time.RFC3339),
+				Status:    status,
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				LatencyMs: float64(latency.Microseconds()) / 1000,
+				RemoteIP:  c.RealIP(),
+				RequestID: requestID(c),
+				UserID:    userID,
+				Headers:   logHeaderValues(req, headers, logSensitive),
+			})
+			if marshalErr == nil {
+				c.Echo().Logger.Output().Write(append(line, '\n'))
+			}
+
+			return err
+		}
+	}
+}
+
+// logHeaderValues returns the present headers from the allowlist, redacting sensitive
+// headers unless logSensitive is set
+func logHeaderValues(req *http.Request, headers []string, logSensitive bool) map[string]string {
+	values := make(map[string]string)
+	for _, header := range headers {
+		name := http.CanonicalHeaderKey(header)
+		value := req.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if sensitiveHeaders[name] && !logSensitive {
+			value = "[REDACTED]"
+		}
+		values[name] = value
+	}
+	return values
+}
+
+// formatRequestID renders the request's correlation ID as ` request_id=<id>`, or nothing without one
+func formatRequestID(c echo.Context) string {
+	id := requestID(c)
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf(" request_id=%q", id)
+}
+
+// formatLogHeaders renders the present headers from the allowlist as ` Name="value"` pairs,
+// redacting sensitive headers unless logSensitive is set
+func formatLogHeaders(req *http.Request, headers []string, logSensitive bool) string {
+	var b strings.Builder
+	for _, header := range headers {
+		name := http.CanonicalHeaderKey(header)
+		value := req.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if sensitiveHeaders[name] && !logSensitive {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(&b, " %s=%q", name, value)
+	}
+	return b.String()
+}
+
+// RecoverMiddleware returns recover middleware configuration. Each panic is logged
+// and reported, with its stack, to reporter.
+func RecoverMiddleware(reporter reporting.ErrorReporter) echo.MiddlewareFunc {
+	return middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			c.Logger().Printf("[PANIC RECOVER] %v %s\n", err, stack)
+
+			report := newReport(c, http.StatusInternalServerError, err.Error())
+			report.Panic = true
+			report.Stack = string(stack)
+			reporter.Report(c.Request().Context(), report)
+
+			return err
+		},
+	})
 }
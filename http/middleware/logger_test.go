@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSlowRequestLoggerMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		status  int
+		wantLog bool
+	}{
+		{name: "fast request is not logged", delay: 0, status: http.StatusOK, wantLog: false},
+		{name: "slow request is logged", delay: 30 * time.Millisecond, status: http.StatusOK, wantLog: true},
+		{name: "fast client error is not logged", delay: 0, status: http.StatusNotFound, wantLog: false},
+		{name: "fast server error is logged", delay: 0, status: http.StatusInternalServerError, wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			e := echo.New()
+			e.Logger.SetOutput(&out)
+			e.GET("/test", func(c echo.Context) error {
+				time.Sleep(tt.delay)
+				if tt.status != http.StatusOK {
+					return echo.NewHTTPError(tt.status)
+				}
+				return c.String(http.StatusOK, "ok")
+			}, SlowRequestLoggerMiddleware(20*time.Millisecond, nil, false))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if logged := strings.Contains(out.String(), "GET /test"); logged != tt.wantLog {
+				t.Errorf("logged = %v, want %v (output %q)", logged, tt.wantLog, out.String())
+			}
+		})
+	}
+}
+
+func TestJSONLoggerMiddlewareSlowThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		wantLog   bool
+	}{
+		{name: "no threshold logs every request", threshold: 0, delay: 0, wantLog: true},
+		{name: "fast request is not logged", threshold: 20 * time.Millisecond, delay: 0, wantLog: false},
+		{name: "slow request is logged", threshold: 20 * time.Millisecond, delay: 30 * time.Millisecond, wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			e := echo.New()
+			e.Logger.SetOutput(&out)
+			e.GET("/test", func(c echo.Context) error {
+				time.Sleep(tt.delay)
+				return c.String(http.StatusOK, "ok")
+			}, JSONLoggerMiddleware(tt.threshold, nil, false))
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if logged := strings.Contains(out.String(), `"path":"/test"`); logged != tt.wantLog {
+				t.Errorf("logged = %v, want %v (output %q)", logged, tt.wantLog, out.String())
+			}
+		})
+	}
+}
+
+func TestLogHeaders(t *testing.T) {
+	headers := []string{"X-Forwarded-For", "user-agent", "Authorization", "Cookie", "X-Missing"}
+
+	tests := []struct {
+		name         string
+		logSensitive bool
+		want         []string
+		wantAbsent   []string
+	}{
+		{
+			name:       "sensitive headers are redacted",
+			want:       []string{`X-Forwarded-For="203.0.113.7"`, `User-Agent="test-agent"`, `Authorization="[REDACTED]"`, `Cookie="[REDACTED]"`},
+			wantAbsent: []string{"Bearer secret-token", "session=abc", "X-Missing", "X-Not-Listed"},
+		},
+		{
+			name:         "sensitive headers logged when dangerously enabled",
+			logSensitive: true,
+			want:         []string{`Authorization="Bearer secret-token"`, `Cookie="session=abc"`},
+			wantAbsent:   []string{"[REDACTED]", "X-Not-Listed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			e := echo.New()
+			e.Logger.SetOutput(&out)
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			}, SlowRequestLoggerMiddleware(0, headers, tt.logSensitive))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			req.Header.Set("User-Agent", "test-agent")
+			req.Header.Set("Authorization", "Bearer secret-token")
+			req.Header.Set("Cookie", "session=abc")
+			req.Header.Set("X-Not-Listed", "hidden")
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			logged := out.String()
+			for _, want := range tt.want {
+				if !strings.Contains(logged, want) {
+					t.Errorf("log %q does not contain %s", logged, want)
+				}
+			}
+			for _, absent := range tt.wantAbsent {
+				if strings.Contains(logged, absent) {
+					t.Errorf("log %q contains %s", logged, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONLoggerMiddlewareHeaders(t *testing.T) {
+	var out bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&out)
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, JSONLoggerMiddleware(0, []string{"User-Agent", "Authorization"}, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		Headers map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("error decoding log %q: %v", out.String(), err)
+	}
+	want := map[string]string{"User-Agent": "test-agent", "Authorization": "[REDACTED]"}
+	if !reflect.DeepEqual(entry.Headers, want) {
+		t.Errorf("headers = %v, want %v", entry.Headers, want)
+	}
+}
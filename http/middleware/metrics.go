@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"echo-base/metrics"
+)
+
+// MetricsMiddleware records the count, latency and in-flight number of requests, labeled
+// by method, route template and status
+func MetricsMiddleware(registry *metrics.Registry) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			done := registry.StartRequest(c.Request().Method, route)
+			err := next(c)
+
+			status, _ := responseStatus(c, err)
+			done(status)
+			return err
+		}
+	}
+}
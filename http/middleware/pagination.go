@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+// paginationContextKey is the context key holding the parsed pagination params
+const paginationContextKey = "pagination"
+
+// PaginationMiddleware parses and validates pagination query params once and stores them in context.
+// sortable lists the fields the route may be sorted by.
+func PaginationMiddleware(sortable ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			params, err := utils.ParsePagination(c, utils.DefaultPagination, sortable...)
+			if err != nil {
+				return echo.NewHTTPError(400, err.Error())
+			}
+
+			c.Set(paginationContextKey, params)
+
+			return next(c)
+		}
+	}
+}
+
+// GetPaginationParams returns the pagination params stored by PaginationMiddleware,
+// falling back to defaults when the middleware did not run
+func GetPaginationParams(c echo.Context) entity.PaginationParams {
+	if params, ok := c.Get(paginationContextKey).(entity.PaginationParams); ok {
+		return params
+	}
+	return utils.DefaultPagination
+}
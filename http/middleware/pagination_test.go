@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+func TestPaginationMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       entity.PaginationParams
+	}{
+		{name: "defaults", query: "", wantStatus: http.StatusOK, want: utils.DefaultPagination},
+		{
+			name:       "valid params",
+			query:      "?page=3&limit=5&sort=name&order=ASC&search=+ann+&role_id=2",
+			wantStatus: http.StatusOK,
+			want:       entity.PaginationParams{Page: 3, Limit: 5, Sort: "name", Order: entity.SortAsc, Search: "ann", RoleID: 2},
+		},
+		{
+			name:       "limit clamped",
+			query:      "?limit=100000",
+			wantStatus: http.StatusOK,
+			want:       entity.PaginationParams{Page: 1, Limit: entity.MaxPageLimit, Sort: "created_at", Order: entity.SortDesc},
+		},
+		{name: "invalid page", query: "?page=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=ten", wantStatus: http.StatusBadRequest},
+		{name: "unsortable field", query: "?sort=password", wantStatus: http.StatusBadRequest},
+		{name: "invalid order", query: "?order=sideways", wantStatus: http.StatusBadRequest},
+		{name: "invalid role", query: "?role_id=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			called := false
+			var got entity.PaginationParams
+			e.GET("/items", func(c echo.Context) error {
+				called = true
+				got = GetPaginationParams(c)
+				return c.NoContent(http.StatusOK)
+			}, PaginationMiddleware("id", "name", "created_at"))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if called {
+					t.Error("handler ran for invalid params")
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("params = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPaginationParamsWithoutMiddleware(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	if got := GetPaginationParams(c); got != utils.DefaultPagination {
+		t.Errorf("params = %+v, want the defaults %+v", got, utils.DefaultPagination)
+	}
+}
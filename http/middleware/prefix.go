@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// StripPrefixMiddleware removes a path prefix added by a reverse proxy before routing.
+// Register it with e.Pre so it runs before the router matches the path.
+func StripPrefixMiddleware(prefix string) echo.MiddlewareFunc {
+	prefix = "/" + strings.Trim(prefix, "/")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			path := req.URL.Path
+
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+				if req.URL.RawPath != "" {
+					req.URL.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.RawPath, prefix), "/")
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
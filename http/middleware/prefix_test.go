@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStripPrefixMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		path       string
+		wantStatus int
+		wantPath   string
+	}{
+		{name: "prefixed route", prefix: "/svc", path: "/svc/api/v1/items", wantStatus: http.StatusOK, wantPath: "/api/v1/items"},
+		{name: "prefix without slashes", prefix: "svc/", path: "/svc/api/v1/items", wantStatus: http.StatusOK, wantPath: "/api/v1/items"},
+		{name: "nested prefix", prefix: "/a/b", path: "/a/b/api/v1/items", wantStatus: http.StatusOK, wantPath: "/api/v1/items"},
+		{name: "bare prefix is the root", prefix: "/svc", path: "/svc", wantStatus: http.StatusOK, wantPath: "/"},
+		{name: "unprefixed path still routes", prefix: "/svc", path: "/api/v1/items", wantStatus: http.StatusOK, wantPath: "/api/v1/items"},
+		{name: "partial segment is not stripped", prefix: "/svc", path: "/svcx/api/v1/items", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Pre(StripPrefixMiddleware(tt.prefix))
+			var gotPath string
+			handler := func(c echo.Context) error {
+				gotPath = c.Request().URL.Path
+				return c.NoContent(http.StatusOK)
+			}
+			e.GET("/api/v1/items", handler)
+			e.GET("/", handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && gotPath != tt.wantPath {
+				t.Errorf("path = %q, want %q", gotPath, tt.wantPath)
+			}
+		})
+	}
+}
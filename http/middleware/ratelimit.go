@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/utils"
+)
+
+// maxAuthBodyPeek bounds how much of an auth request body is read to find the email
+const maxAuthBodyPeek = 64 << 10
+
+// RateLimitStatus describes a caller's quota
+type RateLimitStatus struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+
+	// RetryAfter is how long until the next request is allowed; zero when one is available
+	RetryAfter time.Duration `json:"-"`
+}
+
+// RateLimitStore tracks request quotas per key
+type RateLimitStore interface {
+	// Take consumes one request for key, returning ok=false when the quota is exhausted
+	Take(key string) (status RateLimitStatus, ok bool)
+
+	// Peek returns the quota for key without consuming it
+	Peek(key string) RateLimitStatus
+}
+
+// bucket is a token bucket for a single key
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// memoryRateLimitStore is an in-memory token-bucket RateLimitStore
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	burst     int
+	perSecond float64
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates a token-bucket store allowing perMinute requests
+// per key with bursts of up to burst requests. Quotas are not shared between instances.
+func NewMemoryRateLimitStore(perMinute, burst int) RateLimitStore {
+	if burst < 1 {
+		burst = perMinute
+	}
+	return &memoryRateLimitStore{
+		buckets:   make(map[string]*bucket),
+		burst:     burst,
+		perSecond: float64(perMinute) / 60,
+		lastSweep: time.Now(),
+	}
+}
+
+// Take consumes one token for key
+func (s *memoryRateLimitStore) Take(key string) (RateLimitStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(s.burst), updated: now}
+		s.buckets[key] = b
+	}
+	s.refill(b, now)
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return s.status(b, now), allowed
+}
+
+// Peek returns the quota for key without consuming a token
+func (s *memoryRateLimitStore) Peek(key string) RateLimitStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		return RateLimitStatus{Limit: s.burst, Remaining: s.burst, ResetAt: now}
+	}
+
+	peeked := *b
+	s.refill(&peeked, now)
+	return s.status(&peeked, now)
+}
+
+// refill adds the tokens earned since the bucket was last updated
+func (s *memoryRateLimitStore) refill(b *bucket, now time.Time) {
+	b.tokens = math.Min(float64(s.burst), b.tokens+now.Sub(b.updated).Seconds()*s.perSecond)
+	b.updated = now
+}
+
+// status reports a bucket's quota; ResetAt is when the bucket will be full again
+func (s *memoryRateLimitStore) status(b *bucket, now time.Time) RateLimitStatus {
+	missing := float64(s.burst) - b.tokens
+	resetAt := now
+	if missing > 0 && s.perSecond > 0 {
+		resetAt = now.Add(time.Duration(missing / s.perSecond * float64(time.Second)))
+	}
+	var retryAfter time.Duration
+	if b.tokens < 1 && s.perSecond > 0 {
+		retryAfter = time.Duration((1 - b.tokens) / s.perSecond * float64(time.Second))
+	}
+	return RateLimitStatus{
+		Limit:      s.burst,
+		Remaining:  int(b.tokens),
+		ResetAt:    resetAt,
+		RetryAfter: retryAfter,
+	}
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		s.refill(b, now)
+		if b.tokens >= float64(s.burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitKey identifies the caller: the authenticated user when known, otherwise the client IP
+func RateLimitKey(c echo.Context) string {
+	if userID, ok := c.Get("user_id").(int64); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "ip:" + c.RealIP()
+}
+
+// RateLimitMiddleware rejects requests once the caller's quota in store is exhausted,
+// responding 429 with a Retry-After header. Quota headers are set on every response.
+func RateLimitMiddleware(store RateLimitStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			status, ok := store.Take(RateLimitKey(c))
+			setRateLimitHeaders(c, status)
+
+			if !ok {
+				setRetryAfter(c, status)
+				return echo.NewHTTPError(429, "rate limit exceeded")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AuthRateLimitMiddleware limits attempts on the auth endpoints per client IP. With byEmail,
+// attempts are also limited per email address in the JSON body, across IPs, which slows
+// brute-force attacks on one account from many addresses. Rejected requests get 429, a
+// Retry-After header and the standard error response.
+func AuthRateLimitMiddleware(store RateLimitStore, byEmail bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			status, ok := store.Take("auth:ip:" + c.RealIP())
+			if ok && byEmail {
+				if email := peekEmail(c.Request()); email != "" {
+					status, ok = store.Take("auth:email:" + email)
+				}
+			}
+			setRateLimitHeaders(c, status)
+
+			if !ok {
+				setRetryAfter(c, status)
+				return c.JSON(http.StatusTooManyRequests, utils.ErrorResponse("too many attempts; try again later"))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// peekEmail returns the lowercased email field of a JSON request body, leaving the body
+// readable for the handler, or "" when there is none
+func peekEmail(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxAuthBodyPeek))
+	if err != nil {
+		return ""
+	}
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// setRateLimitHeaders sets the quota headers from status
+func setRateLimitHeaders(c echo.Context, status RateLimitStatus) {
+	header := c.Response().Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+}
+
+// setRetryAfter sets Retry-After to the whole seconds until the next allowed request
+func setRetryAfter(c echo.Context, status RateLimitStatus) {
+	retryAfter := int(math.Ceil(status.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+}
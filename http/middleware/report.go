@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/reporting"
+)
+
+// ErrorReportMiddleware reports requests that end in a 5xx status, whether the handler
+// returned an error or wrote the response itself
+func ErrorReportMiddleware(reporter reporting.ErrorReporter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status, message := responseStatus(c, err)
+			if status >= http.StatusInternalServerError {
+				reporter.Report(c.Request().Context(), newReport(c, status, message))
+			}
+			return err
+		}
+	}
+}
+
+// MonitorMiddleware records the status of every request so the monitor can alert on
+// error rate spikes
+func MonitorMiddleware(monitor *reporting.Monitor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status, _ := responseStatus(c, err)
+			monitor.Record(c.Request().Context(), status)
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status a request ends with and a message describing it,
+// whether the handler returned an error or wrote the response itself
+func responseStatus(c echo.Context, err error) (int, string) {
+	if err == nil {
+		status := c.Response().Status
+		return status, http.StatusText(status)
+	}
+
+	status := http.StatusInternalServerError
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+	}
+	return status, err.Error()
+}
+
+// newReport builds a report with the request's route, request ID and authenticated user
+func newReport(c echo.Context, status int, message string) reporting.Report {
+	req := c.Request()
+
+	userID, _ := c.Get("user_id").(int64)
+
+	return reporting.Report{
+		Message:    message,
+		Status:     status,
+		Method:     req.Method,
+		Route:      c.Path(),
+		Path:       req.URL.Path,
+		RequestID:  requestID(c),
+		UserID:     userID,
+		OccurredAt: time.Now(),
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/reporting"
+)
+
+// stubReporter records the reports it receives
+type stubReporter struct {
+	mu      sync.Mutex
+	reports []reporting.Report
+}
+
+func (r *stubReporter) Report(ctx context.Context, report reporting.Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+}
+
+func TestErrorReporting(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    echo.HandlerFunc
+		wantStatus int
+		wantReport bool
+		wantPanic  bool
+		wantMsg    string
+	}{
+		{
+			name:       "panic",
+			handler:    func(c echo.Context) error { panic("nil map write") },
+			wantStatus: http.StatusInternalServerError,
+			wantReport: true,
+			wantPanic:  true,
+			wantMsg:    "nil map write",
+		},
+		{
+			name:       "returned error",
+			handler:    func(c echo.Context) error { return errors.New("database unreachable") },
+			wantStatus: http.StatusInternalServerError,
+			wantReport: true,
+			wantMsg:    "database unreachable",
+		},
+		{
+			name:       "written 5xx response",
+			handler:    func(c echo.Context) error { return c.NoContent(http.StatusServiceUnavailable) },
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: true,
+			wantMsg:    "Service Unavailable",
+		},
+		{
+			name:       "client error",
+			handler:    func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound, "user not found") },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "success",
+			handler:    func(c echo.Context) error { return c.NoContent(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &stubReporter{}
+			e := echo.New()
+			e.Logger.SetOutput(&strings.Builder{})
+			e.Use(RequestIDMiddleware("X-Request-ID"))
+			e.Use(RecoverMiddleware(reporter))
+			e.Use(ErrorReportMiddleware(reporter))
+
+			authenticated := func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set("user_id", int64(42))
+					return next(c)
+				}
+			}
+			e.GET("/users/:id", tt.handler, authenticated)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !tt.wantReport {
+				if len(reporter.reports) != 0 {
+					t.Fatalf("reports = %+v, want none", reporter.reports)
+				}
+				return
+			}
+			if len(reporter.reports) != 1 {
+				t.Fatalf("got %d reports, want 1", len(reporter.reports))
+			}
+
+			report := reporter.reports[0]
+			if report.Panic != tt.wantPanic {
+				t.Errorf("Panic = %v, want %v", report.Panic, tt.wantPanic)
+			}
+			if tt.wantPanic && report.Stack == "" {
+				t.Error("panic report has no stack")
+			}
+			if !strings.Contains(report.Message, tt.wantMsg) {
+				t.Errorf("Message = %q, want it to contain %q", report.Message, tt.wantMsg)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", report.Status, tt.wantStatus)
+			}
+			if report.Route != "/users/:id" || report.Path != "/users/7" || report.Method != http.MethodGet {
+				t.Errorf("Method, Route, Path = %q, %q, %q; want GET, /users/:id, /users/7", report.Method, report.Route, report.Path)
+			}
+			if report.RequestID != "req-123" {
+				t.Errorf("RequestID = %q, want %q", report.RequestID, "req-123")
+			}
+			if report.UserID != 42 {
+				t.Errorf("UserID = %d, want 42", report.UserID)
+			}
+		})
+	}
+}
+
+func TestNoopReporter(t *testing.T) {
+	e := echo.New()
+	e.Logger.SetOutput(&strings.Builder{})
+	e.Use(RecoverMiddleware(reporting.NewNoopReporter()))
+	e.GET("/", func(c echo.Context) error { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"echo-base/utils"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs so they cannot bloat logs
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware gives each request a correlation ID, taken from the header when the
+// client sent a usable one and generated otherwise. The ID is stored in the context as
+// "request_id" and echoed in the response header.
+func RequestIDMiddleware(header string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(header)
+			if !validRequestID(id) {
+				generated, err := utils.GenerateUUID()
+				if err != nil {
+					return err
+				}
+				id = generated
+			}
+
+			c.Set("request_id", id)
+			c.Response().Header().Set(header, id)
+			return next(c)
+		}
+	}
+}
+
+// validRequestID reports whether id is non-empty, bounded and printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestID returns the request's correlation ID, falling back to the X-Request-ID
+// headers when RequestIDMiddleware is not in the chain
+func requestID(c echo.Context) string {
+	if id, ok := c.Get("request_id").(string); ok {
+		return id
+	}
+	if id := c.Request().Header.Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
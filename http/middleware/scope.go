@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireScope rejects tokens whose scope is not one of scopes, so purpose-specific
+// tokens (reset, verify) cannot be used as general access tokens. It must run after the
+// auth middleware.
+func RequireScope(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tokenScope, ok := c.Get("token_scope").(string)
+			if !ok {
+				return echo.NewHTTPError(401, "unauthorized")
+			}
+			if !hasScope(scopes, tokenScope) {
+				return echo.NewHTTPError(403, fmt.Sprintf("token scope %q cannot access this resource", tokenScope))
+			}
+
+			return next(c)
+		}
+	}
+}
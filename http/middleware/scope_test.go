@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+	"echo-base/utils"
+)
+
+func TestRequireScope(t *testing.T) {
+	signer := utils.NewTokenSigner("test-secret", nil, time.Hour, 24*time.Hour, "")
+
+	tests := []struct {
+		name       string
+		token      func() (string, error)
+		wantStatus int
+	}{
+		{
+			name: "access token",
+			token: func() (string, error) {
+				return signer.GenerateToken(1, "alice@example.com", entity.RoleIDUser, "", utils.ScopeAccess)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "default scope is access",
+			token: func() (string, error) {
+				return signer.GenerateToken(1, "alice@example.com", entity.RoleIDUser, "", "")
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "reset token",
+			token: func() (string, error) {
+				return signer.GenerateScopedToken(1, "alice@example.com", utils.ScopeReset, time.Hour)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			// Reset links are signed with a key derived from the password hash, so they
+			// fail signature checks before their scope is looked at
+			name: "password reset link token",
+			token: func() (string, error) {
+				return signer.GeneratePasswordReset(1, "password-hash", time.Hour)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "verification token",
+			token: func() (string, error) {
+				return signer.GenerateScopedToken(1, "alice@example.com", utils.ScopeVerify, time.Hour)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "refresh token",
+			token: func() (string, error) {
+				return signer.GenerateScopedToken(1, "alice@example.com", utils.ScopeRefresh, time.Hour)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "email change token",
+			token: func() (string, error) {
+				return signer.GenerateEmailChangeToken(1, "alice@example.com", "new@example.com", time.Hour)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "no token",
+			token: func() (string, error) {
+				return "", nil
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/users/:id", func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]string{"email": "alice@example.com"})
+			}, BearerAuthMiddleware(signer), RequireScope(utils.ScopeAccess))
+
+			token, err := tt.token()
+			if err != nil {
+				t.Fatalf("error issuing token: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireScopeAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      interface{}
+		allowed    []string
+		wantStatus int
+	}{
+		{name: "one of several allowed", scope: utils.ScopeAPIKey, allowed: []string{utils.ScopeAccess, utils.ScopeAPIKey}, wantStatus: http.StatusOK},
+		{name: "not allowed", scope: utils.ScopeAPIKey, allowed: []string{utils.ScopeAccess}, wantStatus: http.StatusForbidden},
+		{name: "no scope in context", scope: nil, allowed: []string{utils.ScopeAccess}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/test", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.scope != nil {
+						c.Set("token_scope", tt.scope)
+					}
+					return next(c)
+				}
+			}, RequireScope(tt.allowed...))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/repository"
+)
+
+// TransactionMiddleware runs each request in one database transaction, exposed to
+// repositories through the request context. It commits when the handler succeeds
+// with a 2xx response and rolls back on an error, a non-2xx response or a panic.
+// The response is buffered until the transaction is resolved, so a failed commit
+// is reported as a 500 instead of the handler's success response.
+func TransactionMiddleware(db *sql.DB) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			req := c.Request()
+			tx, err := db.BeginTx(req.Context(), nil)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "error starting transaction")
+			}
+			c.SetRequest(req.WithContext(repository.ContextWithTx(req.Context(), tx)))
+
+			res := c.Response()
+			writer := res.Writer
+			buffered := newBufferedResponseWriter(writer)
+			res.Writer = buffered
+
+			defer func() {
+				if r := recover(); r != nil {
+					res.Writer = writer
+					tx.Rollback()
+					panic(r)
+				}
+			}()
+
+			if err := next(c); err != nil {
+				res.Writer = writer
+				tx.Rollback()
+				buffered.flushTo(writer)
+				return err
+			}
+
+			res.Writer = writer
+			if status := res.Status; status < 200 || status >= 300 {
+				tx.Rollback()
+				buffered.flushTo(writer)
+				return nil
+			}
+
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing request transaction for %s %s: %v\n", req.Method, c.Path(), err)
+
+				// Nothing has reached the client yet, so the error handler can still respond
+				res.Committed = false
+				res.Status = 0
+				res.Size = 0
+				return echo.NewHTTPError(http.StatusInternalServerError, "error committing transaction")
+			}
+			buffered.flushTo(writer)
+			return nil
+		}
+	}
+}
+
+// bufferedResponseWriter holds back a response until the request transaction is resolved
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// newBufferedResponseWriter buffers a response on top of the headers already set on w
+func newBufferedResponseWriter(w http.ResponseWriter) *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: w.Header().Clone()}
+}
+
+// Header returns the buffered response headers
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status code
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write buffers the body, implying a 200 status if none was written
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// flushTo sends the buffered headers, status and body to w, if anything was written
+func (w *bufferedResponseWriter) flushTo(dst http.ResponseWriter) {
+	if w.status == 0 {
+		return
+	}
+
+	header := dst.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	dst.WriteHeader(w.status)
+	dst.Write(w.body.Bytes())
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/repository"
+)
+
+// txDB is a database behind the "txdb" driver that records transactions and applies
+// the statements of a transaction only when it commits
+type txDB struct {
+	mu                         sync.Mutex
+	begins, commits, rollbacks int
+	applied                    []string
+
+	// failCommit makes every commit fail without applying its statements
+	failCommit bool
+}
+
+var (
+	txDBs         sync.Map
+	txDBsRegister sync.Once
+)
+
+// openTxDB opens a *sql.DB backed by a new txDB, closed when the test ends
+func openTxDB(t *testing.T) (*sql.DB, *txDB) {
+	t.Helper()
+
+	txDBsRegister.Do(func() { sql.Register("txdb", txDriver{}) })
+	fake := &txDB{}
+	txDBs.Store(t.Name(), fake)
+
+	db, err := sql.Open("txdb", t.Name())
+	if err != nil {
+		t.Fatalf("error opening fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		txDBs.Delete(t.Name())
+	})
+	return db, fake
+}
+
+// txDriver opens connections to the txDB registered under the DSN
+type txDriver struct{}
+
+func (txDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := txDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return &txConn{db: fake.(*txDB)}, nil
+}
+
+// txConn is a connection holding at most one transaction
+type txConn struct {
+	db      *txDB
+	inTx    bool
+	pending []string
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("txdb: prepared statements are not supported")
+}
+
+func (c *txConn) Close() error { return nil }
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.begins++
+	c.inTx = true
+	c.pending = nil
+	return c, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement := strings.Join(strings.Fields(query), " ")
+	if c.inTx {
+		c.pending = append(c.pending, statement)
+	} else {
+		c.db.mu.Lock()
+		c.db.applied = append(c.db.applied, statement)
+		c.db.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *txConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.db.failCommit {
+		c.inTx = false
+		return errors.New("txdb: commit failed")
+	}
+	c.db.commits++
+	c.db.applied = append(c.db.applied, c.pending...)
+	c.inTx = false
+	return nil
+}
+
+func (c *txConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.rollbacks++
+	c.inTx = false
+	return nil
+}
+
+func TestTransactionMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// failMidWrite makes the handler fail between its two writes
+		failMidWrite bool
+
+		// respond finishes the request after the handler's two writes
+		respond func(c echo.Context) error
+
+		// failCommit makes the transaction fail to commit
+		failCommit bool
+
+		wantStatus int
+		wantBody   bool
+		wantCommit bool
+	}{
+		{
+			name:       "success",
+			respond:    func(c echo.Context) error { return c.NoContent(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+			wantCommit: true,
+		},
+		{
+			name:       "success body",
+			respond:    func(c echo.Context) error { return c.JSON(http.StatusCreated, map[string]string{"status": "done"}) },
+			wantStatus: http.StatusCreated,
+			wantBody:   true,
+			wantCommit: true,
+		},
+		{
+			name:       "commit failure",
+			respond:    func(c echo.Context) error { return c.JSON(http.StatusCreated, map[string]string{"status": "done"}) },
+			failCommit: true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "error mid-write",
+			failMidWrite: true,
+			wantStatus:   http.StatusInternalServerError,
+		},
+		{
+			name:       "error after writes",
+			respond:    func(c echo.Context) error { return errors.New("notification failed") },
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "HTTP error",
+			respond:    func(c echo.Context) error { return echo.NewHTTPError(http.StatusConflict, "conflict") },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "non-2xx response",
+			respond:    func(c echo.Context) error { return c.NoContent(http.StatusBadRequest) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "panic",
+			respond:    func(c echo.Context) error { panic("handler crashed") },
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := openTxDB(t)
+			fake.failCommit = tt.failCommit
+			users := repository.NewUserRepository(db)
+
+			e := echo.New()
+			e.Logger.SetOutput(&strings.Builder{})
+			e.Use(RecoverMiddleware(&stubReporter{}))
+			e.POST("/users/batch", func(c echo.Context) error {
+				ctx := c.Request().Context()
+				if err := users.UpdateStatus(ctx, 1, "suspended"); err != nil {
+					return err
+				}
+				if tt.failMidWrite {
+					return errors.New("second step failed")
+				}
+				if err := users.Delete(ctx, 2); err != nil {
+					return err
+				}
+				return tt.respond(c)
+			}, TransactionMiddleware(db))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users/batch", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			wantCommits, wantRollbacks, wantApplied := 0, 1, 0
+			switch {
+			case tt.wantCommit:
+				wantCommits, wantRollbacks, wantApplied = 1, 0, 2
+			case tt.failCommit:
+				wantRollbacks = 0
+			}
+			if fake.begins != 1 || fake.commits != wantCommits || fake.rollbacks != wantRollbacks {
+				t.Errorf("begins, commits, rollbacks = %d, %d, %d; want 1, %d, %d",
+					fake.begins, fake.commits, fake.rollbacks, wantCommits, wantRollbacks)
+			}
+			if strings.Contains(rec.Body.String(), "done") != tt.wantBody {
+				t.Errorf("body = %q, want handler body %v", rec.Body.String(), tt.wantBody)
+			}
+			if len(fake.applied) != wantApplied {
+				t.Errorf("applied statements = %q, want %d", fake.applied, wantApplied)
+			}
+		})
+	}
+}
@@ -1,18 +1,92 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/labstack/echo/v4"
 
+	"echo-base/domain/entity"
 	"echo-base/http/handler"
 	"echo-base/http/middleware"
+	"echo-base/utils"
+)
+
+// Deprecated routes and their sunset dates
+var (
+	// getAllUsersSunset retires the unbounded user listing in favour of /users/pagination
+	getAllUsersSunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
 )
 
+// Handlers groups the HTTP handlers wired into the routes
+type Handlers struct {
+	User     *handler.UserHandler
+	Health   *handler.HealthHandler
+	Runtime  *handler.RuntimeHandler
+	Job      *handler.JobHandler
+	Deps     *handler.DepsHandler
+	Role     *handler.RoleHandler
+	Counters *handler.CountersHandler
+
+	// APIKeys registers the API key management routes
+	APIKeys bool
+
+	// RolesPublic serves the role list without authentication; otherwise it is admin-only
+	RolesPublic bool
+
+	// RateLimit is nil when rate limiting is disabled
+	RateLimit *handler.RateLimitHandler
+
+	// Metrics is nil when metrics are disabled
+	Metrics *handler.MetricsHandler
+}
+
+// Middleware groups the middleware chains applied by RegisterRoutes
+type Middleware struct {
+	// Auth is the chain applied to protected route groups
+	Auth []echo.MiddlewareFunc
+
+	// OptionalAuth identifies the caller when a valid token is sent, without requiring one
+	OptionalAuth echo.MiddlewareFunc
+
+	// RateLimit is the chain applied to rate-limited public routes (empty when disabled)
+	RateLimit []echo.MiddlewareFunc
+
+	// AuthRateLimit is the chain applied to the auth routes (empty when disabled)
+	AuthRateLimit []echo.MiddlewareFunc
+
+	// Roles responds 503 on role-dependent routes while the required roles are missing
+	Roles echo.MiddlewareFunc
+
+	// Transaction wraps each request in a database transaction (nil when unavailable).
+	// It is opt-in: only the route groups named in TransactionGroups use it.
+	Transaction       echo.MiddlewareFunc
+	TransactionGroups []string
+}
+
+// transactionFor returns the transaction middleware when group opted in to it
+func (mw *Middleware) transactionFor(group string) []echo.MiddlewareFunc {
+	if mw.Transaction == nil {
+		return nil
+	}
+	for _, name := range mw.TransactionGroups {
+		if name == group {
+			return []echo.MiddlewareFunc{mw.Transaction}
+		}
+	}
+	return nil
+}
+
 // RegisterRoutes registers all HTTP routes for the application
-func RegisterRoutes(e *echo.Echo, h *handler.UserHandler) {
-	// Health check
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "ok"})
-	})
+func RegisterRoutes(e *echo.Echo, h *Handlers, mw *Middleware) {
+	// Health checks; /health is kept as an alias of the liveness probe
+	e.GET("/health", h.Health.Live)
+	e.GET("/health/live", h.Health.Live)
+	e.GET("/health/ready", h.Health.Ready)
+
+	// Prometheus scrape endpoint
+	if h.Metrics != nil {
+		e.GET("/metrics", h.Metrics.GetMetrics)
+	}
 
 	const apiVersion = "/api/v1"
 
@@ -20,26 +94,86 @@ func RegisterRoutes(e *echo.Echo, h *handler.UserHandler) {
 
 	// Auth routes
 	authRoutes := api.Group("/auth")
-	authRoutes.POST("/register", h.Register)
-	authRoutes.POST("/login", h.Login)
+	authRoutes.Use(mw.AuthRateLimit...)
+	authRoutes.POST("/register", h.User.Register, mw.Roles)
+	authRoutes.POST("/login", h.User.Login)
+	authRoutes.POST("/login/2fa", h.User.LoginTwoFactor)
+	authRoutes.POST("/login/recovery", h.User.LoginRecovery)
+	authRoutes.POST("/refresh", h.User.RefreshToken)
+	authRoutes.GET("/verify", h.User.VerifyEmail)
+	authRoutes.POST("/verify/resend", h.User.ResendVerification)
+	authRoutes.POST("/forgot-password", h.User.ForgotPassword)
+	authRoutes.POST("/reset-password", h.User.ResetPassword)
+	authRoutes.GET("/suggest-password", h.User.SuggestPassword, mw.RateLimit...)
+
+	// Rate-limit status for the caller (user when authenticated, otherwise IP)
+	if h.RateLimit != nil {
+		api.GET("/ratelimit", h.RateLimit.GetStatus, mw.OptionalAuth)
+	}
+
+	// Role list for registration and admin UIs (admin only unless public)
+	roleRoutes := api.Group("/roles")
+	if !h.RolesPublic {
+		roleRoutes.Use(mw.Auth...)
+		roleRoutes.Use(mw.Roles, middleware.AdminRoleMiddleware)
+	}
+	roleRoutes.GET("", h.Role.GetAll)
 
 	// Admin routes (admin only)
 	adminRoutes := api.Group("/admin")
-	adminRoutes.Use(middleware.BearerAuthMiddleware)
-	adminRoutes.Use(middleware.AdminRoleMiddleware)
-	// Add admin-only endpoints here
+	adminRoutes.Use(mw.Auth...)
+	adminRoutes.Use(mw.Roles, middleware.AdminRoleMiddleware)
+	adminRoutes.Use(mw.transactionFor("admin")...)
+	adminRoutes.GET("/runtime", h.Runtime.GetStats)
+	adminRoutes.GET("/deps", h.Deps.GetDeps)
+	adminRoutes.GET("/stats", h.User.GetStats)
+	adminRoutes.GET("/stats/counters", h.Counters.GetCounters)
+	adminRoutes.GET("/users", h.User.GetAllPagination, middleware.PaginationMiddleware(entity.UserSortFields...))
+	adminRoutes.GET("/users/search/explain", h.User.ExplainSearch)
+	adminRoutes.POST("/users/bulk-role", h.User.BulkAssignRole)
+	adminRoutes.GET("/users/:id", h.User.GetByID)
+	adminRoutes.PUT("/users/:id/role", h.User.AdminUpdateRole)
+	adminRoutes.PUT("/users/:id/status", h.User.AdminSetStatus)
+	adminRoutes.DELETE("/users/:id", h.User.AdminDelete)
+	adminRoutes.POST("/users/:id/restore", h.User.AdminRestore)
+	adminRoutes.POST("/jobs/:name/run", h.Job.Run)
+	adminRoutes.GET("/roles", h.Role.GetAll)
+	adminRoutes.GET("/roles/:id", h.Role.GetByID)
+	adminRoutes.POST("/roles", h.Role.Create)
+	adminRoutes.PUT("/roles/:id", h.Role.Update)
+	adminRoutes.DELETE("/roles/:id", h.Role.Delete)
 
 	// User routes (protected)
 	userRoutes := api.Group("/users")
-	userRoutes.Use(middleware.BearerAuthMiddleware)
-	userRoutes.GET("", h.GetAll)
-	userRoutes.GET("/pagination", h.GetAllPagination)
-	userRoutes.GET("/:id", h.GetByID)
-	userRoutes.PUT("/:id", h.Update)
-	userRoutes.DELETE("/:id", h.Delete)
+	userRoutes.Use(mw.Auth...)
+	userRoutes.Use(mw.transactionFor("users")...)
+	userRoutes.GET("", h.User.GetAll, middleware.Deprecate(getAllUsersSunset))
+	userRoutes.GET("/pagination", h.User.GetAllPagination, middleware.PaginationMiddleware(entity.UserSortFields...))
+	userRoutes.GET("/by-username/:username", h.User.GetByUsername)
+	userRoutes.GET("/:id", h.User.GetByID)
+	userRoutes.GET("/:id/vcard", h.User.GetVCard)
+	userRoutes.PUT("/:id", h.User.Update)
+	userRoutes.PATCH("/:id", h.User.Patch)
+	userRoutes.POST("/:id/password", h.User.ChangePassword)
+	userRoutes.POST("/:id/email", h.User.ChangeEmail)
+	userRoutes.DELETE("/:id", h.User.Delete)
 
 	// Profile route (protected)
 	apiRoutes := api.Group("/profile")
-	apiRoutes.Use(middleware.BearerAuthMiddleware)
-	apiRoutes.GET("", h.GetProfile)
+	apiRoutes.Use(mw.Auth...)
+	apiRoutes.Use(mw.transactionFor("profile")...)
+	apiRoutes.GET("", h.User.GetProfile)
+	apiRoutes.GET("/login-history", h.User.GetLoginHistory, middleware.PaginationMiddleware())
+	apiRoutes.GET("/devices", h.User.GetTrustedDevices)
+	apiRoutes.DELETE("/devices/:id", h.User.RevokeTrustedDevice)
+	apiRoutes.POST("/2fa/setup", h.User.SetupTwoFactor)
+	apiRoutes.POST("/2fa/enable", h.User.EnableTwoFactor)
+	apiRoutes.POST("/2fa/recovery-codes/regenerate", h.User.RegenerateRecoveryCodes)
+	if h.APIKeys {
+		// Keys are managed with access tokens only, so a leaked key cannot mint more
+		accessOnly := middleware.RequireScope(utils.ScopeAccess)
+		apiRoutes.POST("/api-keys", h.User.CreateAPIKey, accessOnly)
+		apiRoutes.GET("/api-keys", h.User.GetAPIKeys, accessOnly)
+		apiRoutes.DELETE("/api-keys/:id", h.User.RevokeAPIKey, accessOnly)
+	}
 }
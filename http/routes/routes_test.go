@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/config"
+	"echo-base/domain/entity"
+	"echo-base/domain/repository/memory"
+	"echo-base/domain/usecase"
+	"echo-base/http/handler"
+	"echo-base/http/middleware"
+	"echo-base/utils"
+)
+
+// newTestRouter registers the routes with the role handler over the in-memory store.
+// Other handlers are left nil, so only the role routes may be served.
+func newTestRouter(t *testing.T, h *Handlers) (*echo.Echo, *utils.TokenSigner) {
+	t.Helper()
+
+	signer := utils.NewTokenSigner("test-secret", nil, time.Hour, 24*time.Hour, "")
+	roleUsecase := usecase.NewRoleUsecase(memory.NewRoleRepository(memory.NewUserRepository()), 0)
+	roleHandler, err := handler.NewRoleHandler(roleUsecase, config.Load())
+	if err != nil {
+		t.Fatalf("error creating role handler: %v", err)
+	}
+	h.Role = roleHandler
+
+	e := echo.New()
+	RegisterRoutes(e, h, &Middleware{
+		Auth:         []echo.MiddlewareFunc{middleware.BearerAuthMiddleware(signer)},
+		OptionalAuth: middleware.OptionalBearerAuthMiddleware(signer),
+		Roles:        middleware.RolesAvailableMiddleware(roleUsecase.CheckRequired),
+	})
+	return e, signer
+}
+
+func TestRolesAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		public     bool
+		roleID     int64
+		wantStatus int
+	}{
+		{name: "public without token", public: true, wantStatus: http.StatusOK},
+		{name: "public with user token", public: true, roleID: entity.RoleIDUser, wantStatus: http.StatusOK},
+		{name: "admin-only without token", public: false, wantStatus: http.StatusUnauthorized},
+		{name: "admin-only with user token", public: false, roleID: entity.RoleIDUser, wantStatus: http.StatusForbidden},
+		{name: "admin-only with admin token", public: false, roleID: entity.RoleIDAdmin, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, signer := newTestRouter(t, &Handlers{RolesPublic: tt.public})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/roles", nil)
+			if tt.roleID != 0 {
+				token, err := signer.GenerateToken(1, "caller@example.com", tt.roleID, "", "")
+				if err != nil {
+					t.Fatalf("error issuing token: %v", err)
+				}
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error decoding response %q: %v", rec.Body.String(), err)
+			}
+			if len(resp.Data) != len(entity.RequiredRoles) {
+				t.Fatalf("roles = %d, want %d", len(resp.Data), len(entity.RequiredRoles))
+			}
+			for _, role := range resp.Data {
+				if len(role) != 2 || role["id"] == nil || role["name"] == nil {
+					t.Errorf("role = %v, want only id and name", role)
+				}
+			}
+		})
+	}
+}
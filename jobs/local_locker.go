@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// localLocker implements Locker within a single process
+type localLocker struct {
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// NewLocalLocker creates a process-local Locker, for deployments without PostgreSQL
+func NewLocalLocker() Locker {
+	return &localLocker{locked: make(map[string]bool)}
+}
+
+// TryLock acquires the named lock without waiting
+func (l *localLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locked[name] {
+		return nil, false, nil
+	}
+	l.locked[name] = true
+
+	unlock := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.locked, name)
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// postgresLocker implements Locker with PostgreSQL session-level advisory locks
+type postgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a Locker backed by pg_try_advisory_lock
+func NewPostgresLocker(db *sql.DB) Locker {
+	return &postgresLocker{db: db}
+}
+
+// TryLock acquires the advisory lock for name on a dedicated connection,
+// which is held until unlock is called
+func (l *postgresLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting connection: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("error acquiring advisory lock: %w", err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", name); err != nil {
+			log.Printf("error releasing advisory lock %s: %v\n", name, err)
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned when no job is registered under the given name
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobLocked is returned when another run of the job holds its lock
+	ErrJobLocked = errors.New("job is already running")
+)
+
+// Job is a named background task run on an interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+
+	// Run executes the job and returns a short summary of what it did
+	Run func(ctx context.Context) (string, error)
+}
+
+// Result represents the outcome of a job run
+type Result struct {
+	Name       string    `json:"name"`
+	Summary    string    `json:"summary"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// Locker acquires a lock shared by all instances so a job never runs twice concurrently
+type Locker interface {
+	// TryLock acquires the named lock without waiting, returning ok=false if it is held
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+// Runner holds the registered jobs, runs them on their schedules and on demand
+type Runner struct {
+	jobs   map[string]Job
+	locker Locker
+}
+
+// NewRunner creates a new job runner
+func NewRunner(locker Locker) *Runner {
+	return &Runner{
+		jobs:   make(map[string]Job),
+		locker: locker,
+	}
+}
+
+// Register adds a job to the runner. Each job needs a unique name and a positive interval.
+func (r *Runner) Register(job Job) error {
+	if _, ok := r.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job %s has a non-positive interval %s", job.Name, job.Interval)
+	}
+	r.jobs[job.Name] = job
+	return nil
+}
+
+// RunNow runs the named job immediately, holding its lock for the duration of the run
+func (r *Runner) RunNow(ctx context.Context, name string) (*Result, error) {
+	job, ok := r.jobs[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	unlock, ok, err := r.locker.TryLock(ctx, job.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring job lock: %w", err)
+	}
+	if !ok {
+		return nil, ErrJobLocked
+	}
+	defer unlock()
+
+	startedAt := time.Now()
+	summary, err := job.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error running job %s: %w", job.Name, err)
+	}
+
+	duration := time.Since(startedAt)
+	return &Result{
+		Name:       job.Name,
+		Summary:    summary,
+		StartedAt:  startedAt,
+		DurationMs: float64(duration) / float64(time.Millisecond),
+	}, nil
+}
+
+// Start runs every job on its interval until ctx is cancelled.
+// Scheduled runs that find the job locked are skipped.
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		go r.schedule(ctx, job)
+	}
+}
+
+// schedule runs a single job on its interval
+func (r *Runner) schedule(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.RunNow(ctx, job.Name); err != nil && !errors.Is(err, ErrJobLocked) {
+				log.Printf("error running scheduled job: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunnerRegister(t *testing.T) {
+	run := func(ctx context.Context) (string, error) { return "", nil }
+
+	tests := []struct {
+		name    string
+		job     Job
+		wantErr bool
+	}{
+		{name: "new job", job: Job{Name: "purge", Interval: time.Minute, Run: run}},
+		{name: "duplicate name", job: Job{Name: "cleanup", Interval: time.Minute, Run: run}, wantErr: true},
+		{name: "zero interval", job: Job{Name: "purge", Run: run}, wantErr: true},
+		{name: "negative interval", job: Job{Name: "purge", Interval: -time.Minute, Run: run}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewRunner(NewLocalLocker())
+			if err := runner.Register(Job{Name: "cleanup", Interval: time.Hour, Run: run}); err != nil {
+				t.Fatalf("error registering cleanup: %v", err)
+			}
+
+			err := runner.Register(tt.job)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			// A rejected job never replaces the registered one
+			if got := runner.jobs["cleanup"].Interval; got != time.Hour {
+				t.Errorf("cleanup interval = %s, want %s", got, time.Hour)
+			}
+			if _, ok := runner.jobs[tt.job.Name]; ok == tt.wantErr && tt.job.Name != "cleanup" {
+				t.Errorf("job %s registered = %v, want %v", tt.job.Name, ok, !tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+
+	"echo-base/reporting"
+)
+
+// alertNotifier emails alerts to operators
+type alertNotifier struct {
+	mailer *Mailer
+	to     []string
+}
+
+// NewAlertNotifier creates a notifier that emails each alert to every address in to
+func NewAlertNotifier(m *Mailer, to []string) reporting.Notifier {
+	return &alertNotifier{mailer: m, to: to}
+}
+
+// Notify sends the alert to each recipient, returning the errors of failed sends
+func (n *alertNotifier) Notify(ctx context.Context, alert reporting.Alert) error {
+	var errs []error
+	for _, to := range n.to {
+		if err := n.mailer.Send(ctx, to, TemplateAlert, Data{Title: alert.Title, Message: alert.Message}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Message represents a rendered email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers rendered emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer renders templates and sends them through a Sender
+type Mailer struct {
+	renderer *Renderer
+	sender   Sender
+	appName  string
+}
+
+// New creates a new mailer
+func New(renderer *Renderer, sender Sender, appName string) *Mailer {
+	return &Mailer{
+		renderer: renderer,
+		sender:   sender,
+		appName:  appName,
+	}
+}
+
+// Send renders the named template with data and sends it to the recipient
+func (m *Mailer) Send(ctx context.Context, to, templateName string, data Data) error {
+	if data.AppName == "" {
+		data.AppName = m.appName
+	}
+
+	subject, body, err := m.renderer.Render(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.Send(ctx, Message{To: to, Subject: subject, Body: body})
+}
+
+// smtpSender sends emails through an SMTP server
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates a Sender that delivers through the SMTP server at host:port
+func NewSMTPSender(host, port, username, password, from string) Sender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpSender{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send delivers a plain-text email over SMTP
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	var b strings.Builder
+	b.WriteString("From: " + s.from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+// logSender writes emails to the log instead of sending them (for development)
+type logSender struct{}
+
+// NewLogSender creates a Sender that logs emails
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+// Send logs the email
+func (logSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("email to=%s subject=%q\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template names
+const (
+	TemplateWelcome            = "welcome"
+	TemplateVerifyEmail        = "verify_email"
+	TemplateConfirmEmailChange = "confirm_email_change"
+	TemplateResetPassword      = "reset_password"
+	TemplateAlert              = "alert"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Data is the data available to email templates
+type Data struct {
+	AppName string
+	Name    string
+	Link    string
+
+	// Title and Message describe an operator alert
+	Title   string
+	Message string
+}
+
+// Renderer renders named email templates. Each template defines a "subject" and a "body".
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+// NewRenderer loads the embedded default templates, overriding them with any
+// <name>.tmpl found in dir (when dir is not empty)
+func NewRenderer(dir string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template)}
+
+	entries, err := defaultTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("error reading default templates: %w", err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		tmpl, err := template.ParseFS(defaultTemplates, "templates/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing default template %s: %w", name, err)
+		}
+		r.templates[name] = tmpl
+	}
+
+	if dir == "" {
+		return r, nil
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error opening templates directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing templates in %s: %w", dir, err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		tmpl, err := template.ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template %s: %w", path, err)
+		}
+		r.templates[name] = tmpl
+	}
+
+	return r, nil
+}
+
+// Render renders the subject and body of the named template
+func (r *Renderer) Render(name string, data Data) (string, string, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("email template %q not found", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("error rendering %s subject: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", fmt.Errorf("error rendering %s body: %w", name, err)
+	}
+
+	return strings.TrimSpace(subject.String()), strings.TrimLeft(body.String(), "\n"), nil
+}
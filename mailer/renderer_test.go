@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	data := Data{
+		AppName: "Echo Base",
+		Name:    "Alice",
+		Link:    "https://app.example.com/verify?token=abc",
+		Title:   "High error rate",
+		Message: "5% of requests failed",
+	}
+
+	tests := []struct {
+		template    string
+		wantSubject string
+		wantBody    []string
+	}{
+		{template: TemplateWelcome, wantSubject: "Welcome to Echo Base", wantBody: []string{"Hi Alice,", "Echo Base"}},
+		{template: TemplateVerifyEmail, wantSubject: "Verify your Echo Base email address", wantBody: []string{"Hi Alice,", data.Link}},
+		{template: TemplateConfirmEmailChange, wantSubject: "Confirm your new Echo Base email address", wantBody: []string{"Hi Alice,", data.Link}},
+		{template: TemplateResetPassword, wantSubject: "Reset your Echo Base password", wantBody: []string{"Hi Alice,", data.Link}},
+		{template: TemplateAlert, wantSubject: "[Echo Base] High error rate", wantBody: []string{"High error rate", "5% of requests failed"}},
+	}
+
+	r, err := NewRenderer("")
+	if err != nil {
+		t.Fatalf("error loading default templates: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			subject, body, err := r.Render(tt.template, data)
+			if err != nil {
+				t.Fatalf("error rendering: %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("body = %q, want it to contain %q", body, want)
+				}
+			}
+			if strings.HasPrefix(body, "\n") {
+				t.Errorf("body = %q, want leading newlines trimmed", body)
+			}
+		})
+	}
+}
+
+func TestRendererOverrides(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "subject"}}Hello from {{.AppName}}{{end}}{{define "body"}}Custom welcome for {{.Name}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, TemplateWelcome+".tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRenderer(dir)
+	if err != nil {
+		t.Fatalf("error loading templates: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		wantSubject string
+		wantErr     bool
+	}{
+		{name: "overridden template", template: TemplateWelcome, wantSubject: "Hello from Echo Base"},
+		{name: "default kept", template: TemplateResetPassword, wantSubject: "Reset your Echo Base password"},
+		{name: "unknown template", template: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, _, err := r.Render(tt.template, Data{AppName: "Echo Base", Name: "Alice"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("error = nil, want a missing template error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error rendering: %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestNewRendererInvalidDir(t *testing.T) {
+	if _, err := NewRenderer(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("error = nil, want an error for a missing templates directory")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte(`{{define "subject"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRenderer(dir); err == nil {
+		t.Error("error = nil, want an error for an unparseable template")
+	}
+}
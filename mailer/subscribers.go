@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"context"
+	"log"
+
+	"echo-base/events"
+	"echo-base/utils"
+)
+
+// SubscribeWelcomeEmail sends a welcome email for every UserRegistered event.
+// Events are delivered by the outbox relay, outside the registration request,
+// and a failed send is logged and retried on the next relay run.
+func SubscribeWelcomeEmail(bus events.Bus, m *Mailer) {
+	bus.Subscribe(events.UserRegistered, func(ctx context.Context, event events.Event) error {
+		var payload events.UserRegisteredPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+
+		if err := m.Send(ctx, payload.Email, TemplateWelcome, Data{Name: payload.Name}); err != nil {
+			log.Printf("error sending welcome email to user %d: %v\n", payload.UserID, err)
+			return err
+		}
+		return nil
+	})
+}
+
+// VerificationTokenFunc issues a token with the given scope proving the user owns email
+type VerificationTokenFunc func(userID int64, email, scope string) (string, error)
+
+// SubscribeVerificationEmail sends an email verification link for every UserRegistered
+// and EmailVerificationRequested event. The token is issued at send time so it is never
+// stored in the outbox.
+func SubscribeVerificationEmail(bus events.Bus, m *Mailer, issueToken VerificationTokenFunc, frontendURL string) {
+	send := func(ctx context.Context, userID int64, name, email string) error {
+		return sendVerification(ctx, m, issueToken, frontendURL, userID, name, email, utils.ScopeVerify, TemplateVerifyEmail)
+	}
+
+	bus.Subscribe(events.UserRegistered, func(ctx context.Context, event events.Event) error {
+		var payload events.UserRegisteredPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		return send(ctx, payload.UserID, payload.Name, payload.Email)
+	})
+
+	bus.Subscribe(events.EmailVerificationRequested, func(ctx context.Context, event events.Event) error {
+		var payload events.EmailVerificationRequestedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		return send(ctx, payload.UserID, payload.Name, payload.Email)
+	})
+}
+
+// EmailChangeTokenFunc issues a token confirming a user's change from currentEmail to newEmail
+type EmailChangeTokenFunc func(userID int64, currentEmail, newEmail string) (string, error)
+
+// SubscribeEmailChangeEmail sends a confirmation link to the new address for every
+// EmailChangeRequested event. Like verification links, the token is issued at send time.
+func SubscribeEmailChangeEmail(bus events.Bus, m *Mailer, issueToken EmailChangeTokenFunc, frontendURL string) {
+	bus.Subscribe(events.EmailChangeRequested, func(ctx context.Context, event events.Event) error {
+		var payload events.EmailChangeRequestedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+
+		token, err := issueToken(payload.UserID, payload.CurrentEmail, payload.Email)
+		if err != nil {
+			return err
+		}
+		return sendVerificationLink(ctx, m, frontendURL, token, payload.UserID, payload.Name, payload.Email, TemplateConfirmEmailChange)
+	})
+}
+
+// sendVerification emails a link carrying a token with scope for email to the user
+func sendVerification(ctx context.Context, m *Mailer, issueToken VerificationTokenFunc, frontendURL string, userID int64, name, email, scope, template string) error {
+	token, err := issueToken(userID, email, scope)
+	if err != nil {
+		return err
+	}
+	return sendVerificationLink(ctx, m, frontendURL, token, userID, name, email, template)
+}
+
+// sendVerificationLink emails a verification link carrying token to the user
+func sendVerificationLink(ctx context.Context, m *Mailer, frontendURL, token string, userID int64, name, email, template string) error {
+	link := utils.EmailVerificationLink(frontendURL, token)
+	if err := m.Send(ctx, email, template, Data{Name: name, Link: link}); err != nil {
+		log.Printf("error sending verification email to user %d: %v\n", userID, err)
+		return err
+	}
+	return nil
+}
+
+// PasswordResetTokenFunc issues a password reset token for a user, returning an empty
+// token when the user no longer exists
+type PasswordResetTokenFunc func(ctx context.Context, userID int64) (string, error)
+
+// SubscribePasswordResetEmail sends a password reset link for every PasswordResetRequested
+// event. Like verification links, the token is issued at send time.
+func SubscribePasswordResetEmail(bus events.Bus, m *Mailer, issueToken PasswordResetTokenFunc, frontendURL string) {
+	bus.Subscribe(events.PasswordResetRequested, func(ctx context.Context, event events.Event) error {
+		var payload events.PasswordResetRequestedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+
+		token, err := issueToken(ctx, payload.UserID)
+		if err != nil || token == "" {
+			return err
+		}
+
+		link := utils.PasswordResetLink(frontendURL, token)
+		if err := m.Send(ctx, payload.Email, TemplateResetPassword, Data{Name: payload.Name, Link: link}); err != nil {
+			log.Printf("error sending password reset email to user %d: %v\n", payload.UserID, err)
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"echo-base/domain/repository/memory"
+	"echo-base/events"
+)
+
+// fakeSender records sent emails and fails while err is set
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func (s *fakeSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestSubscribeWelcomeEmail(t *testing.T) {
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		sendErr   error
+		wantSent  int
+		wantRetry bool
+	}{
+		{name: "welcome email sent", wantSent: 1},
+		{name: "failed send is kept for a retry", sendErr: errors.New("smtp down"), wantSent: 0, wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{err: tt.sendErr}
+			bus := events.NewBus()
+			SubscribeWelcomeEmail(bus, New(renderer, sender, "Echo Base"))
+
+			outbox := memory.NewOutboxRepository()
+			event, err := events.New(events.UserRegistered, events.UserRegisteredPayload{UserID: 7, Name: "Alice", Email: "alice@example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := outbox.Enqueue(context.Background(), event); err != nil {
+				t.Fatal(err)
+			}
+
+			relay := events.NewRelay(outbox, bus, 10, events.RetryPolicy{MaxAttempts: 3})
+			delivered, _ := relay.RunOnce(context.Background())
+
+			if len(sender.sent) != tt.wantSent {
+				t.Fatalf("sent %d emails, want %d", len(sender.sent), tt.wantSent)
+			}
+			if tt.wantSent > 0 {
+				msg := sender.sent[0]
+				if msg.To != "alice@example.com" || msg.Subject != "Welcome to Echo Base" || !strings.Contains(msg.Body, "Hi Alice,") {
+					t.Errorf("message = %+v, want a welcome email to alice@example.com", msg)
+				}
+			}
+
+			if retried := delivered == 0; retried != tt.wantRetry {
+				t.Fatalf("event kept for retry = %v, want %v", retried, tt.wantRetry)
+			}
+			if !tt.wantRetry {
+				return
+			}
+
+			// The next relay run delivers the event once the sender recovers
+			sender.err = nil
+			if delivered, err := relay.RunOnce(context.Background()); err != nil || delivered != 1 {
+				t.Fatalf("retry delivered %d (err %v), want 1", delivered, err)
+			}
+			if len(sender.sent) != 1 {
+				t.Errorf("sent %d emails after retry, want 1", len(sender.sent))
+			}
+		})
+	}
+}
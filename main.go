@@ -1,62 +1,425 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"echo-base/analytics"
 	"echo-base/config"
 	"echo-base/database"
 	"echo-base/domain/repository"
+	"echo-base/domain/repository/memory"
 	"echo-base/domain/usecase"
+	"echo-base/events"
 	"echo-base/http/handler"
 	"echo-base/http/middleware"
 	"echo-base/http/routes"
+	"echo-base/jobs"
+	"echo-base/mailer"
+	"echo-base/metrics"
+	"echo-base/reporting"
+	"echo-base/utils"
 )
 
 func main() {
+	seed := flag.Bool("seed", false, "create the admin user from ADMIN_EMAIL and ADMIN_PASSWORD if no admin exists")
+	migrateDown := flag.Int("migrate-down", 0, "roll back the last `n` applied migrations and exit")
+	confirmProduction := flag.Bool("confirm-production", false, "allow -migrate-down when APP_ENV is production")
+	flag.Parse()
+
+	// Load variables from .env (or ENV_FILE) without overriding the real environment
+	envFile, err := config.LoadEnvFile()
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if envFile != "" {
+		log.Printf("Loaded environment from %s\n", envFile)
+	}
+
 	// Load config
 	cfg := config.Load()
-	dbCfg := config.LoadDatabaseConfig()
-
-	// Initialize database
-	db, err := database.Connect(dbCfg)
+	if err := cfg.LoadEmailDomainDenyListFile(); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if *seed {
+		cfg.SeedAdmin = true
+	}
+	dbCfg, err := config.LoadDatabaseConfig()
 	if err != nil {
-		log.Fatalf("error connecting to database: %v", err)
+		log.Fatalf("error loading database config: %v", err)
+	}
+
+	jwtCfg := config.LoadJWTConfig(cfg.AppEnv)
+
+	// Report every configuration problem at once
+	if err := errors.Join(cfg.Validate(), dbCfg.Validate(cfg.AppEnv), jwtCfg.Validate(cfg.AppEnv)); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
 	}
-	defer database.Close(db)
 
-	// Run migrations
-	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("error running migrations: %v", err)
+	// Configure token signing (previous secrets stay valid during rotation)
+	signer := utils.NewTokenSigner(jwtCfg.Secret, jwtCfg.PreviousSecrets, jwtCfg.Expiration, jwtCfg.RefreshExpiration, jwtCfg.Issuer)
+
+	// Initialize database (skipped for the in-memory store)
+	var db *sql.DB
+	if *migrateDown > 0 && dbCfg.IsMemory() {
+		log.Fatal("-migrate-down requires a database; the in-memory store has no migrations")
+	}
+	if !dbCfg.IsMemory() {
+		db, err = database.Connect(dbCfg)
+		if err != nil {
+			log.Fatalf("error connecting to database: %v", err)
+		}
+		defer database.Close(db)
+
+		// Roll back instead of starting the server when asked to
+		if *migrateDown > 0 {
+			if cfg.IsProduction() && !*confirmProduction {
+				log.Fatal("refusing to roll back migrations in production without -confirm-production")
+			}
+			if err := database.RollbackMigrations(db, *migrateDown); err != nil {
+				log.Fatalf("error rolling back migrations: %v", err)
+			}
+			return
+		}
+
+		// Run migrations
+		if err := database.RunMigrations(db); err != nil {
+			log.Fatalf("error running migrations: %v", err)
+		}
 	}
 
 	// Initialize Echo instance
 	e := echo.New()
 	e.HideBanner = true
+	e.JSONSerializer = &utils.JSONSerializer{Pretty: cfg.JSONPretty, Meta: cfg.ResponseMeta, Version: config.Version}
 
-	// Initialize repositories (using PostgreSQL)
-	userRepo := repository.NewUserRepository(db)
+	// Initialize repositories (PostgreSQL, or in-memory when DB_DRIVER=memory)
+	var (
+		userRepo         repository.UserRepository
+		outboxRepo       repository.OutboxRepository
+		sessionRepo      repository.SessionRepository
+		loginHistoryRepo repository.LoginHistoryRepository
+		deviceRepo       repository.TrustedDeviceRepository
+		twoFactorRepo    repository.TwoFactorRepository
+		apiKeyRepo       repository.APIKeyRepository
+		store            repository.Store
+		systemRepo       repository.SystemRepository
+		roleRepo         repository.RoleRepository
+		counterStore     analytics.Store
+		jobLocker        jobs.Locker
+	)
+	if dbCfg.IsMemory() {
+		log.Println("Using in-memory store; data will be lost on restart")
+		userRepo = memory.NewUserRepository()
+		outboxRepo = memory.NewOutboxRepository()
+		sessionRepo = memory.NewSessionRepository()
+		loginHistoryRepo = memory.NewLoginHistoryRepository()
+		deviceRepo = memory.NewTrustedDeviceRepository()
+		twoFactorRepo = memory.NewTwoFactorRepository()
+		apiKeyRepo = memory.NewAPIKeyRepository()
+		systemRepo = memory.NewSystemRepository()
+		roleRepo = memory.NewRoleRepository(userRepo)
+		store = memory.NewStore(repository.Repositories{Users: userRepo, Roles: roleRepo, Outbox: outboxRepo})
+		jobLocker = jobs.NewLocalLocker()
+	} else {
+		userRepo = repository.NewUserRepository(db)
+		outboxRepo = repository.NewOutboxRepository(db)
+		sessionRepo = repository.NewSessionRepository(db)
+		loginHistoryRepo = repository.NewLoginHistoryRepository(db)
+		deviceRepo = repository.NewTrustedDeviceRepository(db)
+		twoFactorRepo = repository.NewTwoFactorRepository(db)
+		apiKeyRepo = repository.NewAPIKeyRepository(db)
+		store = repository.NewStore(db)
+		systemRepo = repository.NewSystemRepository(db)
+		roleRepo = repository.NewRoleRepository(db)
+		if cfg.StatsCountersPersist {
+			counterStore = repository.NewCounterRepository(db)
+		}
+		jobLocker = jobs.NewPostgresLocker(db)
+	}
+
+	// Initialize mailer (emails are logged when SMTP is not configured)
+	renderer, err := mailer.NewRenderer(cfg.EmailTemplatesDir)
+	if err != nil {
+		log.Fatalf("error loading email templates: %v", err)
+	}
+	sender := mailer.NewLogSender()
+	if cfg.SMTPHost != "" {
+		sender = mailer.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	mail := mailer.New(renderer, sender, cfg.AppName)
+
+	// Initialize event bus and its subscribers
+	bus := events.NewBus()
+	if cfg.WelcomeEmailEnabled {
+		mailer.SubscribeWelcomeEmail(bus, mail)
+	}
+	mailer.SubscribeVerificationEmail(bus, mail, func(userID int64, email, scope string) (string, error) {
+		return signer.GenerateScopedToken(userID, email, scope, cfg.EmailVerificationExpiration)
+	}, cfg.FrontendURL)
+	mailer.SubscribeEmailChangeEmail(bus, mail, func(userID int64, currentEmail, newEmail string) (string, error) {
+		return signer.GenerateEmailChangeToken(userID, currentEmail, newEmail, cfg.EmailVerificationExpiration)
+	}, cfg.FrontendURL)
+	mailer.SubscribePasswordResetEmail(bus, mail, func(ctx context.Context, userID int64) (string, error) {
+		user, err := userRepo.GetByID(ctx, userID)
+		if err != nil || user == nil {
+			return "", err
+		}
+		return signer.GeneratePasswordReset(user.ID, user.Password, cfg.PasswordResetExpiration)
+	}, cfg.FrontendURL)
+	counters := analytics.NewCounters(counterStore)
+	if err := counters.Load(cfg.StatsCountersResetOnStart); err != nil {
+		log.Fatalf("error loading counters: %v", err)
+	}
+	analytics.Subscribe(bus, counters)
+
+	// Cancelled on SIGINT or SIGTERM to stop background work and shut the server down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Register and start background jobs
+	relay := events.NewRelay(outboxRepo, bus, cfg.OutboxBatchSize, events.RetryPolicy{
+		MaxAttempts: cfg.OutboxMaxAttempts,
+		Backoff:     cfg.OutboxRetryBackoff,
+		Lease:       cfg.OutboxLease,
+	})
+	jobRunner := jobs.NewRunner(jobLocker)
+	if err := errors.Join(
+		jobRunner.Register(jobs.Job{
+			Name:     "outbox-relay",
+			Interval: cfg.OutboxPollInterval,
+			Run: func(ctx context.Context) (string, error) {
+				sent, err := relay.RunOnce(ctx)
+				return fmt.Sprintf("%d events relayed", sent), err
+			},
+		}),
+		jobRunner.Register(jobs.Job{
+			Name:     "session-cleanup",
+			Interval: cfg.SessionCleanupInterval,
+			Run: func(ctx context.Context) (string, error) {
+				deleted, err := sessionRepo.DeleteInactive(ctx, time.Now().Add(-cfg.SessionRetention))
+				return fmt.Sprintf("%d inactive sessions deleted", deleted), err
+			},
+		}),
+	); err != nil {
+		log.Fatalf("error registering jobs: %v", err)
+	}
+	jobRunner.Start(ctx)
 
 	// Initialize usecases
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, outboxRepo, sessionRepo, loginHistoryRepo, deviceRepo, twoFactorRepo, apiKeyRepo, store, signer, cfg)
+	roleUsecase := usecase.NewRoleUsecase(roleRepo, cfg.RolesCacheTTL)
+	if err := roleUsecase.EnsureRequired(ctx, cfg.RolesAutoCreate); err != nil {
+		log.Fatalf("error checking roles: %v", err)
+	}
+
+	// Create the first admin of a fresh deployment
+	if cfg.SeedAdmin {
+		created, err := userUsecase.SeedAdmin(ctx, cfg.AdminName, cfg.AdminEmail, cfg.AdminPassword)
+		if err != nil {
+			log.Fatalf("error seeding admin: %v", err)
+		}
+		if created {
+			log.Printf("Created admin user %s\n", cfg.AdminEmail)
+		}
+	}
 
 	// Initialize handlers
-	userHandler := handler.NewUserHandler(userUsecase)
+	userHandler, err := handler.NewUserHandler(userUsecase, cfg)
+	if err != nil {
+		log.Fatalf("error loading validation translations: %v", err)
+	}
+	healthHandler := handler.NewHealthHandler(db, cfg.HealthCheckTimeout)
+	runtimeHandler := handler.NewRuntimeHandler()
+	jobHandler := handler.NewJobHandler(jobRunner)
+	depsHandler := handler.NewDepsHandler(systemRepo)
+	roleHandler, err := handler.NewRoleHandler(roleUsecase, cfg)
+	if err != nil {
+		log.Fatalf("error loading validation translations: %v", err)
+	}
+	countersHandler := handler.NewCountersHandler(counters)
+
+	// Record request and connection pool metrics, if enabled
+	var metricsRegistry *metrics.Registry
+	var metricsHandler *handler.MetricsHandler
+	if cfg.MetricsEnabled {
+		metricsRegistry = metrics.NewRegistry()
+		if db != nil {
+			metrics.RegisterDBStats(metricsRegistry, db)
+		}
+		metricsHandler = handler.NewMetricsHandler(metricsRegistry)
+	}
+
+	var rateLimitStore middleware.RateLimitStore
+	var rateLimitHandler *handler.RateLimitHandler
+	if cfg.RateLimitPerMinute > 0 {
+		rateLimitStore = middleware.NewMemoryRateLimitStore(cfg.RateLimitPerMinute, cfg.RateLimitBurst)
+		rateLimitHandler = handler.NewRateLimitHandler(rateLimitStore)
+	}
+
+	// Strip proxy path prefix before routing
+	if cfg.StripPathPrefix != "" {
+		e.Pre(middleware.StripPrefixMiddleware(cfg.StripPathPrefix))
+	}
+
+	// Serve HEAD requests with the GET routes
+	if cfg.HeadRequests {
+		e.Pre(middleware.HeadMiddleware())
+	}
+
+	// Forward panics and server errors to the error-tracking sink, if configured
+	reporter := reporting.NewNoopReporter()
+	if cfg.ErrorReportURL != "" {
+		reporter = reporting.NewWebhookReporter(cfg.ErrorReportURL, cfg.ErrorReportTimeout)
+	}
+
+	// Alert operators on error rate spikes and failing database health checks, if configured
+	var notifiers []reporting.Notifier
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, reporting.NewWebhookNotifier(cfg.AlertWebhookURL, cfg.ErrorReportTimeout))
+	}
+	if len(cfg.AlertEmails) > 0 {
+		notifiers = append(notifiers, mailer.NewAlertNotifier(mail, cfg.AlertEmails))
+	}
+	var monitor *reporting.Monitor
+	if len(notifiers) > 0 {
+		var healthCheck func(ctx context.Context) error
+		if db != nil {
+			healthCheck = db.PingContext
+		}
+		monitor = reporting.NewMonitor(reporting.MonitorConfig{
+			ErrorRatePercent:     cfg.AlertErrorRatePercent,
+			ErrorRateWindow:      cfg.AlertErrorRateWindow,
+			ErrorRateMinRequests: cfg.AlertErrorRateMinRequests,
+			HealthCheckInterval:  cfg.AlertHealthCheckInterval,
+			HealthCheckFailures:  cfg.AlertHealthCheckFailures,
+			Cooldown:             cfg.AlertCooldown,
+		}, notifiers, healthCheck)
+		monitor.Start(ctx)
+	}
 
 	// Register global middleware
-	e.Use(middleware.LoggerMiddleware())
-	e.Use(middleware.RecoverMiddleware())
-	e.Use(middleware.CORSMiddleware())
+	e.Use(middleware.RequestIDMiddleware(cfg.RequestIDHeader))
+	e.Use(middleware.LoggerMiddleware(cfg))
+
+	// Shed load before it reaches handlers while the service is overloaded
+	if cfg.LoadShedLatency > 0 || cfg.LoadShedMaxInFlight > 0 {
+		shedder := middleware.NewLoadShedder(cfg.LoadShedLatency, cfg.LoadShedMaxInFlight, cfg.LoadShedWindow, cfg.LoadShedExemptPaths)
+		e.Use(shedder.Middleware())
+	}
+
+	e.Use(middleware.RecoverMiddleware(reporter))
+	e.Use(middleware.ErrorReportMiddleware(reporter))
+	if monitor != nil {
+		e.Use(middleware.MonitorMiddleware(monitor))
+	}
+	if metricsRegistry != nil {
+		e.Use(middleware.MetricsMiddleware(metricsRegistry))
+	}
+	if cfg.CompressionEnabled {
+		e.Use(middleware.CompressionMiddleware(cfg.CompressionLevel, cfg.CompressionMinLength, cfg.CompressionExcludedTypes))
+	}
+	e.Use(middleware.CORSMiddleware(cfg.CORSAllowedOrigins, cfg.CORSAllowedMethods, cfg.CORSAllowedHeaders))
+
+	// Reject oversized request bodies; the configuration was validated at startup
+	bodyLimit, bodyLimitRoutes, _ := cfg.BodyLimits()
+	e.Use(middleware.BodyLimitMiddleware(bodyLimit, bodyLimitRoutes))
+
+	// Build the middleware chain for protected routes; with API keys enabled a request
+	// may authenticate with an X-API-Key header instead of a bearer token
+	authMiddleware := []echo.MiddlewareFunc{
+		middleware.AuthMiddleware(cfg, signer),
+		middleware.RequireScope(utils.ScopeAccess),
+	}
+	if cfg.APIKeysEnabled && !cfg.AuthDisabled {
+		authMiddleware[0] = middleware.APIKeyAuthMiddleware(userUsecase, authMiddleware[0])
+		authMiddleware[1] = middleware.RequireScope(utils.ScopeAccess, utils.ScopeAPIKey)
+	}
+	if cfg.NoStoreAuthenticated {
+		authMiddleware = append(authMiddleware, middleware.NoStoreMiddleware)
+	}
+	if cfg.SessionIdleTimeout > 0 && !cfg.AuthDisabled {
+		authMiddleware = append(authMiddleware, middleware.IdleSessionMiddleware(
+			sessionRepo, cfg.SessionIdleTimeout, cfg.SessionActivityWriteInterval,
+		))
+	}
+	if cfg.AuthCheckUserStatus && !cfg.AuthDisabled {
+		authMiddleware = append(authMiddleware, middleware.UserStatusMiddleware(userRepo))
+	}
+	var rateLimitMiddleware []echo.MiddlewareFunc
+	if rateLimitStore != nil {
+		rateLimitMiddleware = append(rateLimitMiddleware, middleware.RateLimitMiddleware(rateLimitStore))
+		authMiddleware = append(authMiddleware, rateLimitMiddleware...)
+	}
+
+	// Limit brute-force attempts on the auth endpoints
+	var authRateLimitMiddleware []echo.MiddlewareFunc
+	if cfg.AuthRateLimitPerMinute > 0 {
+		authRateLimitStore := middleware.NewMemoryRateLimitStore(cfg.AuthRateLimitPerMinute, cfg.AuthRateLimitBurst)
+		authRateLimitMiddleware = append(authRateLimitMiddleware, middleware.AuthRateLimitMiddleware(authRateLimitStore, cfg.AuthRateLimitByEmail))
+	}
+
+	// Request-scoped transactions need a database connection
+	var transactionMiddleware echo.MiddlewareFunc
+	if db != nil {
+		transactionMiddleware = middleware.TransactionMiddleware(db)
+	}
 
 	// Register routes (moved to http/routes)
-	routes.RegisterRoutes(e, userHandler)
+	routes.RegisterRoutes(e, &routes.Handlers{
+		User:      userHandler,
+		Health:    healthHandler,
+		Runtime:   runtimeHandler,
+		Job:       jobHandler,
+		Deps:      depsHandler,
+		Role:      roleHandler,
+		Counters:  countersHandler,
+		RateLimit: rateLimitHandler,
+		Metrics:   metricsHandler,
+
+		APIKeys:     cfg.APIKeysEnabled,
+		RolesPublic: cfg.RolesPublic,
+	}, &routes.Middleware{
+		Auth:          authMiddleware,
+		OptionalAuth:  middleware.OptionalBearerAuthMiddleware(signer),
+		RateLimit:     rateLimitMiddleware,
+		AuthRateLimit: authRateLimitMiddleware,
+		Roles:         middleware.RolesAvailableMiddleware(roleUsecase.CheckRequired),
+
+		Transaction:       transactionMiddleware,
+		TransactionGroups: cfg.RequestTxGroups,
+	})
 
 	// Start server
 	addr := fmt.Sprintf(":%s", cfg.Port)
+	logStartupDiagnostics(cfg, dbCfg, jwtCfg, addr)
 	log.Printf("[%s] Server running on %s\n", cfg.AppName, addr)
-	if err := e.Start(addr); err != nil {
-		log.Fatalf("error starting server: %v", err)
+	go func() {
+		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error starting server: %v", err)
+		}
+	}()
+
+	// Wait for a shutdown signal, then let in-flight requests finish within the
+	// timeout before the database is closed by the deferred Close
+	<-ctx.Done()
+	stop()
+	log.Printf("[%s] Shutting down (timeout %s)\n", cfg.AppName, cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down server: %v\n", err)
 	}
 }
@@ -0,0 +1,22 @@
+package metrics
+
+import "database/sql"
+
+// RegisterDBStats registers gauges for the connection pool of db
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	r.AddGauge("db_open_connections", "Number of established database connections, in use and idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	r.AddGauge("db_in_use_connections", "Number of database connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	r.AddGauge("db_idle_connections", "Number of idle database connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	r.AddGauge("db_max_open_connections", "Maximum number of open database connections (0 is unlimited).", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	r.AddGauge("db_wait_count", "Total number of connections waited for since startup.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the request duration histogram buckets, in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey labels a request series. Route is the route template (e.g. /users/:id),
+// never the raw path, so the number of series stays bounded.
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+// inFlightKey labels the in-flight gauge; the status is unknown until the request ends
+type inFlightKey struct {
+	method string
+	route  string
+}
+
+// histogram counts request durations per bucket; counts are made cumulative when written
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// gaugeFunc is a gauge whose value is read when the metrics are written
+type gaugeFunc struct {
+	name  string
+	help  string
+	value func() float64
+}
+
+// Registry collects HTTP request metrics and gauges and writes them in the Prometheus
+// text exposition format
+type Registry struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	requests map[requestKey]*histogram
+	inFlight map[inFlightKey]int64
+	gauges   []gaugeFunc
+}
+
+// NewRegistry creates an empty registry using DefaultBuckets
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:  DefaultBuckets,
+		requests: make(map[requestKey]*histogram),
+		inFlight: make(map[inFlightKey]int64),
+	}
+}
+
+// AddGauge registers a gauge whose value is read by value on every scrape
+func (r *Registry) AddGauge(name, help string, value func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gauges = append(r.gauges, gaugeFunc{name: name, help: help, value: value})
+}
+
+// StartRequest counts a request as in flight and returns a function that records its
+// status and duration and removes it from the in-flight gauge
+func (r *Registry) StartRequest(method, route string) func(status int) {
+	started := time.Now()
+	key := inFlightKey{method: method, route: route}
+
+	r.mu.Lock()
+	r.inFlight[key]++
+	r.mu.Unlock()
+
+	return func(status int) {
+		seconds := time.Since(started).Seconds()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.inFlight[key]--
+
+		rk := requestKey{method: method, route: route, status: status}
+		h, ok := r.requests[rk]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(r.buckets))}
+			r.requests[rk] = h
+		}
+		for i, bound := range r.buckets {
+			if seconds <= bound {
+				h.counts[i]++
+				break
+			}
+		}
+		h.sum += seconds
+		h.count++
+	}
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	r.mu.Lock()
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := keys[i], keys[j]
+		if a.route != c.route {
+			return a.route < c.route
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.status < c.status
+	})
+
+	b.WriteString("# HELP http_requests_total Total number of HTTP requests.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "http_requests_total{%s} %d\n", key.labels(), r.requests[key].count)
+	}
+
+	b.WriteString("# HELP http_request_duration_seconds HTTP request latency in seconds.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		h := r.requests[key]
+		labels := key.labels()
+		var cumulative uint64
+		for i, bound := range r.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %s\n", labels, formatFloat(h.sum))
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	flightKeys := make([]inFlightKey, 0, len(r.inFlight))
+	for key := range r.inFlight {
+		flightKeys = append(flightKeys, key)
+	}
+	sort.Slice(flightKeys, func(i, j int) bool {
+		if flightKeys[i].route != flightKeys[j].route {
+			return flightKeys[i].route < flightKeys[j].route
+		}
+		return flightKeys[i].method < flightKeys[j].method
+	})
+
+	b.WriteString("# HELP http_requests_in_flight Number of HTTP requests being served.\n")
+	b.WriteString("# TYPE http_requests_in_flight gauge\n")
+	for _, key := range flightKeys {
+		fmt.Fprintf(&b, "http_requests_in_flight{method=%s,route=%s} %d\n",
+			quoteLabel(key.method), quoteLabel(key.route), r.inFlight[key])
+	}
+
+	gauges := append([]gaugeFunc(nil), r.gauges...)
+	r.mu.Unlock()
+
+	// Gauge values are read outside the lock since they may block (e.g. on the pool)
+	for _, g := range gauges {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labels formats the key as Prometheus labels
+func (k requestKey) labels() string {
+	return fmt.Sprintf("method=%s,route=%s,status=%s",
+		quoteLabel(k.method), quoteLabel(k.route), quoteLabel(strconv.Itoa(k.status)))
+}
+
+// labelEscaper escapes label values as the text exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// formatFloat formats a sample value the shortest way that round-trips
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
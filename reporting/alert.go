@@ -0,0 +1,68 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Alert kinds
+const (
+	AlertErrorRate   = "error_rate"
+	AlertHealthCheck = "health_check"
+)
+
+// Alert describes a condition operators should be told about
+type Alert struct {
+	Kind       string    `json:"kind"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Notifier delivers alerts to operators
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// webhookNotifier posts alerts as JSON to an HTTP endpoint
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that POSTs each alert as JSON to url
+func NewWebhookNotifier(url string, timeout time.Duration) Notifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify posts the alert to the webhook
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
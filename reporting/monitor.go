@@ -0,0 +1,157 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MonitorConfig holds the alerting thresholds of a Monitor
+type MonitorConfig struct {
+	// ErrorRatePercent alerts when at least this share of requests in ErrorRateWindow end
+	// in a 5xx status (0 disables). Windows with fewer than ErrorRateMinRequests requests
+	// never alert, so a single failure on an idle instance is not a spike.
+	ErrorRatePercent     int
+	ErrorRateWindow      time.Duration
+	ErrorRateMinRequests int
+
+	// HealthCheckInterval is how often the health check runs (0 disables); an alert is
+	// sent once it has failed HealthCheckFailures times in a row
+	HealthCheckInterval time.Duration
+	HealthCheckFailures int
+
+	// Cooldown is the minimum time between two alerts of the same kind
+	Cooldown time.Duration
+}
+
+// Monitor watches the server error rate and a health check, notifying operators when
+// either crosses its threshold. Alerts of one kind are sent at most once per cooldown.
+type Monitor struct {
+	cfg       MonitorConfig
+	notifiers []Notifier
+	check     func(ctx context.Context) error
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	errors      int
+	failures    int
+	lastAlert   map[string]time.Time
+}
+
+// NewMonitor creates a monitor that sends alerts to notifiers. check is the health check
+// run every HealthCheckInterval; it may be nil.
+func NewMonitor(cfg MonitorConfig, notifiers []Notifier, check func(ctx context.Context) error) *Monitor {
+	return &Monitor{
+		cfg:         cfg,
+		notifiers:   notifiers,
+		check:       check,
+		windowStart: time.Now(),
+		lastAlert:   make(map[string]time.Time),
+	}
+}
+
+// Start runs the health check on its interval until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	if m.check == nil || m.cfg.HealthCheckInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkCtx, cancel := context.WithTimeout(ctx, m.cfg.HealthCheckInterval)
+				m.RecordHealth(checkCtx, m.check(checkCtx))
+				cancel()
+			}
+		}
+	}()
+}
+
+// Record counts a finished request with its response status
+func (m *Monitor) Record(ctx context.Context, status int) {
+	if m.cfg.ErrorRatePercent <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	if now.Sub(m.windowStart) >= m.cfg.ErrorRateWindow {
+		m.windowStart = now
+		m.requests = 0
+		m.errors = 0
+	}
+
+	m.requests++
+	if status >= http.StatusInternalServerError {
+		m.errors++
+	}
+
+	requests, errors := m.requests, m.errors
+	spiking := requests >= m.cfg.ErrorRateMinRequests && errors*100 >= requests*m.cfg.ErrorRatePercent
+	m.mu.Unlock()
+
+	if spiking {
+		m.alert(ctx, Alert{
+			Kind:  AlertErrorRate,
+			Title: "Server error rate is high",
+			Message: fmt.Sprintf("%d of the last %d requests failed with a server error (threshold %d%% over %s)",
+				errors, requests, m.cfg.ErrorRatePercent, m.cfg.ErrorRateWindow),
+		})
+	}
+}
+
+// RecordHealth records the outcome of a health check
+func (m *Monitor) RecordHealth(ctx context.Context, err error) {
+	m.mu.Lock()
+	if err == nil {
+		m.failures = 0
+		m.mu.Unlock()
+		return
+	}
+	m.failures++
+	failures := m.failures
+	m.mu.Unlock()
+
+	if failures >= m.cfg.HealthCheckFailures {
+		m.alert(ctx, Alert{
+			Kind:    AlertHealthCheck,
+			Title:   "Health check is failing",
+			Message: fmt.Sprintf("the health check failed %d times in a row: %v", failures, err),
+		})
+	}
+}
+
+// alert sends the alert to every notifier unless one of the same kind was sent within
+// the cooldown. Notifications are sent in the background so requests are never delayed.
+func (m *Monitor) alert(ctx context.Context, alert Alert) {
+	alert.OccurredAt = time.Now()
+
+	m.mu.Lock()
+	if last, ok := m.lastAlert[alert.Kind]; ok && alert.OccurredAt.Sub(last) < m.cfg.Cooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.lastAlert[alert.Kind] = alert.OccurredAt
+	m.mu.Unlock()
+
+	log.Printf("alert %s: %s\n", alert.Kind, alert.Message)
+
+	ctx = context.WithoutCancel(ctx)
+	for _, notifier := range m.notifiers {
+		go func(notifier Notifier) {
+			if err := notifier.Notify(ctx, alert); err != nil {
+				log.Printf("error sending %s alert: %v\n", alert.Kind, err)
+			}
+		}(notifier)
+	}
+}
@@ -0,0 +1,217 @@
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier records the alerts it is sent
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// sent returns the alerts received once the background notifications have settled
+func (n *recordingNotifier) sent() []Alert {
+	time.Sleep(50 * time.Millisecond)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Alert(nil), n.alerts...)
+}
+
+func TestMonitorErrorRate(t *testing.T) {
+	cfg := MonitorConfig{
+		ErrorRatePercent:     50,
+		ErrorRateWindow:      time.Minute,
+		ErrorRateMinRequests: 4,
+		Cooldown:             time.Minute,
+	}
+
+	tests := []struct {
+		name       string
+		cfg        func(cfg *MonitorConfig)
+		statuses   []int
+		pause      time.Duration
+		wantAlerts int
+	}{
+		{
+			name:       "healthy traffic",
+			statuses:   []int{200, 200, 404, 500, 200, 200},
+			wantAlerts: 0,
+		},
+		{
+			name:       "too few requests",
+			statuses:   []int{500, 500, 500},
+			wantAlerts: 0,
+		},
+		{
+			name:       "threshold crossed",
+			statuses:   []int{200, 500, 503, 500},
+			wantAlerts: 1,
+		},
+		{
+			name:       "spike deduplicated within cooldown",
+			statuses:   []int{500, 500, 500, 500, 500, 500, 500, 500, 500, 500},
+			wantAlerts: 1,
+		},
+		{
+			name:       "alert repeated after cooldown",
+			cfg:        func(cfg *MonitorConfig) { cfg.Cooldown = 20 * time.Millisecond },
+			statuses:   []int{500, 500, 500, 500, 500},
+			pause:      40 * time.Millisecond,
+			wantAlerts: 2,
+		},
+		{
+			name:       "window reset forgets earlier errors",
+			cfg:        func(cfg *MonitorConfig) { cfg.ErrorRateWindow = 20 * time.Millisecond },
+			statuses:   []int{500, 500, 500},
+			pause:      40 * time.Millisecond,
+			wantAlerts: 0,
+		},
+		{
+			name:       "disabled",
+			cfg:        func(cfg *MonitorConfig) { cfg.ErrorRatePercent = 0 },
+			statuses:   []int{500, 500, 500, 500, 500},
+			wantAlerts: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			notifier := &recordingNotifier{}
+			monitor := NewMonitor(cfg, []Notifier{notifier}, nil)
+
+			// With a pause, the statuses are recorded twice with the pause in between
+			rounds := 1
+			if tt.pause > 0 {
+				rounds = 2
+			}
+			for round := range rounds {
+				if round > 0 {
+					time.Sleep(tt.pause)
+				}
+				for _, status := range tt.statuses {
+					monitor.Record(context.Background(), status)
+				}
+			}
+
+			alerts := notifier.sent()
+			if len(alerts) != tt.wantAlerts {
+				t.Fatalf("sent %d alerts, want %d: %+v", len(alerts), tt.wantAlerts, alerts)
+			}
+			for _, alert := range alerts {
+				if alert.Kind != AlertErrorRate {
+					t.Errorf("Kind = %q, want %q", alert.Kind, AlertErrorRate)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitorHealthCheck(t *testing.T) {
+	errUnreachable := errors.New("database unreachable")
+
+	tests := []struct {
+		name       string
+		results    []error
+		wantAlerts int
+	}{
+		{name: "healthy", results: []error{nil, nil, nil}, wantAlerts: 0},
+		{name: "failures below threshold", results: []error{errUnreachable, errUnreachable}, wantAlerts: 0},
+		{name: "success resets the count", results: []error{errUnreachable, errUnreachable, nil, errUnreachable, errUnreachable}, wantAlerts: 0},
+		{name: "repeated failures", results: []error{errUnreachable, errUnreachable, errUnreachable}, wantAlerts: 1},
+		{name: "continued failures deduplicated", results: []error{errUnreachable, errUnreachable, errUnreachable, errUnreachable, errUnreachable}, wantAlerts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			monitor := NewMonitor(MonitorConfig{HealthCheckFailures: 3, Cooldown: time.Minute}, []Notifier{notifier}, nil)
+
+			for _, err := range tt.results {
+				monitor.RecordHealth(context.Background(), err)
+			}
+
+			alerts := notifier.sent()
+			if len(alerts) != tt.wantAlerts {
+				t.Fatalf("sent %d alerts, want %d: %+v", len(alerts), tt.wantAlerts, alerts)
+			}
+			for _, alert := range alerts {
+				if alert.Kind != AlertHealthCheck {
+					t.Errorf("Kind = %q, want %q", alert.Kind, AlertHealthCheck)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitorStart(t *testing.T) {
+	notifier := &recordingNotifier{}
+	check := func(ctx context.Context) error { return errors.New("database unreachable") }
+	monitor := NewMonitor(MonitorConfig{
+		HealthCheckInterval: 5 * time.Millisecond,
+		HealthCheckFailures: 2,
+		Cooldown:            time.Minute,
+	}, []Notifier{notifier}, check)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	monitor.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if alerts := notifier.sent(); len(alerts) != 1 {
+		t.Errorf("sent %d alerts, want 1: %+v", len(alerts), alerts)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusBadGateway, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received Alert
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("error decoding alert: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			alert := Alert{Kind: AlertErrorRate, Title: "Server error rate is high", Message: "5 of 5 failed"}
+			err := NewWebhookNotifier(server.URL, time.Second).Notify(context.Background(), alert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if received.Kind != alert.Kind || received.Message != alert.Message {
+				t.Errorf("received %+v, want %+v", received, alert)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Report describes a panic or server error for an error-tracking sink
+type Report struct {
+	Message    string    `json:"message"`
+	Panic      bool      `json:"panic"`
+	Stack      string    `json:"stack,omitempty"`
+	Status     int       `json:"status"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	RequestID  string    `json:"request_id,omitempty"`
+	UserID     int64     `json:"user_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ErrorReporter forwards reports to an external error-tracking sink
+type ErrorReporter interface {
+	Report(ctx context.Context, report Report)
+}
+
+// noopReporter discards reports; it is used when no sink is configured
+type noopReporter struct{}
+
+// NewNoopReporter creates a reporter that discards every report
+func NewNoopReporter() ErrorReporter {
+	return noopReporter{}
+}
+
+// Report discards the report
+func (noopReporter) Report(ctx context.Context, report Report) {}
+
+// webhookReporter posts reports as JSON to an HTTP endpoint
+type webhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookReporter creates a reporter that POSTs each report as JSON to url.
+// Reports are sent in the background so a slow sink never delays responses.
+func NewWebhookReporter(url string, timeout time.Duration) ErrorReporter {
+	return &webhookReporter{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Report sends the report in the background; delivery errors are only logged
+func (r *webhookReporter) Report(ctx context.Context, report Report) {
+	go func() {
+		if err := r.send(report); err != nil {
+			log.Printf("error sending error report: %v\n", err)
+		}
+	}()
+}
+
+// send posts one report to the webhook
+func (r *webhookReporter) send(report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error encoding error report: %w", err)
+	}
+
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error report rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
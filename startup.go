@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+
+	"echo-base/config"
+)
+
+// logStartupDiagnostics logs a snapshot of the running configuration. Secrets are never logged.
+func logStartupDiagnostics(cfg *config.Config, dbCfg *config.DatabaseConfig, jwtCfg *config.JWTConfig, addr string) {
+	slog.Info("startup",
+		"app", cfg.AppName,
+		"version", config.Version,
+		"env", cfg.AppEnv,
+		"addr", addr,
+		"shutdown_timeout", cfg.ShutdownTimeout,
+		"health_check_timeout", cfg.HealthCheckTimeout,
+	)
+
+	if dbCfg.IsMemory() {
+		slog.Info("startup database", "driver", dbCfg.Driver)
+	} else {
+		slog.Info("startup database",
+			"driver", dbCfg.Driver,
+			"host", dbCfg.Host,
+			"port", dbCfg.Port,
+			"name", dbCfg.Database,
+			"user", dbCfg.User,
+			"sslmode", dbCfg.SSLMode,
+			"max_open_conns", dbCfg.MaxOpenConns,
+			"max_idle_conns", dbCfg.MaxIdleConns,
+			"conn_max_lifetime", dbCfg.ConnMaxLifetime,
+			"conn_max_idle_time", dbCfg.ConnMaxIdleTime,
+			"migrations", "applied",
+		)
+	}
+
+	slog.Info("startup features",
+		"request_id_header", cfg.RequestIDHeader,
+		"log_format", cfg.LogFormat,
+		"log_slow_only", cfg.LogSlowOnly,
+		"log_slow_threshold", cfg.LogSlowThreshold,
+		"jwt_expiration", jwtCfg.Expiration,
+		"jwt_refresh_expiration", jwtCfg.RefreshExpiration,
+		"refresh_token_rotate", cfg.RefreshTokenRotate,
+		"jwt_issuer", jwtCfg.Issuer,
+		"jwt_previous_secrets", len(jwtCfg.PreviousSecrets),
+		"auth_disabled", cfg.AuthDisabled,
+		"totp_issuer", cfg.TOTPIssuer,
+		"log_headers", cfg.LogHeaders,
+		"log_sensitive_headers", cfg.LogSensitiveHeaders,
+		"validation_locales", cfg.ValidationLocales,
+		"json_pretty", cfg.JSONPretty,
+		"response_meta", cfg.ResponseMeta,
+		"username_required", cfg.UsernameRequired,
+		"username_lowercase", cfg.UsernameLowercase,
+		"strip_path_prefix", cfg.StripPathPrefix,
+		"head_requests", cfg.HeadRequests,
+		"no_store_authenticated", cfg.NoStoreAuthenticated,
+		"request_tx_groups", cfg.RequestTxGroups,
+		"email_domain_denylist", len(cfg.EmailDomainDenyList),
+		"email_domain_allowlist", len(cfg.EmailDomainAllowList),
+		"strict_email_mx", cfg.StrictEmailMX,
+		"session_idle_timeout", cfg.SessionIdleTimeout,
+		"auth_check_user_status", cfg.AuthCheckUserStatus,
+		"suspicious_login_new_device", cfg.SuspiciousLoginNewDevice,
+		"suspicious_login_max_sessions", cfg.SuspiciousLoginMaxSessions,
+		"login_history_mask_ip", cfg.LoginHistoryMaskIP,
+		"outbox_poll_interval", cfg.OutboxPollInterval,
+		"outbox_max_attempts", cfg.OutboxMaxAttempts,
+		"smtp_host", cfg.SMTPHost,
+		"metrics", cfg.MetricsEnabled,
+		"seed_admin", cfg.SeedAdmin,
+		"cors_allowed_origins", cfg.CORSAllowedOrigins,
+		"body_limit", cfg.BodyLimit,
+		"body_limit_routes", len(cfg.BodyLimitRoutes),
+		"compression", cfg.CompressionEnabled,
+		"compression_level", cfg.CompressionLevel,
+		"compression_min_length", cfg.CompressionMinLength,
+		"error_reporting", cfg.ErrorReportURL != "",
+		"alerting", cfg.AlertWebhookURL != "" || len(cfg.AlertEmails) > 0,
+		"alert_error_rate_percent", cfg.AlertErrorRatePercent,
+		"alert_health_check_interval", cfg.AlertHealthCheckInterval,
+		"welcome_email", cfg.WelcomeEmailEnabled,
+		"require_email_verification", cfg.RequireEmailVerification,
+		"email_verification_expiration", cfg.EmailVerificationExpiration,
+		"email_change_confirm", cfg.EmailChangeConfirm,
+		"password_reset_expiration", cfg.PasswordResetExpiration,
+		"rate_limit_per_minute", cfg.RateLimitPerMinute,
+		"auth_rate_limit_per_minute", cfg.AuthRateLimitPerMinute,
+		"auth_rate_limit_by_email", cfg.AuthRateLimitByEmail,
+		"load_shed_latency", cfg.LoadShedLatency,
+		"load_shed_max_in_flight", cfg.LoadShedMaxInFlight,
+		"password_min_length", cfg.PasswordMinLength,
+		"api_keys", cfg.APIKeysEnabled,
+		"roles_public", cfg.RolesPublic,
+		"roles_auto_create", cfg.RolesAutoCreate,
+		"stats_counters_persist", cfg.StatsCountersPersist,
+	)
+}
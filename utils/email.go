@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// EmailDomain returns the normalized (lowercase, no trailing dot) domain part of an email
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+}
+
+// DomainMatches reports whether domain equals, or is a subdomain of, any domain in list
+func DomainMatches(domain string, list []string) bool {
+	for _, entry := range list {
+		entry = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(entry)), ".")
+		if entry == "" {
+			continue
+		}
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// MXResolver looks up MX records; *net.Resolver satisfies it
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// HasMX reports whether domain publishes an MX record able to receive mail.
+// A domain that does not exist or has no MX records (or only a null MX) reports false
+// with a nil error; other lookup failures are returned as errors.
+func HasMX(ctx context.Context, resolver MXResolver, domain string) (bool, error) {
+	records, err := resolver.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, mx := range records {
+		// A null MX (RFC 7505) declares the domain accepts no mail
+		if mx.Host != "." && mx.Host != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
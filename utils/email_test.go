@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestEmailDomain(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"alice@example.com", "example.com"},
+		{"Alice@Example.COM", "example.com"},
+		{"alice@example.com.", "example.com"},
+		{"a@b@mail.example.com", "mail.example.com"},
+		{"no-at-sign", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if got := EmailDomain(tt.email); got != tt.want {
+				t.Errorf("EmailDomain(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainMatches(t *testing.T) {
+	list := []string{"Mailinator.com", " tempmail.org. ", ""}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"mailinator.com", true},
+		{"eu.mailinator.com", true},
+		{"tempmail.org", true},
+		{"notmailinator.com", false},
+		{"example.com", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := DomainMatches(tt.domain, list); got != tt.want {
+				t.Errorf("DomainMatches(%q) = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeMXResolver answers MX lookups from a fixed table
+type fakeMXResolver map[string][]*net.MX
+
+func (r fakeMXResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	switch name {
+	case "unresolvable.example":
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	case "missing.example":
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return r[name], nil
+}
+
+func TestHasMX(t *testing.T) {
+	resolver := fakeMXResolver{
+		"example.com":  {{Host: "mx1.example.com.", Pref: 10}},
+		"null.example": {{Host: ".", Pref: 0}},
+	}
+
+	tests := []struct {
+		domain  string
+		want    bool
+		wantErr bool
+	}{
+		{domain: "example.com", want: true},
+		{domain: "no-records.example", want: false},
+		{domain: "null.example", want: false},
+		{domain: "missing.example", want: false},
+		{domain: "unresolvable.example", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := HasMX(context.Background(), resolver, tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HasMX(%q) = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JSONFieldNames returns the JSON field names of a struct value or type, used as a field allowlist
+func JSONFieldNames(v interface{}) []string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseFields parses a comma-separated field list, rejecting names not in allowed
+func ParseFields(raw string, allowed []string) ([]string, error) {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+
+	fields := make([]string, 0)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !allowedSet[field] {
+			return nil, fmt.Errorf("invalid field %q, allowed fields: %s", field, strings.Join(allowed, ","))
+		}
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields must not be empty")
+	}
+	return fields, nil
+}
+
+// SelectFields reduces v (a struct or a slice of structs) to the given JSON fields
+func SelectFields(v interface{}, fields []string) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response: %w", err)
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	switch value := decoded.(type) {
+	case map[string]interface{}:
+		return pickFields(value, fields), nil
+	case []interface{}:
+		items := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if m, ok := item.(map[string]interface{}); ok {
+				items = append(items, pickFields(m, fields))
+			} else {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	default:
+		return decoded, nil
+	}
+}
+
+// pickFields returns a copy of m holding only the given keys
+func pickFields(m map[string]interface{}, fields []string) map[string]interface{} {
+	picked := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := m[field]; ok {
+			picked[field] = value
+		}
+	}
+	return picked
+}
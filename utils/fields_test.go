@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fieldsTestItem struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Secret string `json:"-"`
+}
+
+func TestJSONFieldNames(t *testing.T) {
+	want := []string{"id", "name", "email"}
+
+	for _, v := range []interface{}{fieldsTestItem{}, &fieldsTestItem{}} {
+		if got := JSONFieldNames(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("JSONFieldNames(%T) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	allowed := []string{"id", "name", "email"}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr string
+	}{
+		{name: "subset", raw: "id,name", want: []string{"id", "name"}},
+		{name: "spaces and empty entries", raw: " email , ,id ", want: []string{"email", "id"}},
+		{name: "invalid field", raw: "id,password", wantErr: `invalid field "password"`},
+		{name: "empty", raw: " , ", wantErr: "must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFields(tt.raw, allowed)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	item := fieldsTestItem{ID: 1, Name: "Alice", Email: "alice@example.com", Secret: "hidden"}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		fields []string
+		want   interface{}
+	}{
+		{
+			name:   "struct",
+			value:  item,
+			fields: []string{"id", "name"},
+			want:   map[string]interface{}{"id": float64(1), "name": "Alice"},
+		},
+		{
+			name:   "slice",
+			value:  []fieldsTestItem{item, {ID: 2, Name: "Bob"}},
+			fields: []string{"name", "email"},
+			want: []interface{}{
+				map[string]interface{}{"name": "Alice", "email": "alice@example.com"},
+				map[string]interface{}{"name": "Bob"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectFields(tt.value, tt.fields)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectFields = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
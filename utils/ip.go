@@ -0,0 +1,16 @@
+package utils
+
+import "net"
+
+// MaskIP coarsens an IP address for display: IPv4 addresses keep their /24 network
+// and IPv6 addresses their /48. Unparseable input is returned empty.
+func MaskIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PasswordResetLink builds the frontend password reset link carrying the token
+func PasswordResetLink(frontendURL, token string) string {
+	return buildFrontendLink(frontendURL, "/reset-password", token)
+}
+
+// EmailVerificationLink builds the frontend email verification link carrying the token
+func EmailVerificationLink(frontendURL, token string) string {
+	return buildFrontendLink(frontendURL, "/verify-email", token)
+}
+
+// buildFrontendLink joins the frontend base URL with path and sets the token query param
+func buildFrontendLink(frontendURL, path, token string) string {
+	u, err := url.Parse(strings.TrimRight(frontendURL, "/") + path)
+	if err != nil {
+		return ""
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
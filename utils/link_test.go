@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFrontendLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(frontendURL, token string) string
+		base     string
+		token    string
+		wantPath string
+	}{
+		{name: "reset link", build: PasswordResetLink, base: "https://app.example.com", token: "abc.def", wantPath: "/reset-password"},
+		{name: "reset link trailing slash", build: PasswordResetLink, base: "https://app.example.com/", token: "abc", wantPath: "/reset-password"},
+		{name: "reset link base path", build: PasswordResetLink, base: "https://example.com/app", token: "abc", wantPath: "/app/reset-password"},
+		{name: "verify link", build: EmailVerificationLink, base: "http://localhost:3000", token: "x+y/z=", wantPath: "/verify-email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := tt.build(tt.base, tt.token)
+
+			u, err := url.Parse(link)
+			if err != nil {
+				t.Fatalf("link %q does not parse: %v", link, err)
+			}
+			base, _ := url.Parse(tt.base)
+			if u.Scheme != base.Scheme || u.Host != base.Host {
+				t.Errorf("link %q does not use the base %q", link, tt.base)
+			}
+			if u.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", u.Path, tt.wantPath)
+			}
+			if got := u.Query().Get("token"); got != tt.token {
+				t.Errorf("token = %q, want %q", got, tt.token)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+)
+
+// DefaultPagination is the first page of the default size, newest first
+var DefaultPagination = entity.PaginationParams{
+	Page:  1,
+	Limit: entity.DefaultPageLimit,
+	Sort:  "created_at",
+	Order: entity.SortDesc,
+}
+
+// ParsePagination reads page, limit, search, sort, order, role_id, created_after and
+// created_before from the query string. Missing values fall back to defaults, limit is
+// clamped to entity.MaxPageLimit, sort must be one of sortable and the created_* bounds
+// are RFC3339 times such as 2026-01-02T15:04:05Z.
+func ParsePagination(c echo.Context, defaults entity.PaginationParams, sortable ...string) (entity.PaginationParams, error) {
+	params := defaults
+	params.Search = strings.TrimSpace(c.QueryParam("search"))
+
+	if p := c.QueryParam("page"); p != "" {
+		parsed, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || parsed < 1 {
+			return params, fmt.Errorf("page must be a positive integer")
+		}
+		params.Page = parsed
+	}
+
+	if l := c.QueryParam("limit"); l != "" {
+		parsed, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || parsed < 1 {
+			return params, fmt.Errorf("limit must be a positive integer")
+		}
+		params.Limit = parsed
+	}
+	if params.Limit > entity.MaxPageLimit {
+		params.Limit = entity.MaxPageLimit
+	}
+
+	if r := c.QueryParam("role_id"); r != "" {
+		parsed, err := strconv.ParseInt(r, 10, 64)
+		if err != nil || parsed < 1 {
+			return params, fmt.Errorf("role_id must be a positive integer")
+		}
+		params.RoleID = parsed
+	}
+
+	for _, bound := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"created_after", &params.CreatedAfter},
+		{"created_before", &params.CreatedBefore},
+	} {
+		if v := c.QueryParam(bound.name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return params, fmt.Errorf("%s must be an RFC3339 time such as 2026-01-02T15:04:05Z", bound.name)
+			}
+			*bound.dest = &parsed
+		}
+	}
+	if params.CreatedAfter != nil && params.CreatedBefore != nil && !params.CreatedAfter.Before(*params.CreatedBefore) {
+		return params, fmt.Errorf("created_after must be before created_before")
+	}
+
+	if d := c.QueryParam("include_deleted"); d != "" {
+		parsed, err := strconv.ParseBool(d)
+		if err != nil {
+			return params, fmt.Errorf("include_deleted must be true or false")
+		}
+		params.IncludeDeleted = parsed
+	}
+
+	if s := c.QueryParam("sort"); s != "" {
+		allowed := false
+		for _, field := range sortable {
+			if field == s {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return params, fmt.Errorf("invalid sort field %q, allowed fields: %s", s, strings.Join(sortable, ","))
+		}
+		params.Sort = s
+	}
+
+	if o := strings.ToLower(c.QueryParam("order")); o != "" {
+		if o != entity.SortAsc && o != entity.SortDesc {
+			return params, fmt.Errorf("order must be %s or %s", entity.SortAsc, entity.SortDesc)
+		}
+		params.Order = o
+	}
+
+	return params, nil
+}
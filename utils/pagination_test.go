@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"echo-base/domain/entity"
+)
+
+func TestParsePagination(t *testing.T) {
+	after := time.Date(2026, time.January, 2, 15, 4, 5, 0, time.UTC)
+	before := after.Add(24 * time.Hour)
+	custom := entity.PaginationParams{Page: 2, Limit: 50, Sort: "name", Order: entity.SortAsc}
+
+	tests := []struct {
+		name     string
+		query    string
+		defaults entity.PaginationParams
+		want     entity.PaginationParams
+		wantErr  string
+	}{
+		{name: "defaults", query: "", defaults: DefaultPagination, want: DefaultPagination},
+		{name: "custom defaults", query: "", defaults: custom, want: custom},
+		{
+			name:     "all params",
+			query:    "page=3&limit=5&search=+ann+&sort=email&order=ASC&role_id=2&include_deleted=true",
+			defaults: DefaultPagination,
+			want:     entity.PaginationParams{Page: 3, Limit: 5, Search: "ann", Sort: "email", Order: entity.SortAsc, RoleID: 2, IncludeDeleted: true},
+		},
+		{
+			name:     "limit clamped",
+			query:    "limit=100000",
+			defaults: DefaultPagination,
+			want:     entity.PaginationParams{Page: 1, Limit: entity.MaxPageLimit, Sort: "created_at", Order: entity.SortDesc},
+		},
+		{
+			name:     "oversized default clamped",
+			query:    "",
+			defaults: entity.PaginationParams{Page: 1, Limit: 1000},
+			want:     entity.PaginationParams{Page: 1, Limit: entity.MaxPageLimit},
+		},
+		{
+			name:     "created range",
+			query:    "created_after=2026-01-02T15:04:05Z&created_before=2026-01-03T15:04:05Z",
+			defaults: DefaultPagination,
+			want:     entity.PaginationParams{Page: 1, Limit: entity.DefaultPageLimit, Sort: "created_at", Order: entity.SortDesc, CreatedAfter: &after, CreatedBefore: &before},
+		},
+		{name: "zero page", query: "page=0", defaults: DefaultPagination, wantErr: "page must be a positive integer"},
+		{name: "non-numeric page", query: "page=two", defaults: DefaultPagination, wantErr: "page must be a positive integer"},
+		{name: "negative limit", query: "limit=-5", defaults: DefaultPagination, wantErr: "limit must be a positive integer"},
+		{name: "invalid role", query: "role_id=admin", defaults: DefaultPagination, wantErr: "role_id must be a positive integer"},
+		{name: "unsortable field", query: "sort=password", defaults: DefaultPagination, wantErr: `invalid sort field "password"`},
+		{name: "invalid order", query: "order=sideways", defaults: DefaultPagination, wantErr: "order must be asc or desc"},
+		{name: "malformed time", query: "created_after=yesterday", defaults: DefaultPagination, wantErr: "created_after must be an RFC3339 time"},
+		{
+			name:     "inverted range",
+			query:    "created_after=2026-01-03T15:04:05Z&created_before=2026-01-02T15:04:05Z",
+			defaults: DefaultPagination,
+			wantErr:  "created_after must be before created_before",
+		},
+		{name: "invalid include_deleted", query: "include_deleted=maybe", defaults: DefaultPagination, wantErr: "include_deleted must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil), httptest.NewRecorder())
+
+			got, err := ParsePagination(c, tt.defaults, entity.UserSortFields...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("params = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package utils
 
 import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,3 +24,119 @@ func CheckPassword(hash, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// Character classes used by PasswordPolicy
+const (
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!@#$%^&*()-_=+[]{}?"
+)
+
+// generatedPasswordMinLength is the shortest password GeneratePassword returns
+const generatedPasswordMinLength = 16
+
+// PasswordPolicy describes the complexity a password must meet
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// ValidatePassword checks password against the policy, describing the first unmet rule
+func ValidatePassword(password string, policy PasswordPolicy) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters", policy.MinLength)
+	}
+	if policy.RequireUpper && !strings.ContainsAny(password, passwordUpper) {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if policy.RequireLower && !strings.ContainsAny(password, passwordLower) {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !strings.ContainsAny(password, passwordDigits) {
+		return errors.New("password must contain a digit")
+	}
+	if policy.RequireSymbol && !strings.ContainsAny(password, passwordSymbols) {
+		return errors.New("password must contain a symbol")
+	}
+	return nil
+}
+
+// Thresholds below which an allowed password is still flagged as weak
+const (
+	weakPasswordLength  = 12
+	weakPasswordClasses = 3
+)
+
+// PasswordWarnings describes weaknesses of a password that the policy allows:
+// a short length or few character classes. It returns nil for a strong password.
+func PasswordWarnings(password string) []string {
+	var warnings []string
+	if len([]rune(password)) < weakPasswordLength {
+		warnings = append(warnings, fmt.Sprintf("password is shorter than %d characters", weakPasswordLength))
+	}
+
+	classes := 0
+	for _, class := range []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols} {
+		if strings.ContainsAny(password, class) {
+			classes++
+		}
+	}
+	if classes < weakPasswordClasses {
+		warnings = append(warnings, "password uses few character types; mix upper and lower case letters, digits and symbols")
+	}
+
+	return warnings
+}
+
+// GeneratePassword returns a random password from crypto/rand that satisfies the policy.
+// It always contains every character class and is at least 16 characters long.
+func GeneratePassword(policy PasswordPolicy) (string, error) {
+	length := policy.MinLength
+	if length < generatedPasswordMinLength {
+		length = generatedPasswordMinLength
+	}
+
+	// One character from each class guarantees the policy, the rest come from all classes
+	classes := []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols}
+	all := strings.Join(classes, "")
+
+	password := make([]byte, 0, length)
+	for _, class := range classes {
+		c, err := randomChar(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle so the guaranteed characters are not always first
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("error generating password: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomChar picks a uniformly random byte from chars
+func randomChar(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, fmt.Errorf("error generating password: %w", err)
+	}
+	return chars[n.Int64()], nil
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePasswordMeetsPolicy(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name       string
+		policy     PasswordPolicy
+		wantLength int
+	}{
+		{name: "no rules", policy: PasswordPolicy{}, wantLength: 16},
+		{name: "all classes", policy: strict, wantLength: 16},
+		{name: "long minimum", policy: PasswordPolicy{MinLength: 40, RequireSymbol: true}, wantLength: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			for range 200 {
+				password, err := GeneratePassword(tt.policy)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(password) != tt.wantLength {
+					t.Fatalf("length = %d, want %d", len(password), tt.wantLength)
+				}
+				// Generated passwords satisfy the strictest policy of their length, not just tt.policy
+				if err := ValidatePassword(password, tt.policy); err != nil {
+					t.Fatalf("password %q fails the policy: %v", password, err)
+				}
+				if err := ValidatePassword(password, strict); err != nil {
+					t.Fatalf("password %q is missing a character class: %v", password, err)
+				}
+				if PasswordWarnings(password) != nil {
+					t.Errorf("password %q is flagged as weak: %v", password, PasswordWarnings(password))
+				}
+				if seen[password] {
+					t.Fatalf("password %q generated twice", password)
+				}
+				seen[password] = true
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		password string
+		wantErr  string
+	}{
+		{password: "Abcdef1!", wantErr: ""},
+		{password: "Abc1!", wantErr: "at least 8 characters"},
+		{password: "abcdefg1!", wantErr: "uppercase"},
+		{password: "ABCDEFG1!", wantErr: "lowercase"},
+		{password: "Abcdefgh!", wantErr: "digit"},
+		{password: "Abcdefgh1", wantErr: "symbol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := ValidatePassword(tt.password, policy)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordWarnings(t *testing.T) {
+	tests := []struct {
+		password  string
+		wantShort bool
+		wantMixed bool
+	}{
+		{password: "Str0ng-Passphrase", wantShort: false, wantMixed: false},
+		{password: "Abcdef1!", wantShort: true, wantMixed: false},
+		{password: "alllowercaseletters", wantShort: false, wantMixed: true},
+		{password: "abcdefgh", wantShort: true, wantMixed: true},
+		{password: "lower-and-symbols", wantShort: false, wantMixed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			var gotShort, gotMixed bool
+			for _, warning := range PasswordWarnings(tt.password) {
+				switch {
+				case strings.Contains(warning, "shorter than"):
+					gotShort = true
+				case strings.Contains(warning, "character types"):
+					gotMixed = true
+				default:
+					t.Errorf("unexpected warning %q", warning)
+				}
+			}
+			if gotShort != tt.wantShort || gotMixed != tt.wantMixed {
+				t.Errorf("short/mixed warnings = %v/%v, want %v/%v", gotShort, gotMixed, tt.wantShort, tt.wantMixed)
+			}
+		})
+	}
+}
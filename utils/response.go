@@ -1,33 +1,79 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"time"
 
-// APIResponse represents the standard API response format
+	ut "github.com/go-playground/universal-translator"
+)
+
+// APIResponse represents the standard API response format. The helpers leave Code
+// unset: JSONSerializer fills it in with the HTTP status the response is written with.
 type APIResponse struct {
-	Success   bool        `json:"success"`
-	Code      int         `json:"code"`
-	Message   string      `json:"message"`
-	Data      interface{} `json:"data,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
+	Success   bool              `json:"success"`
+	Code      int               `json:"code"`
+	Message   string            `json:"message"`
+	Data      interface{}       `json:"data,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	Warnings  []string          `json:"warnings,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+
+	// RequestID is set by JSONSerializer from the request's correlation ID
+	RequestID string `json:"request_id,omitempty"`
+
+	// APIVersion and ServerTime are set by JSONSerializer when envelope metadata is enabled
+	APIVersion string     `json:"api_version,omitempty"`
+	ServerTime *time.Time `json:"server_time,omitempty"`
 }
 
 // SuccessResponse creates a success response
 func SuccessResponse(message string, data interface{}) APIResponse {
 	return APIResponse{
 		Success:   true,
-		Code:      200,
 		Message:   message,
 		Data:      data,
 		Timestamp: time.Now(),
 	}
 }
 
+// WithWarnings returns the response with non-fatal warnings for the client to surface
+func (r APIResponse) WithWarnings(warnings []string) APIResponse {
+	r.Warnings = warnings
+	return r
+}
+
 // ErrorResponse creates an error response
 func ErrorResponse(message string) APIResponse {
 	return APIResponse{
 		Success:   false,
-		Code:      400,
 		Message:   message,
 		Timestamp: time.Now(),
 	}
 }
+
+// ValidationErrorResponse creates an error response with per-field error messages
+func ValidationErrorResponse(message string, errors map[string]string) APIResponse {
+	return APIResponse{
+		Success:   false,
+		Message:   message,
+		Errors:    errors,
+		Timestamp: time.Now(),
+	}
+}
+
+// ValidationFailedResponse creates an error response from validator errors, listing at most
+// max field errors (0 lists all) and noting how many more were omitted. Field messages are
+// translated with trans, or in English when it is nil.
+func ValidationFailedResponse(err error, max int, trans ut.Translator) APIResponse {
+	fields, dropped := FormatValidationErrors(err, max, trans)
+	if fields == nil {
+		return ErrorResponse(err.Error())
+	}
+
+	message := "validation failed"
+	if dropped > 0 {
+		message = fmt.Sprintf("validation failed (and %d more)", dropped)
+	}
+
+	return ValidationErrorResponse(message, fields)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidationFailedResponseMaxErrors(t *testing.T) {
+	type payload struct {
+		Name     string `json:"name" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"min=6"`
+		Age      int    `json:"age" validate:"gte=18"`
+	}
+	err := NewValidator().Struct(payload{Email: "not-an-email", Password: "abc"})
+
+	tests := []struct {
+		name        string
+		max         int
+		wantMessage string
+		wantFields  []string
+	}{
+		{name: "no cap lists all", max: 0, wantMessage: "validation failed", wantFields: []string{"name", "email", "password", "age"}},
+		{name: "cap above count", max: 10, wantMessage: "validation failed", wantFields: []string{"name", "email", "password", "age"}},
+		{name: "cap at count", max: 4, wantMessage: "validation failed", wantFields: []string{"name", "email", "password", "age"}},
+		{name: "low cap truncates", max: 2, wantMessage: "validation failed (and 2 more)", wantFields: []string{"name", "email"}},
+		{name: "cap of one", max: 1, wantMessage: "validation failed (and 3 more)", wantFields: []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ValidationFailedResponse(err, tt.max, nil)
+
+			if resp.Success {
+				t.Errorf("response = %+v, want a failure", resp)
+			}
+			if resp.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Message, tt.wantMessage)
+			}
+			fields := make([]string, 0, len(resp.Errors))
+			for _, field := range tt.wantFields {
+				if _, ok := resp.Errors[field]; ok {
+					fields = append(fields, field)
+				}
+			}
+			if len(resp.Errors) != len(tt.wantFields) || !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("errors = %v, want fields %v", resp.Errors, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidationFailedResponseNonValidationError(t *testing.T) {
+	resp := ValidationFailedResponse(errors.New("boom"), 2, nil)
+	if resp.Message != "boom" || resp.Errors != nil {
+		t.Errorf("response = %+v, want a plain error response", resp)
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JSONSerializer wraps echo's default serializer with optional indentation and
+// envelope metadata. APIResponse envelopes always carry the response status as their
+// code and the request ID, when there is one.
+type JSONSerializer struct {
+	echo.DefaultJSONSerializer
+	Pretty bool
+
+	// Version is added to APIResponse envelopes as api_version, together with
+	// server_time, when Meta is enabled
+	Meta    bool
+	Version string
+}
+
+// Serialize encodes i as JSON, indenting it when Pretty is enabled
+func (s *JSONSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	if s.Pretty && indent == "" {
+		indent = "  "
+	}
+	if resp, ok := i.(APIResponse); ok {
+		// The status is written before the body, so the envelope code can mirror it
+		if status := c.Response().Status; status != 0 {
+			resp.Code = status
+		}
+		resp.RequestID, _ = c.Get("request_id").(string)
+		if s.Meta {
+			now := time.Now().UTC()
+			resp.APIVersion = s.Version
+			resp.ServerTime = &now
+		}
+		i = resp
+	}
+	return s.DefaultJSONSerializer.Serialize(c, i, indent)
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serialize writes payload with serializer the way c.JSON does and returns the body
+func serialize(t *testing.T, serializer *JSONSerializer, payload interface{}) []byte {
+	t.Helper()
+
+	e := echo.New()
+	e.JSONSerializer = serializer
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	if err := c.JSON(http.StatusOK, payload); err != nil {
+		t.Fatalf("error serializing: %v", err)
+	}
+	return rec.Body.Bytes()
+}
+
+func TestJSONSerializerPretty(t *testing.T) {
+	payload := map[string]interface{}{"name": "Alice", "roles": []string{"user", "admin"}}
+
+	pretty := serialize(t, &JSONSerializer{Pretty: true}, payload)
+	compact := serialize(t, &JSONSerializer{Pretty: false}, payload)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		wantIndent bool
+	}{
+		{name: "pretty", body: pretty, wantIndent: true},
+		{name: "compact", body: compact, wantIndent: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if indented := bytes.Contains(tt.body, []byte("\n  \"")); indented != tt.wantIndent {
+				t.Errorf("indented = %v, want %v: %s", indented, tt.wantIndent, tt.body)
+			}
+		})
+	}
+
+	var fromPretty, fromCompact bytes.Buffer
+	if err := json.Compact(&fromPretty, pretty); err != nil {
+		t.Fatalf("pretty output is not valid JSON: %v", err)
+	}
+	if err := json.Compact(&fromCompact, compact); err != nil {
+		t.Fatalf("compact output is not valid JSON: %v", err)
+	}
+	if fromPretty.String() != fromCompact.String() {
+		t.Errorf("pretty and compact output differ:\n%s\n%s", fromPretty.String(), fromCompact.String())
+	}
+}
+
+func TestJSONSerializerMeta(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     bool
+		payload  interface{}
+		wantMeta bool
+	}{
+		{name: "enabled", meta: true, payload: SuccessResponse("ok", nil), wantMeta: true},
+		{name: "enabled for errors", meta: true, payload: ErrorResponse("user not found"), wantMeta: true},
+		{name: "disabled", meta: false, payload: SuccessResponse("ok", nil), wantMeta: false},
+		{name: "enabled but not an envelope", meta: true, payload: map[string]string{"status": "ok"}, wantMeta: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().UTC().Truncate(time.Second)
+			body := serialize(t, &JSONSerializer{Meta: tt.meta, Version: "v1.2.3"}, tt.payload)
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatalf("error decoding %s: %v", body, err)
+			}
+			version, hasVersion := fields["api_version"]
+			serverTime, hasTime := fields["server_time"]
+			if hasVersion != tt.wantMeta || hasTime != tt.wantMeta {
+				t.Fatalf("body %s has api_version %v and server_time %v, want both %v", body, hasVersion, hasTime, tt.wantMeta)
+			}
+			if !tt.wantMeta {
+				return
+			}
+
+			if version != "v1.2.3" {
+				t.Errorf("api_version = %v, want v1.2.3", version)
+			}
+			parsed, err := time.Parse(time.RFC3339Nano, serverTime.(string))
+			if err != nil {
+				t.Fatalf("server_time %v is not RFC 3339: %v", serverTime, err)
+			}
+			if parsed.Before(before) || parsed.After(time.Now().UTC()) || parsed.Location() != time.UTC {
+				t.Errorf("server_time = %v, want the current UTC time", parsed)
+			}
+		})
+	}
+}
+
+func TestJSONSerializerCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		resp   APIResponse
+	}{
+		{name: "created", status: http.StatusCreated, resp: SuccessResponse("user created", nil)},
+		{name: "not found", status: http.StatusNotFound, resp: ErrorResponse("user not found")},
+		{name: "internal error", status: http.StatusInternalServerError, resp: ErrorResponse("internal server error")},
+		{name: "conflict with field errors", status: http.StatusConflict, resp: ValidationErrorResponse("taken", map[string]string{"name": "taken"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.JSONSerializer = &JSONSerializer{}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			if err := c.JSON(tt.status, tt.resp); err != nil {
+				t.Fatalf("error serializing: %v", err)
+			}
+
+			var body APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("error decoding body: %v", err)
+			}
+			if rec.Code != tt.status || body.Code != tt.status {
+				t.Errorf("status = %d, body code = %d; want both %d", rec.Code, body.Code, tt.status)
+			}
+		})
+	}
+}
@@ -1,42 +1,158 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token scopes limit what a token may be used for. Only access tokens reach protected routes.
+const (
+	ScopeAccess      = "access"
+	ScopeReset       = "reset"
+	ScopeVerify      = "verify"
+	ScopeRefresh     = "refresh"
+	ScopeEmailChange = "email_change"
+
+	// ScopeAPIKey marks requests authenticated with an API key rather than a token
+	ScopeAPIKey = "api_key"
+)
+
 // JWTClaims represents JWT claims
 type JWTClaims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	RoleID int64  `json:"role_id"`
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	RoleID    int64  `json:"role_id"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	// PreviousEmail is the address an email change token was requested from
+	PreviousEmail string `json:"prev_email,omitempty"`
 	jwt.RegisteredClaims
 }
 
-const (
-	// JWTSecret is the secret key for JWT (in production, use environment variable)
-	JWTSecret = "your-secret-key-change-in-production"
-	// TokenExpiration is the token expiration duration
-	TokenExpiration = 24 * time.Hour
-)
+// TokenScope returns the token's scope; tokens issued before scopes existed are access tokens
+func (c *JWTClaims) TokenScope() string {
+	if c.Scope == "" {
+		return ScopeAccess
+	}
+	return c.Scope
+}
 
-// GenerateToken generates a JWT token
-func GenerateToken(userID int64, email string, roleID int64) (string, error) {
-	claims := &JWTClaims{
-		UserID: userID,
-		Email:  email,
-		RoleID: roleID,
+// jwtSigningMethod is the only algorithm tokens are signed and accepted with
+var jwtSigningMethod = jwt.SigningMethodHS256
+
+// jwtKey is an HMAC signing secret identified by the token's kid header
+type jwtKey struct {
+	id     string
+	secret []byte
+}
+
+// newJWTKey derives the key ID from a hash of the secret so it never reveals the secret itself
+func newJWTKey(secret string) jwtKey {
+	sum := sha256.Sum256([]byte(secret))
+	return jwtKey{id: hex.EncodeToString(sum[:4]), secret: []byte(secret)}
+}
+
+// TokenSigner issues and validates access and login challenge tokens
+type TokenSigner struct {
+	// keys holds the primary signing key first, followed by validation-only previous keys
+	keys              []jwtKey
+	expiration        time.Duration
+	refreshExpiration time.Duration
+	issuer            string
+}
+
+// NewTokenSigner creates a signer that signs with secret and also accepts tokens signed
+// with previous secrets during a rotation window. Access tokens expire after expiration,
+// refresh tokens after refreshExpiration, and both carry issuer as their iss claim when
+// it is not empty.
+func NewTokenSigner(secret string, previous []string, expiration, refreshExpiration time.Duration, issuer string) *TokenSigner {
+	keys := []jwtKey{newJWTKey(secret)}
+	for _, prev := range previous {
+		if prev != "" && prev != secret {
+			keys = append(keys, newJWTKey(prev))
+		}
+	}
+
+	return &TokenSigner{
+		keys:              keys,
+		expiration:        expiration,
+		refreshExpiration: refreshExpiration,
+		issuer:            issuer,
+	}
+}
+
+// parserOptions restricts parsing to the signing algorithm, rejecting alg=none and
+// algorithm-substitution tokens, and requires the configured issuer
+func (s *TokenSigner) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwtSigningMethod.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+	return opts
+}
+
+// GenerateToken generates a JWT token signed with the primary secret. An empty scope
+// issues an access token.
+func (s *TokenSigner) GenerateToken(userID int64, email string, roleID int64, sessionID, scope string) (string, error) {
+	if scope == "" {
+		scope = ScopeAccess
+	}
+	return s.sign(userID, email, roleID, sessionID, scope, s.expiration)
+}
+
+// GenerateScopedToken generates a token for a single purpose (reset, verify) that
+// expires after expiration instead of the access token lifetime
+func (s *TokenSigner) GenerateScopedToken(userID int64, email, scope string, expiration time.Duration) (string, error) {
+	return s.sign(userID, email, 0, "", scope, expiration)
+}
+
+// GenerateEmailChangeToken generates a token confirming a change from currentEmail to
+// newEmail. It is only honoured while the user's address is still currentEmail, so once
+// one change is applied the links for every other pending change stop working.
+func (s *TokenSigner) GenerateEmailChangeToken(userID int64, currentEmail, newEmail string, expiration time.Duration) (string, error) {
+	claims := s.claims(userID, newEmail, 0, "", ScopeEmailChange, expiration)
+	claims.PreviousEmail = currentEmail
+	return s.signClaims(claims)
+}
+
+// sign signs a token with the given claims using the primary secret
+func (s *TokenSigner) sign(userID int64, email string, roleID int64, sessionID, scope string, expiration time.Duration) (string, error) {
+	return s.signClaims(s.claims(userID, email, roleID, sessionID, scope, expiration))
+}
+
+// claims builds the claims of a token issued now that expires after expiration
+func (s *TokenSigner) claims(userID int64, email string, roleID int64, sessionID, scope string, expiration time.Duration) *JWTClaims {
+	now := time.Now()
+	return &JWTClaims{
+		UserID:    userID,
+		Email:     email,
+		RoleID:    roleID,
+		SessionID: sessionID,
+		Scope:     scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    s.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(JWTSecret))
+// signClaims signs claims with the primary secret
+func (s *TokenSigner) signClaims(claims *JWTClaims) (string, error) {
+	primary := s.keys[0]
+	token := jwt.NewWithClaims(jwtSigningMethod, claims)
+	token.Header["kid"] = primary.id
+	tokenString, err := token.SignedString(primary.secret)
 	if err != nil {
 		return "", fmt.Errorf("error signing token: %w", err)
 	}
@@ -44,20 +160,213 @@ func GenerateToken(userID int64, email string, roleID int64) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token
-func ValidateToken(tokenString string) (*JWTClaims, error) {
+// ValidateToken validates a JWT token against the primary and previous secrets.
+// The kid header selects the secret directly; tokens without a known kid try each secret.
+func (s *TokenSigner) ValidateToken(tokenString string) (*JWTClaims, error) {
 	claims := &JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(JWTSecret), nil
-	})
+		if kid, ok := token.Header["kid"].(string); ok {
+			for _, key := range s.keys {
+				if key.id == kid {
+					return key.secret, nil
+				}
+			}
+		}
+
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(s.keys))}
+		for _, key := range s.keys {
+			set.Keys = append(set.Keys, key.secret)
+		}
+		return set, nil
+	}, s.parserOptions()...)
 
 	if err != nil {
 		return nil, fmt.Errorf("error parsing token: %w", err)
 	}
 
 	if !token.Valid {
-		return nil, fmt.Errorf("token is invalid")
+		return nil, errors.New("token is invalid")
+	}
+
+	return claims, nil
+}
+
+// IsTokenExpired reports whether a ValidateToken error is caused by the token having expired
+func IsTokenExpired(err error) bool {
+	return errors.Is(err, jwt.ErrTokenExpired)
+}
+
+// LoginChallengeExpiration is how long a login challenge token stays valid
+const LoginChallengeExpiration = 5 * time.Minute
+
+// loginChallengeClaims identify a user who passed the password step of a two-step login.
+// The token ID (jti) names the challenge, which the server keeps open until it is used.
+type loginChallengeClaims struct {
+	UserID int64 `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// derivedSecret derives the signing key of a token purpose from a JWT secret, so
+// challenge and refresh tokens never validate as access tokens and vice versa
+func derivedSecret(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// challengeSecret derives the login challenge signing key from a JWT secret
+func challengeSecret(secret []byte) []byte {
+	return derivedSecret(secret, "login-challenge")
+}
+
+// refreshSecret derives the refresh token signing key from a JWT secret
+func refreshSecret(secret []byte) []byte {
+	return derivedSecret(secret, "refresh-token")
+}
+
+// resetSecret derives the password reset signing key from a JWT secret and the user's
+// current password hash, so a reset token stops validating once the password changes
+func resetSecret(secret []byte, passwordHash string) []byte {
+	return derivedSecret(secret, "password-reset:"+passwordHash)
+}
+
+// GenerateLoginChallenge issues a short-lived token for completing the two-step login
+// identified by challengeID
+func (s *TokenSigner) GenerateLoginChallenge(userID int64, challengeID string) (string, error) {
+	now := time.Now()
+	claims := &loginChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			Issuer:    s.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(LoginChallengeExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwtSigningMethod, claims)
+	tokenString, err := token.SignedString(challengeSecret(s.keys[0].secret))
+	if err != nil {
+		return "", fmt.Errorf("error signing challenge: %w", err)
 	}
+	return tokenString, nil
+}
 
+// ValidateLoginChallenge validates a login challenge token and returns its user and challenge IDs
+func (s *TokenSigner) ValidateLoginChallenge(tokenString string) (int64, string, error) {
+	claims := &loginChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(s.keys))}
+		for _, key := range s.keys {
+			set.Keys = append(set.Keys, challengeSecret(key.secret))
+		}
+		return set, nil
+	}, s.parserOptions()...)
+
+	if err != nil || !token.Valid || claims.ID == "" {
+		return 0, "", errors.New("invalid or expired login challenge")
+	}
+	return claims.UserID, claims.ID, nil
+}
+
+// RefreshClaims identify the user and session a refresh token was issued for
+type RefreshClaims struct {
+	UserID    int64  `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateRefreshToken issues a long-lived token that can be exchanged for new access tokens
+func (s *TokenSigner) GenerateRefreshToken(userID int64, sessionID string) (string, error) {
+	now := time.Now()
+	claims := &RefreshClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.refreshExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwtSigningMethod, claims)
+	tokenString, err := token.SignedString(refreshSecret(s.keys[0].secret))
+	if err != nil {
+		return "", fmt.Errorf("error signing refresh token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// ValidateRefreshToken validates a refresh token. Access and challenge tokens are
+// signed with different keys and are rejected.
+func (s *TokenSigner) ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(s.keys))}
+		for _, key := range s.keys {
+			set.Keys = append(set.Keys, refreshSecret(key.secret))
+		}
+		return set, nil
+	}, s.parserOptions()...)
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired refresh token")
+	}
 	return claims, nil
 }
+
+// GeneratePasswordReset issues a password reset token for a user whose password hash is
+// passwordHash. The token can be used once: changing the password invalidates it, along
+// with every other reset token issued to the user. Issued tokens are not recorded.
+func (s *TokenSigner) GeneratePasswordReset(userID int64, passwordHash string, expiration time.Duration) (string, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID: userID,
+		Scope:  ScopeReset,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwtSigningMethod, claims)
+	tokenString, err := token.SignedString(resetSecret(s.keys[0].secret, passwordHash))
+	if err != nil {
+		return "", fmt.Errorf("error signing reset token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// ValidatePasswordReset validates a password reset token and returns its user ID.
+// passwordHash looks up the user's current password hash, which the signing key is
+// derived from. Expired tokens fail with an error IsTokenExpired recognizes.
+func (s *TokenSigner) ValidatePasswordReset(tokenString string, passwordHash func(userID int64) (string, error)) (int64, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if claims.Scope != ScopeReset {
+			return nil, errors.New("not a password reset token")
+		}
+
+		hash, err := passwordHash(claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(s.keys))}
+		for _, key := range s.keys {
+			set.Keys = append(set.Keys, resetSecret(key.secret, hash))
+		}
+		return set, nil
+	}, s.parserOptions()...)
+
+	if err != nil {
+		return 0, fmt.Errorf("error parsing reset token: %w", err)
+	}
+	if !token.Valid {
+		return 0, errors.New("token is invalid")
+	}
+	return claims.UserID, nil
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenSignerRotation(t *testing.T) {
+	const (
+		current  = "current-secret"
+		previous = "previous-secret"
+		retired  = "retired-secret"
+	)
+	signer := NewTokenSigner(current, []string{previous, "", current}, time.Hour, 24*time.Hour, "")
+
+	// sign signs claims with secret directly, optionally setting a kid header
+	sign := func(t *testing.T, secret, kid string) string {
+		t.Helper()
+
+		now := time.Now()
+		token := jwt.NewWithClaims(jwtSigningMethod, &JWTClaims{
+			UserID: 7,
+			Email:  "user@example.com",
+			Scope:  ScopeAccess,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		tokenString, err := token.SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name      string
+		token     func(t *testing.T) string
+		wantValid bool
+	}{
+		{
+			name: "issued by the signer",
+			token: func(t *testing.T) string {
+				token, err := signer.GenerateToken(7, "user@example.com", 2, "", "")
+				if err != nil {
+					t.Fatalf("error generating token: %v", err)
+				}
+				return token
+			},
+			wantValid: true,
+		},
+		{
+			name: "issued before the rotation",
+			token: func(t *testing.T) string {
+				token, err := NewTokenSigner(previous, nil, time.Hour, 24*time.Hour, "").GenerateToken(7, "user@example.com", 2, "", "")
+				if err != nil {
+					t.Fatalf("error generating token: %v", err)
+				}
+				return token
+			},
+			wantValid: true,
+		},
+		{name: "previous secret without kid", token: func(t *testing.T) string { return sign(t, previous, "") }, wantValid: true},
+		{name: "previous secret with unknown kid", token: func(t *testing.T) string { return sign(t, previous, "unknown") }, wantValid: true},
+		{name: "kid of another key", token: func(t *testing.T) string { return sign(t, previous, newJWTKey(current).id) }, wantValid: false},
+		{name: "retired secret", token: func(t *testing.T) string { return sign(t, retired, "") }, wantValid: false},
+		{name: "retired secret with its kid", token: func(t *testing.T) string { return sign(t, retired, newJWTKey(retired).id) }, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := signer.ValidateToken(tt.token(t))
+			if !tt.wantValid {
+				if err == nil {
+					t.Fatal("expected the token to be rejected, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims.UserID != 7 {
+				t.Errorf("user ID = %d, want 7", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestTokenSignerSignsWithPrimary(t *testing.T) {
+	signer := NewTokenSigner("current-secret", []string{"previous-secret"}, time.Hour, 24*time.Hour, "")
+
+	tokenString, err := signer.GenerateToken(7, "user@example.com", 2, "", "")
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &JWTClaims{})
+	if err != nil {
+		t.Fatalf("error parsing token: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != newJWTKey("current-secret").id {
+		t.Errorf("kid = %v, want the primary key ID %q", kid, newJWTKey("current-secret").id)
+	}
+	if kid := token.Header["kid"].(string); kid == "" || kid == "current-secret" {
+		t.Errorf("kid = %q, want a non-empty ID that does not reveal the secret", kid)
+	}
+
+	// A signer that only knows the previous secret must reject tokens from the new primary
+	if _, err := NewTokenSigner("previous-secret", nil, time.Hour, 24*time.Hour, "").ValidateToken(tokenString); err == nil {
+		t.Error("token signed with the new primary validated against the previous secret only")
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	old := NewTokenSigner("previous-secret", nil, time.Hour, 24*time.Hour, "")
+	rotated := NewTokenSigner("current-secret", []string{"previous-secret"}, time.Hour, 24*time.Hour, "")
+	retired := NewTokenSigner("current-secret", nil, time.Hour, 24*time.Hour, "")
+
+	refresh, err := old.GenerateRefreshToken(7, "session-1")
+	if err != nil {
+		t.Fatalf("error generating refresh token: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		signer    *TokenSigner
+		wantValid bool
+	}{
+		{name: "previous secret still accepted", signer: rotated, wantValid: true},
+		{name: "previous secret dropped", signer: retired, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.ValidateRefreshToken(refresh)
+			if !tt.wantValid {
+				if err == nil {
+					t.Fatal("expected the refresh token to be rejected, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims.UserID != 7 || claims.SessionID != "session-1" {
+				t.Errorf("claims = %+v, want user 7 and session-1", claims)
+			}
+		})
+	}
+}
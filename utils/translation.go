@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// DefaultValidationLocale is the locale of the built-in validation messages
+const DefaultValidationLocale = "en"
+
+// validationLocale is a locale validation messages can be translated into
+type validationLocale struct {
+	translator locales.Translator
+	register   func(v *validator.Validate, trans ut.Translator) error
+
+	// username translates the custom username tag; {0} is the field name
+	username string
+}
+
+// validationLocales are the locales with validation translations, besides English
+var validationLocales = map[string]validationLocale{
+	"es": {es.New(), es_translations.RegisterDefaultTranslations, "{0} debe tener de 3 a 30 letras, dígitos, guiones bajos o puntos y no ser un nombre reservado"},
+	"fr": {fr.New(), fr_translations.RegisterDefaultTranslations, "{0} doit contenir de 3 à 30 lettres, chiffres, tirets bas ou points et ne pas être un nom réservé"},
+	"id": {id.New(), id_translations.RegisterDefaultTranslations, "{0} harus 3-30 huruf, angka, garis bawah atau titik dan bukan nama yang dicadangkan"},
+}
+
+// ValidationTranslator picks the language of validation messages from a request's
+// Accept-Language header. English uses the built-in messages of FormatValidationErrors.
+type ValidationTranslator struct {
+	uni           *ut.UniversalTranslator
+	supported     map[string]bool
+	defaultLocale string
+}
+
+// NewValidationTranslator registers translations for the supported locales on v, which must
+// be the validator whose errors are translated. defaultLocale is used when the request
+// names no supported locale.
+func NewValidationTranslator(v *validator.Validate, supported []string, defaultLocale string) (*ValidationTranslator, error) {
+	t := &ValidationTranslator{
+		uni:           ut.New(en.New()),
+		supported:     map[string]bool{DefaultValidationLocale: true},
+		defaultLocale: defaultLocale,
+	}
+
+	for _, code := range supported {
+		code = strings.ToLower(code)
+		if code == DefaultValidationLocale {
+			continue
+		}
+
+		locale, ok := validationLocales[code]
+		if !ok {
+			return nil, fmt.Errorf("unsupported validation locale %q", code)
+		}
+		if err := t.uni.AddTranslator(locale.translator, true); err != nil {
+			return nil, fmt.Errorf("error adding %s translator: %w", code, err)
+		}
+
+		trans, _ := t.uni.GetTranslator(code)
+		if err := locale.register(v, trans); err != nil {
+			return nil, fmt.Errorf("error registering %s translations: %w", code, err)
+		}
+		if err := registerUsernameTranslation(v, trans, locale.username); err != nil {
+			return nil, fmt.Errorf("error registering %s translations: %w", code, err)
+		}
+		t.supported[code] = true
+	}
+
+	if !t.supported[defaultLocale] {
+		return nil, fmt.Errorf("default validation locale %q is not a supported locale", defaultLocale)
+	}
+	return t, nil
+}
+
+// registerUsernameTranslation translates the custom username tag
+func registerUsernameTranslation(v *validator.Validate, trans ut.Translator, message string) error {
+	return v.RegisterTranslation("username", trans,
+		func(trans ut.Translator) error {
+			return trans.Add("username", message, true)
+		},
+		func(trans ut.Translator, fe validator.FieldError) string {
+			msg, err := trans.T("username", fe.Field())
+			if err != nil {
+				return fe.Error()
+			}
+			return msg
+		},
+	)
+}
+
+// Translator returns the locale negotiated from an Accept-Language header and its
+// translator, or a nil translator for English
+func (t *ValidationTranslator) Translator(acceptLanguage string) (string, ut.Translator) {
+	locale := t.negotiate(acceptLanguage)
+	if locale == DefaultValidationLocale {
+		return locale, nil
+	}
+	trans, _ := t.uni.GetTranslator(locale)
+	return locale, trans
+}
+
+// negotiate returns the supported locale the client prefers most, falling back to the default.
+// Regional tags such as es-MX match their base language.
+func (t *ValidationTranslator) negotiate(acceptLanguage string) string {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		base := strings.SplitN(tag, "-", 2)[0]
+		if t.supported[base] {
+			return base
+		}
+	}
+	return t.defaultLocale
+}
+
+// parseAcceptLanguage returns the lowercase language tags of an Accept-Language header
+// ordered by quality, dropping tags with q=0
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	tags := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, w := range tags {
+		result[i] = w.tag
+	}
+	return result
+}
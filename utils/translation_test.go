@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidationTranslatorMessages(t *testing.T) {
+	type payload struct {
+		Name     string `json:"name" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required,min=6"`
+		Username string `json:"username" validate:"omitempty,username"`
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantLocale     string
+		want           map[string]string
+	}{
+		{
+			name:           "spanish",
+			acceptLanguage: "es",
+			wantLocale:     "es",
+			want: map[string]string{
+				"name":     "name es un campo requerido",
+				"email":    "email debe ser una dirección de correo electrónico válida",
+				"password": "password debe tener al menos 6 caracteres de longitud",
+				"username": "username debe tener de 3 a 30 letras, dígitos, guiones bajos o puntos y no ser un nombre reservado",
+			},
+		},
+		{
+			name:           "french",
+			acceptLanguage: "fr-CA, en;q=0.5",
+			wantLocale:     "fr",
+			want: map[string]string{
+				"name":     "name est un champ obligatoire",
+				"email":    "email doit être une adresse email valide",
+				"password": "password doit faire une taille minimum de 6 caractères",
+				"username": "username doit contenir de 3 à 30 lettres, chiffres, tirets bas ou points et ne pas être un nom réservé",
+			},
+		},
+		{
+			name:           "unsupported locale falls back to english",
+			acceptLanguage: "de",
+			wantLocale:     "en",
+			want: map[string]string{
+				"name":     "is required",
+				"email":    "must be a valid email",
+				"password": "must be at least 6 characters",
+				"username": "must be 3-30 letters, digits, underscores or dots and not a reserved name",
+			},
+		},
+	}
+
+	v := NewValidator()
+	translator, err := NewValidationTranslator(v, []string{"en", "es", "fr"}, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := payload{Email: "not-an-email", Password: "abc", Username: "a b"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locale, trans := translator.Translator(tt.acceptLanguage)
+			if locale != tt.wantLocale {
+				t.Errorf("locale = %q, want %q", locale, tt.wantLocale)
+			}
+
+			fields, omitted := FormatValidationErrors(v.Struct(invalid), 0, trans)
+			if omitted != 0 {
+				t.Errorf("omitted = %d, want 0", omitted)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("fields = %q, want %q", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationTranslatorNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		defaultLocale  string
+		want           string
+	}{
+		{name: "no header", acceptLanguage: "", defaultLocale: "en", want: "en"},
+		{name: "no header with another default", acceptLanguage: "", defaultLocale: "es", want: "es"},
+		{name: "exact match", acceptLanguage: "es", defaultLocale: "en", want: "es"},
+		{name: "regional tag", acceptLanguage: "es-MX", defaultLocale: "en", want: "es"},
+		{name: "upper case tag", acceptLanguage: "ES", defaultLocale: "en", want: "es"},
+		{name: "quality order", acceptLanguage: "en;q=0.4, es;q=0.9", defaultLocale: "en", want: "es"},
+		{name: "first supported tag", acceptLanguage: "de, ja, es", defaultLocale: "en", want: "es"},
+		{name: "zero quality dropped", acceptLanguage: "es;q=0, en", defaultLocale: "es", want: "en"},
+		{name: "wildcard uses default", acceptLanguage: "de, *", defaultLocale: "es", want: "es"},
+		{name: "configured but unsupported locale", acceptLanguage: "fr", defaultLocale: "en", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator, err := NewValidationTranslator(NewValidator(), []string{"en", "es"}, tt.defaultLocale)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			locale, trans := translator.Translator(tt.acceptLanguage)
+			if locale != tt.want {
+				t.Errorf("locale = %q, want %q", locale, tt.want)
+			}
+			if (trans == nil) != (locale == DefaultValidationLocale) {
+				t.Errorf("translator = %v for locale %q, want nil only for english", trans, locale)
+			}
+		})
+	}
+}
+
+func TestNewValidationTranslatorErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		supported     []string
+		defaultLocale string
+		wantErr       bool
+	}{
+		{name: "english only", supported: []string{"en"}, defaultLocale: "en"},
+		{name: "english implied", supported: nil, defaultLocale: "en"},
+		{name: "all locales", supported: []string{"en", "ES", "fr", "id"}, defaultLocale: "id"},
+		{name: "unknown locale", supported: []string{"en", "xx"}, defaultLocale: "en", wantErr: true},
+		{name: "default not supported", supported: []string{"en"}, defaultLocale: "es", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewValidationTranslator(NewValidator(), tt.supported, tt.defaultLocale)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// usernamePattern allows letters, digits, underscores and dots, 3 to 30 characters long
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.]{3,30}$`)
+
+// NewValidator creates a validator with the application's custom validations registered.
+// Field errors are reported under their JSON names.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return IsValidUsername(fl.Field().String())
+	})
+	return v
+}
+
+// IsValidUsername checks the username charset and length; reserved names are checked at registration
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+// FormatValidationErrors converts validator errors into a field -> message map, translating
+// messages with trans when it is not nil. At most max entries are kept (0 keeps all);
+// the number of dropped errors is returned.
+func FormatValidationErrors(err error, max int, trans ut.Translator) (map[string]string, int) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil, 0
+	}
+
+	fields := make(map[string]string, len(validationErrors))
+	dropped := 0
+	for _, fe := range validationErrors {
+		if _, exists := fields[fe.Field()]; exists {
+			continue
+		}
+		if max > 0 && len(fields) >= max {
+			dropped++
+			continue
+		}
+		if trans != nil {
+			fields[fe.Field()] = fe.Translate(trans)
+		} else {
+			fields[fe.Field()] = validationMessage(fe)
+		}
+	}
+
+	return fields, dropped
+}
+
+// validationMessage returns a human-readable message for a field error
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be exactly %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain exactly %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be exactly %s", fe.Param())
+	case "numeric":
+		return "must contain only digits"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "username":
+		return "must be 3-30 letters, digits, underscores or dots and not a reserved name"
+	default:
+		return "is invalid"
+	}
+}
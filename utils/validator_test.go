@@ -0,0 +1,54 @@
+package utils
+
+import "testing"
+
+func TestIsValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"alice", true},
+		{"Alice_Smith.99", true},
+		{"abc", true},
+		{"ab", false},
+		{"abcdefghijklmnopqrstuvwxyz01234", false},
+		{"alice smith", false},
+		{"alice-smith", false},
+		{"alice@example", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			if got := IsValidUsername(tt.username); got != tt.want {
+				t.Errorf("IsValidUsername(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorUsernameTag(t *testing.T) {
+	type payload struct {
+		Username string `json:"username" validate:"omitempty,username"`
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		wantField bool
+	}{
+		{name: "empty is allowed", username: ""},
+		{name: "valid", username: "alice"},
+		{name: "invalid charset", username: "alice!", wantField: true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, _ := FormatValidationErrors(v.Struct(payload{Username: tt.username}), 0, nil)
+			if _, ok := fields["username"]; ok != tt.wantField {
+				t.Errorf("username field error = %v, want %v (fields %v)", ok, tt.wantField, fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+)
+
+// vcardEscaper escapes text values per RFC 6350 section 3.4
+var vcardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	";", `\;`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// BuildVCard renders a minimal vCard 4.0 document for a contact; empty nickname and
+// email are left out
+func BuildVCard(name, email, nickname string) string {
+	var b strings.Builder
+
+	b.WriteString("BEGIN:VCARD\r\n")
+	b.WriteString("VERSION:4.0\r\n")
+	b.WriteString("FN:" + vcardEscaper.Replace(name) + "\r\n")
+	if nickname != "" {
+		b.WriteString("NICKNAME:" + vcardEscaper.Replace(nickname) + "\r\n")
+	}
+	if email != "" {
+		b.WriteString("EMAIL:" + vcardEscaper.Replace(email) + "\r\n")
+	}
+	b.WriteString("END:VCARD\r\n")
+
+	return b.String()
+}
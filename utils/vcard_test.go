@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestBuildVCard(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		email    string
+		nickname string
+		want     string
+	}{
+		{
+			name:     "all fields",
+			fullName: "Alice Smith", email: "alice@example.com", nickname: "alice",
+			want: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Alice Smith\r\nNICKNAME:alice\r\nEMAIL:alice@example.com\r\nEND:VCARD\r\n",
+		},
+		{
+			name:     "hidden email and no username",
+			fullName: "Alice Smith",
+			want:     "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Alice Smith\r\nEND:VCARD\r\n",
+		},
+		{
+			name:     "special characters are escaped",
+			fullName: "Smith, Alice; \\ Jr.\nline",
+			want:     "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Smith\\, Alice\\; \\\\ Jr.\\nline\r\nEND:VCARD\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildVCard(tt.fullName, tt.email, tt.nickname); got != tt.want {
+				t.Errorf("BuildVCard() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
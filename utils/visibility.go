@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Field visibility levels, declared on response DTOs with the `visible` struct tag.
+// Untagged fields are visible to everyone.
+const (
+	// VisibleOwner fields are shown to the resource owner and admins
+	VisibleOwner = "owner"
+
+	// VisibleAdmin fields are shown to admins only
+	VisibleAdmin = "admin"
+)
+
+// Owned is implemented by DTOs whose VisibleOwner fields belong to a user
+type Owned interface {
+	OwnerID() int64
+}
+
+// Viewer identifies who a response is being shaped for
+type Viewer struct {
+	UserID  int64
+	IsAdmin bool
+}
+
+// ApplyVisibility reduces v (a struct or a slice of structs) to the JSON fields viewer may see
+func ApplyVisibility(v interface{}, viewer Viewer) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return v, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return visibleFields(rv, viewer)
+	case reflect.Slice:
+		items := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := ApplyVisibility(rv.Index(i).Interface(), viewer)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return v, nil
+	}
+}
+
+// visibleFields encodes a struct as a map, dropping fields hidden from viewer
+func visibleFields(rv reflect.Value, viewer Viewer) (map[string]interface{}, error) {
+	raw, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	isOwner := false
+	if owned, ok := rv.Interface().(Owned); ok {
+		isOwner = viewer.UserID != 0 && owned.OwnerID() == viewer.UserID
+	}
+
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		switch field.Tag.Get("visible") {
+		case VisibleAdmin:
+			if !viewer.IsAdmin {
+				delete(m, name)
+			}
+		case VisibleOwner:
+			if !viewer.IsAdmin && !isOwner {
+				delete(m, name)
+			}
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// visibilityDTO declares one field at each visibility level
+type visibilityDTO struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email" visible:"owner"`
+	Status string `json:"status" visible:"admin"`
+	Secret string `json:"-"`
+}
+
+func (d visibilityDTO) OwnerID() int64 { return d.ID }
+
+// keys returns the sorted keys of m
+func keys(m interface{}) []string {
+	names := make([]string, 0)
+	for name := range m.(map[string]interface{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestApplyVisibility(t *testing.T) {
+	dto := visibilityDTO{ID: 7, Name: "Alice", Email: "alice@example.com", Status: "active", Secret: "x"}
+
+	tests := []struct {
+		name   string
+		viewer Viewer
+		want   []string
+	}{
+		{name: "admin", viewer: Viewer{UserID: 1, IsAdmin: true}, want: []string{"email", "id", "name", "status"}},
+		{name: "owner", viewer: Viewer{UserID: 7}, want: []string{"email", "id", "name"}},
+		{name: "stranger", viewer: Viewer{UserID: 8}, want: []string{"id", "name"}},
+		{name: "anonymous", viewer: Viewer{}, want: []string{"id", "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyVisibility(&dto, tt.viewer)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(keys(got), tt.want) {
+				t.Errorf("fields = %v, want %v", keys(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyVisibilitySlice(t *testing.T) {
+	dtos := []*visibilityDTO{
+		{ID: 7, Name: "Alice", Email: "alice@example.com"},
+		{ID: 8, Name: "Bob", Email: "bob@example.com"},
+	}
+
+	got, err := ApplyVisibility(dtos, Viewer{UserID: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := got.([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if want := []string{"email", "id", "name"}; !reflect.DeepEqual(keys(items[0]), want) {
+		t.Errorf("own item fields = %v, want %v", keys(items[0]), want)
+	}
+	if want := []string{"id", "name"}; !reflect.DeepEqual(keys(items[1]), want) {
+		t.Errorf("other item fields = %v, want %v", keys(items[1]), want)
+	}
+}
+
+func TestApplyVisibilityPassThrough(t *testing.T) {
+	var nilDTO *visibilityDTO
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "nil pointer", v: nilDTO},
+		{name: "string", v: "plain"},
+		{name: "map", v: map[string]int{"a": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyVisibility(tt.v, Viewer{})
+			if err != nil || !reflect.DeepEqual(got, tt.v) {
+				t.Errorf("ApplyVisibility() = %v, %v; want the value unchanged", got, err)
+			}
+		})
+	}
+}